@@ -0,0 +1,15 @@
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/Alias1177/Auth/internal/app"
+)
+
+func main() {
+	adminApp := app.NewAdminApp()
+	if err := adminApp.Run(os.Args[1:]); err != nil {
+		log.Fatal("Admin command failed:", err)
+	}
+}
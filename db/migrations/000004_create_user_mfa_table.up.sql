@@ -0,0 +1,9 @@
+-- Второй фактор (TOTP). enabled_at = NULL означает, что регистрация не подтверждена кодом.
+-- last_used_step хранит шаг последнего принятого кода для защиты от повторного использования.
+CREATE TABLE IF NOT EXISTS user_mfa (
+    user_id INTEGER PRIMARY KEY REFERENCES UsersLog(id) ON DELETE CASCADE,
+    totp_secret VARCHAR(64) NOT NULL,
+    enabled_at TIMESTAMP WITH TIME ZONE,
+    last_used_step BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
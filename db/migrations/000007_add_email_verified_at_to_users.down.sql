@@ -0,0 +1 @@
+ALTER TABLE UsersLog DROP COLUMN IF EXISTS email_verified_at;
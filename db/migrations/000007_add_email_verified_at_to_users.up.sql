@@ -0,0 +1,12 @@
+-- Подтверждение адреса почты
+ALTER TABLE UsersLog ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMP WITH TIME ZONE;
+
+-- Подтвержденными считаются только учетные записи, владение адресом которых чем-то подтверждено:
+-- созданные входом через провайдера (без пароля) и те, в которые уже входили.
+-- Остальным адрес нужно подтвердить письмом (admin users send-verification): отметка разрешает
+-- автоматическую привязку внешних учетных записей, и ее нельзя выдавать адресу, который никто не подтверждал.
+UPDATE UsersLog u SET email_verified_at = COALESCE(u.created_at, CURRENT_TIMESTAMP)
+WHERE u.email_verified_at IS NULL
+  AND (u.password = ''
+    OR EXISTS (SELECT 1 FROM user_sessions s WHERE s.user_id = u.id)
+    OR EXISTS (SELECT 1 FROM refresh_tokens t WHERE t.user_id = u.id));
@@ -0,0 +1,3 @@
+ALTER TABLE UsersLog DROP COLUMN IF EXISTS locked_until;
+ALTER TABLE UsersLog DROP COLUMN IF EXISTS last_failed_login_at;
+ALTER TABLE UsersLog DROP COLUMN IF EXISTS failed_login_attempts;
@@ -0,0 +1,4 @@
+-- Блокировка учетной записи после серии неудачных входов
+ALTER TABLE UsersLog ADD COLUMN IF NOT EXISTS failed_login_attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE UsersLog ADD COLUMN IF NOT EXISTS last_failed_login_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE UsersLog ADD COLUMN IF NOT EXISTS locked_until TIMESTAMP WITH TIME ZONE;
@@ -0,0 +1,10 @@
+-- Хеши прежних паролей пользователя для запрета повторного использования.
+-- Хранятся только последние PASSWORD_HISTORY_SIZE записей, старые удаляются при добавлении новой.
+CREATE TABLE IF NOT EXISTS password_history (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES UsersLog(id) ON DELETE CASCADE,
+    password_hash VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_password_history_user_id ON password_history(user_id, id DESC);
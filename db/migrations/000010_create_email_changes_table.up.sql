@@ -0,0 +1,10 @@
+-- Незавершенная смена адреса почты: не больше одной на пользователя, новая заявка заменяет прежнюю.
+-- Хранятся только SHA-256 токенов подтверждения (на новый адрес) и отмены (на старый адрес).
+CREATE TABLE IF NOT EXISTS email_changes (
+    user_id INTEGER PRIMARY KEY REFERENCES UsersLog(id) ON DELETE CASCADE,
+    new_email VARCHAR(255) NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    cancel_token_hash VARCHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
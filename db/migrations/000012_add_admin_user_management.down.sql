@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS audit_log;
+ALTER TABLE UsersLog DROP COLUMN IF EXISTS disabled_at;
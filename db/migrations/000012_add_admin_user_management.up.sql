@@ -0,0 +1,16 @@
+-- Отключение учетной записи администратором: вход и обмен refresh токенов запрещены, пока disabled_at задан
+ALTER TABLE UsersLog ADD COLUMN IF NOT EXISTS disabled_at TIMESTAMP WITH TIME ZONE;
+
+-- Журнал действий администраторов над учетными записями.
+-- target_user_id без внешнего ключа: запись об удалении должна пережить удаленного пользователя.
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    actor_id INTEGER REFERENCES UsersLog(id) ON DELETE SET NULL,
+    action VARCHAR(64) NOT NULL,
+    target_user_id INTEGER,
+    details JSONB NOT NULL DEFAULT '{}',
+    ip VARCHAR(64) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_target_user_id ON audit_log(target_user_id, id DESC);
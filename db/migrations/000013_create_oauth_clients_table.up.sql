@@ -0,0 +1,8 @@
+-- Приложения, которые входят через этот сервис по OpenID Connect (authorization code + PKCE).
+-- Код авторизации отправляется только на адреса из redirect_uris (точное совпадение).
+CREATE TABLE IF NOT EXISTS oauth_clients (
+    id VARCHAR(64) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    redirect_uris TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,8 @@
+DELETE FROM permissions WHERE name IN ('clients:read', 'clients:write');
+
+ALTER TABLE oauth_clients
+    DROP COLUMN IF EXISTS scopes,
+    DROP COLUMN IF EXISTS secret_rotated_at,
+    DROP COLUMN IF EXISTS previous_secret_expires_at,
+    DROP COLUMN IF EXISTS previous_secret_hash,
+    DROP COLUMN IF EXISTS secret_hash;
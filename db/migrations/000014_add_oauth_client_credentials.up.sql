@@ -0,0 +1,19 @@
+-- Конфиденциальные клиенты: сервисы, получающие токены по client_credentials.
+-- Секрет хранится как SHA-256: он случайный и длинный, медленный хеш не нужен.
+-- При ротации прежний секрет действует до previous_secret_expires_at, чтобы сервисы успели обновиться.
+ALTER TABLE oauth_clients
+    ADD COLUMN IF NOT EXISTS secret_hash VARCHAR(64),
+    ADD COLUMN IF NOT EXISTS previous_secret_hash VARCHAR(64),
+    ADD COLUMN IF NOT EXISTS previous_secret_expires_at TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN IF NOT EXISTS secret_rotated_at TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN IF NOT EXISTS scopes TEXT[] NOT NULL DEFAULT '{}';
+
+INSERT INTO permissions (name, description) VALUES
+    ('clients:read', 'View OAuth clients'),
+    ('clients:write', 'Register OAuth clients and rotate their secrets')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r CROSS JOIN permissions p
+WHERE r.name = 'admin' AND p.name IN ('clients:read', 'clients:write')
+ON CONFLICT DO NOTHING;
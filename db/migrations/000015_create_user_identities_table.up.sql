@@ -0,0 +1,15 @@
+-- Внешние учетные записи (Google и т.п.), привязанные к пользователю.
+-- Пользователь находится по идентификатору у провайдера, а не по адресу почты:
+-- адрес у провайдера может смениться или принадлежать другому человеку.
+CREATE TABLE IF NOT EXISTS user_identities (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES UsersLog(id) ON DELETE CASCADE,
+    provider VARCHAR(32) NOT NULL,
+    provider_user_id VARCHAR(255) NOT NULL,
+    email VARCHAR(255) NOT NULL DEFAULT '',
+    email_verified BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    UNIQUE (provider, provider_user_id),
+    UNIQUE (user_id, provider)
+);
@@ -0,0 +1,11 @@
+-- Возврат прежнего адреса после подтвержденной смены: ссылка отмены из уведомления на старый адрес
+-- продолжает действовать до expires_at. Хранится только SHA-256 токена отмены.
+CREATE TABLE IF NOT EXISTS email_change_reverts (
+    cancel_token_hash VARCHAR(64) PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES UsersLog(id) ON DELETE CASCADE,
+    old_email VARCHAR(255) NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_change_reverts_user_id ON email_change_reverts(user_id);
@@ -0,0 +1,6 @@
+-- Привязка семейства refresh токенов к приложению, получившему его на /oauth2/token.
+-- Пустой client_id означает вход в сам сервис: такие токены не обмениваются на /oauth2/token,
+-- а токены приложений - на /refresh-token. scope сохраняется, чтобы ротация выдавала те же области доступа.
+ALTER TABLE refresh_tokens
+    ADD COLUMN IF NOT EXISTS client_id VARCHAR(64) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS scope VARCHAR(255) NOT NULL DEFAULT '';
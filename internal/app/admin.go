@@ -0,0 +1,99 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/Alias1177/Auth/internal/config"
+)
+
+// errUsage возвращается при неверном вызове административной утилиты
+var errUsage = errors.New("invalid usage")
+
+const adminUsage = `Использование: admin <группа> <команда> [флаги]
+
+Ключи подписи JWT (каталог JWT_KEYS_DIR или флаг -dir):
+  keys list                             Показать ключи и их окна действия
+  keys generate -alg ES256              Добавить ключ (по умолчанию активен сразу)
+  keys rotate -alg ES256                Запланировать ротацию: новый ключ и вывод текущих
+  keys retire -kid <kid> [-at RFC3339]  Назначить вывод ключа
+  keys prune                            Удалить выведенные ключи
+
+Пользователи (база из DATABASE_DSN):
+  users unlock -email <email> | -id <id> Снять блокировку входа после неудачных попыток
+  users send-verification               Отправить письмо подтверждения всем с неподтвержденным адресом
+
+Роли (база из DATABASE_DSN):
+  roles list                            Показать роли и их разрешения
+  roles assign -email <email> | -id <id> -role <role>
+                                        Назначить роль, например admin
+  roles remove -email <email> | -id <id> -role <role>
+                                        Снять роль
+
+Приложения OpenID Connect и сервисы (база из DATABASE_DSN):
+  clients list                          Показать приложения, их адреса возврата и области доступа
+  clients create -name <name> [-redirect-uris <uri,uri>] [-confidential] [-scopes <scope,scope>]
+                                        Зарегистрировать приложение и выдать client_id
+                                        (конфиденциальному - еще и секрет для client_credentials)
+  clients rotate-secret -id <client_id> [-grace 24h]
+                                        Выдать новый секрет; прежний действует еще grace
+  clients delete -id <client_id>        Удалить приложение
+
+База утекших паролей:
+  breach build-bloom -in <hashes> -out <file> [-fp 0.001]
+                                        Построить фильтр Блума из выгрузки SHA-1 (HASH[:COUNT])
+`
+
+// AdminApp представляет административную утилиту
+type AdminApp struct {
+	config *config.Config
+	out    io.Writer
+}
+
+// NewAdminApp создает новую административную утилиту
+func NewAdminApp() *AdminApp {
+	return &AdminApp{out: os.Stdout}
+}
+
+// Run выполняет административную команду
+func (a *AdminApp) Run(args []string) error {
+	if len(args) < 2 {
+		fmt.Fprint(a.out, adminUsage)
+		return errUsage
+	}
+
+	if err := a.loadConfig(); err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "keys":
+		return a.runKeys(args[1], args[2:])
+	case "users":
+		return a.runUsers(args[1], args[2:])
+	case "breach":
+		return a.runBreach(args[1], args[2:])
+	case "roles":
+		return a.runRoles(args[1], args[2:])
+	case "clients":
+		return a.runClients(args[1], args[2:])
+	default:
+		fmt.Fprint(a.out, adminUsage)
+		return errUsage
+	}
+}
+
+// loadConfig загружает конфигурацию из .env файла или переменных окружения
+func (a *AdminApp) loadConfig() error {
+	cfg, err := config.Load(".env")
+	if err != nil {
+		cfg = &config.Config{}
+		if err := config.LoadFromEnv(cfg); err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+	}
+	a.config = cfg
+	return nil
+}
@@ -0,0 +1,96 @@
+package app
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	crypto "github.com/Alias1177/Auth/pkg/security"
+)
+
+// runBreach выполняет команды подготовки базы утекших паролей
+func (a *AdminApp) runBreach(command string, args []string) error {
+	fs := flag.NewFlagSet("breach "+command, flag.ContinueOnError)
+	fs.SetOutput(a.out)
+
+	var (
+		in     = fs.String("in", "", "Файл SHA-1 хешей, по одному в строке (HASH или HASH:COUNT)")
+		out    = fs.String("out", "", "Файл фильтра Блума для BREACH_BLOOM_PATH")
+		fpRate = fs.Float64("fp", 0.001, "Допустимая доля ложных срабатываний")
+	)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	switch command {
+	case "build-bloom":
+		if *in == "" || *out == "" {
+			return errors.New("-in and -out are required")
+		}
+		return a.buildBreachBloom(*in, *out, *fpRate)
+
+	default:
+		fmt.Fprint(a.out, adminUsage)
+		return errUsage
+	}
+}
+
+// buildBreachBloom строит фильтр Блума по выгрузке хешей; файл читается дважды, чтобы подобрать размер фильтра
+func (a *AdminApp) buildBreachBloom(in, out string, fpRate float64) error {
+	var count uint64
+	if err := scanBreachHashes(in, func(string) error {
+		count++
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	filter := crypto.NewBloomFilter(count, fpRate)
+	if err := scanBreachHashes(in, filter.AddHex); err != nil {
+		return err
+	}
+
+	file, err := os.Create(out)
+	if err != nil {
+		return fmt.Errorf("failed to create bloom filter file: %w", err)
+	}
+	w := bufio.NewWriter(file)
+	if _, err := filter.WriteTo(w); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write bloom filter: %w", err)
+	}
+	if err := w.Flush(); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write bloom filter: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(a.out, "Фильтр на %d хешей записан в %s\n", count, out)
+	return nil
+}
+
+// scanBreachHashes вызывает fn для каждого хеша из выгрузки, отбрасывая счетчики после двоеточия
+func scanBreachHashes(path string, fn func(hash string) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open hash list: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		hash, _, _ := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if hash == "" {
+			continue
+		}
+		if err := fn(hash); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,132 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Alias1177/Auth/internal/domain"
+	"github.com/Alias1177/Auth/internal/repository/postgres"
+	"github.com/Alias1177/Auth/internal/service"
+	"github.com/Alias1177/Auth/pkg/database/connect"
+	"github.com/Alias1177/Auth/pkg/logger"
+)
+
+// runClients выполняет команды регистрации приложений OpenID Connect и сервисов с client_credentials
+func (a *AdminApp) runClients(command string, args []string) error {
+	fs := flag.NewFlagSet("clients "+command, flag.ContinueOnError)
+	fs.SetOutput(a.out)
+
+	var (
+		id           = fs.String("id", "", "client_id приложения")
+		name         = fs.String("name", "", "Название приложения, показывается на странице входа")
+		redirectURIs = fs.String("redirect-uris", "", "Разрешенные адреса возврата через запятую")
+		scopes       = fs.String("scopes", "", "Области доступа для client_credentials через запятую")
+		confidential = fs.Bool("confidential", false, "Выдать секрет (конфиденциальный клиент)")
+		grace        = fs.Duration("grace", 24*time.Hour, "Сколько еще действует прежний секрет после ротации")
+	)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	switch command {
+	case "list":
+		return a.withClients(func(ctx context.Context, clients *service.OAuthClientServiceImpl) error {
+			list, err := clients.ListClients(ctx)
+			if err != nil {
+				return err
+			}
+			for _, c := range list {
+				kind := "public"
+				if c.Confidential() {
+					kind = "confidential"
+				}
+				fmt.Fprintf(a.out, "%-36s  %-24s %-12s %s  %s\n",
+					c.ID, c.Name, kind, strings.Join(c.RedirectURIs, ","), strings.Join(c.Scopes, ","))
+			}
+			return nil
+		})
+
+	case "create":
+		spec := domain.OAuthClientSpec{
+			Name:         *name,
+			RedirectURIs: splitList(*redirectURIs),
+			Scopes:       splitList(*scopes),
+			Confidential: *confidential,
+		}
+		return a.withClients(func(ctx context.Context, clients *service.OAuthClientServiceImpl) error {
+			client, secret, err := clients.RegisterClient(ctx, spec)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(a.out, "Приложение %s зарегистрировано, client_id: %s\n", client.Name, client.ID)
+			if secret != "" {
+				fmt.Fprintf(a.out, "client_secret: %s\nСекрет показывается один раз, сохраните его\n", secret)
+			}
+			return nil
+		})
+
+	case "rotate-secret":
+		if *id == "" {
+			return errors.New("-id is required")
+		}
+		return a.withClients(func(ctx context.Context, clients *service.OAuthClientServiceImpl) error {
+			secret, err := clients.RotateSecret(ctx, *id, *grace)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(a.out, "Новый client_secret приложения %s: %s\nПрежний секрет действует еще %s\n", *id, secret, *grace)
+			return nil
+		})
+
+	case "delete":
+		if *id == "" {
+			return errors.New("-id is required")
+		}
+		return a.withClients(func(ctx context.Context, clients *service.OAuthClientServiceImpl) error {
+			if err := clients.DeleteClient(ctx, *id); err != nil {
+				return err
+			}
+			fmt.Fprintf(a.out, "Приложение %s удалено\n", *id)
+			return nil
+		})
+
+	default:
+		fmt.Fprint(a.out, adminUsage)
+		return errUsage
+	}
+}
+
+// splitList разбирает список значений через запятую, пропуская пустые
+func splitList(raw string) []string {
+	var values []string
+	for _, value := range strings.Split(raw, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// withClients подключается к базе и вызывает fn с сервисом приложений
+func (a *AdminApp) withClients(fn func(ctx context.Context, clients *service.OAuthClientServiceImpl) error) error {
+	ctx := context.Background()
+
+	log, err := logger.New("error")
+	if err != nil {
+		return err
+	}
+	defer log.Close()
+
+	db, err := connect.NewPostgresDB(ctx, a.config.Database.DSN)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	repo := postgres.NewPostgresRepository(db.GetConn(), nil, log)
+	return fn(ctx, service.NewOAuthClientService(repo, log))
+}
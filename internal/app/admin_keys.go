@@ -0,0 +1,125 @@
+package app
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Alias1177/Auth/pkg/jwt"
+)
+
+// runKeys выполняет команды управления ключами подписи JWT
+func (a *AdminApp) runKeys(command string, args []string) error {
+	fs := flag.NewFlagSet("keys "+command, flag.ContinueOnError)
+	fs.SetOutput(a.out)
+
+	var (
+		dir           = fs.String("dir", a.config.JWT.KeysDir, "Каталог связки ключей")
+		alg           = fs.String("alg", jwt.AlgES256, "Алгоритм: HS256, RS256, ES256, EdDSA")
+		activateAfter = fs.Duration("activate-after", 0, "Через сколько новый ключ начнет подписывать токены")
+		retireAfter   = fs.Duration("retire-after", 8*24*time.Hour, "Сколько после активации нового ключа принимать старые (не меньше жизни refresh токена)")
+		kid           = fs.String("kid", "", "Идентификатор ключа")
+		at            = fs.String("at", "", "Момент вывода ключа в формате RFC3339 (по умолчанию сейчас)")
+	)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *dir == "" {
+		return errors.New("keys directory is not set: use -dir or JWT_KEYS_DIR")
+	}
+
+	switch command {
+	case "list":
+		return a.listKeys(*dir)
+
+	case "generate":
+		// При первом запуске ротации по умолчанию ключ активен сразу
+		entry, err := jwt.GenerateKey(*dir, *alg, time.Now().Add(*activateAfter))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(a.out, "Создан ключ %s (%s), активен с %s\n", entry.ID, entry.Algorithm, entry.ActivatesAt.Format(time.RFC3339))
+		return nil
+
+	case "rotate":
+		if *activateAfter == 0 {
+			// Даем потребителям JWKS время закэшировать новый ключ до начала подписи
+			*activateAfter = 15 * time.Minute
+		}
+		entry, err := jwt.RotateKeys(*dir, *alg, *activateAfter, *retireAfter)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(a.out, "Создан ключ %s (%s), станет ключом подписи %s\n", entry.ID, entry.Algorithm, entry.ActivatesAt.Format(time.RFC3339))
+		return a.listKeys(*dir)
+
+	case "retire":
+		if *kid == "" {
+			return errors.New("-kid is required")
+		}
+		retireAt := time.Now()
+		if *at != "" {
+			parsed, err := time.Parse(time.RFC3339, *at)
+			if err != nil {
+				return fmt.Errorf("invalid -at value: %w", err)
+			}
+			retireAt = parsed
+		}
+		if err := jwt.RetireKey(*dir, *kid, retireAt); err != nil {
+			return err
+		}
+		fmt.Fprintf(a.out, "Ключ %s будет выведен %s\n", *kid, retireAt.UTC().Format(time.RFC3339))
+		return nil
+
+	case "prune":
+		pruned, err := jwt.PruneKeys(*dir, time.Now())
+		if err != nil {
+			return err
+		}
+		for _, entry := range pruned {
+			fmt.Fprintf(a.out, "Удален ключ %s\n", entry.ID)
+		}
+		return nil
+
+	default:
+		fmt.Fprint(a.out, adminUsage)
+		return errUsage
+	}
+}
+
+// listKeys печатает ключи связки с их статусом
+func (a *AdminApp) listKeys(dir string) error {
+	manifest, err := jwt.ReadManifest(dir)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	w := tabwriter.NewWriter(a.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "KID\tALG\tSTATUS\tACTIVATES\tRETIRES")
+	for _, entry := range manifest.Keys {
+		retires := "-"
+		if entry.RetiresAt != nil {
+			retires = entry.RetiresAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+			entry.ID, entry.Algorithm, keyStatus(entry, now), entry.ActivatesAt.Format(time.RFC3339), retires)
+	}
+	return w.Flush()
+}
+
+// keyStatus описывает состояние ключа относительно момента now
+func keyStatus(entry jwt.KeyEntry, now time.Time) string {
+	switch {
+	case entry.RetiresAt != nil && !now.Before(*entry.RetiresAt):
+		return "retired"
+	case now.Before(entry.ActivatesAt):
+		return "pending"
+	case entry.RetiresAt != nil:
+		return "retiring"
+	default:
+		return "active"
+	}
+}
@@ -0,0 +1,109 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/Alias1177/Auth/internal/config"
+	"github.com/Alias1177/Auth/internal/domain"
+	"github.com/Alias1177/Auth/internal/repository/postgres"
+	"github.com/Alias1177/Auth/internal/repository/redis"
+	"github.com/Alias1177/Auth/internal/service"
+	"github.com/Alias1177/Auth/pkg/database/connect"
+	"github.com/Alias1177/Auth/pkg/logger"
+)
+
+// runRoles выполняет команды управления ролями; нужны, чтобы назначить первого администратора
+func (a *AdminApp) runRoles(command string, args []string) error {
+	fs := flag.NewFlagSet("roles "+command, flag.ContinueOnError)
+	fs.SetOutput(a.out)
+
+	var (
+		email = fs.String("email", "", "Email пользователя")
+		id    = fs.Int("id", 0, "ID пользователя")
+		role  = fs.String("role", "", "Имя роли")
+	)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	switch command {
+	case "list":
+		return a.withRoles("", 0, func(ctx context.Context, roles *service.RoleServiceImpl, _ int) error {
+			list, err := roles.ListRoles(ctx)
+			if err != nil {
+				return err
+			}
+			for _, r := range list {
+				fmt.Fprintf(a.out, "%-20s %s\n", r.Name, strings.Join(r.Permissions, ","))
+			}
+			return nil
+		})
+
+	case "assign", "remove":
+		if *email == "" && *id == 0 {
+			return errors.New("-email or -id is required")
+		}
+		if *role == "" {
+			return errors.New("-role is required")
+		}
+		return a.withRoles(*email, *id, func(ctx context.Context, roles *service.RoleServiceImpl, userID int) error {
+			if command == "assign" {
+				if err := roles.AssignRole(ctx, domain.AuditActor{}, userID, *role); err != nil {
+					return err
+				}
+				fmt.Fprintf(a.out, "Роль %s назначена пользователю %d\n", *role, userID)
+				return nil
+			}
+			if err := roles.RemoveRole(ctx, domain.AuditActor{}, userID, *role); err != nil {
+				return err
+			}
+			fmt.Fprintf(a.out, "Роль %s снята с пользователя %d\n", *role, userID)
+			return nil
+		})
+
+	default:
+		fmt.Fprint(a.out, adminUsage)
+		return errUsage
+	}
+}
+
+// withRoles подключается к базе и вызывает fn с сервисом ролей; email, если задан, переводится в ID пользователя
+func (a *AdminApp) withRoles(email string, id int, fn func(ctx context.Context, roles *service.RoleServiceImpl, userID int) error) error {
+	ctx := context.Background()
+
+	log, err := logger.New("error")
+	if err != nil {
+		return err
+	}
+	defer log.Close()
+
+	db, err := connect.NewPostgresDB(ctx, a.config.Database.DSN)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	repo := postgres.NewPostgresRepository(db.GetConn(), nil, log)
+	if email != "" {
+		user, err := repo.GetUserByEmail(ctx, email)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("user %s not found", email)
+			}
+			return err
+		}
+		id = user.ID
+	}
+
+	// Снятие роли отзывает access токены пользователя, список отозванных хранится в Redis
+	redisClient := config.NewRedisClient(a.config.Redis)
+	defer redisClient.Close()
+	denylist := service.NewAccessTokenDenylist(redis.NewRedisRepository(redisClient, log))
+
+	return fn(ctx, service.NewRoleService(repo, repo, repo, denylist, log), id)
+}
@@ -0,0 +1,149 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/Alias1177/Auth/internal/config"
+	"github.com/Alias1177/Auth/internal/domain"
+	"github.com/Alias1177/Auth/internal/repository/postgres"
+	"github.com/Alias1177/Auth/internal/repository/redis"
+	"github.com/Alias1177/Auth/internal/service"
+	"github.com/Alias1177/Auth/pkg/database/connect"
+	"github.com/Alias1177/Auth/pkg/jwt"
+	"github.com/Alias1177/Auth/pkg/kafka"
+	"github.com/Alias1177/Auth/pkg/logger"
+)
+
+// runUsers выполняет команды управления пользователями
+func (a *AdminApp) runUsers(command string, args []string) error {
+	fs := flag.NewFlagSet("users "+command, flag.ContinueOnError)
+	fs.SetOutput(a.out)
+
+	var (
+		email = fs.String("email", "", "Email пользователя")
+		id    = fs.Int("id", 0, "ID пользователя")
+	)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	switch command {
+	case "unlock":
+		if *email == "" && *id == 0 {
+			return errors.New("-email or -id is required")
+		}
+		return a.unlockUser(*email, *id)
+
+	case "send-verification":
+		return a.sendVerification()
+
+	default:
+		fmt.Fprint(a.out, adminUsage)
+		return errUsage
+	}
+}
+
+// unlockUser снимает блокировку входа и обнуляет счетчик неудачных попыток
+func (a *AdminApp) unlockUser(email string, id int) error {
+	ctx := context.Background()
+
+	log, err := logger.New("error")
+	if err != nil {
+		return err
+	}
+	defer log.Close()
+
+	db, err := connect.NewPostgresDB(ctx, a.config.Database.DSN)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	repo := postgres.NewPostgresRepository(db.GetConn(), nil, log)
+	if email != "" {
+		user, err := repo.GetUserByEmail(ctx, email)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("user %s not found", email)
+			}
+			return err
+		}
+		id = user.ID
+	}
+
+	if err := repo.ResetFailedLogins(ctx, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("user %d not found", id)
+		}
+		return err
+	}
+	fmt.Fprintf(a.out, "Пользователь %d разблокирован\n", id)
+	return nil
+}
+
+// sendVerificationPageSize сколько неподтвержденных пользователей читается из базы за раз
+const sendVerificationPageSize = 100
+
+// sendVerification отправляет письмо подтверждения всем активным пользователям с неподтвержденным адресом,
+// например учетным записям, которые миграция не отметила подтвержденными
+func (a *AdminApp) sendVerification() error {
+	ctx := context.Background()
+
+	log, err := logger.New("error")
+	if err != nil {
+		return err
+	}
+	defer log.Close()
+
+	db, err := connect.NewPostgresDB(ctx, a.config.Database.DSN)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	redisClient := config.NewRedisClient(a.config.Redis)
+	defer redisClient.Close()
+	if err := redisClient.Ping(ctx).Err(); err != nil {
+		return fmt.Errorf("failed to connect to redis: %w", err)
+	}
+
+	tokenManager, err := jwt.NewJWTTokenManager(a.config.JWT)
+	if err != nil {
+		return err
+	}
+	producer := kafka.NewProducer(a.config.Kafka.BrokerAddress, a.config.Kafka.EmailTopic, log)
+	defer producer.Close()
+
+	repo := postgres.NewPostgresRepository(db.GetConn(), nil, log)
+	verification := service.NewEmailVerificationService(
+		repo, repo, redis.NewRedisRepository(redisClient, log), tokenManager, producer, a.config.EmailVerification, log,
+	)
+
+	// Отправка не меняет отметку подтверждения, поэтому страницы по смещению не сдвигаются
+	sent := 0
+	filter := domain.UserFilter{Status: domain.UserStatusUnverified}
+	for offset := 0; ; offset += sendVerificationPageSize {
+		users, _, err := repo.SearchUsers(ctx, filter, sendVerificationPageSize, offset)
+		if err != nil {
+			return err
+		}
+		for i := range users {
+			if users[i].Disabled() {
+				continue
+			}
+			if err := verification.Send(ctx, &users[i]); err != nil {
+				return fmt.Errorf("failed to send verification to user %d: %w", users[i].ID, err)
+			}
+			sent++
+		}
+		if len(users) < sendVerificationPageSize {
+			break
+		}
+	}
+	fmt.Fprintf(a.out, "Письма подтверждения отправлены: %d\n", sent)
+	return nil
+}
@@ -6,8 +6,10 @@ import (
 
 	"github.com/Alias1177/Auth/db/migrations/manager"
 	"github.com/Alias1177/Auth/internal/config"
+	"github.com/Alias1177/Auth/internal/handler/admin"
 	"github.com/Alias1177/Auth/internal/handler/auth"
 	"github.com/Alias1177/Auth/internal/handler/user"
+	"github.com/Alias1177/Auth/internal/middleware"
 	"github.com/Alias1177/Auth/internal/oauth"
 	"github.com/Alias1177/Auth/internal/repository"
 	"github.com/Alias1177/Auth/internal/repository/postgres"
@@ -15,10 +17,12 @@ import (
 	"github.com/Alias1177/Auth/internal/service"
 	"github.com/Alias1177/Auth/pkg/appcontext"
 	"github.com/Alias1177/Auth/pkg/database/connect"
+	"github.com/Alias1177/Auth/pkg/httputil"
 	"github.com/Alias1177/Auth/pkg/jwt"
 	"github.com/Alias1177/Auth/pkg/kafka"
 	"github.com/Alias1177/Auth/pkg/logger"
 	"github.com/Alias1177/Auth/pkg/notification"
+	crypto "github.com/Alias1177/Auth/pkg/security"
 	"github.com/Alias1177/Auth/pkg/validator"
 	redisClient "github.com/redis/go-redis/v9"
 )
@@ -34,9 +38,24 @@ type Container struct {
 	mainRepo     *repository.Repository
 
 	// Services
-	tokenManager       service.TokenManager
-	kafkaProducer      *kafka.Producer
-	notificationClient *notification.NotificationClient
+	tokenManager        service.TokenManager
+	refreshTokenService *service.RefreshTokenServiceImpl
+	roleService         *service.RoleServiceImpl
+	tokenDenylist       *service.AccessTokenDenylistImpl
+	sessionService      *service.SessionServiceImpl
+	mfaService          *service.MFAServiceImpl
+	recoveryCodeService *service.RecoveryCodeServiceImpl
+	webAuthnService     *service.WebAuthnServiceImpl
+	emailVerification   *service.EmailVerificationServiceImpl
+	emailChangeService  *service.EmailChangeServiceImpl
+	identityService     *service.IdentityServiceImpl
+	lockoutService      *service.LockoutServiceImpl
+	passwordPolicy      *service.PasswordPolicyImpl
+	passwordHistory     *service.PasswordHistoryServiceImpl
+	authService         *service.AuthServiceImpl
+	oidcService         *service.OIDCServiceImpl
+	kafkaProducer       *kafka.Producer
+	notificationClient  *notification.NotificationClient
 
 	// Handlers
 	authHandler          *auth.AuthHandler
@@ -44,6 +63,18 @@ type Container struct {
 	userHandler          *user.UserHandler
 	passwordResetHandler *auth.PasswordResetHandler
 	oauthHandler         *auth.OAuthHandler
+	recoveryHandler      *auth.RecoveryHandler
+	verificationHandler  *auth.EmailVerificationHandler
+	unlockHandler        *auth.UnlockHandler
+	emailChangeHandler   *auth.EmailChangeHandler
+	roleHandler          *admin.RoleHandler
+	adminUserHandler     *admin.UserHandler
+	clientHandler        *admin.ClientHandler
+	oidcHandler          *auth.OIDCHandler
+
+	// Middleware
+	rateLimitMiddleware *middleware.RateLimitMiddleware
+	trustedProxies      httputil.TrustedProxies
 }
 
 // New создает новый контейнер зависимостей
@@ -62,7 +93,7 @@ func New(ctx context.Context, cfg *config.Config, log *logger.Logger) (*Containe
 		return nil, err
 	}
 
-	if err := container.initServices(); err != nil {
+	if err := container.initServices(ctx); err != nil {
 		return nil, err
 	}
 
@@ -157,16 +188,184 @@ func (c *Container) initRepositories() error {
 }
 
 // initServices инициализирует сервисы
-func (c *Container) initServices() error {
+func (c *Container) initServices(ctx context.Context) error {
 	// JWT Token Manager
-	c.tokenManager = jwt.NewJWTTokenManager(c.config.JWT)
+	tokenManager, err := jwt.NewJWTTokenManager(c.config.JWT)
+	if err != nil {
+		c.logger.Errorw("Failed to initialize JWT token manager", "error", err)
+		return err
+	}
+	// Подхватываем ключи, добавленные командой ротации, без рестарта сервиса
+	tokenManager.StartAutoReload(ctx, c.config.JWT.KeysReloadInterval, func(err error) {
+		c.logger.Errorw("Failed to reload JWT key ring", "error", err)
+	})
+	c.tokenManager = tokenManager
+
+	// Алгоритм хеширования паролей
+	hasher, err := crypto.NewHasherFromConfig(
+		c.config.PasswordHash.Algorithm,
+		crypto.Argon2idParams{
+			Memory:      c.config.PasswordHash.Argon2Memory,
+			Time:        c.config.PasswordHash.Argon2Time,
+			Parallelism: c.config.PasswordHash.Argon2Parallelism,
+		},
+		c.config.PasswordHash.BcryptCost,
+	)
+	if err != nil {
+		c.logger.Errorw("Invalid password hash configuration", "error", err)
+		return err
+	}
+	crypto.SetDefaultHasher(hasher)
+
+	// Политика паролей, общая для всех мест, где пароль устанавливается
+	breachChecker, err := newBreachChecker(c.config.BreachCheck)
+	if err != nil {
+		c.logger.Errorw("Invalid breached password check configuration", "error", err)
+		return err
+	}
+	c.passwordPolicy, err = service.NewPasswordPolicy(
+		c.config.PasswordPolicy,
+		breachChecker,
+		c.config.BreachCheck.FailOpen,
+		c.logger,
+	)
+	if err != nil {
+		c.logger.Errorw("Invalid password policy configuration", "error", err)
+		return err
+	}
+	c.passwordHistory = service.NewPasswordHistoryService(c.postgresRepo, c.config.PasswordPolicy.HistorySize, c.logger)
+
+	// Отзыв access токенов при выходе и снятии ролей
+	c.tokenDenylist = service.NewAccessTokenDenylist(c.redisRepo)
+
+	// Роли и разрешения пользователей, записываются в access токены
+	c.roleService = service.NewRoleService(c.postgresRepo, c.mainRepo, c.postgresRepo, c.tokenDenylist, c.logger)
 
+	// Хранилище refresh токенов с ротацией и периодической очисткой истекших записей
+	c.refreshTokenService = service.NewRefreshTokenService(
+		c.postgresRepo,
+		c.tokenManager,
+		c.mainRepo,
+		c.roleService,
+		c.logger,
+	)
+	c.runPeriodic(ctx, time.Hour, c.refreshTokenService.PruneExpired)
+
+	// Сессии пользователей поверх семейств refresh токенов
+	c.sessionService = service.NewSessionService(
+		c.postgresRepo,
+		c.refreshTokenService,
+		c.tokenManager,
+		c.tokenDenylist,
+		c.logger,
+	)
 	// Kafka Producer
 	c.kafkaProducer = kafka.NewProducer(
 		c.config.Kafka.BrokerAddress,
 		c.config.Kafka.EmailTopic,
 		c.logger,
 	)
+	// Второй фактор (TOTP)
+	c.mfaService = service.NewMFAService(
+		c.postgresRepo,
+		c.redisRepo,
+		c.tokenManager,
+		c.config.MFA.Issuer,
+		c.logger,
+	)
+	// Коды восстановления доступа
+	c.recoveryCodeService = service.NewRecoveryCodeService(
+		c.postgresRepo,
+		c.mainRepo,
+		c.postgresRepo,
+		c.redisRepo,
+		c.sessionService,
+		c.passwordPolicy,
+		c.passwordHistory,
+		c.kafkaProducer,
+		c.logger,
+	)
+	// WebAuthn (passkeys)
+	c.webAuthnService, err = service.NewWebAuthnService(
+		c.config.WebAuthn,
+		c.postgresRepo,
+		c.mainRepo,
+		c.redisRepo,
+		c.logger,
+	)
+	if err != nil {
+		c.logger.Errorw("Failed to initialize WebAuthn", "error", err)
+		return err
+	}
+
+	// Подтверждение почты (письма отправляются через Kafka)
+	c.emailVerification = service.NewEmailVerificationService(
+		c.postgresRepo,
+		c.mainRepo,
+		c.redisRepo,
+		c.tokenManager,
+		c.kafkaProducer,
+		c.config.EmailVerification,
+		c.logger,
+	)
+
+	// Смена адреса почты с подтверждением нового адреса и уведомлением старого
+	c.emailChangeService = service.NewEmailChangeService(
+		c.postgresRepo,
+		c.mainRepo,
+		c.redisRepo,
+		c.sessionService,
+		c.mfaService,
+		c.kafkaProducer,
+		c.config.EmailVerification,
+		c.logger,
+	)
+
+	// Вход через внешних провайдеров и привязка их учетных записей к пользователям
+	c.identityService = service.NewIdentityService(
+		c.postgresRepo,
+		c.mainRepo,
+		c.postgresRepo,
+		c.redisRepo,
+		c.config.Identity,
+		c.logger,
+	)
+
+	// Блокировка входа после неудачных попыток (письма разблокировки через Kafka)
+	c.lockoutService = service.NewLockoutService(
+		c.postgresRepo,
+		c.redisRepo,
+		c.tokenManager,
+		c.kafkaProducer,
+		c.config.Lockout,
+		c.logger,
+	)
+
+	// Вход по паролю и выход, общие для /login и страницы входа OpenID Connect
+	c.authService = service.NewAuthService(
+		c.mainRepo,
+		c.tokenManager,
+		c.refreshTokenService,
+		c.sessionService,
+		c.tokenDenylist,
+		c.lockoutService,
+		c.emailVerification,
+		c.logger,
+	)
+
+	// Провайдер OpenID Connect для других приложений
+	c.oidcService = service.NewOIDCService(
+		c.postgresRepo,
+		c.mainRepo,
+		c.redisRepo,
+		c.sessionService,
+		c.tokenManager,
+		c.config.OIDC,
+		c.logger,
+	)
+	if len(c.tokenManager.JWKS().Keys) == 0 {
+		c.logger.Warnw("JWT tokens are signed with a shared secret: applications cannot verify OIDC ID tokens, configure an asymmetric JWT key")
+	}
 
 	// Notification Client
 	c.notificationClient = notification.NewNotificationClient(c.config.Notification.ServiceURL)
@@ -178,6 +377,11 @@ func (c *Container) initServices() error {
 func (c *Container) initHandlers() error {
 	c.authHandler = auth.NewAuthHandler(
 		c.tokenManager,
+		c.sessionService,
+		c.mfaService,
+		c.webAuthnService,
+		c.emailVerification,
+		c.authService,
 		c.config.JWT,
 		c.mainRepo,
 		c.logger,
@@ -186,37 +390,142 @@ func (c *Container) initHandlers() error {
 	c.registrationHandler = auth.NewRegistrationHandler(
 		c.mainRepo,
 		c.tokenManager,
+		c.sessionService,
+		c.emailVerification,
+		c.passwordPolicy,
+		c.passwordHistory,
 		c.config.JWT,
 		c.logger,
 		c.kafkaProducer,
 	)
 
-	c.userHandler = user.NewUserHandler(c.mainRepo, c.logger)
+	// Валидатор
+	validator := validator.New()
+
+	c.userHandler = user.NewUserHandler(
+		c.mainRepo,
+		c.sessionService,
+		c.mfaService,
+		c.recoveryCodeService,
+		c.webAuthnService,
+		c.emailChangeService,
+		c.identityService,
+		c.lockoutService,
+		c.passwordPolicy,
+		c.passwordHistory,
+		c.kafkaProducer,
+		validator,
+		c.logger,
+	)
 
 	// Инициализация сервиса сброса пароля
 	passwordResetService := service.NewPasswordResetService(
 		c.mainRepo,
 		c.redisRepo,
+		c.passwordPolicy,
+		c.passwordHistory,
 		c.logger,
 		c.kafkaProducer,
 		c.notificationClient,
 	)
 
-	// Валидатор
-	validator := validator.New()
-
 	c.passwordResetHandler = auth.NewPasswordResetHandler(
 		passwordResetService,
 		validator,
 		c.logger,
 	)
 
+	c.recoveryHandler = auth.NewRecoveryHandler(c.recoveryCodeService, validator, c.logger)
+
+	c.verificationHandler = auth.NewEmailVerificationHandler(c.emailVerification, validator, c.logger)
+
+	c.unlockHandler = auth.NewUnlockHandler(c.lockoutService, validator, c.logger)
+
+	c.emailChangeHandler = auth.NewEmailChangeHandler(c.emailChangeService, validator, c.logger)
+
+	c.roleHandler = admin.NewRoleHandler(c.roleService, validator, c.logger)
+
+	// Управление учетными записями администратором с записью действий в журнал аудита
+	adminUserService := service.NewAdminUserService(
+		c.postgresRepo,
+		c.postgresRepo,
+		c.mainRepo,
+		c.redisRepo,
+		c.sessionService,
+		c.passwordPolicy,
+		c.passwordHistory,
+		passwordResetService,
+		c.logger,
+	)
+	c.adminUserHandler = admin.NewUserHandler(adminUserService, c.roleService, validator, c.logger)
+
+	c.clientHandler = admin.NewClientHandler(service.NewOAuthClientService(c.postgresRepo, c.logger), validator, c.logger)
+
+	// Интроспекция и отзыв токенов для шлюзов и приложений учитывают отзыв сессий и токенов на сервере
+	tokenIntrospection := service.NewTokenIntrospectionService(
+		c.postgresRepo,
+		c.postgresRepo,
+		c.sessionService,
+		c.tokenDenylist,
+		c.tokenManager,
+		c.logger,
+	)
+	c.oidcHandler = auth.NewOIDCHandler(
+		c.oidcService,
+		tokenIntrospection,
+		c.authService,
+		c.mfaService,
+		c.tokenManager,
+		c.mainRepo,
+		c.config.OIDC,
+		c.logger,
+	)
+
 	// Инициализация OAuth handler
-	c.oauthHandler = auth.NewOAuthService(c.logger, c.tokenManager, c.mainRepo)
+	c.oauthHandler = auth.NewOAuthService(
+		c.logger,
+		c.tokenManager,
+		c.sessionService,
+		c.identityService,
+		c.mfaService,
+		c.emailVerification,
+	)
+
+	// Ограничение частоты запросов к ручкам аутентификации
+	rateLimitMiddleware, err := middleware.NewRateLimitMiddleware(c.redisRepo, c.config.RateLimit, c.logger)
+	if err != nil {
+		c.logger.Errorw("Invalid rate limit configuration", "error", err)
+		return err
+	}
+	c.rateLimitMiddleware = rateLimitMiddleware
+
+	// Адрес клиента из X-Forwarded-For принимается только от доверенных прокси
+	trustedProxies, err := httputil.ParseTrustedProxies(c.config.App.TrustedProxies)
+	if err != nil {
+		c.logger.Errorw("Invalid trusted proxies configuration", "error", err)
+		return err
+	}
+	c.trustedProxies = trustedProxies
 
 	return nil
 }
 
+// runPeriodic выполняет задачу с заданным интервалом до отмены контекста
+func (c *Container) runPeriodic(ctx context.Context, interval time.Duration, task func(ctx context.Context)) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				task(ctx)
+			}
+		}
+	}()
+}
+
 // RunMigrations запускает миграции базы данных
 func (c *Container) RunMigrations(ctx context.Context) error {
 	dbContext := appcontext.GetInstance()
@@ -262,6 +571,10 @@ func (c *Container) GetTokenManager() service.TokenManager {
 	return c.tokenManager
 }
 
+func (c *Container) GetTokenDenylist() service.AccessTokenDenylist {
+	return c.tokenDenylist
+}
+
 func (c *Container) GetLogger() *logger.Logger {
 	return c.logger
 }
@@ -270,6 +583,46 @@ func (c *Container) GetOAuthHandler() *auth.OAuthHandler {
 	return c.oauthHandler
 }
 
+func (c *Container) GetRecoveryHandler() *auth.RecoveryHandler {
+	return c.recoveryHandler
+}
+
+func (c *Container) GetEmailVerificationHandler() *auth.EmailVerificationHandler {
+	return c.verificationHandler
+}
+
+func (c *Container) GetUnlockHandler() *auth.UnlockHandler {
+	return c.unlockHandler
+}
+
+func (c *Container) GetEmailChangeHandler() *auth.EmailChangeHandler {
+	return c.emailChangeHandler
+}
+
+func (c *Container) GetRoleHandler() *admin.RoleHandler {
+	return c.roleHandler
+}
+
+func (c *Container) GetAdminUserHandler() *admin.UserHandler {
+	return c.adminUserHandler
+}
+
+func (c *Container) GetClientHandler() *admin.ClientHandler {
+	return c.clientHandler
+}
+
+func (c *Container) GetOIDCHandler() *auth.OIDCHandler {
+	return c.oidcHandler
+}
+
+func (c *Container) GetRateLimitMiddleware() *middleware.RateLimitMiddleware {
+	return c.rateLimitMiddleware
+}
+
+func (c *Container) GetTrustedProxies() httputil.TrustedProxies {
+	return c.trustedProxies
+}
+
 // Close закрывает все соединения
 func (c *Container) Close() {
 	if c.kafkaProducer != nil {
@@ -281,3 +634,30 @@ func (c *Container) Close() {
 		dbContext.Close()
 	}
 }
+
+// newBreachChecker собирает проверку по базе утечек из настроенных источников; nil, если источников нет
+func newBreachChecker(cfg config.BreachCheckConfig) (crypto.BreachChecker, error) {
+	var checkers crypto.MultiBreachChecker
+	if cfg.BloomPath != "" {
+		filter, err := crypto.LoadBloomFilter(cfg.BloomPath)
+		if err != nil {
+			return nil, err
+		}
+		checkers = append(checkers, filter)
+	}
+	if cfg.RangeDir != "" {
+		rangeDir, err := crypto.NewRangeDirChecker(cfg.RangeDir)
+		if err != nil {
+			return nil, err
+		}
+		checkers = append(checkers, rangeDir)
+	}
+	if cfg.RangeURL != "" {
+		checkers = append(checkers, crypto.NewRangeClient(cfg.RangeURL, cfg.RangeTimeout))
+	}
+
+	if len(checkers) == 0 {
+		return nil, nil
+	}
+	return checkers, nil
+}
@@ -1,7 +1,11 @@
 package config
 
 import (
+	"fmt"
 	"log"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
 	"github.com/redis/go-redis/v9"
@@ -14,8 +18,138 @@ type RedisConfig struct {
 	DB       int    `env:"REDIS_DB" env-default:"0"`
 }
 
+// JWTConfig конфигурация подписи JWT токенов.
+// Если задан JWT_PRIVATE_KEY (PEM) или JWT_PRIVATE_KEY_PATH, токены подписываются
+// асимметричным ключом (RS256/ES256/EdDSA), иначе используется HS256 с JWT_SECRET.
 type JWTConfig struct {
-	Secret string `env:"JWT_SECRET"`
+	Secret         string `env:"JWT_SECRET"`
+	Algorithm      string `env:"JWT_ALGORITHM" env-default:""`
+	PrivateKey     string `env:"JWT_PRIVATE_KEY" env-default:""`
+	PrivateKeyPath string `env:"JWT_PRIVATE_KEY_PATH" env-default:""`
+	KeyID          string `env:"JWT_KEY_ID" env-default:""`
+
+	// Связка ключей с ротацией. Если каталог задан, ключ из JWT_SECRET/JWT_PRIVATE_KEY
+	// только проверяет ранее выпущенные токены.
+	KeysDir            string        `env:"JWT_KEYS_DIR" env-default:""`
+	KeysReloadInterval time.Duration `env:"JWT_KEYS_RELOAD_INTERVAL" env-default:"1m"`
+}
+
+// MFAConfig конфигурация второго фактора
+type MFAConfig struct {
+	// Issuer название сервиса, отображаемое в приложении-аутентификаторе
+	Issuer string `env:"MFA_ISSUER" env-default:"Auth"`
+}
+
+// EmailVerificationConfig конфигурация подтверждения почты
+type EmailVerificationConfig struct {
+	// Required запрещает вход по паролю до подтверждения адреса
+	Required bool          `env:"EMAIL_VERIFICATION_REQUIRED" env-default:"false"`
+	TokenTTL time.Duration `env:"EMAIL_VERIFICATION_TTL" env-default:"24h"`
+	// ChangeTTL срок действия ссылок подтверждения и отмены смены адреса
+	ChangeTTL time.Duration `env:"EMAIL_CHANGE_TTL" env-default:"24h"`
+}
+
+// WebAuthnConfig конфигурация WebAuthn (passkeys).
+// RPID - домен сайта без схемы и порта, RPOrigins - полные origin фронтенда, с которых выполняются церемонии.
+type WebAuthnConfig struct {
+	RPID          string   `env:"WEBAUTHN_RP_ID" env-default:"localhost"`
+	RPDisplayName string   `env:"WEBAUTHN_RP_NAME" env-default:"Auth"`
+	RPOrigins     []string `env:"WEBAUTHN_RP_ORIGINS" env-separator:"," env-default:"http://localhost:3000"`
+}
+
+// OIDCConfig провайдер OpenID Connect для других приложений.
+// Issuer - внешний URL сервиса без завершающего слеша: из него строятся адреса в discovery документе,
+// и он же записывается в claim iss ID токенов.
+type OIDCConfig struct {
+	Issuer     string        `env:"OIDC_ISSUER" env-default:"http://localhost:8080"`
+	CodeTTL    time.Duration `env:"OIDC_CODE_TTL" env-default:"1m"`
+	IDTokenTTL time.Duration `env:"OIDC_ID_TOKEN_TTL" env-default:"1h"`
+	// Срок жизни access токенов сервисов, выданных по client_credentials; refresh токенов у них нет
+	ClientTokenTTL time.Duration `env:"OIDC_CLIENT_TOKEN_TTL" env-default:"15m"`
+	// Вход на устройствах без браузера (RFC 8628): срок жизни кодов и минимальный интервал опроса
+	DeviceCodeTTL      time.Duration `env:"OIDC_DEVICE_CODE_TTL" env-default:"10m"`
+	DevicePollInterval time.Duration `env:"OIDC_DEVICE_POLL_INTERVAL" env-default:"5s"`
+}
+
+// PasswordHashConfig конфигурация хеширования паролей.
+// Хеши другого алгоритма или с другими параметрами пересчитываются при следующем входе.
+type PasswordHashConfig struct {
+	Algorithm         string `env:"PASSWORD_HASH_ALGORITHM" env-default:"argon2id"`
+	Argon2Memory      uint32 `env:"PASSWORD_ARGON2_MEMORY" env-default:"65536"` // KiB
+	Argon2Time        uint32 `env:"PASSWORD_ARGON2_TIME" env-default:"3"`
+	Argon2Parallelism uint8  `env:"PASSWORD_ARGON2_PARALLELISM" env-default:"2"`
+	BcryptCost        int    `env:"PASSWORD_BCRYPT_COST" env-default:"10"`
+}
+
+// PasswordPolicyConfig политика паролей, единая для регистрации, смены, сброса и установки пароля администратором.
+// DictionaryPath указывает файл со словами (по одному в строке), дополняющий встроенный список распространенных паролей.
+// HistorySize последних паролей нельзя установить повторно.
+type PasswordPolicyConfig struct {
+	MinLength      int    `env:"PASSWORD_MIN_LENGTH" env-default:"8"`
+	MaxLength      int    `env:"PASSWORD_MAX_LENGTH" env-default:"128"`
+	RequireUpper   bool   `env:"PASSWORD_REQUIRE_UPPER" env-default:"true"`
+	RequireLower   bool   `env:"PASSWORD_REQUIRE_LOWER" env-default:"true"`
+	RequireDigit   bool   `env:"PASSWORD_REQUIRE_DIGIT" env-default:"true"`
+	RequireSpecial bool   `env:"PASSWORD_REQUIRE_SPECIAL" env-default:"true"`
+	DisallowUser   bool   `env:"PASSWORD_DISALLOW_USER_INFO" env-default:"true"`
+	DictionaryPath string `env:"PASSWORD_DICTIONARY_PATH"`
+	HistorySize    int    `env:"PASSWORD_HISTORY_SIZE" env-default:"5"` // 0 отключает историю
+}
+
+// BreachCheckConfig проверка паролей по базе утечек. Источники можно сочетать:
+// каталог файлов диапазонов SHA-1, фильтр Блума и range API (k-anonymity) внутреннего зеркала.
+// Если ни один источник не задан, проверка отключена.
+type BreachCheckConfig struct {
+	RangeDir     string        `env:"BREACH_RANGE_DIR"`
+	BloomPath    string        `env:"BREACH_BLOOM_PATH"`
+	RangeURL     string        `env:"BREACH_RANGE_URL"`
+	RangeTimeout time.Duration `env:"BREACH_RANGE_TIMEOUT" env-default:"2s"`
+	FailOpen     bool          `env:"BREACH_FAIL_OPEN" env-default:"true"`
+}
+
+// LockoutConfig блокировка учетной записи после неудачных входов.
+// После Threshold неудач подряд вход блокируется на BaseDuration, каждая следующая неудача удваивает срок до MaxDuration.
+type LockoutConfig struct {
+	Threshold      int           `env:"LOCKOUT_THRESHOLD" env-default:"5"`
+	BaseDuration   time.Duration `env:"LOCKOUT_BASE_DURATION" env-default:"1m"`
+	MaxDuration    time.Duration `env:"LOCKOUT_MAX_DURATION" env-default:"24h"`
+	ResetAfter     time.Duration `env:"LOCKOUT_RESET_AFTER" env-default:"24h"`
+	UnlockTokenTTL time.Duration `env:"LOCKOUT_UNLOCK_TTL" env-default:"1h"`
+}
+
+// RateLimitConfig ограничение частоты запросов к ручкам аутентификации.
+// Лимиты задаются в формате "запросов/окно", например "10/1m", и применяются отдельно к IP и к email.
+type RateLimitConfig struct {
+	Enabled              bool   `env:"RATE_LIMIT_ENABLED" env-default:"true"`
+	Login                string `env:"RATE_LIMIT_LOGIN" env-default:"10/1m"`
+	Register             string `env:"RATE_LIMIT_REGISTER" env-default:"5/1h"`
+	PasswordResetRequest string `env:"RATE_LIMIT_PASSWORD_RESET_REQUEST" env-default:"3/15m"`
+	PasswordResetConfirm string `env:"RATE_LIMIT_PASSWORD_RESET_CONFIRM" env-default:"10/15m"`
+	// Reauth - повторная проверка пароля вошедшим пользователем (смена пароля и т.п.), лимит на пользователя
+	Reauth string `env:"RATE_LIMIT_REAUTH" env-default:"5/15m"`
+}
+
+// RateLimit лимит запросов в скользящем окне
+type RateLimit struct {
+	Requests int
+	Window   time.Duration
+}
+
+// ParseRateLimit разбирает лимит в формате "запросов/окно"
+func ParseRateLimit(value string) (RateLimit, error) {
+	requests, window, ok := strings.Cut(strings.TrimSpace(value), "/")
+	if !ok {
+		return RateLimit{}, fmt.Errorf("invalid rate limit %q: expected requests/window", value)
+	}
+	n, err := strconv.Atoi(requests)
+	if err != nil || n <= 0 {
+		return RateLimit{}, fmt.Errorf("invalid rate limit %q: requests must be a positive number", value)
+	}
+	d, err := time.ParseDuration(window)
+	if err != nil || d <= 0 {
+		return RateLimit{}, fmt.Errorf("invalid rate limit %q: window must be a positive duration", value)
+	}
+	return RateLimit{Requests: n, Window: d}, nil
 }
 
 // DatabaseConfig конфигурация для PostgreSQL
@@ -47,6 +181,9 @@ type SentryConfig struct {
 type AppConfig struct {
 	Environment string `env:"APP_ENV" env-default:"development"`
 	Debug       bool   `env:"APP_DEBUG" env-default:"true"`
+	// TrustedProxies сети (CIDR) или адреса прокси, которым разрешено передавать адрес клиента
+	// в X-Forwarded-For и X-Real-IP. Пустой список - адрес клиента берется из соединения.
+	TrustedProxies []string `env:"TRUSTED_PROXIES" env-separator:"," env-default:""`
 }
 
 type GoogleConfig struct {
@@ -55,16 +192,34 @@ type GoogleConfig struct {
 	RedirectURL  string `env:"GOOGLE_REDIRECT_URL"`
 }
 
+// IdentityConfig политика привязки внешних учетных записей (Google и т.п.)
+type IdentityConfig struct {
+	// AutoLinkVerifiedEmail - первый вход через провайдера привязывается к пользователю с тем же адресом,
+	// только если адрес подтвержден и провайдером, и у нас. Иначе учетную запись привязывают вручную после входа.
+	AutoLinkVerifiedEmail bool `env:"OAUTH_AUTO_LINK_VERIFIED_EMAIL" env-default:"true"`
+}
+
 // Config общая конфигурация приложения
 type Config struct {
 	App          AppConfig
 	Database     DatabaseConfig
 	Redis        RedisConfig
 	JWT          JWTConfig
+	MFA          MFAConfig
+	WebAuthn     WebAuthnConfig
+	OIDC         OIDCConfig
 	Kafka        KafkaConfig
 	Notification NotificationConfig
 	Sentry       SentryConfig
 	Google       GoogleConfig
+	Identity     IdentityConfig
+
+	EmailVerification EmailVerificationConfig
+	RateLimit         RateLimitConfig
+	Lockout           LockoutConfig
+	PasswordHash      PasswordHashConfig
+	PasswordPolicy    PasswordPolicyConfig
+	BreachCheck       BreachCheckConfig
 }
 
 // NewRedisClient создает новый клиент Redis на основе конфигурации
@@ -1,6 +1,9 @@
 package domain
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // User - модель пользователя с валидацией
 type User struct {
@@ -10,6 +13,30 @@ type User struct {
 	Password  string    `db:"password" json:"password" validate:"required"`
 	CreatedAt time.Time `db:"created_at" json:"created_at,omitempty"`
 	UpdatedAt time.Time `db:"updated_at" json:"updated_at,omitempty"`
+
+	EmailVerifiedAt *time.Time `db:"email_verified_at" json:"email_verified_at,omitempty"`
+
+	// Блокировка после неудачных попыток входа
+	FailedLoginAttempts int        `db:"failed_login_attempts" json:"-"`
+	LockedUntil         *time.Time `db:"locked_until" json:"-"`
+
+	// DisabledAt время отключения учетной записи администратором
+	DisabledAt *time.Time `db:"disabled_at" json:"disabled_at,omitempty"`
+}
+
+// IsLocked сообщает, заблокирован ли вход пользователя на момент now
+func (u *User) IsLocked(now time.Time) bool {
+	return u.LockedUntil != nil && now.Before(*u.LockedUntil)
+}
+
+// EmailVerified сообщает, подтвержден ли адрес почты пользователя
+func (u *User) EmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+// Disabled сообщает, отключена ли учетная запись администратором
+func (u *User) Disabled() bool {
+	return u.DisabledAt != nil
 }
 
 // UserClaims - модель токена с валидацией
@@ -17,4 +44,413 @@ type UserClaims struct {
 	UserID    string `json:"user_id"`
 	Email     string `json:"email,omitempty"`
 	ExpiresAt int64  `json:"exp,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+	// IssuedAtMilli время выдачи с точностью до миллисекунд: отзыв всех токенов пользователя
+	// не должен пропускать токены, выданные в ту же секунду
+	IssuedAtMilli int64  `json:"-"`
+	TokenID       string `json:"jti,omitempty"`
+	FamilyID      string `json:"fid,omitempty"`
+	// Roles и Permissions роли пользователя и разрешения этих ролей на момент выдачи токена
+	Roles       []string `json:"roles,omitempty"`
+	Permissions []string `json:"perms,omitempty"`
+	// ClientID и Scope задаются у токенов, выданных приложению через /oauth2/token (claims aud и scope).
+	// Такие токены не несут ролей и действуют только на /oauth2/userinfo.
+	ClientID string `json:"aud,omitempty"`
+	Scope    string `json:"scope,omitempty"`
+}
+
+// HasPermission сообщает, есть ли у владельца токена разрешение permission
+func (c *UserClaims) HasPermission(permission string) bool {
+	for _, p := range c.Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// RefreshToken - запись о выданном refresh токене.
+// Все токены, полученные ротацией от одного входа, принадлежат одному семейству (FamilyID).
+// ClientID - приложение, которому выдано семейство; пусто для входа в сам сервис.
+type RefreshToken struct {
+	ID        string     `db:"id"`
+	FamilyID  string     `db:"family_id"`
+	UserID    int        `db:"user_id"`
+	Device    string     `db:"device"`
+	ClientID  string     `db:"client_id"`
+	Scope     string     `db:"scope"`
+	IssuedAt  time.Time  `db:"issued_at"`
+	ExpiresAt time.Time  `db:"expires_at"`
+	UsedAt    *time.Time `db:"used_at"`
+	RevokedAt *time.Time `db:"revoked_at"`
+}
+
+// TokenPair - пара токенов, выдаваемая при входе и обновлении
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Session - вход пользователя с конкретного устройства.
+// Идентификатор сессии совпадает с семейством refresh токенов этого входа.
+type Session struct {
+	ID          string     `db:"id" json:"id"`
+	UserID      int        `db:"user_id" json:"-"`
+	DeviceName  string     `db:"device_name" json:"device_name"`
+	UserAgent   string     `db:"user_agent" json:"user_agent"`
+	IPAddress   string     `db:"ip_address" json:"ip_address"`
+	LoginMethod string     `db:"login_method" json:"login_method"`
+	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
+	LastUsedAt  time.Time  `db:"last_used_at" json:"last_used_at"`
+	RevokedAt   *time.Time `db:"revoked_at" json:"-"`
+}
+
+// ClientInfo - сведения о клиенте, с которого выполняется вход
+type ClientInfo struct {
+	DeviceName  string
+	UserAgent   string
+	IPAddress   string
+	LoginMethod string
+}
+
+// Способы входа, сохраняемые в сессии
+const (
+	LoginMethodPassword = "password"
+	LoginMethodRegister = "register"
+	LoginMethodOAuth    = "oauth"
+	LoginMethodMFA      = "password+totp"
+	LoginMethodWebAuthn = "webauthn"
+	LoginMethodDevice   = "device"
+)
+
+// UserMFA - настройки второго фактора пользователя
+type UserMFA struct {
+	UserID       int        `db:"user_id"`
+	TOTPSecret   string     `db:"totp_secret"`
+	EnabledAt    *time.Time `db:"enabled_at"`
+	LastUsedStep int64      `db:"last_used_step"`
+	CreatedAt    time.Time  `db:"created_at"`
+}
+
+// Enabled сообщает, подтверждена ли регистрация TOTP
+func (m *UserMFA) Enabled() bool {
+	return m.EnabledAt != nil
+}
+
+// TOTPEnrollment - данные для добавления секрета в приложение-аутентификатор
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+// WebAuthnCredential - зарегистрированный ключ WebAuthn (passkey)
+type WebAuthnCredential struct {
+	ID              []byte     `db:"id"`
+	UserID          int        `db:"user_id"`
+	Name            string     `db:"name"`
+	PublicKey       []byte     `db:"public_key"`
+	AttestationType string     `db:"attestation_type"`
+	Transports      string     `db:"transports"`
+	AAGUID          []byte     `db:"aaguid"`
+	SignCount       int64      `db:"sign_count"`
+	BackupEligible  bool       `db:"backup_eligible"`
+	BackupState     bool       `db:"backup_state"`
+	CreatedAt       time.Time  `db:"created_at"`
+	LastUsedAt      *time.Time `db:"last_used_at"`
+}
+
+// UserIdentity - внешняя учетная запись (Google и т.п.), привязанная к пользователю.
+// Email и EmailVerified - как их сообщил провайдер при последнем входе.
+type UserIdentity struct {
+	ID             int        `db:"id"`
+	UserID         int        `db:"user_id"`
+	Provider       string     `db:"provider"`
+	ProviderUserID string     `db:"provider_user_id"`
+	Email          string     `db:"email"`
+	EmailVerified  bool       `db:"email_verified"`
+	CreatedAt      time.Time  `db:"created_at"`
+	LastUsedAt     *time.Time `db:"last_used_at"`
+}
+
+// ExternalIdentity - пользователь, которого вернул внешний провайдер после входа
+type ExternalIdentity struct {
+	Provider       string
+	ProviderUserID string
+	Email          string
+	EmailVerified  bool
+	UserName       string
+}
+
+// EmailChange - незавершенная смена адреса почты.
+// Хранятся только хеши токенов подтверждения (отправлен на новый адрес) и отмены (на старый).
+type EmailChange struct {
+	UserID          int       `db:"user_id"`
+	NewEmail        string    `db:"new_email"`
+	TokenHash       string    `db:"token_hash"`
+	CancelTokenHash string    `db:"cancel_token_hash"`
+	ExpiresAt       time.Time `db:"expires_at"`
+	CreatedAt       time.Time `db:"created_at"`
+}
+
+// EmailChangeRevert - возможность вернуть прежний адрес после подтвержденной смены.
+// Действует по ссылке отмены из уведомления на старый адрес, пока не истечет срок.
+type EmailChangeRevert struct {
+	UserID          int       `db:"user_id"`
+	OldEmail        string    `db:"old_email"`
+	CancelTokenHash string    `db:"cancel_token_hash"`
+	ExpiresAt       time.Time `db:"expires_at"`
+	CreatedAt       time.Time `db:"created_at"`
+}
+
+// RoleAdmin встроенная роль со всеми разрешениями сервиса
+const RoleAdmin = "admin"
+
+// Встроенные разрешения сервиса (создаются миграцией и принадлежат роли admin)
+const (
+	PermissionUsersRead  = "users:read"
+	PermissionUsersWrite = "users:write"
+	PermissionRolesRead  = "roles:read"
+	PermissionRolesWrite = "roles:write"
+
+	PermissionClientsRead  = "clients:read"
+	PermissionClientsWrite = "clients:write"
+)
+
+// Role - роль пользователя, объединяющая набор разрешений
+type Role struct {
+	ID          int       `db:"id" json:"id"`
+	Name        string    `db:"name" json:"name"`
+	Description string    `db:"description" json:"description"`
+	Permissions []string  `db:"-" json:"permissions"`
+	CreatedAt   time.Time `db:"created_at" json:"created_at"`
+}
+
+// Permission - разрешение на действие, проверяется по имени
+type Permission struct {
+	ID          int    `db:"id" json:"id"`
+	Name        string `db:"name" json:"name"`
+	Description string `db:"description" json:"description"`
+}
+
+// Статусы учетной записи для поиска пользователей администратором
+const (
+	UserStatusActive     = "active"
+	UserStatusDisabled   = "disabled"
+	UserStatusLocked     = "locked"
+	UserStatusUnverified = "unverified"
+)
+
+// UserFilter условия поиска пользователей; пустые поля не ограничивают выборку.
+// Email и Username ищутся по подстроке без учета регистра.
+type UserFilter struct {
+	Email    string
+	Username string
+	Status   string
+}
+
+// Действия администратора, записываемые в журнал аудита
+const (
+	AuditActionUserCreate        = "user.create"
+	AuditActionUserDisable       = "user.disable"
+	AuditActionUserEnable        = "user.enable"
+	AuditActionUserPasswordReset = "user.password_reset"
+	AuditActionUserRevokeSession = "user.revoke_sessions"
+	AuditActionUserDelete        = "user.delete"
+	AuditActionRoleAssign        = "role.assign"
+	AuditActionRoleRemove        = "role.remove"
+)
+
+// AuditActor администратор, выполняющий действие.
+// Нулевой UserID означает административную утилиту с прямым доступом к базе.
+type AuditActor struct {
+	UserID int
+	IP     string
+}
+
+// System сообщает, что действие выполняет административная утилита, а не пользователь
+func (a AuditActor) System() bool {
+	return a.UserID == 0
+}
+
+// AuditRecord - запись журнала аудита о действии администратора над учетной записью
+type AuditRecord struct {
+	ID           int64           `db:"id" json:"id"`
+	ActorID      *int            `db:"actor_id" json:"actor_id"`
+	Action       string          `db:"action" json:"action"`
+	TargetUserID *int            `db:"target_user_id" json:"target_user_id"`
+	Details      json.RawMessage `db:"details" json:"details"`
+	IP           string          `db:"ip" json:"ip"`
+	CreatedAt    time.Time       `db:"created_at" json:"created_at"`
+}
+
+// OAuthClient - приложение или сервис, получающий токены этого сервиса.
+// Публичные клиенты (SPA, мобильные и CLI приложения) входят по OpenID Connect без секрета, код авторизации
+// защищен PKCE. Конфиденциальные клиенты имеют секрет и могут получать собственные токены по client_credentials
+// в пределах Scopes.
+type OAuthClient struct {
+	ID           string    `json:"client_id"`
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	Scopes       []string  `json:"scopes"`
+	CreatedAt    time.Time `json:"created_at"`
+
+	// SHA-256 текущего секрета и прежнего, который после ротации действует до PreviousSecretExpiresAt
+	SecretHash              string     `json:"-"`
+	PreviousSecretHash      string     `json:"-"`
+	PreviousSecretExpiresAt *time.Time `json:"-"`
+	SecretRotatedAt         *time.Time `json:"secret_rotated_at,omitempty"`
+}
+
+// Confidential сообщает, выдан ли клиенту секрет
+func (c *OAuthClient) Confidential() bool {
+	return c.SecretHash != ""
+}
+
+// AllowsScopes сообщает, входят ли все запрошенные области в разрешенные клиенту
+func (c *OAuthClient) AllowsScopes(scopes []string) bool {
+	for _, scope := range scopes {
+		allowed := false
+		for _, s := range c.Scopes {
+			if s == scope {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false
+		}
+	}
+	return true
+}
+
+// AllowsRedirectURI сообщает, зарегистрирован ли адрес возврата; сравнение точное, без нормализации
+func (c *OAuthClient) AllowsRedirectURI(uri string) bool {
+	for _, allowed := range c.RedirectURIs {
+		if allowed == uri {
+			return true
+		}
+	}
+	return false
+}
+
+// OAuthClientSpec - параметры регистрации клиента
+type OAuthClientSpec struct {
+	Name         string
+	RedirectURIs []string
+	Scopes       []string
+	Confidential bool
+}
+
+// Области доступа OpenID Connect
+const (
+	ScopeOpenID  = "openid"
+	ScopeProfile = "profile"
+	ScopeEmail   = "email"
+)
+
+// AuthorizationRequest - параметры запроса кода авторизации (/oauth2/authorize)
+type AuthorizationRequest struct {
+	ClientID            string
+	RedirectURI         string
+	ResponseType        string
+	Scope               string
+	State               string
+	Nonce               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+}
+
+// AuthorizationCode - выданный код авторизации; хранится в Redis до обмена на токены
+type AuthorizationCode struct {
+	ClientID      string `json:"client_id"`
+	RedirectURI   string `json:"redirect_uri"`
+	UserID        int    `json:"user_id"`
+	Scope         string `json:"scope"`
+	Nonce         string `json:"nonce,omitempty"`
+	CodeChallenge string `json:"code_challenge"`
+	// Браузер, в котором пользователь вошел, и способ входа сохраняются в сессии приложения
+	UserAgent   string `json:"user_agent"`
+	IPAddress   string `json:"ip_address"`
+	LoginMethod string `json:"login_method"`
+	AuthTime    int64  `json:"auth_time"`
+}
+
+// IDTokenClaims - содержимое ID токена OpenID Connect.
+// Email попадает в токен при области email, Username - при области profile.
+type IDTokenClaims struct {
+	Issuer        string
+	Subject       string
+	Audience      string
+	Nonce         string
+	AuthTime      int64
+	Email         string
+	EmailVerified bool
+	Username      string
+}
+
+// ClientClaims - содержимое access токена, выданного сервису по client_credentials.
+// Субъект токена - сам клиент (sub = client_id), а не пользователь.
+type ClientClaims struct {
+	ClientID  string   `json:"client_id"`
+	Scopes    []string `json:"scope,omitempty"`
+	TokenID   string   `json:"jti,omitempty"`
+	IssuedAt  int64    `json:"iat,omitempty"`
+	ExpiresAt int64    `json:"exp,omitempty"`
+}
+
+// Состояния запроса авторизации устройства
+const (
+	DeviceAuthorizationPending  = "pending"
+	DeviceAuthorizationApproved = "approved"
+	DeviceAuthorizationDenied   = "denied"
+)
+
+// DeviceAuthorization - запрос авторизации устройства (RFC 8628); хранится в Redis до обмена на токены или истечения.
+// UserAgent и IPAddress - самого устройства: сессия, выданная после подтверждения, относится к нему, а не к браузеру.
+type DeviceAuthorization struct {
+	ClientID  string `json:"client_id"`
+	Scope     string `json:"scope"`
+	UserCode  string `json:"user_code"`
+	Status    string `json:"status"`
+	UserID    int    `json:"user_id,omitempty"`
+	AuthTime  int64  `json:"auth_time,omitempty"`
+	UserAgent string `json:"user_agent"`
+	IPAddress string `json:"ip_address"`
+	ExpiresAt int64  `json:"expires_at"`
+}
+
+// DeviceCode - коды, выдаваемые устройству на /oauth2/device_authorization
+type DeviceCode struct {
+	DeviceCode string
+	UserCode   string
+	ExpiresIn  int
+	Interval   int
+}
+
+// Типы токенов в запросах интроспекции и отзыва (token_type_hint, RFC 7009) и в ответе интроспекции
+const (
+	TokenTypeHintAccessToken  = "access_token"
+	TokenTypeHintRefreshToken = "refresh_token"
+)
+
+// TokenIntrospection - состояние токена для /oauth2/introspect (RFC 7662).
+// Для недействительного, истекшего или отозванного токена заполнено только Active = false.
+type TokenIntrospection struct {
+	Active    bool
+	TokenType string
+	Subject   string
+	ClientID  string
+	Scope     string
+	TokenID   string
+	IssuedAt  int64
+	ExpiresAt int64
+}
+
+// OAuthTokens - токены, выдаваемые приложению на /oauth2/token
+type OAuthTokens struct {
+	AccessToken  string
+	RefreshToken string
+	IDToken      string
+	ExpiresIn    int
+	Scope        string
 }
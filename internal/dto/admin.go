@@ -0,0 +1,84 @@
+package dto
+
+import "time"
+
+// CreateRoleRequest DTO создания роли
+type CreateRoleRequest struct {
+	Name        string   `json:"name" validate:"required,min=2,max=64"`
+	Description string   `json:"description" validate:"max=255"`
+	Permissions []string `json:"permissions"`
+}
+
+// SetRolePermissionsRequest DTO замены разрешений роли; пустой список снимает все разрешения
+type SetRolePermissionsRequest struct {
+	Permissions []string `json:"permissions"`
+}
+
+// UserRolesResponse DTO ролей пользователя
+type UserRolesResponse struct {
+	UserID int      `json:"user_id"`
+	Roles  []string `json:"roles"`
+}
+
+// AdminUserSearchRequest DTO поиска пользователей администратором (параметры строки запроса)
+type AdminUserSearchRequest struct {
+	PaginationRequest
+	Email    string `json:"email" validate:"max=255"`
+	Username string `json:"username" validate:"max=50"`
+	Status   string `json:"status" validate:"omitempty,oneof=active disabled locked unverified"`
+}
+
+// AdminCreateUserRequest DTO создания пользователя администратором
+type AdminCreateUserRequest struct {
+	Username      string `json:"username" validate:"required,min=3,max=50"`
+	Email         string `json:"email" validate:"required,email"`
+	Password      string `json:"password" validate:"required"`
+	EmailVerified bool   `json:"email_verified"`
+}
+
+// AdminUserDTO DTO пользователя для администратора: со статусом учетной записи
+type AdminUserDTO struct {
+	ID                  int        `json:"id"`
+	Username            string     `json:"username"`
+	Email               string     `json:"email"`
+	EmailVerifiedAt     *time.Time `json:"email_verified_at"`
+	DisabledAt          *time.Time `json:"disabled_at"`
+	LockedUntil         *time.Time `json:"locked_until"`
+	FailedLoginAttempts int        `json:"failed_login_attempts"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+	Roles               []string   `json:"roles,omitempty"`
+}
+
+// CreateOAuthClientRequest DTO регистрации клиента. Публичному клиенту нужны адреса возврата,
+// конфиденциальный получает секрет и может запрашивать токены по client_credentials в пределах scopes.
+type CreateOAuthClientRequest struct {
+	Name         string   `json:"name" validate:"required,max=255"`
+	RedirectURIs []string `json:"redirect_uris"`
+	Scopes       []string `json:"scopes"`
+	Confidential bool     `json:"confidential"`
+}
+
+// RotateClientSecretRequest DTO ротации секрета; grace - сколько еще действует прежний секрет (например, "24h")
+type RotateClientSecretRequest struct {
+	Grace string `json:"grace"`
+}
+
+// OAuthClientDTO DTO клиента; секрет заполняется только в ответе на создание и ротацию
+type OAuthClientDTO struct {
+	ClientID        string     `json:"client_id"`
+	Name            string     `json:"name"`
+	RedirectURIs    []string   `json:"redirect_uris"`
+	Scopes          []string   `json:"scopes"`
+	Confidential    bool       `json:"confidential"`
+	ClientSecret    string     `json:"client_secret,omitempty"`
+	SecretRotatedAt *time.Time `json:"secret_rotated_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// ClientSecretResponse DTO нового секрета клиента
+type ClientSecretResponse struct {
+	ClientID                string     `json:"client_id"`
+	ClientSecret            string     `json:"client_secret"`
+	PreviousSecretExpiresAt *time.Time `json:"previous_secret_expires_at,omitempty"`
+}
@@ -1,5 +1,7 @@
 package dto
 
+import "encoding/json"
+
 // LoginRequest DTO для запроса входа
 type LoginRequest struct {
 	Email    string `json:"email" validate:"required,email"`
@@ -17,7 +19,7 @@ type LoginResponse struct {
 type RegisterRequest struct {
 	Username string `json:"username" validate:"required,min=3,max=50"`
 	Email    string `json:"email" validate:"required,email"`
-	Password string `json:"password" validate:"required,min=6"`
+	Password string `json:"password" validate:"required"`
 }
 
 // RegisterResponse DTO для ответа регистрации
@@ -49,9 +51,56 @@ type RequestPasswordResetResponse struct {
 type ConfirmPasswordResetRequest struct {
 	Email    string `json:"email" validate:"required,email"`
 	Code     string `json:"code" validate:"required,min=6,max=6"`
-	Password string `json:"password" validate:"required,min=8"`
+	Password string `json:"password" validate:"required"`
 }
 
 // ConfirmPasswordResetResponse DTO для ответа подтверждения сброса пароля
 type ConfirmPasswordResetResponse struct {
 }
+
+// MFARequiredResponse DTO ответа на вход, требующий второй фактор
+type MFARequiredResponse struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// RecoverAccountRequest DTO для восстановления доступа кодом восстановления
+type RecoverAccountRequest struct {
+	Email    string `json:"email" validate:"required,email"`
+	Code     string `json:"code" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+// VerifyEmailRequest DTO для подтверждения почты токеном из письма
+type VerifyEmailRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// EmailChangeTokenRequest DTO для подтверждения или отмены смены адреса токеном из письма
+type EmailChangeTokenRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// ResendVerificationRequest DTO для повторной отправки письма подтверждения
+type ResendVerificationRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// UnlockAccountRequest DTO для разблокировки входа по ссылке из письма
+type UnlockAccountRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// WebAuthnOptionsResponse DTO начала церемонии WebAuthn: параметры для navigator.credentials
+type WebAuthnOptionsResponse struct {
+	CeremonyID string      `json:"ceremony_id"`
+	Options    interface{} `json:"options"`
+}
+
+// WebAuthnLoginRequest DTO завершения входа по passkey
+type WebAuthnLoginRequest struct {
+	CeremonyID string          `json:"ceremony_id"`
+	Credential json.RawMessage `json:"credential"`
+	DeviceName string          `json:"device_name"`
+}
@@ -13,6 +13,51 @@ const (
 	MsgSuccessPasswordResetRequested = 1007
 	MsgSuccessPasswordResetConfirmed = 1008
 	MsgSuccessUserInfoRetrieved      = 1009
+	MsgSuccessLogoutAll              = 1010
+	MsgSuccessSessionsRetrieved      = 1011
+	MsgSuccessSessionRevoked         = 1012
+	MsgSuccessOtherSessionsRevoked   = 1013
+	MsgMFARequired                   = 1014
+	MsgSuccessMFAEnrollmentStarted   = 1015
+	MsgSuccessMFAEnabled             = 1016
+	MsgSuccessMFADisabled            = 1017
+	MsgSuccessRecoveryCodesGenerated = 1018
+	MsgSuccessAccountRecovered       = 1019
+	MsgSuccessWebAuthnOptions        = 1020
+	MsgSuccessPasskeyRegistered      = 1021
+	MsgSuccessPasskeysRetrieved      = 1022
+	MsgSuccessPasskeyDeleted         = 1023
+	MsgSuccessEmailVerified          = 1024
+	MsgSuccessVerificationSent       = 1025
+	MsgSuccessRegisterVerifyEmail    = 1026
+	MsgSuccessAccountUnlocked        = 1027
+	MsgSuccessEmailChangeRequested   = 1028
+	MsgSuccessEmailChanged           = 1029
+	MsgSuccessEmailChangeCancelled   = 1030
+	MsgSuccessRolesRetrieved         = 1031
+	MsgSuccessRoleCreated            = 1032
+	MsgSuccessRoleUpdated            = 1033
+	MsgSuccessRoleDeleted            = 1034
+	MsgSuccessPermissionsRetrieved   = 1035
+	MsgSuccessUserRolesRetrieved     = 1036
+	MsgSuccessRoleAssigned           = 1037
+	MsgSuccessRoleRemoved            = 1038
+	MsgSuccessUsersRetrieved         = 1039
+	MsgSuccessUserCreated            = 1040
+	MsgSuccessUserDisabled           = 1041
+	MsgSuccessUserEnabled            = 1042
+	MsgSuccessPasswordResetForced    = 1043
+	MsgSuccessUserSessionsRevoked    = 1044
+	MsgSuccessUserDeleted            = 1045
+	MsgSuccessAuditLogRetrieved      = 1046
+	MsgSuccessClientsRetrieved       = 1047
+	MsgSuccessClientCreated          = 1048
+	MsgSuccessClientSecretRotated    = 1049
+	MsgSuccessClientDeleted          = 1050
+	MsgSuccessIdentitiesRetrieved    = 1051
+	MsgSuccessIdentityLinkStarted    = 1052
+	MsgSuccessIdentityLinked         = 1053
+	MsgSuccessIdentityUnlinked       = 1054
 
 	// Ошибки валидации (2000-2999)
 	MsgInvalidEmail           = 2000
@@ -25,6 +70,8 @@ const (
 	MsgInvalidUserID          = 2007
 	MsgMissingEmailOrPassword = 2008
 	MsgInvalidRequestData     = 2009
+	MsgFieldNotUpdatable      = 2010
+	MsgUnknownPermission      = 2011
 
 	// Ошибки аутентификации (3000-3999)
 	MsgWrongPassword        = 3000
@@ -36,6 +83,32 @@ const (
 	MsgResetCodeInvalid     = 3006
 	MsgResourceNotFound     = 3007
 	MsgTooManyResetAttempts = 3008
+	MsgRefreshTokenReused   = 3009
+	MsgSessionNotFound      = 3010
+	MsgInvalidMFACode       = 3011
+	MsgMFANotEnrolled       = 3012
+	MsgMFAAlreadyEnabled    = 3013
+	MsgInvalidRecoveryCode  = 3014
+	MsgRecoveryCodesExist   = 3015
+	MsgTooManyRecoveryTries = 3016
+	MsgWebAuthnFailed       = 3017
+	MsgPasskeyNotFound      = 3018
+	MsgEmailNotVerified     = 3019
+	MsgTooManyVerifyEmails  = 3020
+	MsgTooManyRequests      = 3021
+	MsgAccountLocked        = 3022
+	MsgForbidden            = 3023
+	MsgPreconditionFailed   = 3024
+	MsgRoleNotFound         = 3025
+	MsgRoleAlreadyExists    = 3026
+	MsgProtectedRole        = 3027
+	MsgAccountDisabled      = 3028
+	MsgOAuthClientNotFound  = 3029
+	MsgIdentityNotFound     = 3030
+	MsgIdentityLinked       = 3031
+	MsgAccountLinkRequired  = 3032
+	MsgLastLoginMethod      = 3033
+	MsgReauthRequired       = 3034
 
 	// Ошибки сервера (4000-4999)
 	MsgInternalError        = 4000
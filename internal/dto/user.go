@@ -1,6 +1,9 @@
 package dto
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // UserDTO DTO для пользователя
 type UserDTO struct {
@@ -11,10 +14,10 @@ type UserDTO struct {
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
-// UpdateUserRequest DTO для обновления пользователя
+// UpdateUserRequest DTO частичного обновления профиля (JSON merge patch).
+// Отсутствующее поле не меняется; email меняется отдельным подтверждаемым запросом, пароль — через /user/me/password.
 type UpdateUserRequest struct {
-	Username string `json:"username" validate:"required,min=3,max=50"`
-	Email    string `json:"email" validate:"required,email"`
+	Username *string `json:"username" validate:"omitempty,min=3,max=50"`
 }
 
 // UserResponse DTO для ответа с пользователем
@@ -30,11 +33,79 @@ type ResetPasswordRequest struct {
 // ChangePasswordRequest DTO для смены пароля
 type ChangePasswordRequest struct {
 	OldPassword string `json:"old_password" validate:"required"`
-	NewPassword string `json:"new_password" validate:"required,min=6"`
+	NewPassword string `json:"new_password" validate:"required"`
 }
 
-// ResetPasswordByEmailRequest DTO для сброса пароля по email
-type ResetPasswordByEmailRequest struct {
-	Email    string `json:"email" validate:"required,email"`
-	Password string `json:"password" validate:"required,min=8"`
+// ChangeEmailRequest DTO для запроса смены адреса почты.
+// Пароль обязателен для пользователей, у которых он установлен. Пользователь без пароля
+// подтверждает запрос кодом TOTP или недавним входом.
+type ChangeEmailRequest struct {
+	NewEmail string `json:"new_email" validate:"required,email"`
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+// SessionResponse DTO сессии пользователя
+type SessionResponse struct {
+	ID          string    `json:"id"`
+	DeviceName  string    `json:"device_name"`
+	UserAgent   string    `json:"user_agent"`
+	IPAddress   string    `json:"ip_address"`
+	LoginMethod string    `json:"login_method"`
+	CreatedAt   time.Time `json:"created_at"`
+	LastUsedAt  time.Time `json:"last_used_at"`
+	Current     bool      `json:"current"`
+}
+
+// UserIdentityResponse DTO привязанной внешней учетной записи
+type UserIdentityResponse struct {
+	Provider      string     `json:"provider"`
+	Email         string     `json:"email"`
+	EmailVerified bool       `json:"email_verified"`
+	CreatedAt     time.Time  `json:"created_at"`
+	LastUsedAt    *time.Time `json:"last_used_at,omitempty"`
+}
+
+// IdentityLinkResponse DTO начала привязки: браузер продолжает вход у провайдера по этому адресу
+type IdentityLinkResponse struct {
+	AuthorizationURL string `json:"authorization_url"`
+}
+
+// UserInfoResponse DTO для ответа /user/me
+type UserInfoResponse struct {
+	ID                     int       `json:"id"`
+	Username               string    `json:"username"`
+	Email                  string    `json:"email"`
+	CreatedAt              time.Time `json:"created_at"`
+	UpdatedAt              time.Time `json:"updated_at"`
+	EmailVerified          bool      `json:"email_verified"`
+	RecoveryCodesRemaining int       `json:"recovery_codes_remaining"`
+}
+
+// RecoveryCodesRequest DTO для выпуска кодов восстановления: текущий пароль или код TOTP.
+// Пользователь без пароля и TOTP подтверждает запрос недавним входом.
+type RecoveryCodesRequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+// RecoveryCodesResponse DTO с новым набором кодов восстановления (показывается один раз)
+type RecoveryCodesResponse struct {
+	Codes []string `json:"codes"`
+}
+
+// WebAuthnRegisterRequest DTO завершения регистрации passkey
+type WebAuthnRegisterRequest struct {
+	CeremonyID string          `json:"ceremony_id"`
+	Name       string          `json:"name"`
+	Credential json.RawMessage `json:"credential"`
+}
+
+// PasskeyResponse DTO зарегистрированного passkey
+type PasskeyResponse struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	Synced     bool       `json:"synced"`
 }
@@ -19,10 +19,45 @@ const (
 	ErrCodeInvalidPassword ErrorCode = "INVALID_PASSWORD"
 
 	// Аутентификация
-	ErrCodeInvalidToken  ErrorCode = "INVALID_TOKEN"
-	ErrCodeExpiredToken  ErrorCode = "EXPIRED_TOKEN"
-	ErrCodeInvalidLogin  ErrorCode = "INVALID_LOGIN"
-	ErrCodeLoginRequired ErrorCode = "LOGIN_REQUIRED"
+	ErrCodeInvalidToken   ErrorCode = "INVALID_TOKEN"
+	ErrCodeExpiredToken   ErrorCode = "EXPIRED_TOKEN"
+	ErrCodeInvalidLogin   ErrorCode = "INVALID_LOGIN"
+	ErrCodeLoginRequired  ErrorCode = "LOGIN_REQUIRED"
+	ErrCodeTokenReused    ErrorCode = "TOKEN_REUSED"
+	ErrCodeReauthRequired ErrorCode = "REAUTH_REQUIRED"
+
+	// Второй фактор
+	ErrCodeMFANotEnrolled    ErrorCode = "MFA_NOT_ENROLLED"
+	ErrCodeMFAAlreadyEnabled ErrorCode = "MFA_ALREADY_ENABLED"
+	ErrCodeInvalidMFACode    ErrorCode = "INVALID_MFA_CODE"
+
+	// Коды восстановления
+	ErrCodeInvalidRecoveryCode ErrorCode = "INVALID_RECOVERY_CODE"
+	ErrCodeRecoveryCodesExist  ErrorCode = "RECOVERY_CODES_EXIST"
+
+	// WebAuthn
+	ErrCodeWebAuthnFailed ErrorCode = "WEBAUTHN_FAILED"
+
+	// Подтверждение почты
+	ErrCodeEmailNotVerified ErrorCode = "EMAIL_NOT_VERIFIED"
+
+	// Блокировка учетной записи
+	ErrCodeAccountLocked   ErrorCode = "ACCOUNT_LOCKED"
+	ErrCodeAccountDisabled ErrorCode = "ACCOUNT_DISABLED"
+
+	// Роли и разрешения
+	ErrCodeRoleNotFound      ErrorCode = "ROLE_NOT_FOUND"
+	ErrCodeRoleExists        ErrorCode = "ROLE_EXISTS"
+	ErrCodeUnknownPermission ErrorCode = "UNKNOWN_PERMISSION"
+	ErrCodeProtectedRole     ErrorCode = "PROTECTED_ROLE"
+
+	// OAuth клиенты
+	ErrCodeOAuthClientNotFound ErrorCode = "OAUTH_CLIENT_NOT_FOUND"
+
+	// Внешние учетные записи
+	ErrCodeIdentityLinked      ErrorCode = "IDENTITY_ALREADY_LINKED"
+	ErrCodeAccountLinkRequired ErrorCode = "ACCOUNT_LINK_REQUIRED"
+	ErrCodeLastLoginMethod     ErrorCode = "LAST_LOGIN_METHOD"
 
 	// База данных
 	ErrCodeDatabase    ErrorCode = "DATABASE_ERROR"
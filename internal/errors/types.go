@@ -3,6 +3,7 @@ package errors
 import (
 	"fmt"
 	"net/http"
+	"strings"
 )
 
 // AppError представляет ошибку приложения
@@ -62,10 +63,45 @@ var (
 	ErrInvalidPassword = NewAppError(ErrCodeInvalidPassword, "Invalid password", http.StatusBadRequest)
 
 	// Аутентификация
-	ErrInvalidToken  = NewAppError(ErrCodeInvalidToken, "Invalid token", http.StatusUnauthorized)
-	ErrExpiredToken  = NewAppError(ErrCodeExpiredToken, "Token expired", http.StatusUnauthorized)
-	ErrInvalidLogin  = NewAppError(ErrCodeInvalidLogin, "Invalid login credentials", http.StatusUnauthorized)
-	ErrLoginRequired = NewAppError(ErrCodeLoginRequired, "Login required", http.StatusUnauthorized)
+	ErrInvalidToken   = NewAppError(ErrCodeInvalidToken, "Invalid token", http.StatusUnauthorized)
+	ErrExpiredToken   = NewAppError(ErrCodeExpiredToken, "Token expired", http.StatusUnauthorized)
+	ErrInvalidLogin   = NewAppError(ErrCodeInvalidLogin, "Invalid login credentials", http.StatusUnauthorized)
+	ErrLoginRequired  = NewAppError(ErrCodeLoginRequired, "Login required", http.StatusUnauthorized)
+	ErrTokenReused    = NewAppError(ErrCodeTokenReused, "Refresh token reuse detected", http.StatusUnauthorized)
+	ErrReauthRequired = NewAppError(ErrCodeReauthRequired, "Recent sign-in or two-factor code required", http.StatusUnauthorized)
+
+	// Второй фактор
+	ErrMFANotEnrolled    = NewAppError(ErrCodeMFANotEnrolled, "Two-factor authentication is not enrolled", http.StatusNotFound)
+	ErrMFAAlreadyEnabled = NewAppError(ErrCodeMFAAlreadyEnabled, "Two-factor authentication is already enabled", http.StatusConflict)
+	ErrInvalidMFACode    = NewAppError(ErrCodeInvalidMFACode, "Invalid two-factor code", http.StatusUnauthorized)
+
+	// Коды восстановления
+	ErrInvalidRecoveryCode = NewAppError(ErrCodeInvalidRecoveryCode, "Invalid recovery code", http.StatusUnauthorized)
+	ErrRecoveryCodesExist  = NewAppError(ErrCodeRecoveryCodesExist, "Recovery codes already generated", http.StatusConflict)
+
+	// WebAuthn
+	ErrWebAuthnFailed = NewAppError(ErrCodeWebAuthnFailed, "WebAuthn ceremony failed", http.StatusUnauthorized)
+
+	// Подтверждение почты
+	ErrEmailNotVerified = NewAppError(ErrCodeEmailNotVerified, "Email address is not verified", http.StatusForbidden)
+
+	// Блокировка учетной записи
+	ErrAccountLocked   = NewAppError(ErrCodeAccountLocked, "Account temporarily locked", http.StatusLocked)
+	ErrAccountDisabled = NewAppError(ErrCodeAccountDisabled, "Account disabled", http.StatusForbidden)
+
+	// Роли и разрешения
+	ErrRoleNotFound      = NewAppError(ErrCodeRoleNotFound, "Role not found", http.StatusNotFound)
+	ErrRoleExists        = NewAppError(ErrCodeRoleExists, "Role already exists", http.StatusConflict)
+	ErrUnknownPermission = NewAppError(ErrCodeUnknownPermission, "Unknown permission", http.StatusBadRequest)
+	ErrProtectedRole     = NewAppError(ErrCodeProtectedRole, "Built-in role cannot be changed", http.StatusForbidden)
+
+	// OAuth клиенты
+	ErrOAuthClientNotFound = NewAppError(ErrCodeOAuthClientNotFound, "OAuth client not found", http.StatusNotFound)
+
+	// Внешние учетные записи
+	ErrIdentityLinked      = NewAppError(ErrCodeIdentityLinked, "External account is already linked", http.StatusConflict)
+	ErrAccountLinkRequired = NewAppError(ErrCodeAccountLinkRequired, "Account exists, sign in and link the external account", http.StatusConflict)
+	ErrLastLoginMethod     = NewAppError(ErrCodeLastLoginMethod, "Cannot remove the last sign-in method", http.StatusConflict)
 
 	// База данных
 	ErrDatabase    = NewAppError(ErrCodeDatabase, "Database error", http.StatusInternalServerError)
@@ -76,3 +112,70 @@ var (
 	ErrKafka = NewAppError(ErrCodeKafka, "Kafka error", http.StatusInternalServerError)
 	ErrEmail = NewAppError(ErrCodeEmail, "Email service error", http.StatusInternalServerError)
 )
+
+// PasswordViolation нарушенное правило политики паролей
+type PasswordViolation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// PasswordPolicyError пароль не прошел политику; перечисляет все нарушенные правила
+type PasswordPolicyError struct {
+	Violations []PasswordViolation
+}
+
+// Error реализует интерфейс error
+func (e *PasswordPolicyError) Error() string {
+	rules := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		rules = append(rules, v.Rule)
+	}
+	return fmt.Sprintf("%s: password policy violated: %s", ErrCodeInvalidPassword, strings.Join(rules, ", "))
+}
+
+// Unwrap позволяет сопоставлять ошибку с ErrInvalidPassword через errors.Is
+func (e *PasswordPolicyError) Unwrap() error {
+	return ErrInvalidPassword
+}
+
+// Коды ошибок протокола OAuth 2.0 (RFC 6749, раздел 5.2) и OpenID Connect
+const (
+	OAuthInvalidRequest          = "invalid_request"
+	OAuthInvalidClient           = "invalid_client"
+	OAuthInvalidGrant            = "invalid_grant"
+	OAuthInvalidScope            = "invalid_scope"
+	OAuthUnauthorizedClient      = "unauthorized_client"
+	OAuthUnsupportedGrantType    = "unsupported_grant_type"
+	OAuthUnsupportedResponseType = "unsupported_response_type"
+	OAuthAccessDenied            = "access_denied"
+	OAuthLoginRequired           = "login_required"
+	// Опрос ручки токенов устройством (RFC 8628, раздел 3.5)
+	OAuthAuthorizationPending = "authorization_pending"
+	OAuthSlowDown             = "slow_down"
+	OAuthExpiredToken         = "expired_token"
+)
+
+// OAuthError ошибка протокола OAuth; код и описание передаются клиенту как есть
+// в полях error и error_description (в JSON ответе или в параметрах адреса возврата)
+type OAuthError struct {
+	Code        string
+	Description string
+}
+
+// NewOAuthError создает ошибку протокола OAuth
+func NewOAuthError(code, description string) *OAuthError {
+	return &OAuthError{Code: code, Description: description}
+}
+
+// Error реализует интерфейс error
+func (e *OAuthError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Description)
+}
+
+// HTTPStatus статус ответа ручки токенов: неаутентифицированный клиент получает 401, остальные ошибки - 400
+func (e *OAuthError) HTTPStatus() int {
+	if e.Code == OAuthInvalidClient {
+		return http.StatusUnauthorized
+	}
+	return http.StatusBadRequest
+}
@@ -0,0 +1,155 @@
+package admin
+
+import (
+	stderrors "errors"
+	"net/http"
+	"time"
+
+	"github.com/Alias1177/Auth/internal/domain"
+	"github.com/Alias1177/Auth/internal/dto"
+	apperrors "github.com/Alias1177/Auth/internal/errors"
+	"github.com/Alias1177/Auth/internal/service"
+	"github.com/Alias1177/Auth/pkg/errors"
+	"github.com/Alias1177/Auth/pkg/httputil"
+	"github.com/Alias1177/Auth/pkg/logger"
+	"github.com/Alias1177/Auth/pkg/validator"
+	"github.com/go-chi/chi/v5"
+)
+
+// defaultSecretGrace сколько действует прежний секрет после ротации, если grace не указан
+const defaultSecretGrace = 24 * time.Hour
+
+// ClientHandler управление приложениями OpenID Connect и сервисами с client_credentials
+type ClientHandler struct {
+	clients   service.OAuthClientService
+	validator *validator.Validator
+	logger    *logger.Logger
+}
+
+// NewClientHandler создает новый обработчик клиентов
+func NewClientHandler(clients service.OAuthClientService, validator *validator.Validator, logger *logger.Logger) *ClientHandler {
+	return &ClientHandler{
+		clients:   clients,
+		validator: validator,
+		logger:    logger,
+	}
+}
+
+// ListClients возвращает всех зарегистрированных клиентов без секретов
+func (h *ClientHandler) ListClients(w http.ResponseWriter, r *http.Request) {
+	clients, err := h.clients.ListClients(r.Context())
+	if err != nil {
+		errors.HandleInternalError(w, err, h.logger, "list oauth clients")
+		return
+	}
+
+	response := make([]dto.OAuthClientDTO, 0, len(clients))
+	for i := range clients {
+		response = append(response, toOAuthClientDTO(&clients[i], ""))
+	}
+	if err := httputil.JSONSuccessWithID(w, http.StatusOK, dto.MsgSuccessClientsRetrieved, response); err != nil {
+		errors.HandleInternalError(w, err, h.logger, "encode response")
+	}
+}
+
+// CreateClient регистрирует клиента. Секрет конфиденциального клиента возвращается только в этом ответе.
+func (h *ClientHandler) CreateClient(w http.ResponseWriter, r *http.Request) {
+	var req dto.CreateOAuthClientRequest
+	if err := httputil.DecodeJSON(r, &req, h.logger); err != nil {
+		httputil.JSONErrorWithID(w, http.StatusBadRequest, dto.MsgInvalidRequest)
+		return
+	}
+	if err := h.validator.Validate(req); err != nil {
+		errors.HandleValidationError(w, err, h.logger)
+		return
+	}
+
+	client, secret, err := h.clients.RegisterClient(r.Context(), domain.OAuthClientSpec{
+		Name:         req.Name,
+		RedirectURIs: req.RedirectURIs,
+		Scopes:       req.Scopes,
+		Confidential: req.Confidential,
+	})
+	if err != nil {
+		h.handleClientError(w, err, "create oauth client")
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	if err := httputil.JSONSuccessWithID(w, http.StatusCreated, dto.MsgSuccessClientCreated, toOAuthClientDTO(client, secret)); err != nil {
+		errors.HandleInternalError(w, err, h.logger, "encode response")
+	}
+}
+
+// RotateSecret выдает клиенту новый секрет; прежний действует еще grace (по умолчанию 24 часа, "0s" - отозвать сразу)
+func (h *ClientHandler) RotateSecret(w http.ResponseWriter, r *http.Request) {
+	var req dto.RotateClientSecretRequest
+	if r.ContentLength != 0 {
+		if err := httputil.DecodeJSON(r, &req, h.logger); err != nil {
+			httputil.JSONErrorWithID(w, http.StatusBadRequest, dto.MsgInvalidRequest)
+			return
+		}
+	}
+	grace := defaultSecretGrace
+	if req.Grace != "" {
+		parsed, err := time.ParseDuration(req.Grace)
+		if err != nil {
+			errors.HandleValidationError(w, err, h.logger)
+			return
+		}
+		grace = parsed
+	}
+
+	clientID := chi.URLParam(r, "id")
+	secret, err := h.clients.RotateSecret(r.Context(), clientID, grace)
+	if err != nil {
+		h.handleClientError(w, err, "rotate client secret")
+		return
+	}
+
+	response := dto.ClientSecretResponse{ClientID: clientID, ClientSecret: secret}
+	if grace > 0 {
+		expiresAt := time.Now().Add(grace)
+		response.PreviousSecretExpiresAt = &expiresAt
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	if err := httputil.JSONSuccessWithID(w, http.StatusOK, dto.MsgSuccessClientSecretRotated, response); err != nil {
+		errors.HandleInternalError(w, err, h.logger, "encode response")
+	}
+}
+
+// DeleteClient удаляет клиента; выданные ему access токены перестают проходить интроспекцию
+func (h *ClientHandler) DeleteClient(w http.ResponseWriter, r *http.Request) {
+	if err := h.clients.DeleteClient(r.Context(), chi.URLParam(r, "id")); err != nil {
+		h.handleClientError(w, err, "delete oauth client")
+		return
+	}
+	if err := httputil.JSONSuccessWithID(w, http.StatusOK, dto.MsgSuccessClientDeleted, nil); err != nil {
+		errors.HandleInternalError(w, err, h.logger, "encode response")
+	}
+}
+
+// handleClientError отвечает по ошибке сервиса клиентов
+func (h *ClientHandler) handleClientError(w http.ResponseWriter, err error, operation string) {
+	switch {
+	case stderrors.Is(err, apperrors.ErrOAuthClientNotFound):
+		httputil.JSONErrorWithID(w, http.StatusNotFound, dto.MsgOAuthClientNotFound)
+	case stderrors.Is(err, apperrors.ErrValidation):
+		errors.HandleValidationError(w, err, h.logger)
+	default:
+		errors.HandleInternalError(w, err, h.logger, operation)
+	}
+}
+
+func toOAuthClientDTO(client *domain.OAuthClient, secret string) dto.OAuthClientDTO {
+	return dto.OAuthClientDTO{
+		ClientID:        client.ID,
+		Name:            client.Name,
+		RedirectURIs:    client.RedirectURIs,
+		Scopes:          client.Scopes,
+		Confidential:    client.Confidential(),
+		ClientSecret:    secret,
+		SecretRotatedAt: client.SecretRotatedAt,
+		CreatedAt:       client.CreatedAt,
+	}
+}
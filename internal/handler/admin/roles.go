@@ -0,0 +1,199 @@
+package admin
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Alias1177/Auth/internal/dto"
+	apperrors "github.com/Alias1177/Auth/internal/errors"
+	"github.com/Alias1177/Auth/internal/service"
+	"github.com/Alias1177/Auth/pkg/errors"
+	"github.com/Alias1177/Auth/pkg/httputil"
+	"github.com/Alias1177/Auth/pkg/logger"
+	"github.com/Alias1177/Auth/pkg/validator"
+	"github.com/go-chi/chi/v5"
+)
+
+// RoleHandler управление ролями, разрешениями и назначением ролей пользователям
+type RoleHandler struct {
+	roles     service.RoleService
+	validator *validator.Validator
+	logger    *logger.Logger
+}
+
+// NewRoleHandler создает новый обработчик ролей
+func NewRoleHandler(roles service.RoleService, validator *validator.Validator, logger *logger.Logger) *RoleHandler {
+	return &RoleHandler{
+		roles:     roles,
+		validator: validator,
+		logger:    logger,
+	}
+}
+
+// ListRoles возвращает все роли с разрешениями
+func (h *RoleHandler) ListRoles(w http.ResponseWriter, r *http.Request) {
+	roles, err := h.roles.ListRoles(r.Context())
+	if err != nil {
+		errors.HandleInternalError(w, err, h.logger, "list roles")
+		return
+	}
+	if err := httputil.JSONSuccessWithID(w, http.StatusOK, dto.MsgSuccessRolesRetrieved, roles); err != nil {
+		errors.HandleInternalError(w, err, h.logger, "encode response")
+	}
+}
+
+// CreateRole создает роль с набором разрешений
+func (h *RoleHandler) CreateRole(w http.ResponseWriter, r *http.Request) {
+	var req dto.CreateRoleRequest
+	if err := httputil.DecodeJSON(r, &req, h.logger); err != nil {
+		httputil.JSONErrorWithID(w, http.StatusBadRequest, dto.MsgInvalidRequest)
+		return
+	}
+	if err := h.validator.Validate(req); err != nil {
+		errors.HandleValidationError(w, err, h.logger)
+		return
+	}
+
+	role, err := h.roles.CreateRole(r.Context(), req.Name, req.Description, req.Permissions)
+	if err != nil {
+		h.handleRoleError(w, err, "create role")
+		return
+	}
+	if err := httputil.JSONSuccessWithID(w, http.StatusCreated, dto.MsgSuccessRoleCreated, role); err != nil {
+		errors.HandleInternalError(w, err, h.logger, "encode response")
+	}
+}
+
+// SetRolePermissions заменяет разрешения роли
+func (h *RoleHandler) SetRolePermissions(w http.ResponseWriter, r *http.Request) {
+	var req dto.SetRolePermissionsRequest
+	if err := httputil.DecodeJSON(r, &req, h.logger); err != nil {
+		httputil.JSONErrorWithID(w, http.StatusBadRequest, dto.MsgInvalidRequest)
+		return
+	}
+
+	role, err := h.roles.SetRolePermissions(r.Context(), chi.URLParam(r, "name"), req.Permissions)
+	if err != nil {
+		h.handleRoleError(w, err, "set role permissions")
+		return
+	}
+	if err := httputil.JSONSuccessWithID(w, http.StatusOK, dto.MsgSuccessRoleUpdated, role); err != nil {
+		errors.HandleInternalError(w, err, h.logger, "encode response")
+	}
+}
+
+// DeleteRole удаляет роль и снимает ее со всех пользователей
+func (h *RoleHandler) DeleteRole(w http.ResponseWriter, r *http.Request) {
+	if err := h.roles.DeleteRole(r.Context(), chi.URLParam(r, "name")); err != nil {
+		h.handleRoleError(w, err, "delete role")
+		return
+	}
+	if err := httputil.JSONSuccessWithID(w, http.StatusOK, dto.MsgSuccessRoleDeleted, nil); err != nil {
+		errors.HandleInternalError(w, err, h.logger, "encode response")
+	}
+}
+
+// ListPermissions возвращает все разрешения сервиса
+func (h *RoleHandler) ListPermissions(w http.ResponseWriter, r *http.Request) {
+	permissions, err := h.roles.ListPermissions(r.Context())
+	if err != nil {
+		errors.HandleInternalError(w, err, h.logger, "list permissions")
+		return
+	}
+	if err := httputil.JSONSuccessWithID(w, http.StatusOK, dto.MsgSuccessPermissionsRetrieved, permissions); err != nil {
+		errors.HandleInternalError(w, err, h.logger, "encode response")
+	}
+}
+
+// ListUserRoles возвращает роли пользователя
+func (h *RoleHandler) ListUserRoles(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDParam(w, r, h.logger)
+	if !ok {
+		return
+	}
+
+	roles, err := h.roles.UserRoles(r.Context(), userID)
+	if err != nil {
+		h.handleRoleError(w, err, "list user roles")
+		return
+	}
+	response := dto.UserRolesResponse{UserID: userID, Roles: roles}
+	if err := httputil.JSONSuccessWithID(w, http.StatusOK, dto.MsgSuccessUserRolesRetrieved, response); err != nil {
+		errors.HandleInternalError(w, err, h.logger, "encode response")
+	}
+}
+
+// AssignRole назначает роль пользователю. Новые разрешения попадут в токен при следующем обмене refresh токена.
+// Назначить можно только роль, все разрешения которой есть у администратора; роль admin - только администратору.
+func (h *RoleHandler) AssignRole(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDParam(w, r, h.logger)
+	if !ok {
+		return
+	}
+
+	actor, ok := auditActor(w, r, h.logger)
+	if !ok {
+		return
+	}
+
+	if err := h.roles.AssignRole(r.Context(), actor, userID, chi.URLParam(r, "role")); err != nil {
+		h.handleRoleError(w, err, "assign role")
+		return
+	}
+	if err := httputil.JSONSuccessWithID(w, http.StatusOK, dto.MsgSuccessRoleAssigned, nil); err != nil {
+		errors.HandleInternalError(w, err, h.logger, "encode response")
+	}
+}
+
+// RemoveRole снимает роль с пользователя и отзывает его access токены
+func (h *RoleHandler) RemoveRole(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDParam(w, r, h.logger)
+	if !ok {
+		return
+	}
+
+	actor, ok := auditActor(w, r, h.logger)
+	if !ok {
+		return
+	}
+
+	if err := h.roles.RemoveRole(r.Context(), actor, userID, chi.URLParam(r, "role")); err != nil {
+		h.handleRoleError(w, err, "remove role")
+		return
+	}
+	if err := httputil.JSONSuccessWithID(w, http.StatusOK, dto.MsgSuccessRoleRemoved, nil); err != nil {
+		errors.HandleInternalError(w, err, h.logger, "encode response")
+	}
+}
+
+// userIDParam разбирает ID пользователя из пути; при ошибке отправляет ответ и возвращает false
+func userIDParam(w http.ResponseWriter, r *http.Request, log *logger.Logger) (int, bool) {
+	userIDStr := chi.URLParam(r, "id")
+	userID, err := strconv.Atoi(userIDStr)
+	if err != nil {
+		log.Warnw("Invalid user ID", "user_id", userIDStr, "error", err)
+		httputil.JSONErrorWithID(w, http.StatusBadRequest, dto.MsgInvalidUserID)
+		return 0, false
+	}
+	return userID, true
+}
+
+// handleRoleError отвечает по ошибке сервиса ролей
+func (h *RoleHandler) handleRoleError(w http.ResponseWriter, err error, operation string) {
+	switch err {
+	case apperrors.ErrRoleNotFound:
+		httputil.JSONErrorWithID(w, http.StatusNotFound, dto.MsgRoleNotFound)
+	case apperrors.ErrRoleExists:
+		httputil.JSONErrorWithID(w, http.StatusConflict, dto.MsgRoleAlreadyExists)
+	case apperrors.ErrUnknownPermission:
+		httputil.JSONErrorWithID(w, http.StatusBadRequest, dto.MsgUnknownPermission)
+	case apperrors.ErrProtectedRole:
+		httputil.JSONErrorWithID(w, http.StatusForbidden, dto.MsgProtectedRole)
+	case apperrors.ErrUserNotFound:
+		httputil.JSONErrorWithID(w, http.StatusNotFound, dto.MsgUserNotFound)
+	case apperrors.ErrForbidden:
+		httputil.JSONErrorWithID(w, http.StatusForbidden, dto.MsgForbidden)
+	default:
+		errors.HandleInternalError(w, err, h.logger, operation)
+	}
+}
@@ -0,0 +1,281 @@
+package admin
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/Alias1177/Auth/internal/domain"
+	"github.com/Alias1177/Auth/internal/dto"
+	apperrors "github.com/Alias1177/Auth/internal/errors"
+	"github.com/Alias1177/Auth/internal/middleware"
+	"github.com/Alias1177/Auth/internal/service"
+	"github.com/Alias1177/Auth/pkg/errors"
+	"github.com/Alias1177/Auth/pkg/httputil"
+	"github.com/Alias1177/Auth/pkg/logger"
+	"github.com/Alias1177/Auth/pkg/validator"
+)
+
+// Размер страницы списков, если limit не передан
+const defaultPageLimit = 20
+
+// UserHandler управление учетными записями пользователей администратором
+type UserHandler struct {
+	users     service.AdminUserService
+	roles     service.RoleService
+	validator *validator.Validator
+	logger    *logger.Logger
+}
+
+// NewUserHandler создает новый обработчик управления пользователями
+func NewUserHandler(
+	users service.AdminUserService,
+	roles service.RoleService,
+	validator *validator.Validator,
+	logger *logger.Logger,
+) *UserHandler {
+	return &UserHandler{
+		users:     users,
+		roles:     roles,
+		validator: validator,
+		logger:    logger,
+	}
+}
+
+// SearchUsers ищет пользователей по подстроке email или username и статусу, постранично
+func (h *UserHandler) SearchUsers(w http.ResponseWriter, r *http.Request) {
+	page, ok := h.pagination(w, r)
+	if !ok {
+		return
+	}
+	query := r.URL.Query()
+	req := dto.AdminUserSearchRequest{
+		PaginationRequest: page,
+		Email:             query.Get("email"),
+		Username:          query.Get("username"),
+		Status:            query.Get("status"),
+	}
+	if err := h.validator.Validate(req); err != nil {
+		errors.HandleValidationError(w, err, h.logger)
+		return
+	}
+
+	filter := domain.UserFilter{Email: req.Email, Username: req.Username, Status: req.Status}
+	users, total, err := h.users.SearchUsers(r.Context(), filter, req.Page, req.Limit)
+	if err != nil {
+		errors.HandleInternalError(w, err, h.logger, "search users")
+		return
+	}
+
+	items := make([]dto.AdminUserDTO, 0, len(users))
+	for i := range users {
+		items = append(items, adminUserDTO(&users[i], nil))
+	}
+	h.respondPage(w, dto.MsgSuccessUsersRetrieved, req.PaginationRequest, total, items)
+}
+
+// GetUser возвращает учетную запись вместе с ролями
+func (h *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDParam(w, r, h.logger)
+	if !ok {
+		return
+	}
+
+	user, err := h.users.GetUser(r.Context(), userID)
+	if err != nil {
+		h.handleUserError(w, err, "get user")
+		return
+	}
+	roles, err := h.roles.UserRoles(r.Context(), userID)
+	if err != nil {
+		h.handleUserError(w, err, "get user roles")
+		return
+	}
+
+	if err := httputil.JSONSuccessWithID(w, http.StatusOK, dto.MsgSuccessUserInfoRetrieved, adminUserDTO(user, roles)); err != nil {
+		errors.HandleInternalError(w, err, h.logger, "encode response")
+	}
+}
+
+// CreateUser создает учетную запись; пароль проверяется политикой паролей
+func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
+	actor, ok := auditActor(w, r, h.logger)
+	if !ok {
+		return
+	}
+
+	var req dto.AdminCreateUserRequest
+	if err := httputil.DecodeJSON(r, &req, h.logger); err != nil {
+		httputil.JSONErrorWithID(w, http.StatusBadRequest, dto.MsgInvalidRequest)
+		return
+	}
+	if err := h.validator.Validate(req); err != nil {
+		errors.HandleValidationError(w, err, h.logger)
+		return
+	}
+
+	user, err := h.users.CreateUser(r.Context(), actor, req.Username, req.Email, req.Password, req.EmailVerified)
+	if err != nil {
+		if errors.HandlePasswordPolicyError(w, err, h.logger) {
+			return
+		}
+		h.handleUserError(w, err, "create user")
+		return
+	}
+
+	if err := httputil.JSONSuccessWithID(w, http.StatusCreated, dto.MsgSuccessUserCreated, adminUserDTO(user, nil)); err != nil {
+		errors.HandleInternalError(w, err, h.logger, "encode response")
+	}
+}
+
+// DisableUser отключает учетную запись и завершает ее сессии
+func (h *UserHandler) DisableUser(w http.ResponseWriter, r *http.Request) {
+	h.userAction(w, r, h.users.DisableUser, dto.MsgSuccessUserDisabled, "disable user")
+}
+
+// EnableUser включает отключенную учетную запись
+func (h *UserHandler) EnableUser(w http.ResponseWriter, r *http.Request) {
+	h.userAction(w, r, h.users.EnableUser, dto.MsgSuccessUserEnabled, "enable user")
+}
+
+// ForcePasswordReset делает текущий пароль недействительным и отправляет пользователю письмо сброса
+func (h *UserHandler) ForcePasswordReset(w http.ResponseWriter, r *http.Request) {
+	h.userAction(w, r, h.users.ForcePasswordReset, dto.MsgSuccessPasswordResetForced, "force password reset")
+}
+
+// RevokeSessions завершает все сессии пользователя
+func (h *UserHandler) RevokeSessions(w http.ResponseWriter, r *http.Request) {
+	h.userAction(w, r, h.users.RevokeSessions, dto.MsgSuccessUserSessionsRevoked, "revoke user sessions")
+}
+
+// DeleteUser удаляет учетную запись
+func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	h.userAction(w, r, h.users.DeleteUser, dto.MsgSuccessUserDeleted, "delete user")
+}
+
+// AuditLog возвращает журнал действий администраторов над учетной записью, начиная с новых
+func (h *UserHandler) AuditLog(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDParam(w, r, h.logger)
+	if !ok {
+		return
+	}
+	page, ok := h.pagination(w, r)
+	if !ok {
+		return
+	}
+	if err := h.validator.Validate(page); err != nil {
+		errors.HandleValidationError(w, err, h.logger)
+		return
+	}
+
+	records, total, err := h.users.AuditLog(r.Context(), userID, page.Page, page.Limit)
+	if err != nil {
+		errors.HandleInternalError(w, err, h.logger, "list audit log")
+		return
+	}
+	h.respondPage(w, dto.MsgSuccessAuditLogRetrieved, page, total, records)
+}
+
+// userAction выполняет действие администратора над пользователем из пути и отвечает messageID
+func (h *UserHandler) userAction(
+	w http.ResponseWriter,
+	r *http.Request,
+	action func(ctx context.Context, actor domain.AuditActor, userID int) error,
+	messageID int,
+	operation string,
+) {
+	actor, ok := auditActor(w, r, h.logger)
+	if !ok {
+		return
+	}
+	userID, ok := userIDParam(w, r, h.logger)
+	if !ok {
+		return
+	}
+
+	if err := action(r.Context(), actor, userID); err != nil {
+		h.handleUserError(w, err, operation)
+		return
+	}
+	if err := httputil.JSONSuccessWithID(w, http.StatusOK, messageID, nil); err != nil {
+		errors.HandleInternalError(w, err, h.logger, "encode response")
+	}
+}
+
+// auditActor администратор из access токена и его адрес для журнала аудита
+func auditActor(w http.ResponseWriter, r *http.Request, log *logger.Logger) (domain.AuditActor, bool) {
+	userClaims, ok := r.Context().Value(middleware.CtxUserKey).(*domain.UserClaims)
+	if !ok {
+		errors.HandleInternalError(w, nil, log, "get user claims from context")
+		return domain.AuditActor{}, false
+	}
+	actorID, err := strconv.Atoi(userClaims.UserID)
+	if err != nil || actorID == 0 {
+		log.Errorw("Invalid user ID in claims", "user_id", userClaims.UserID, "error", err)
+		httputil.JSONErrorWithID(w, http.StatusBadRequest, dto.MsgInvalidUserID)
+		return domain.AuditActor{}, false
+	}
+	return domain.AuditActor{UserID: actorID, IP: httputil.ClientIP(r)}, true
+}
+
+// pagination разбирает page и limit из строки запроса; по умолчанию первая страница из defaultPageLimit записей
+func (h *UserHandler) pagination(w http.ResponseWriter, r *http.Request) (dto.PaginationRequest, bool) {
+	page := dto.PaginationRequest{Page: 1, Limit: defaultPageLimit}
+	query := r.URL.Query()
+	for name, target := range map[string]*int{"page": &page.Page, "limit": &page.Limit} {
+		raw := query.Get(name)
+		if raw == "" {
+			continue
+		}
+		value, err := strconv.Atoi(raw)
+		if err != nil {
+			httputil.JSONErrorWithID(w, http.StatusBadRequest, dto.MsgInvalidRequest)
+			return page, false
+		}
+		*target = value
+	}
+	return page, true
+}
+
+// respondPage отвечает страницей списка в формате dto.PaginationResponse
+func (h *UserHandler) respondPage(w http.ResponseWriter, messageID int, page dto.PaginationRequest, total int64, data interface{}) {
+	response := dto.PaginationResponse{
+		Page:       page.Page,
+		Limit:      page.Limit,
+		Total:      total,
+		TotalPages: int((total + int64(page.Limit) - 1) / int64(page.Limit)),
+		Data:       data,
+	}
+	if err := httputil.JSONSuccessWithID(w, http.StatusOK, messageID, response); err != nil {
+		errors.HandleInternalError(w, err, h.logger, "encode response")
+	}
+}
+
+// handleUserError отвечает по ошибке сервиса управления пользователями
+func (h *UserHandler) handleUserError(w http.ResponseWriter, err error, operation string) {
+	switch err {
+	case apperrors.ErrUserNotFound:
+		httputil.JSONErrorWithID(w, http.StatusNotFound, dto.MsgUserNotFound)
+	case apperrors.ErrUserExists:
+		httputil.JSONErrorWithID(w, http.StatusConflict, dto.MsgEmailAlreadyExists)
+	case apperrors.ErrForbidden:
+		httputil.JSONErrorWithID(w, http.StatusForbidden, dto.MsgForbidden)
+	default:
+		errors.HandleInternalError(w, err, h.logger, operation)
+	}
+}
+
+func adminUserDTO(user *domain.User, roles []string) dto.AdminUserDTO {
+	return dto.AdminUserDTO{
+		ID:                  user.ID,
+		Username:            user.UserName,
+		Email:               user.Email,
+		EmailVerifiedAt:     user.EmailVerifiedAt,
+		DisabledAt:          user.DisabledAt,
+		LockedUntil:         user.LockedUntil,
+		FailedLoginAttempts: user.FailedLoginAttempts,
+		CreatedAt:           user.CreatedAt,
+		UpdatedAt:           user.UpdatedAt,
+		Roles:               roles,
+	}
+}
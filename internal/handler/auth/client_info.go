@@ -0,0 +1,26 @@
+package auth
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/Alias1177/Auth/internal/domain"
+	"github.com/Alias1177/Auth/pkg/httputil"
+)
+
+// deviceNameHeader заголовок, которым клиент может передать название устройства
+const deviceNameHeader = "X-Device-Name"
+
+// clientInfo собирает сведения о клиенте для новой сессии.
+// Название устройства из тела запроса имеет приоритет над заголовком X-Device-Name.
+func clientInfo(r *http.Request, deviceName, loginMethod string) domain.ClientInfo {
+	if deviceName == "" {
+		deviceName = r.Header.Get(deviceNameHeader)
+	}
+	return domain.ClientInfo{
+		DeviceName:  strings.TrimSpace(deviceName),
+		UserAgent:   r.UserAgent(),
+		IPAddress:   httputil.ClientIP(r),
+		LoginMethod: loginMethod,
+	}
+}
@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/Alias1177/Auth/internal/dto"
+	apperrors "github.com/Alias1177/Auth/internal/errors"
+	"github.com/Alias1177/Auth/internal/service"
+	"github.com/Alias1177/Auth/pkg/errors"
+	"github.com/Alias1177/Auth/pkg/httputil"
+	"github.com/Alias1177/Auth/pkg/logger"
+	"github.com/Alias1177/Auth/pkg/validator"
+)
+
+// EmailChangeHandler подтверждение и отмена смены адреса по ссылкам из писем
+type EmailChangeHandler struct {
+	emailChange service.EmailChangeService
+	validator   *validator.Validator
+	logger      *logger.Logger
+}
+
+// NewEmailChangeHandler создает новый обработчик смены адреса
+func NewEmailChangeHandler(
+	emailChange service.EmailChangeService,
+	validator *validator.Validator,
+	logger *logger.Logger,
+) *EmailChangeHandler {
+	return &EmailChangeHandler{
+		emailChange: emailChange,
+		validator:   validator,
+		logger:      logger,
+	}
+}
+
+// ConfirmEmailChange применяет смену адреса токеном из письма на новый адрес
+func (h *EmailChangeHandler) ConfirmEmailChange(w http.ResponseWriter, r *http.Request) {
+	req, ok := h.decodeToken(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.emailChange.Confirm(r.Context(), req.Token); err != nil {
+		switch err {
+		case apperrors.ErrInvalidToken:
+			httputil.JSONErrorWithID(w, http.StatusBadRequest, dto.MsgInvalidToken)
+		case apperrors.ErrUserExists:
+			httputil.JSONErrorWithID(w, http.StatusConflict, dto.MsgEmailAlreadyExists)
+		default:
+			errors.HandleInternalError(w, err, h.logger, "confirm email change")
+		}
+		return
+	}
+
+	if err := httputil.JSONSuccessWithID(w, http.StatusOK, dto.MsgSuccessEmailChanged, nil); err != nil {
+		errors.HandleInternalError(w, err, h.logger, "encode response")
+	}
+}
+
+// CancelEmailChange отменяет смену адреса токеном из уведомления на старый адрес.
+// После подтвержденной смены тот же токен возвращает прежний адрес.
+func (h *EmailChangeHandler) CancelEmailChange(w http.ResponseWriter, r *http.Request) {
+	req, ok := h.decodeToken(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.emailChange.Cancel(r.Context(), req.Token); err != nil {
+		switch err {
+		case apperrors.ErrInvalidToken:
+			httputil.JSONErrorWithID(w, http.StatusBadRequest, dto.MsgInvalidToken)
+		case apperrors.ErrUserExists:
+			httputil.JSONErrorWithID(w, http.StatusConflict, dto.MsgEmailAlreadyExists)
+		default:
+			errors.HandleInternalError(w, err, h.logger, "cancel email change")
+		}
+		return
+	}
+
+	if err := httputil.JSONSuccessWithID(w, http.StatusOK, dto.MsgSuccessEmailChangeCancelled, nil); err != nil {
+		errors.HandleInternalError(w, err, h.logger, "encode response")
+	}
+}
+
+func (h *EmailChangeHandler) decodeToken(w http.ResponseWriter, r *http.Request) (dto.EmailChangeTokenRequest, bool) {
+	var req dto.EmailChangeTokenRequest
+
+	if err := httputil.DecodeJSON(r, &req, h.logger); err != nil {
+		httputil.JSONErrorWithID(w, http.StatusBadRequest, dto.MsgInvalidRequest)
+		return req, false
+	}
+
+	if err := h.validator.Validate(req); err != nil {
+		httputil.JSONErrorWithID(w, http.StatusBadRequest, dto.MsgInvalidRequestData)
+		return req, false
+	}
+	return req, true
+}
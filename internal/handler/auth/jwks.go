@@ -0,0 +1,17 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/Alias1177/Auth/pkg/errors"
+	"github.com/Alias1177/Auth/pkg/httputil"
+)
+
+// JWKS публикует публичные ключи, которыми проверяются access токены
+func (h *AuthHandler) JWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+
+	if err := httputil.JSONResponse(w, http.StatusOK, h.tokenManager.JWKS()); err != nil {
+		errors.HandleInternalError(w, err, h.logger, "encode jwks response")
+	}
+}
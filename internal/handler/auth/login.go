@@ -7,24 +7,44 @@ import (
 	"github.com/Alias1177/Auth/internal/config"
 	"github.com/Alias1177/Auth/internal/domain"
 	"github.com/Alias1177/Auth/internal/dto"
+	apperrors "github.com/Alias1177/Auth/internal/errors"
 	"github.com/Alias1177/Auth/internal/service"
 	"github.com/Alias1177/Auth/pkg/errors"
 	"github.com/Alias1177/Auth/pkg/httputil"
 	"github.com/Alias1177/Auth/pkg/logger"
-	crypto "github.com/Alias1177/Auth/pkg/security"
 	"github.com/Alias1177/Auth/pkg/sentry"
 )
 
 type AuthHandler struct {
 	tokenManager   service.TokenManager
+	sessions       service.SessionService
+	mfa            service.MFAService
+	webauthn       service.WebAuthnService
+	verification   service.EmailVerificationService
+	authService    service.AuthService
 	jwtConfig      config.JWTConfig
 	userRepository service.UserRepository
 	logger         *logger.Logger
 }
 
-func NewAuthHandler(manager service.TokenManager, cfg config.JWTConfig, repo service.UserRepository, log *logger.Logger) *AuthHandler {
+func NewAuthHandler(
+	manager service.TokenManager,
+	sessions service.SessionService,
+	mfa service.MFAService,
+	webauthn service.WebAuthnService,
+	verification service.EmailVerificationService,
+	authService service.AuthService,
+	cfg config.JWTConfig,
+	repo service.UserRepository,
+	log *logger.Logger,
+) *AuthHandler {
 	return &AuthHandler{
 		tokenManager:   manager,
+		sessions:       sessions,
+		mfa:            mfa,
+		webauthn:       webauthn,
+		verification:   verification,
+		authService:    authService,
 		jwtConfig:      cfg,
 		userRepository: repo,
 		logger:         log,
@@ -33,8 +53,9 @@ func NewAuthHandler(manager service.TokenManager, cfg config.JWTConfig, repo ser
 
 func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	var req struct {
-		Email    string `json:"email"`
-		Password string `json:"password"`
+		Email      string `json:"email"`
+		Password   string `json:"password"`
+		DeviceName string `json:"device_name"`
 	}
 
 	// Декодирование JSON запроса
@@ -45,20 +66,10 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Получение пользователя по email
-	user, err := h.userRepository.GetUserByEmail(r.Context(), req.Email)
+	// Проверка пароля с учетом блокировки входа, отключения учетной записи и подтверждения почты
+	user, err := h.authService.Login(r.Context(), req.Email, req.Password)
 	if err != nil {
-		// Отправляем ошибку в Sentry с контекстом
-		sentry.CaptureError(r.Context(), err, r)
-		errors.HandleDatabaseError(w, err, h.logger, "get user by email")
-		return
-	}
-
-	// Проверка пароля
-	if err := crypto.VerifyPassword(user.Password, req.Password); err != nil {
-		// Отправляем предупреждение в Sentry о неудачной попытке входа
-		sentry.CaptureWarning(r.Context(), "Failed login attempt", r)
-		httputil.JSONErrorWithID(w, http.StatusUnauthorized, dto.MsgWrongPassword)
+		h.handlePasswordError(w, r, err)
 		return
 	}
 
@@ -68,18 +79,21 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		Email:  user.Email,
 	}
 
-	accessToken, err := h.tokenManager.GenerateAccessToken(claims)
+	// При включенном втором факторе токены выдаются только после ввода кода на /login/mfa
+	mfaEnabled, err := h.mfa.IsEnabled(r.Context(), user.ID)
 	if err != nil {
-		// Отправляем ошибку в Sentry
 		sentry.CaptureError(r.Context(), err, r)
-		errors.HandleInternalError(w, err, h.logger, "generate access token")
+		errors.HandleInternalError(w, err, h.logger, "check mfa")
+		return
+	}
+	if mfaEnabled {
+		h.requireMFA(w, r, claims)
 		return
 	}
-	refreshToken, err := h.tokenManager.GenerateRefreshToken(claims)
+
+	tokens, err := h.sessions.Start(r.Context(), claims, clientInfo(r, req.DeviceName, domain.LoginMethodPassword))
 	if err != nil {
-		// Отправляем ошибку в Sentry
-		sentry.CaptureError(r.Context(), err, r)
-		errors.HandleInternalError(w, err, h.logger, "generate refresh token")
+		h.handleIssueError(w, r, err)
 		return
 	}
 
@@ -87,12 +101,12 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	sentry.AddUserInfo(r.Context(), strconv.Itoa(user.ID), user.Email)
 
 	// Установка токена в куки
-	httputil.SetTokenCookie(w, "access-token", accessToken)
+	httputil.SetTokenCookie(w, "access-token", tokens.AccessToken)
 
 	// Отправка успешного ответа
 	response := map[string]string{
-		"access_token":  accessToken,
-		"refresh_token": refreshToken,
+		"access_token":  tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
 	}
 
 	if err := httputil.JSONSuccessWithID(w, http.StatusOK, dto.MsgSuccessLogin, response); err != nil {
@@ -101,3 +115,34 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		errors.HandleInternalError(w, err, h.logger, "encode response")
 	}
 }
+
+// handleIssueError отвечает на ошибку выдачи токенов при входе
+func (h *AuthHandler) handleIssueError(w http.ResponseWriter, r *http.Request, err error) {
+	if err == apperrors.ErrAccountDisabled {
+		httputil.JSONErrorWithID(w, http.StatusForbidden, dto.MsgAccountDisabled)
+		return
+	}
+	// Отправляем ошибку в Sentry
+	sentry.CaptureError(r.Context(), err, r)
+	errors.HandleInternalError(w, err, h.logger, "issue tokens")
+}
+
+// handlePasswordError отвечает на отказ во входе по паролю
+func (h *AuthHandler) handlePasswordError(w http.ResponseWriter, r *http.Request, err error) {
+	switch err {
+	case apperrors.ErrInvalidLogin:
+		// Отправляем предупреждение в Sentry о неудачной попытке входа
+		sentry.CaptureWarning(r.Context(), "Failed login attempt", r)
+		httputil.JSONErrorWithID(w, http.StatusUnauthorized, dto.MsgWrongPassword)
+	case apperrors.ErrAccountLocked:
+		sentry.CaptureWarning(r.Context(), "Login attempt on locked account", r)
+		httputil.JSONErrorWithID(w, http.StatusLocked, dto.MsgAccountLocked)
+	case apperrors.ErrAccountDisabled:
+		httputil.JSONErrorWithID(w, http.StatusForbidden, dto.MsgAccountDisabled)
+	case apperrors.ErrEmailNotVerified:
+		httputil.JSONErrorWithID(w, http.StatusForbidden, dto.MsgEmailNotVerified)
+	default:
+		sentry.CaptureError(r.Context(), err, r)
+		errors.HandleInternalError(w, err, h.logger, "check password")
+	}
+}
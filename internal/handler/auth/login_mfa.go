@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/Alias1177/Auth/internal/domain"
+	"github.com/Alias1177/Auth/internal/dto"
+	apperrors "github.com/Alias1177/Auth/internal/errors"
+	"github.com/Alias1177/Auth/internal/service"
+	"github.com/Alias1177/Auth/pkg/errors"
+	"github.com/Alias1177/Auth/pkg/httputil"
+	"github.com/Alias1177/Auth/pkg/jwt"
+	"github.com/Alias1177/Auth/pkg/logger"
+	"github.com/Alias1177/Auth/pkg/sentry"
+)
+
+// requireMFA отвечает на вход с верным паролем токеном mfa_pending вместо пары токенов
+func (h *AuthHandler) requireMFA(w http.ResponseWriter, r *http.Request, claims domain.UserClaims) {
+	respondMFARequired(w, r, h.mfa, claims, h.logger)
+}
+
+// respondMFARequired выдает токен mfa_pending: вход завершается на /login/mfa вводом кода,
+// каким бы способом пользователь ни подтвердил первый фактор
+func respondMFARequired(w http.ResponseWriter, r *http.Request, mfa service.MFAService, claims domain.UserClaims, log *logger.Logger) {
+	mfaToken, err := mfa.StartChallenge(claims)
+	if err != nil {
+		sentry.CaptureError(r.Context(), err, r)
+		errors.HandleInternalError(w, err, log, "issue mfa token")
+		return
+	}
+
+	response := dto.MFARequiredResponse{
+		MFARequired: true,
+		MFAToken:    mfaToken,
+		ExpiresIn:   int(jwt.MFAPendingTokenTTL.Seconds()),
+	}
+	if err := httputil.JSONSuccessWithID(w, http.StatusOK, dto.MsgMFARequired, response); err != nil {
+		errors.HandleInternalError(w, err, log, "encode response")
+	}
+}
+
+// LoginMFA завершает вход: обменивает mfa_pending токен и код TOTP на access и refresh токены
+func (h *AuthHandler) LoginMFA(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		MFAToken   string `json:"mfa_token"`
+		Code       string `json:"code"`
+		DeviceName string `json:"device_name"`
+	}
+
+	if err := httputil.DecodeJSON(r, &req, h.logger); err != nil {
+		httputil.JSONErrorWithID(w, http.StatusBadRequest, dto.MsgInvalidRequest)
+		return
+	}
+
+	claims, err := h.mfa.CompleteChallenge(r.Context(), req.MFAToken, req.Code)
+	if err != nil {
+		switch err {
+		case apperrors.ErrInvalidToken:
+			httputil.JSONErrorWithID(w, http.StatusUnauthorized, dto.MsgTokenInvalid)
+		case apperrors.ErrInvalidMFACode:
+			sentry.CaptureWarning(r.Context(), "Failed MFA attempt", r)
+			httputil.JSONErrorWithID(w, http.StatusUnauthorized, dto.MsgInvalidMFACode)
+		case apperrors.ErrMFANotEnrolled:
+			httputil.JSONErrorWithID(w, http.StatusUnauthorized, dto.MsgMFANotEnrolled)
+		default:
+			sentry.CaptureError(r.Context(), err, r)
+			errors.HandleInternalError(w, err, h.logger, "complete mfa challenge")
+		}
+		return
+	}
+
+	tokens, err := h.sessions.Start(r.Context(), *claims, clientInfo(r, req.DeviceName, domain.LoginMethodMFA))
+	if err != nil {
+		h.handleIssueError(w, r, err)
+		return
+	}
+
+	sentry.AddUserInfo(r.Context(), claims.UserID, claims.Email)
+	httputil.SetTokenCookie(w, "access-token", tokens.AccessToken)
+
+	response := map[string]string{
+		"access_token":  tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+	}
+	if err := httputil.JSONSuccessWithID(w, http.StatusOK, dto.MsgSuccessLogin, response); err != nil {
+		errors.HandleInternalError(w, err, h.logger, "encode response")
+	}
+}
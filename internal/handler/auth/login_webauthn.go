@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Alias1177/Auth/internal/domain"
+	"github.com/Alias1177/Auth/internal/dto"
+	apperrors "github.com/Alias1177/Auth/internal/errors"
+	"github.com/Alias1177/Auth/pkg/errors"
+	"github.com/Alias1177/Auth/pkg/httputil"
+	"github.com/Alias1177/Auth/pkg/sentry"
+)
+
+// BeginWebAuthnLogin начинает вход по passkey без ввода email
+func (h *AuthHandler) BeginWebAuthnLogin(w http.ResponseWriter, r *http.Request) {
+	assertion, ceremonyID, err := h.webauthn.BeginLogin(r.Context())
+	if err != nil {
+		errors.HandleInternalError(w, err, h.logger, "begin webauthn login")
+		return
+	}
+
+	response := dto.WebAuthnOptionsResponse{CeremonyID: ceremonyID, Options: assertion}
+	if err := httputil.JSONSuccessWithID(w, http.StatusOK, dto.MsgSuccessWebAuthnOptions, response); err != nil {
+		errors.HandleInternalError(w, err, h.logger, "encode response")
+	}
+}
+
+// FinishWebAuthnLogin проверяет подпись passkey и выдает токены так же, как Login.
+// Passkey с проверкой пользователя уже является двухфакторным, поэтому TOTP не запрашивается.
+func (h *AuthHandler) FinishWebAuthnLogin(w http.ResponseWriter, r *http.Request) {
+	var req dto.WebAuthnLoginRequest
+	if err := httputil.DecodeJSON(r, &req, h.logger); err != nil {
+		httputil.JSONErrorWithID(w, http.StatusBadRequest, dto.MsgInvalidRequest)
+		return
+	}
+
+	user, err := h.webauthn.FinishLogin(r.Context(), req.CeremonyID, req.Credential)
+	if err != nil {
+		if err == apperrors.ErrWebAuthnFailed {
+			sentry.CaptureWarning(r.Context(), "Failed passkey login attempt", r)
+			httputil.JSONErrorWithID(w, http.StatusUnauthorized, dto.MsgWebAuthnFailed)
+			return
+		}
+		sentry.CaptureError(r.Context(), err, r)
+		errors.HandleInternalError(w, err, h.logger, "finish webauthn login")
+		return
+	}
+
+	if h.verification.Required() && !user.EmailVerified() {
+		httputil.JSONErrorWithID(w, http.StatusForbidden, dto.MsgEmailNotVerified)
+		return
+	}
+
+	claims := domain.UserClaims{
+		UserID: strconv.Itoa(user.ID),
+		Email:  user.Email,
+	}
+	tokens, err := h.sessions.Start(r.Context(), claims, clientInfo(r, req.DeviceName, domain.LoginMethodWebAuthn))
+	if err != nil {
+		h.handleIssueError(w, r, err)
+		return
+	}
+
+	sentry.AddUserInfo(r.Context(), strconv.Itoa(user.ID), user.Email)
+	httputil.SetTokenCookie(w, "access-token", tokens.AccessToken)
+
+	response := map[string]string{
+		"access_token":  tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+	}
+	if err := httputil.JSONSuccessWithID(w, http.StatusOK, dto.MsgSuccessLogin, response); err != nil {
+		errors.HandleInternalError(w, err, h.logger, "encode response")
+	}
+}
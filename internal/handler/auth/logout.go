@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/Alias1177/Auth/internal/domain"
+	"github.com/Alias1177/Auth/internal/dto"
+	"github.com/Alias1177/Auth/internal/middleware"
+	"github.com/Alias1177/Auth/pkg/errors"
+	"github.com/Alias1177/Auth/pkg/httputil"
+)
+
+// Logout завершает текущий вход: отзывает refresh токены этого входа и access токен
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	userClaims, ok := r.Context().Value(middleware.CtxUserKey).(*domain.UserClaims)
+	if !ok {
+		errors.HandleInternalError(w, nil, h.logger, "get user claims from context")
+		return
+	}
+
+	if err := h.authService.Logout(r.Context(), userClaims); err != nil {
+		errors.HandleInternalError(w, err, h.logger, "logout")
+		return
+	}
+
+	httputil.ClearTokenCookie(w, "access-token")
+	if err := httputil.JSONSuccessWithID(w, http.StatusOK, dto.MsgSuccessLogout, nil); err != nil {
+		errors.HandleInternalError(w, err, h.logger, "encode response")
+	}
+}
+
+// LogoutAll завершает все входы пользователя на всех устройствах
+func (h *AuthHandler) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	userClaims, ok := r.Context().Value(middleware.CtxUserKey).(*domain.UserClaims)
+	if !ok {
+		errors.HandleInternalError(w, nil, h.logger, "get user claims from context")
+		return
+	}
+
+	if err := h.authService.LogoutAll(r.Context(), userClaims); err != nil {
+		errors.HandleInternalError(w, err, h.logger, "logout all")
+		return
+	}
+
+	h.logger.Infow("User logged out on all devices", "user_id", userClaims.UserID)
+	httputil.ClearTokenCookie(w, "access-token")
+	if err := httputil.JSONSuccessWithID(w, http.StatusOK, dto.MsgSuccessLogoutAll, nil); err != nil {
+		errors.HandleInternalError(w, err, h.logger, "encode response")
+	}
+}
@@ -2,15 +2,21 @@ package auth
 
 import (
 	"context"
-	"fmt"
 	"net/http"
 	"strconv"
 	"text/template"
+	"time"
 
 	"github.com/Alias1177/Auth/internal/domain"
+	"github.com/Alias1177/Auth/internal/dto"
+	apperrors "github.com/Alias1177/Auth/internal/errors"
+	"github.com/Alias1177/Auth/internal/middleware"
 	"github.com/Alias1177/Auth/internal/service"
+	"github.com/Alias1177/Auth/pkg/errors"
+	"github.com/Alias1177/Auth/pkg/httputil"
 	"github.com/Alias1177/Auth/pkg/logger"
 	"github.com/go-chi/chi/v5"
+	"github.com/markbates/goth"
 	"github.com/markbates/goth/gothic"
 )
 
@@ -28,21 +34,37 @@ const userTemplate = `
 <p>RefreshToken: {{.RefreshToken}}</p>
 `
 
+// identityLinkCookie куки с токеном привязки учетной записи провайдера к вошедшему пользователю.
+// Токен живет в куки, а не в адресе: чужой токен нельзя подсунуть жертве ссылкой.
+const identityLinkCookie = "oauth-link"
+
+// identityLinkCookieTTL совпадает со временем жизни токена привязки
+const identityLinkCookieTTL = 10 * time.Minute
+
 type OAuthHandler struct {
 	logger       *logger.Logger
 	tokenManager service.TokenManager
-	userRepo     service.UserRepository
+	sessions     service.SessionService
+	identities   service.IdentityService
+	mfa          service.MFAService
+	verification service.EmailVerificationService
 }
 
 func NewOAuthService(
 	logger *logger.Logger,
 	tokenManager service.TokenManager,
-	userRepo service.UserRepository,
+	sessions service.SessionService,
+	identities service.IdentityService,
+	mfa service.MFAService,
+	verification service.EmailVerificationService,
 ) *OAuthHandler {
 	return &OAuthHandler{
 		logger:       logger,
 		tokenManager: tokenManager,
-		userRepo:     userRepo,
+		sessions:     sessions,
+		identities:   identities,
+		mfa:          mfa,
+		verification: verification,
 	}
 }
 
@@ -57,23 +79,34 @@ func (s *OAuthHandler) GetCallback(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "OAuth authentication failed", http.StatusInternalServerError)
 		return
 	}
+	external := externalIdentity(provider, gothUser)
+
+	// Пользователь вернулся от провайдера после начала привязки в настройках учетной записи
+	if cookie, err := r.Cookie(identityLinkCookie); err == nil {
+		clearIdentityLinkCookie(w)
+		s.completeLink(w, r, cookie.Value, external)
+		return
+	}
 
-	// Проверяем, существует ли пользователь в нашей БД
-	existingUser, err := s.userRepo.GetUserByEmail(r.Context(), gothUser.Email)
+	// Пользователь находится по учетной записи провайдера, а не по адресу почты
+	existingUser, err := s.identities.SignIn(r.Context(), external)
 	if err != nil {
-		// Пользователь не существует, создаем нового
-		newUser := &domain.User{
-			Email:    gothUser.Email,
-			UserName: gothUser.NickName,
-			// Пароль не нужен для OAuth пользователей
+		switch err {
+		case apperrors.ErrAccountLinkRequired:
+			httputil.JSONErrorWithID(w, http.StatusConflict, dto.MsgAccountLinkRequired)
+		case apperrors.ErrValidation:
+			httputil.JSONErrorWithID(w, http.StatusBadRequest, dto.MsgInvalidRequest)
+		default:
+			s.logger.Errorw("Failed to sign in with external account", "error", err, "provider", provider)
+			http.Error(w, "Failed to sign in", http.StatusInternalServerError)
 		}
+		return
+	}
 
-		if err := s.userRepo.CreateUser(r.Context(), newUser); err != nil {
-			s.logger.Errorw("Failed to create OAuth user", "error", err, "email", gothUser.Email)
-			http.Error(w, "Failed to create user", http.StatusInternalServerError)
-			return
-		}
-		existingUser = newUser
+	// Вход через провайдера подчиняется тем же правилам, что и вход по паролю
+	if s.verification.Required() && !existingUser.EmailVerified() {
+		httputil.JSONErrorWithID(w, http.StatusForbidden, dto.MsgEmailNotVerified)
+		return
 	}
 
 	// Генерируем JWT токены
@@ -82,31 +115,144 @@ func (s *OAuthHandler) GetCallback(w http.ResponseWriter, r *http.Request) {
 		Email:  existingUser.Email,
 	}
 
-	accessToken, err := s.tokenManager.GenerateAccessToken(claims)
+	// Провайдер подтверждает только первый фактор: при включенном TOTP токены выдаются после /login/mfa
+	mfaEnabled, err := s.mfa.IsEnabled(r.Context(), existingUser.ID)
+	if err != nil {
+		errors.HandleInternalError(w, err, s.logger, "check mfa")
+		return
+	}
+	if mfaEnabled {
+		respondMFARequired(w, r, s.mfa, claims, s.logger)
+		return
+	}
+
+	tokens, err := s.sessions.Start(r.Context(), claims, clientInfo(r, "", domain.LoginMethodOAuth))
+	if err != nil {
+		if err == apperrors.ErrAccountDisabled {
+			httputil.JSONErrorWithID(w, http.StatusForbidden, dto.MsgAccountDisabled)
+			return
+		}
+		errors.HandleInternalError(w, err, s.logger, "issue tokens")
+		return
+	}
+
+	response := dto.LoginResponse{
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		User: dto.UserDTO{
+			ID:        existingUser.ID,
+			Username:  existingUser.UserName,
+			Email:     existingUser.Email,
+			CreatedAt: existingUser.CreatedAt,
+			UpdatedAt: existingUser.UpdatedAt,
+		},
+	}
+	if err := httputil.JSONSuccessWithID(w, http.StatusOK, dto.MsgSuccessLogin, response); err != nil {
+		errors.HandleInternalError(w, err, s.logger, "encode response")
+	}
+}
+
+// StartIdentityLink начинает привязку учетной записи провайдера к вошедшему пользователю:
+// сохраняет токен привязки в куки и возвращает адрес, по которому браузер продолжает вход у провайдера
+func (s *OAuthHandler) StartIdentityLink(w http.ResponseWriter, r *http.Request) {
+	userClaims, ok := r.Context().Value(middleware.CtxUserKey).(*domain.UserClaims)
+	if !ok {
+		errors.HandleInternalError(w, nil, s.logger, "get user claims from context")
+		return
+	}
+	userID, err := strconv.Atoi(userClaims.UserID)
+	if err != nil {
+		httputil.JSONErrorWithID(w, http.StatusBadRequest, dto.MsgInvalidUserID)
+		return
+	}
+
+	provider := chi.URLParam(r, "provider")
+	if _, err := goth.GetProvider(provider); err != nil {
+		httputil.JSONErrorWithID(w, http.StatusNotFound, dto.MsgResourceNotFound)
+		return
+	}
+
+	token, err := s.identities.StartLink(r.Context(), userID, provider)
 	if err != nil {
-		s.logger.Errorw("Failed to generate access token", "error", err)
-		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		errors.HandleInternalError(w, err, s.logger, "start identity link")
 		return
 	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     identityLinkCookie,
+		Value:    token,
+		Path:     "/auth/",
+		MaxAge:   int(identityLinkCookieTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   true,
+		// Возврат от провайдера - переход с чужого сайта, куки со Strict в нем не отправляются
+		SameSite: http.SameSiteLaxMode,
+	})
 
-	refreshToken, err := s.tokenManager.GenerateRefreshToken(claims)
+	response := dto.IdentityLinkResponse{AuthorizationURL: "/auth/" + provider}
+	if err := httputil.JSONSuccessWithID(w, http.StatusOK, dto.MsgSuccessIdentityLinkStarted, response); err != nil {
+		errors.HandleInternalError(w, err, s.logger, "encode identity link response")
+	}
+}
+
+// completeLink привязывает учетную запись, с которой пользователь вернулся от провайдера
+func (s *OAuthHandler) completeLink(w http.ResponseWriter, r *http.Request, token string, external domain.ExternalIdentity) {
+	identity, err := s.identities.CompleteLink(r.Context(), token, external)
 	if err != nil {
-		s.logger.Errorw("Failed to generate refresh token", "error", err)
-		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		switch err {
+		case apperrors.ErrInvalidToken:
+			httputil.JSONErrorWithID(w, http.StatusBadRequest, dto.MsgTokenInvalid)
+		case apperrors.ErrIdentityLinked:
+			httputil.JSONErrorWithID(w, http.StatusConflict, dto.MsgIdentityLinked)
+		default:
+			errors.HandleInternalError(w, err, s.logger, "complete identity link")
+		}
 		return
 	}
 
-	// Возвращаем JSON ответ с токенами
-	w.Header().Set("Content-Type", "application/json")
-	fmt.Fprintf(w, `{
-		"access_token": "%s",
-		"refresh_token": "%s",
-		"user": {
-			"id": %d,
-			"email": "%s",
-			"username": "%s"
+	response := dto.UserIdentityResponse{
+		Provider:      identity.Provider,
+		Email:         identity.Email,
+		EmailVerified: identity.EmailVerified,
+		CreatedAt:     identity.CreatedAt,
+		LastUsedAt:    identity.LastUsedAt,
+	}
+	if err := httputil.JSONSuccessWithID(w, http.StatusOK, dto.MsgSuccessIdentityLinked, response); err != nil {
+		errors.HandleInternalError(w, err, s.logger, "encode identity response")
+	}
+}
+
+// externalIdentity переводит пользователя goth в учетную запись провайдера.
+// Подтверждение адреса берется из ответа провайдера: verified_email (Google) или email_verified (OpenID Connect);
+// без него адрес считается неподтвержденным.
+func externalIdentity(provider string, user goth.User) domain.ExternalIdentity {
+	verified := false
+	for _, field := range []string{"verified_email", "email_verified"} {
+		switch value := user.RawData[field].(type) {
+		case bool:
+			verified = verified || value
+		case string:
+			verified = verified || value == "true"
 		}
-	}`, accessToken, refreshToken, existingUser.ID, existingUser.Email, existingUser.UserName)
+	}
+	return domain.ExternalIdentity{
+		Provider:       provider,
+		ProviderUserID: user.UserID,
+		Email:          user.Email,
+		EmailVerified:  verified,
+		UserName:       user.NickName,
+	}
+}
+
+func clearIdentityLinkCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     identityLinkCookie,
+		Value:    "",
+		Path:     "/auth/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
 }
 
 func (s *OAuthHandler) GetLogout(w http.ResponseWriter, r *http.Request) {
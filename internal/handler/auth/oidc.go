@@ -0,0 +1,299 @@
+package auth
+
+import (
+	stderrors "errors"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/Alias1177/Auth/internal/config"
+	"github.com/Alias1177/Auth/internal/domain"
+	"github.com/Alias1177/Auth/internal/dto"
+	apperrors "github.com/Alias1177/Auth/internal/errors"
+	"github.com/Alias1177/Auth/internal/middleware"
+	"github.com/Alias1177/Auth/internal/service"
+	"github.com/Alias1177/Auth/pkg/errors"
+	"github.com/Alias1177/Auth/pkg/httputil"
+	"github.com/Alias1177/Auth/pkg/logger"
+)
+
+// Типы грантов ручки /oauth2/token
+const (
+	grantTypeAuthorizationCode = "authorization_code"
+	grantTypeRefreshToken      = "refresh_token"
+	grantTypeClientCredentials = "client_credentials"
+	grantTypeDeviceCode        = "urn:ietf:params:oauth:grant-type:device_code"
+)
+
+// OIDCHandler провайдер OpenID Connect для других приложений: discovery, вход со страницей согласия,
+// обмен кода авторизации на токены, userinfo, интроспекция и отзыв токенов
+type OIDCHandler struct {
+	oidc           service.OIDCService
+	introspection  service.TokenIntrospectionService
+	authService    service.AuthService
+	mfa            service.MFAService
+	tokenManager   service.TokenManager
+	userRepository service.UserRepository
+	cfg            config.OIDCConfig
+	logger         *logger.Logger
+}
+
+// NewOIDCHandler создает новый обработчик OpenID Connect
+func NewOIDCHandler(
+	oidc service.OIDCService,
+	introspection service.TokenIntrospectionService,
+	authService service.AuthService,
+	mfa service.MFAService,
+	tokenManager service.TokenManager,
+	repo service.UserRepository,
+	cfg config.OIDCConfig,
+	log *logger.Logger,
+) *OIDCHandler {
+	return &OIDCHandler{
+		oidc:           oidc,
+		introspection:  introspection,
+		authService:    authService,
+		mfa:            mfa,
+		tokenManager:   tokenManager,
+		userRepository: repo,
+		cfg:            cfg,
+		logger:         log,
+	}
+}
+
+// Discovery публикует документ /.well-known/openid-configuration
+func (h *OIDCHandler) Discovery(w http.ResponseWriter, r *http.Request) {
+	// ID токены подписываются теми же ключами, что и access токены
+	var algs []string
+	seen := make(map[string]bool)
+	for _, key := range h.tokenManager.JWKS().Keys {
+		if !seen[key.Alg] {
+			seen[key.Alg] = true
+			algs = append(algs, key.Alg)
+		}
+	}
+
+	response := dto.OIDCDiscoveryResponse{
+		Issuer:                      h.cfg.Issuer,
+		AuthorizationEndpoint:       h.cfg.Issuer + "/oauth2/authorize",
+		TokenEndpoint:               h.cfg.Issuer + "/oauth2/token",
+		UserInfoEndpoint:            h.cfg.Issuer + "/oauth2/userinfo",
+		IntrospectionEndpoint:       h.cfg.Issuer + "/oauth2/introspect",
+		RevocationEndpoint:          h.cfg.Issuer + "/oauth2/revoke",
+		DeviceAuthorizationEndpoint: h.cfg.Issuer + "/oauth2/device_authorization",
+		JWKSURI:                     h.cfg.Issuer + "/.well-known/jwks.json",
+		ResponseTypesSupported:      []string{"code"},
+		GrantTypesSupported: []string{
+			grantTypeAuthorizationCode, grantTypeRefreshToken, grantTypeClientCredentials, grantTypeDeviceCode,
+		},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  algs,
+		ScopesSupported:                   service.SupportedScopes,
+		TokenEndpointAuthMethodsSupported: []string{"none", "client_secret_basic", "client_secret_post"},
+		ClaimsSupported: []string{
+			"iss", "sub", "aud", "exp", "iat", "auth_time", "nonce",
+			"email", "email_verified", "preferred_username",
+		},
+		CodeChallengeMethodsSupported: []string{"S256"},
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	if err := httputil.JSONResponse(w, http.StatusOK, response); err != nil {
+		errors.HandleInternalError(w, err, h.logger, "encode discovery response")
+	}
+}
+
+// Token выдает токены по коду авторизации, refresh токену, client_credentials или device_code (application/x-www-form-urlencoded).
+// Публичный клиент передает client_id в теле или как имя пользователя в Basic авторизации,
+// конфиденциальный - client_id и client_secret в Basic авторизации или в теле.
+func (h *OIDCHandler) Token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		h.writeOAuthError(w, apperrors.NewOAuthError(apperrors.OAuthInvalidRequest, "malformed form body"))
+		return
+	}
+	clientID, clientSecret, authErr := tokenClientCredentials(r)
+	if authErr != nil {
+		h.writeOAuthError(w, authErr)
+		return
+	}
+
+	var (
+		tokens *domain.OAuthTokens
+		err    error
+	)
+	switch r.PostForm.Get("grant_type") {
+	case grantTypeAuthorizationCode:
+		tokens, err = h.oidc.ExchangeCode(r.Context(), clientID, clientSecret,
+			r.PostForm.Get("code"), r.PostForm.Get("redirect_uri"), r.PostForm.Get("code_verifier"))
+	case grantTypeRefreshToken:
+		tokens, err = h.oidc.Refresh(r.Context(), clientID, clientSecret, r.PostForm.Get("refresh_token"))
+	case grantTypeClientCredentials:
+		tokens, err = h.oidc.ClientCredentials(r.Context(), clientID, clientSecret, r.PostForm.Get("scope"))
+	case grantTypeDeviceCode:
+		tokens, err = h.oidc.ExchangeDeviceCode(r.Context(), clientID, clientSecret, r.PostForm.Get("device_code"))
+	default:
+		err = apperrors.NewOAuthError(apperrors.OAuthUnsupportedGrantType,
+			"supported grant types: authorization_code, refresh_token, client_credentials, "+grantTypeDeviceCode)
+	}
+	if err != nil {
+		h.handleOAuthError(w, err, "issue oauth tokens")
+		return
+	}
+
+	response := dto.OAuthTokenResponse{
+		AccessToken:  tokens.AccessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    tokens.ExpiresIn,
+		RefreshToken: tokens.RefreshToken,
+		IDToken:      tokens.IDToken,
+		Scope:        tokens.Scope,
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	if err := httputil.JSONResponse(w, http.StatusOK, response); err != nil {
+		errors.HandleInternalError(w, err, h.logger, "encode token response")
+	}
+}
+
+// Introspect сообщает конфиденциальному клиенту состояние токена (RFC 7662)
+func (h *OIDCHandler) Introspect(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		h.writeOAuthError(w, apperrors.NewOAuthError(apperrors.OAuthInvalidRequest, "malformed form body"))
+		return
+	}
+	clientID, clientSecret, authErr := tokenClientCredentials(r)
+	if authErr != nil {
+		h.writeOAuthError(w, authErr)
+		return
+	}
+	token := r.PostForm.Get("token")
+	if token == "" {
+		h.writeOAuthError(w, apperrors.NewOAuthError(apperrors.OAuthInvalidRequest, "token is required"))
+		return
+	}
+
+	result, err := h.introspection.Introspect(r.Context(), clientID, clientSecret, token, r.PostForm.Get("token_type_hint"))
+	if err != nil {
+		h.handleOAuthError(w, err, "introspect token")
+		return
+	}
+
+	response := dto.OAuthIntrospectionResponse{
+		Active:    result.Active,
+		TokenType: result.TokenType,
+		Subject:   result.Subject,
+		ClientID:  result.ClientID,
+		Scope:     result.Scope,
+		TokenID:   result.TokenID,
+		IssuedAt:  result.IssuedAt,
+		ExpiresAt: result.ExpiresAt,
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	if err := httputil.JSONResponse(w, http.StatusOK, response); err != nil {
+		errors.HandleInternalError(w, err, h.logger, "encode introspection response")
+	}
+}
+
+// Revoke отзывает access или refresh токен (RFC 7009). Неизвестный или уже недействительный токен
+// тоже дает 200: клиенту достаточно знать, что токен больше не действует.
+func (h *OIDCHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		h.writeOAuthError(w, apperrors.NewOAuthError(apperrors.OAuthInvalidRequest, "malformed form body"))
+		return
+	}
+	clientID, clientSecret, authErr := tokenClientCredentials(r)
+	if authErr != nil {
+		h.writeOAuthError(w, authErr)
+		return
+	}
+	token := r.PostForm.Get("token")
+	if token == "" {
+		h.writeOAuthError(w, apperrors.NewOAuthError(apperrors.OAuthInvalidRequest, "token is required"))
+		return
+	}
+
+	if err := h.introspection.Revoke(r.Context(), clientID, clientSecret, token, r.PostForm.Get("token_type_hint")); err != nil {
+		h.handleOAuthError(w, err, "revoke token")
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+}
+
+// UserInfo возвращает сведения о владельце access токена
+func (h *OIDCHandler) UserInfo(w http.ResponseWriter, r *http.Request) {
+	userClaims, ok := r.Context().Value(middleware.CtxUserKey).(*domain.UserClaims)
+	if !ok {
+		errors.HandleInternalError(w, nil, h.logger, "get user claims from context")
+		return
+	}
+	userID, err := strconv.Atoi(userClaims.UserID)
+	if err != nil {
+		httputil.JSONErrorWithID(w, http.StatusUnauthorized, dto.MsgInvalidToken)
+		return
+	}
+
+	user, err := h.userRepository.GetUserByID(r.Context(), userID)
+	if err != nil {
+		errors.HandleDatabaseError(w, err, h.logger, "get user by id")
+		return
+	}
+
+	response := dto.OIDCUserInfoResponse{
+		Subject:           userClaims.UserID,
+		Email:             user.Email,
+		EmailVerified:     user.EmailVerified(),
+		PreferredUsername: user.UserName,
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	if err := httputil.JSONResponse(w, http.StatusOK, response); err != nil {
+		errors.HandleInternalError(w, err, h.logger, "encode userinfo response")
+	}
+}
+
+// tokenClientCredentials читает client_id и client_secret из Basic авторизации или из тела запроса.
+// В Basic авторизации они закодированы как application/x-www-form-urlencoded (RFC 6749, раздел 2.3.1);
+// одновременное использование обоих способов запрещено.
+func tokenClientCredentials(r *http.Request) (string, string, *apperrors.OAuthError) {
+	clientID, clientSecret := r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		return clientID, clientSecret, nil
+	}
+	if clientSecret != "" || (clientID != "" && clientID != username) {
+		return "", "", apperrors.NewOAuthError(apperrors.OAuthInvalidRequest, "use only one client authentication method")
+	}
+	basicID, err := url.QueryUnescape(username)
+	if err != nil {
+		return "", "", apperrors.NewOAuthError(apperrors.OAuthInvalidClient, "malformed client credentials")
+	}
+	basicSecret, err := url.QueryUnescape(password)
+	if err != nil {
+		return "", "", apperrors.NewOAuthError(apperrors.OAuthInvalidClient, "malformed client credentials")
+	}
+	return basicID, basicSecret, nil
+}
+
+// handleOAuthError отвечает ошибкой протокола OAuth или, для остальных ошибок, внутренней ошибкой
+func (h *OIDCHandler) handleOAuthError(w http.ResponseWriter, err error, operation string) {
+	var oauthErr *apperrors.OAuthError
+	if stderrors.As(err, &oauthErr) {
+		h.writeOAuthError(w, oauthErr)
+		return
+	}
+	errors.HandleInternalError(w, err, h.logger, operation)
+}
+
+// writeOAuthError отвечает ошибкой протокола OAuth в формате RFC 6749
+func (h *OIDCHandler) writeOAuthError(w http.ResponseWriter, err *apperrors.OAuthError) {
+	h.logger.Warnw("OAuth request rejected", "error", err.Code, "description", err.Description)
+	w.Header().Set("Cache-Control", "no-store")
+	if err.Code == apperrors.OAuthInvalidClient {
+		w.Header().Set("WWW-Authenticate", `Basic realm="oauth2"`)
+	}
+	response := dto.OAuthErrorResponse{Error: err.Code, ErrorDescription: err.Description}
+	if encodeErr := httputil.JSONResponse(w, err.HTTPStatus(), response); encodeErr != nil {
+		h.logger.Errorw("Failed to encode oauth error", "error", encodeErr)
+	}
+}
@@ -0,0 +1,287 @@
+package auth
+
+import (
+	stderrors "errors"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/Alias1177/Auth/internal/domain"
+	"github.com/Alias1177/Auth/internal/dto"
+	apperrors "github.com/Alias1177/Auth/internal/errors"
+	"github.com/Alias1177/Auth/pkg/httputil"
+)
+
+// authorizePage страница входа и согласия. Параметры запроса авторизации передаются скрытыми полями,
+// поэтому ее POST проверяется заново так же, как исходный GET.
+var authorizePage = template.Must(template.New("authorize").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Sign in to {{.ClientName}}</title>
+<style>
+body { font-family: sans-serif; max-width: 360px; margin: 48px auto; padding: 0 16px; }
+label, input, button { display: block; width: 100%; box-sizing: border-box; }
+input { margin: 4px 0 12px; padding: 8px; }
+button { margin-top: 8px; padding: 10px; }
+.error { color: #b00020; }
+</style>
+</head>
+<body>
+<h1>Sign in</h1>
+<p><strong>{{.ClientName}}</strong> wants to access your account: {{range $i, $s := .Scopes}}{{if $i}}, {{end}}{{$s}}{{end}}.</p>
+{{if .Error}}<p class="error">{{.Error}}</p>{{end}}
+<form method="post" action="/oauth2/authorize">
+{{range $name, $value := .Params}}<input type="hidden" name="{{$name}}" value="{{$value}}">
+{{end}}{{if .MFAToken}}<input type="hidden" name="mfa_token" value="{{.MFAToken}}">
+<label for="code">Two-factor code</label>
+<input id="code" name="code" inputmode="numeric" autocomplete="one-time-code" autofocus required>
+{{else}}<label for="email">Email</label>
+<input id="email" name="email" type="email" value="{{.Email}}" autocomplete="username" required>
+<label for="password">Password</label>
+<input id="password" name="password" type="password" autocomplete="current-password" required>
+{{end}}<button type="submit" name="action" value="allow">Allow</button>
+<button type="submit" name="action" value="deny" formnovalidate>Deny</button>
+</form>
+</body>
+</html>
+`))
+
+// authorizePageData данные страницы входа и согласия
+type authorizePageData struct {
+	ClientName string
+	Scopes     []string
+	Params     map[string]string
+	Email      string
+	MFAToken   string
+	Error      string
+}
+
+// Authorize начинает вход приложения: проверяет запрос и показывает страницу входа и согласия.
+// Сессии провайдера в браузере нет, поэтому prompt=none всегда завершается login_required.
+func (h *OIDCHandler) Authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	req := authorizationRequest(query)
+	client, scope, ok := h.checkAuthorization(w, r, req)
+	if !ok {
+		return
+	}
+	if query.Get("prompt") == "none" {
+		h.redirectError(w, r, req, apperrors.NewOAuthError(apperrors.OAuthLoginRequired, "user must sign in"))
+		return
+	}
+
+	h.renderAuthorize(w, http.StatusOK, client, scope, req, authorizePageData{})
+}
+
+// AuthorizeSubmit обрабатывает форму страницы входа: пароль проверяется так же, как на /login,
+// при включенном втором факторе запрашивается код, после чего приложению выдается код авторизации
+func (h *OIDCHandler) AuthorizeSubmit(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	form := r.PostForm
+	req := authorizationRequest(form)
+	client, scope, ok := h.checkAuthorization(w, r, req)
+	if !ok {
+		return
+	}
+	if form.Get("action") != "allow" {
+		h.redirectError(w, r, req, apperrors.NewOAuthError(apperrors.OAuthAccessDenied, "user denied access"))
+		return
+	}
+
+	var (
+		claims      domain.UserClaims
+		loginMethod string
+	)
+	if mfaToken := form.Get("mfa_token"); mfaToken != "" {
+		completed, err := h.mfa.CompleteChallenge(r.Context(), mfaToken, form.Get("code"))
+		switch err {
+		case nil:
+			claims, loginMethod = *completed, domain.LoginMethodMFA
+		case apperrors.ErrInvalidMFACode:
+			h.renderAuthorize(w, http.StatusUnauthorized, client, scope, req, authorizePageData{
+				MFAToken: mfaToken,
+				Error:    httputil.MessageEnByID(dto.MsgInvalidMFACode),
+			})
+			return
+		case apperrors.ErrInvalidToken, apperrors.ErrMFANotEnrolled:
+			// Попытки ввода кода исчерпаны или токен истек: вход начинается заново
+			h.renderAuthorize(w, http.StatusUnauthorized, client, scope, req, authorizePageData{
+				Error: httputil.MessageEnByID(dto.MsgInvalidToken),
+			})
+			return
+		default:
+			h.authorizeInternalError(w, err, "complete mfa challenge")
+			return
+		}
+	} else {
+		email := form.Get("email")
+		user, err := h.authService.Login(r.Context(), email, form.Get("password"))
+		if err != nil {
+			if message, ok := passwordErrorMessage(err); ok {
+				h.renderAuthorize(w, http.StatusUnauthorized, client, scope, req, authorizePageData{Email: email, Error: message})
+				return
+			}
+			h.authorizeInternalError(w, err, "check password")
+			return
+		}
+		claims = domain.UserClaims{UserID: strconv.Itoa(user.ID), Email: user.Email}
+		loginMethod = domain.LoginMethodPassword
+
+		mfaEnabled, err := h.mfa.IsEnabled(r.Context(), user.ID)
+		if err != nil {
+			h.authorizeInternalError(w, err, "check mfa")
+			return
+		}
+		if mfaEnabled {
+			mfaToken, err := h.mfa.StartChallenge(claims)
+			if err != nil {
+				h.authorizeInternalError(w, err, "start mfa challenge")
+				return
+			}
+			h.renderAuthorize(w, http.StatusOK, client, scope, req, authorizePageData{MFAToken: mfaToken})
+			return
+		}
+	}
+
+	userID, err := strconv.Atoi(claims.UserID)
+	if err != nil {
+		h.authorizeInternalError(w, err, "parse user id")
+		return
+	}
+	code, err := h.oidc.IssueCode(r.Context(), req, scope, userID, clientInfo(r, "", loginMethod))
+	if err != nil {
+		h.authorizeInternalError(w, err, "issue authorization code")
+		return
+	}
+
+	h.redirect(w, r, req, url.Values{"code": {code}})
+}
+
+// checkAuthorization проверяет приложение и параметры запроса. Неизвестное приложение или адрес возврата
+// показываются пользователю, не уходя на адрес возврата; остальные ошибки отправляются приложению.
+func (h *OIDCHandler) checkAuthorization(w http.ResponseWriter, r *http.Request, req domain.AuthorizationRequest) (*domain.OAuthClient, string, bool) {
+	client, err := h.oidc.Client(r.Context(), req.ClientID, req.RedirectURI)
+	if err != nil {
+		var oauthErr *apperrors.OAuthError
+		if stderrors.As(err, &oauthErr) {
+			h.logger.Warnw("Authorization request rejected", "client_id", req.ClientID, "error", oauthErr.Code)
+			http.Error(w, "Invalid client_id or redirect_uri", http.StatusBadRequest)
+			return nil, "", false
+		}
+		h.authorizeInternalError(w, err, "get oauth client")
+		return nil, "", false
+	}
+
+	scope, err := h.oidc.ValidateRequest(req)
+	if err != nil {
+		h.redirectError(w, r, req, err)
+		return nil, "", false
+	}
+	return client, scope, true
+}
+
+// renderAuthorize показывает страницу входа и согласия
+func (h *OIDCHandler) renderAuthorize(
+	w http.ResponseWriter,
+	status int,
+	client *domain.OAuthClient,
+	scope string,
+	req domain.AuthorizationRequest,
+	data authorizePageData,
+) {
+	data.ClientName = client.Name
+	data.Scopes = strings.Fields(scope)
+	data.Params = map[string]string{
+		"client_id":             req.ClientID,
+		"redirect_uri":          req.RedirectURI,
+		"response_type":         req.ResponseType,
+		"scope":                 req.Scope,
+		"state":                 req.State,
+		"nonce":                 req.Nonce,
+		"code_challenge":        req.CodeChallenge,
+		"code_challenge_method": req.CodeChallengeMethod,
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	// Страницу с паролем и кнопкой согласия нельзя встраивать во фреймы чужих сайтов
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; frame-ancestors 'none'")
+	w.WriteHeader(status)
+	if err := authorizePage.Execute(w, data); err != nil {
+		h.logger.Errorw("Failed to render authorize page", "error", err)
+	}
+}
+
+// redirectError отправляет ошибку приложению на проверенный адрес возврата
+func (h *OIDCHandler) redirectError(w http.ResponseWriter, r *http.Request, req domain.AuthorizationRequest, err error) {
+	var oauthErr *apperrors.OAuthError
+	if !stderrors.As(err, &oauthErr) {
+		h.authorizeInternalError(w, err, "validate authorization request")
+		return
+	}
+	h.redirect(w, r, req, url.Values{
+		"error":             {oauthErr.Code},
+		"error_description": {oauthErr.Description},
+	})
+}
+
+// redirect возвращает браузер на адрес возврата приложения с параметрами и state
+func (h *OIDCHandler) redirect(w http.ResponseWriter, r *http.Request, req domain.AuthorizationRequest, params url.Values) {
+	target, err := url.Parse(req.RedirectURI)
+	if err != nil {
+		h.authorizeInternalError(w, err, "parse redirect uri")
+		return
+	}
+	query := target.Query()
+	for name, values := range params {
+		query[name] = values
+	}
+	if req.State != "" {
+		query.Set("state", req.State)
+	}
+	target.RawQuery = query.Encode()
+
+	http.Redirect(w, r, target.String(), http.StatusFound)
+}
+
+func (h *OIDCHandler) authorizeInternalError(w http.ResponseWriter, err error, operation string) {
+	h.logger.Errorw("Authorization failed", "operation", operation, "error", err)
+	http.Error(w, "Internal server error", http.StatusInternalServerError)
+}
+
+// authorizationRequest читает параметры запроса авторизации из строки запроса или формы
+func authorizationRequest(values url.Values) domain.AuthorizationRequest {
+	return domain.AuthorizationRequest{
+		ClientID:            values.Get("client_id"),
+		RedirectURI:         values.Get("redirect_uri"),
+		ResponseType:        values.Get("response_type"),
+		Scope:               values.Get("scope"),
+		State:               values.Get("state"),
+		Nonce:               values.Get("nonce"),
+		CodeChallenge:       values.Get("code_challenge"),
+		CodeChallengeMethod: values.Get("code_challenge_method"),
+	}
+}
+
+// passwordErrorMessage текст отказа во входе по паролю для страницы входа; false для внутренних ошибок
+func passwordErrorMessage(err error) (string, bool) {
+	switch err {
+	case apperrors.ErrInvalidLogin:
+		return httputil.MessageEnByID(dto.MsgWrongPassword), true
+	case apperrors.ErrAccountLocked:
+		return httputil.MessageEnByID(dto.MsgAccountLocked), true
+	case apperrors.ErrAccountDisabled:
+		return httputil.MessageEnByID(dto.MsgAccountDisabled), true
+	case apperrors.ErrEmailNotVerified:
+		return httputil.MessageEnByID(dto.MsgEmailNotVerified), true
+	}
+	return "", false
+}
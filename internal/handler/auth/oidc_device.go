@@ -0,0 +1,271 @@
+package auth
+
+import (
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/Alias1177/Auth/internal/domain"
+	"github.com/Alias1177/Auth/internal/dto"
+	apperrors "github.com/Alias1177/Auth/internal/errors"
+	"github.com/Alias1177/Auth/internal/middleware"
+	"github.com/Alias1177/Auth/internal/service"
+	"github.com/Alias1177/Auth/pkg/errors"
+	"github.com/Alias1177/Auth/pkg/httputil"
+)
+
+// devicePage страница подтверждения устройства: ввод user_code, затем согласие на вход приложения.
+// Вошедший пользователь узнается по куки access-token, которая не уходит с чужих сайтов (SameSite=Strict).
+// Без нее форма согласия запрашивает пароль, как /oauth2/authorize, а user_code передается скрытым полем.
+var devicePage = template.Must(template.New("device").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Connect a device</title>
+<style>
+body { font-family: sans-serif; max-width: 360px; margin: 48px auto; padding: 0 16px; }
+label, input, button { display: block; width: 100%; box-sizing: border-box; }
+input { margin: 4px 0 12px; padding: 8px; }
+#user_code { text-transform: uppercase; }
+button { margin-top: 8px; padding: 10px; }
+.error { color: #b00020; }
+</style>
+</head>
+<body>
+<h1>Connect a device</h1>
+{{if .Done}}<p>{{.Done}}</p>
+{{else if .ClientName}}<p><strong>{{.ClientName}}</strong> on another device wants to access your account{{if .Scopes}}: {{range $i, $s := .Scopes}}{{if $i}}, {{end}}{{$s}}{{end}}{{end}}.</p>
+<p>Make sure the device shows the code <strong>{{.UserCode}}</strong>.</p>
+{{if .Error}}<p class="error">{{.Error}}</p>{{end}}
+<form method="post" action="/oauth2/device">
+<input type="hidden" name="user_code" value="{{.UserCode}}">
+{{if .MFAToken}}<input type="hidden" name="mfa_token" value="{{.MFAToken}}">
+<label for="code">Two-factor code</label>
+<input id="code" name="code" inputmode="numeric" autocomplete="one-time-code" autofocus required>
+{{else if .SignIn}}<label for="email">Email</label>
+<input id="email" name="email" type="email" value="{{.Email}}" autocomplete="username" required>
+<label for="password">Password</label>
+<input id="password" name="password" type="password" autocomplete="current-password" required>
+{{end}}<button type="submit" name="action" value="allow">Allow</button>
+<button type="submit" name="action" value="deny" formnovalidate>Deny</button>
+</form>
+{{else}}{{if .Error}}<p class="error">{{.Error}}</p>{{end}}
+<form method="get" action="/oauth2/device">
+<label for="user_code">Enter the code shown on your device</label>
+<input id="user_code" name="user_code" value="{{.UserCode}}" autocomplete="off" autofocus required>
+<button type="submit">Continue</button>
+</form>
+{{end}}</body>
+</html>
+`))
+
+// devicePageData данные страницы подтверждения устройства
+type devicePageData struct {
+	ClientName string
+	Scopes     []string
+	UserCode   string
+	// SignIn показать поля входа: страница открыта без куки access-token
+	SignIn   bool
+	Email    string
+	MFAToken string
+	Error    string
+	Done     string
+}
+
+// DeviceAuthorization выдает устройству device_code и user_code (RFC 8628, раздел 3.1)
+func (h *OIDCHandler) DeviceAuthorization(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		h.writeOAuthError(w, apperrors.NewOAuthError(apperrors.OAuthInvalidRequest, "malformed form body"))
+		return
+	}
+	clientID, clientSecret, authErr := tokenClientCredentials(r)
+	if authErr != nil {
+		h.writeOAuthError(w, authErr)
+		return
+	}
+
+	code, err := h.oidc.StartDeviceAuthorization(r.Context(), clientID, clientSecret,
+		r.PostForm.Get("scope"), clientInfo(r, "", domain.LoginMethodDevice))
+	if err != nil {
+		h.handleOAuthError(w, err, "start device authorization")
+		return
+	}
+
+	verificationURI := h.cfg.Issuer + "/oauth2/device"
+	response := dto.OAuthDeviceAuthorizationResponse{
+		DeviceCode:              code.DeviceCode,
+		UserCode:                code.UserCode,
+		VerificationURI:         verificationURI,
+		VerificationURIComplete: verificationURI + "?" + url.Values{"user_code": {code.UserCode}}.Encode(),
+		ExpiresIn:               code.ExpiresIn,
+		Interval:                code.Interval,
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	if err := httputil.JSONResponse(w, http.StatusOK, response); err != nil {
+		errors.HandleInternalError(w, err, h.logger, "encode device authorization response")
+	}
+}
+
+// Device показывает форму ввода user_code, а для введенного кода - приложение и запрошенные области доступа.
+// Если пользователь не вошел, форма согласия запрашивает email и пароль.
+func (h *OIDCHandler) Device(w http.ResponseWriter, r *http.Request) {
+	userCode := r.URL.Query().Get("user_code")
+	if userCode == "" {
+		h.renderDevice(w, http.StatusOK, devicePageData{})
+		return
+	}
+
+	_, signedIn := r.Context().Value(middleware.CtxUserKey).(*domain.UserClaims)
+	h.renderDeviceConsent(w, r, http.StatusOK, userCode, devicePageData{SignIn: !signedIn})
+}
+
+// DeviceSubmit подтверждает или отклоняет вход устройства. Вошедший пользователь определяется по access токену,
+// остальные входят паролем и, если включено, кодом второго фактора прямо в форме согласия.
+func (h *OIDCHandler) DeviceSubmit(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	userCode := r.PostForm.Get("user_code")
+	approve := r.PostForm.Get("action") == "allow"
+	var userID int
+	if userClaims, ok := r.Context().Value(middleware.CtxUserKey).(*domain.UserClaims); ok {
+		id, err := strconv.Atoi(userClaims.UserID)
+		if err != nil {
+			http.Error(w, "Unauthorized - invalid token", http.StatusUnauthorized)
+			return
+		}
+		userID = id
+	} else if approve {
+		// Отказ, как и на /oauth2/authorize, входа не требует: пользователь в нем не записывается
+		id, ok := h.deviceSignIn(w, r, userCode)
+		if !ok {
+			return
+		}
+		userID = id
+	}
+
+	if err := h.oidc.CompleteDeviceAuthorization(r.Context(), userCode, userID, approve); err != nil {
+		h.deviceCodeError(w, err, userCode)
+		return
+	}
+
+	done := "Access denied. You can close this page."
+	if approve {
+		done = "Device connected. You can return to your device."
+	}
+	h.renderDevice(w, http.StatusOK, devicePageData{Done: done})
+}
+
+// deviceSignIn проверяет вход из формы согласия так же, как AuthorizeSubmit. Если вход не завершен
+// (неверный пароль, нужен код второго фактора), форма показывается заново и возвращается false.
+func (h *OIDCHandler) deviceSignIn(w http.ResponseWriter, r *http.Request, userCode string) (int, bool) {
+	// Неизвестный код показываем до проверки пароля
+	if _, _, err := h.oidc.DeviceAuthorization(r.Context(), userCode); err != nil {
+		h.deviceCodeError(w, err, userCode)
+		return 0, false
+	}
+
+	form := r.PostForm
+	var claims domain.UserClaims
+	if mfaToken := form.Get("mfa_token"); mfaToken != "" {
+		completed, err := h.mfa.CompleteChallenge(r.Context(), mfaToken, form.Get("code"))
+		switch err {
+		case nil:
+			claims = *completed
+		case apperrors.ErrInvalidMFACode:
+			h.renderDeviceConsent(w, r, http.StatusUnauthorized, userCode, devicePageData{
+				SignIn:   true,
+				MFAToken: mfaToken,
+				Error:    httputil.MessageEnByID(dto.MsgInvalidMFACode),
+			})
+			return 0, false
+		case apperrors.ErrInvalidToken, apperrors.ErrMFANotEnrolled:
+			// Попытки ввода кода исчерпаны или токен истек: вход начинается заново
+			h.renderDeviceConsent(w, r, http.StatusUnauthorized, userCode, devicePageData{
+				SignIn: true,
+				Error:  httputil.MessageEnByID(dto.MsgInvalidToken),
+			})
+			return 0, false
+		default:
+			h.authorizeInternalError(w, err, "complete mfa challenge")
+			return 0, false
+		}
+	} else {
+		email := form.Get("email")
+		user, err := h.authService.Login(r.Context(), email, form.Get("password"))
+		if err != nil {
+			if message, ok := passwordErrorMessage(err); ok {
+				h.renderDeviceConsent(w, r, http.StatusUnauthorized, userCode, devicePageData{SignIn: true, Email: email, Error: message})
+				return 0, false
+			}
+			h.authorizeInternalError(w, err, "check password")
+			return 0, false
+		}
+		claims = domain.UserClaims{UserID: strconv.Itoa(user.ID), Email: user.Email}
+
+		mfaEnabled, err := h.mfa.IsEnabled(r.Context(), user.ID)
+		if err != nil {
+			h.authorizeInternalError(w, err, "check mfa")
+			return 0, false
+		}
+		if mfaEnabled {
+			mfaToken, err := h.mfa.StartChallenge(claims)
+			if err != nil {
+				h.authorizeInternalError(w, err, "start mfa challenge")
+				return 0, false
+			}
+			h.renderDeviceConsent(w, r, http.StatusOK, userCode, devicePageData{SignIn: true, MFAToken: mfaToken})
+			return 0, false
+		}
+	}
+
+	userID, err := strconv.Atoi(claims.UserID)
+	if err != nil {
+		h.authorizeInternalError(w, err, "parse user id")
+		return 0, false
+	}
+	return userID, true
+}
+
+// renderDeviceConsent показывает форму согласия для кода устройства, дополняя data приложением и областями доступа
+func (h *OIDCHandler) renderDeviceConsent(w http.ResponseWriter, r *http.Request, status int, userCode string, data devicePageData) {
+	authorization, client, err := h.oidc.DeviceAuthorization(r.Context(), userCode)
+	if err != nil {
+		h.deviceCodeError(w, err, userCode)
+		return
+	}
+	data.ClientName = client.Name
+	data.Scopes = strings.Fields(authorization.Scope)
+	data.UserCode = service.FormatUserCode(authorization.UserCode)
+	h.renderDevice(w, status, data)
+}
+
+// deviceCodeError показывает форму ввода кода заново, если код неизвестен или истек
+func (h *OIDCHandler) deviceCodeError(w http.ResponseWriter, err error, userCode string) {
+	if err != apperrors.ErrNotFound {
+		h.authorizeInternalError(w, err, "find device authorization")
+		return
+	}
+	h.renderDevice(w, http.StatusNotFound, devicePageData{
+		UserCode: userCode,
+		Error:    "The code is invalid or has expired. Check the code on your device and try again.",
+	})
+}
+
+// renderDevice показывает страницу подтверждения устройства
+func (h *OIDCHandler) renderDevice(w http.ResponseWriter, status int, data devicePageData) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	// Кнопку согласия нельзя встраивать во фреймы чужих сайтов
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; frame-ancestors 'none'")
+	w.WriteHeader(status)
+	if err := devicePage.Execute(w, data); err != nil {
+		h.logger.Errorw("Failed to render device page", "error", err)
+	}
+}
@@ -92,6 +92,10 @@ func (h *PasswordResetHandler) ConfirmPasswordReset(w http.ResponseWriter, r *ht
 	if err := h.passwordResetService.ConfirmReset(r.Context(), req.Email, req.Code, req.Password); err != nil {
 		h.logger.Errorw("Failed to confirm password reset", "email", req.Email, "error", err)
 
+		if errors.HandlePasswordPolicyError(w, err, h.logger) {
+			return
+		}
+
 		// Обрабатываем различные типы ошибок
 		switch err {
 		case apperrors.ErrInvalidToken:
@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/Alias1177/Auth/internal/dto"
+	apperrors "github.com/Alias1177/Auth/internal/errors"
+	"github.com/Alias1177/Auth/internal/service"
+	"github.com/Alias1177/Auth/pkg/errors"
+	"github.com/Alias1177/Auth/pkg/httputil"
+	"github.com/Alias1177/Auth/pkg/logger"
+	"github.com/Alias1177/Auth/pkg/sentry"
+	"github.com/Alias1177/Auth/pkg/validator"
+)
+
+// RecoveryHandler восстановление доступа кодами восстановления (без участия почты)
+type RecoveryHandler struct {
+	recoveryCodes service.RecoveryCodeService
+	validator     *validator.Validator
+	logger        *logger.Logger
+}
+
+// NewRecoveryHandler создает новый обработчик восстановления доступа
+func NewRecoveryHandler(
+	recoveryCodes service.RecoveryCodeService,
+	validator *validator.Validator,
+	logger *logger.Logger,
+) *RecoveryHandler {
+	return &RecoveryHandler{
+		recoveryCodes: recoveryCodes,
+		validator:     validator,
+		logger:        logger,
+	}
+}
+
+// Recover погашает код восстановления, устанавливает новый пароль и завершает все сессии
+func (h *RecoveryHandler) Recover(w http.ResponseWriter, r *http.Request) {
+	var req dto.RecoverAccountRequest
+
+	if err := httputil.DecodeJSON(r, &req, h.logger); err != nil {
+		httputil.JSONErrorWithID(w, http.StatusBadRequest, dto.MsgInvalidRequest)
+		return
+	}
+
+	if err := h.validator.Validate(req); err != nil {
+		h.logger.Warnw("Validation failed for account recovery", "email", req.Email, "error", err)
+		httputil.JSONErrorWithID(w, http.StatusBadRequest, dto.MsgInvalidRequestData)
+		return
+	}
+
+	if err := h.recoveryCodes.Redeem(r.Context(), req.Email, req.Code, req.Password); err != nil {
+		if errors.HandlePasswordPolicyError(w, err, h.logger) {
+			return
+		}
+		switch err {
+		case apperrors.ErrInvalidRecoveryCode:
+			sentry.CaptureWarning(r.Context(), "Failed account recovery attempt", r)
+			httputil.JSONErrorWithID(w, http.StatusUnauthorized, dto.MsgInvalidRecoveryCode)
+		case apperrors.ErrTooManyRequests:
+			httputil.JSONErrorWithID(w, http.StatusTooManyRequests, dto.MsgTooManyRecoveryTries)
+		default:
+			sentry.CaptureError(r.Context(), err, r)
+			errors.HandleInternalError(w, err, h.logger, "redeem recovery code")
+		}
+		return
+	}
+
+	if err := httputil.JSONSuccessWithID(w, http.StatusOK, dto.MsgSuccessAccountRecovered, nil); err != nil {
+		errors.HandleInternalError(w, err, h.logger, "encode response")
+	}
+}
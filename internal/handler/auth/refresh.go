@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	"github.com/Alias1177/Auth/internal/dto"
+	apperrors "github.com/Alias1177/Auth/internal/errors"
 	"github.com/Alias1177/Auth/pkg/errors"
 	"github.com/Alias1177/Auth/pkg/httputil"
 	"github.com/Alias1177/Auth/pkg/jwt"
@@ -17,15 +18,25 @@ func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	newaccess, newrefresh, err := h.tokenManager.RefreshTokens(ref.Token)
+	// Токены приложений обмениваются только на /oauth2/token
+	tokens, err := h.sessions.Refresh(r.Context(), ref.Token, "")
 	if err != nil {
-		httputil.JSONErrorWithID(w, http.StatusBadRequest, dto.MsgTokenInvalid)
+		switch err {
+		case apperrors.ErrTokenReused:
+			httputil.JSONErrorWithID(w, http.StatusUnauthorized, dto.MsgRefreshTokenReused)
+		case apperrors.ErrInvalidToken:
+			httputil.JSONErrorWithID(w, http.StatusBadRequest, dto.MsgTokenInvalid)
+		case apperrors.ErrAccountDisabled:
+			httputil.JSONErrorWithID(w, http.StatusForbidden, dto.MsgAccountDisabled)
+		default:
+			errors.HandleInternalError(w, err, h.logger, "rotate refresh token")
+		}
 		return
 	}
 
 	response := map[string]string{
-		"access_token":  newaccess,
-		"refresh_token": newrefresh,
+		"access_token":  tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
 	}
 
 	if err := httputil.JSONSuccessWithID(w, http.StatusOK, dto.MsgSuccessTokenRefresh, response); err != nil {
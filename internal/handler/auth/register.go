@@ -18,6 +18,10 @@ import (
 type RegistrationHandler struct {
 	userRepository service.UserRepository
 	tokenManager   service.TokenManager
+	sessions       service.SessionService
+	verification   service.EmailVerificationService
+	policy         service.PasswordPolicy
+	history        service.PasswordHistoryService
 	jwtConfig      config.JWTConfig
 	logger         *logger.Logger
 	kafkaProducer  *kafka.Producer
@@ -26,6 +30,10 @@ type RegistrationHandler struct {
 func NewRegistrationHandler(
 	repo service.UserRepository,
 	manager service.TokenManager,
+	sessions service.SessionService,
+	verification service.EmailVerificationService,
+	policy service.PasswordPolicy,
+	history service.PasswordHistoryService,
 	cfg config.JWTConfig,
 	log *logger.Logger,
 	producer *kafka.Producer,
@@ -33,6 +41,10 @@ func NewRegistrationHandler(
 	return &RegistrationHandler{
 		userRepository: repo,
 		tokenManager:   manager,
+		sessions:       sessions,
+		verification:   verification,
+		policy:         policy,
+		history:        history,
 		jwtConfig:      cfg,
 		logger:         log,
 		kafkaProducer:  producer,
@@ -41,9 +53,10 @@ func NewRegistrationHandler(
 
 func (h *RegistrationHandler) Register(w http.ResponseWriter, r *http.Request) {
 	var req struct {
-		Email    string `json:"email"`
-		Username string `json:"username"`
-		Password string `json:"password"`
+		Email      string `json:"email"`
+		Username   string `json:"username"`
+		Password   string `json:"password"`
+		DeviceName string `json:"device_name"`
 	}
 
 	// Декодирование JSON запроса
@@ -60,6 +73,14 @@ func (h *RegistrationHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Проверка пароля по политике
+	if err := h.policy.Check(r.Context(), req.Password, &domain.User{Email: req.Email, UserName: req.Username}); err != nil {
+		if !errors.HandlePasswordPolicyError(w, err, h.logger) {
+			errors.HandleInternalError(w, err, h.logger, "check password policy")
+		}
+		return
+	}
+
 	// Хеширование пароля
 	hashedPassword, err := crypto.HashPassword(req.Password)
 	if err != nil {
@@ -78,6 +99,7 @@ func (h *RegistrationHandler) Register(w http.ResponseWriter, r *http.Request) {
 		errors.HandleInternalError(w, err, h.logger, "create user")
 		return
 	}
+	h.history.Record(r.Context(), newUser.ID, hashedPassword)
 
 	// Отправка в Kafka (не критично для успеха регистрации)
 	if h.kafkaProducer != nil {
@@ -86,24 +108,38 @@ func (h *RegistrationHandler) Register(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Письмо подтверждения почты; при ошибке пользователь может запросить его повторно
+	if err := h.verification.Send(r.Context(), &newUser); err != nil {
+		h.logger.Errorw("Failed to send email verification", "error", err, "email", req.Email)
+	}
+
+	// Вход до подтверждения почты запрещен, токены не выдаются
+	if h.verification.Required() {
+		if err := httputil.JSONSuccessWithID(w, http.StatusCreated, dto.MsgSuccessRegisterVerifyEmail, nil); err != nil {
+			errors.HandleInternalError(w, err, h.logger, "encode response")
+		}
+		return
+	}
+
 	// Генерация JWT токена
 	claims := domain.UserClaims{
 		UserID: strconv.Itoa(newUser.ID),
 		Email:  newUser.Email,
 	}
 
-	accessToken, err := h.tokenManager.GenerateAccessToken(claims)
+	tokens, err := h.sessions.Start(r.Context(), claims, clientInfo(r, req.DeviceName, domain.LoginMethodRegister))
 	if err != nil {
-		errors.HandleInternalError(w, err, h.logger, "generate access token")
+		errors.HandleInternalError(w, err, h.logger, "issue tokens")
 		return
 	}
 
 	// Установка токена в куки
-	httputil.SetTokenCookie(w, "access-token", accessToken)
+	httputil.SetTokenCookie(w, "access-token", tokens.AccessToken)
 
 	// Отправка успешного ответа
 	response := map[string]string{
-		"access_token": accessToken,
+		"access_token":  tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
 	}
 
 	if err := httputil.JSONSuccessWithID(w, http.StatusCreated, dto.MsgSuccessRegister, response); err != nil {
@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/Alias1177/Auth/internal/dto"
+	apperrors "github.com/Alias1177/Auth/internal/errors"
+	"github.com/Alias1177/Auth/internal/service"
+	"github.com/Alias1177/Auth/pkg/errors"
+	"github.com/Alias1177/Auth/pkg/httputil"
+	"github.com/Alias1177/Auth/pkg/logger"
+	"github.com/Alias1177/Auth/pkg/validator"
+)
+
+// UnlockHandler снятие блокировки входа по ссылке из письма
+type UnlockHandler struct {
+	lockout   service.LockoutService
+	validator *validator.Validator
+	logger    *logger.Logger
+}
+
+// NewUnlockHandler создает новый обработчик разблокировки
+func NewUnlockHandler(
+	lockout service.LockoutService,
+	validator *validator.Validator,
+	logger *logger.Logger,
+) *UnlockHandler {
+	return &UnlockHandler{
+		lockout:   lockout,
+		validator: validator,
+		logger:    logger,
+	}
+}
+
+// Unlock снимает блокировку входа токеном из письма
+func (h *UnlockHandler) Unlock(w http.ResponseWriter, r *http.Request) {
+	var req dto.UnlockAccountRequest
+
+	if err := httputil.DecodeJSON(r, &req, h.logger); err != nil {
+		httputil.JSONErrorWithID(w, http.StatusBadRequest, dto.MsgInvalidRequest)
+		return
+	}
+
+	if err := h.validator.Validate(req); err != nil {
+		httputil.JSONErrorWithID(w, http.StatusBadRequest, dto.MsgInvalidRequestData)
+		return
+	}
+
+	if err := h.lockout.Unlock(r.Context(), req.Token); err != nil {
+		switch err {
+		case apperrors.ErrInvalidToken, apperrors.ErrUserNotFound:
+			httputil.JSONErrorWithID(w, http.StatusBadRequest, dto.MsgInvalidToken)
+		default:
+			errors.HandleInternalError(w, err, h.logger, "unlock account")
+		}
+		return
+	}
+
+	if err := httputil.JSONSuccessWithID(w, http.StatusOK, dto.MsgSuccessAccountUnlocked, nil); err != nil {
+		errors.HandleInternalError(w, err, h.logger, "encode response")
+	}
+}
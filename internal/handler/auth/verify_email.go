@@ -0,0 +1,90 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/Alias1177/Auth/internal/dto"
+	apperrors "github.com/Alias1177/Auth/internal/errors"
+	"github.com/Alias1177/Auth/internal/service"
+	"github.com/Alias1177/Auth/pkg/errors"
+	"github.com/Alias1177/Auth/pkg/httputil"
+	"github.com/Alias1177/Auth/pkg/logger"
+	"github.com/Alias1177/Auth/pkg/validator"
+)
+
+// EmailVerificationHandler подтверждение адреса почты
+type EmailVerificationHandler struct {
+	verification service.EmailVerificationService
+	validator    *validator.Validator
+	logger       *logger.Logger
+}
+
+// NewEmailVerificationHandler создает новый обработчик подтверждения почты
+func NewEmailVerificationHandler(
+	verification service.EmailVerificationService,
+	validator *validator.Validator,
+	logger *logger.Logger,
+) *EmailVerificationHandler {
+	return &EmailVerificationHandler{
+		verification: verification,
+		validator:    validator,
+		logger:       logger,
+	}
+}
+
+// VerifyEmail подтверждает адрес токеном из письма
+func (h *EmailVerificationHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req dto.VerifyEmailRequest
+
+	if err := httputil.DecodeJSON(r, &req, h.logger); err != nil {
+		httputil.JSONErrorWithID(w, http.StatusBadRequest, dto.MsgInvalidRequest)
+		return
+	}
+
+	if err := h.validator.Validate(req); err != nil {
+		httputil.JSONErrorWithID(w, http.StatusBadRequest, dto.MsgInvalidRequestData)
+		return
+	}
+
+	if err := h.verification.Verify(r.Context(), req.Token); err != nil {
+		if err == apperrors.ErrInvalidToken {
+			httputil.JSONErrorWithID(w, http.StatusBadRequest, dto.MsgInvalidToken)
+			return
+		}
+		errors.HandleInternalError(w, err, h.logger, "verify email")
+		return
+	}
+
+	if err := httputil.JSONSuccessWithID(w, http.StatusOK, dto.MsgSuccessEmailVerified, nil); err != nil {
+		errors.HandleInternalError(w, err, h.logger, "encode response")
+	}
+}
+
+// ResendVerification повторно отправляет письмо подтверждения.
+// Ответ не зависит от того, существует ли аккаунт с таким адресом.
+func (h *EmailVerificationHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	var req dto.ResendVerificationRequest
+
+	if err := httputil.DecodeJSON(r, &req, h.logger); err != nil {
+		httputil.JSONErrorWithID(w, http.StatusBadRequest, dto.MsgInvalidRequest)
+		return
+	}
+
+	if err := h.validator.Validate(req); err != nil {
+		httputil.JSONErrorWithID(w, http.StatusBadRequest, dto.MsgInvalidRequestData)
+		return
+	}
+
+	if err := h.verification.Resend(r.Context(), req.Email); err != nil {
+		if err == apperrors.ErrTooManyRequests {
+			httputil.JSONErrorWithID(w, http.StatusTooManyRequests, dto.MsgTooManyVerifyEmails)
+			return
+		}
+		errors.HandleInternalError(w, err, h.logger, "resend verification email")
+		return
+	}
+
+	if err := httputil.JSONSuccessWithID(w, http.StatusOK, dto.MsgSuccessVerificationSent, nil); err != nil {
+		errors.HandleInternalError(w, err, h.logger, "encode response")
+	}
+}
@@ -0,0 +1,54 @@
+package user
+
+import (
+	"net/http"
+
+	"github.com/Alias1177/Auth/internal/dto"
+	apperrors "github.com/Alias1177/Auth/internal/errors"
+	"github.com/Alias1177/Auth/pkg/errors"
+	"github.com/Alias1177/Auth/pkg/httputil"
+)
+
+// RequestEmailChange создает заявку на смену адреса текущего пользователя.
+// Адрес меняется только после перехода по ссылке из письма на новый адрес.
+func (h *UserHandler) RequestEmailChange(w http.ResponseWriter, r *http.Request) {
+	userClaims, userID, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req dto.ChangeEmailRequest
+	if err := httputil.DecodeJSON(r, &req, h.logger); err != nil {
+		httputil.JSONErrorWithID(w, http.StatusBadRequest, dto.MsgInvalidRequest)
+		return
+	}
+	if err := h.validator.Validate(req); err != nil {
+		errors.HandleValidationError(w, err, h.logger)
+		return
+	}
+
+	if err := h.emailChange.Request(r.Context(), userID, userClaims.FamilyID, req.NewEmail, req.Password, req.Code); err != nil {
+		switch err {
+		case apperrors.ErrInvalidLogin:
+			h.logger.Warnw("Wrong password on email change request", "user_id", userID)
+			httputil.JSONErrorWithID(w, http.StatusUnauthorized, dto.MsgWrongPassword)
+		case apperrors.ErrInvalidMFACode:
+			httputil.JSONErrorWithID(w, http.StatusUnauthorized, dto.MsgInvalidMFACode)
+		case apperrors.ErrReauthRequired:
+			httputil.JSONErrorWithID(w, http.StatusUnauthorized, dto.MsgReauthRequired)
+		case apperrors.ErrValidation:
+			httputil.JSONErrorWithID(w, http.StatusBadRequest, dto.MsgInvalidEmail)
+		case apperrors.ErrUserExists:
+			httputil.JSONErrorWithID(w, http.StatusConflict, dto.MsgEmailAlreadyExists)
+		case apperrors.ErrTooManyRequests:
+			httputil.JSONErrorWithID(w, http.StatusTooManyRequests, dto.MsgTooManyRequests)
+		default:
+			errors.HandleInternalError(w, err, h.logger, "request email change")
+		}
+		return
+	}
+
+	if err := httputil.JSONSuccessWithID(w, http.StatusAccepted, dto.MsgSuccessEmailChangeRequested, nil); err != nil {
+		errors.HandleInternalError(w, err, h.logger, "encode response")
+	}
+}
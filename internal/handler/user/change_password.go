@@ -2,56 +2,160 @@ package user
 
 import (
 	"net/http"
+	"time"
 
+	"github.com/Alias1177/Auth/internal/domain"
 	"github.com/Alias1177/Auth/internal/dto"
+	apperrors "github.com/Alias1177/Auth/internal/errors"
+	"github.com/Alias1177/Auth/internal/service"
 	"github.com/Alias1177/Auth/pkg/errors"
 	"github.com/Alias1177/Auth/pkg/httputil"
 	crypto "github.com/Alias1177/Auth/pkg/security"
 )
 
-// ResetPasswordHandler обработчик для сброса пароля по email
-func (h *UserHandler) ResetPasswordHandler(w http.ResponseWriter, r *http.Request) {
+// ChangePassword меняет пароль текущего пользователя после проверки старого пароля.
+// Остальные сессии завершаются, текущая остается активной.
+func (h *UserHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	userClaims, userID, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
 
-	// Декодирование JSON запроса
-	var req dto.ResetPasswordByEmailRequest
+	var req dto.ChangePasswordRequest
 	if err := httputil.DecodeJSON(r, &req, h.logger); err != nil {
 		httputil.JSONErrorWithID(w, http.StatusBadRequest, dto.MsgInvalidRequest)
 		return
 	}
-
-	// Проверяем, что email и пароль заполнены
-	if req.Email == "" || req.Password == "" {
-		h.logger.Warnw("Missing email or password in reset request", "email", req.Email)
-		httputil.JSONErrorWithID(w, http.StatusBadRequest, dto.MsgMissingEmailOrPassword)
+	if req.OldPassword == "" || req.NewPassword == "" {
+		httputil.JSONErrorWithID(w, http.StatusBadRequest, dto.MsgInvalidRequestData)
 		return
 	}
 
-	// Находим пользователя по email
-	user, err := h.userRepository.GetUserByEmail(r.Context(), req.Email)
+	user, err := h.userRepository.GetUserByID(r.Context(), userID)
 	if err != nil {
-		errors.HandleDatabaseError(w, err, h.logger, "get user by email for password reset")
+		errors.HandleDatabaseError(w, err, h.logger, "get user for password change")
+		return
+	}
+
+	if !h.verifyCurrentPassword(w, r, user, req.OldPassword) {
+		return
+	}
+
+	if !h.checkNewPassword(w, r, user, req.NewPassword) {
 		return
 	}
 
-	// Хешируем новый пароль
-	hashedPassword, err := crypto.HashPassword(req.Password)
+	hashedPassword, err := crypto.HashPassword(req.NewPassword)
 	if err != nil {
 		errors.HandleInternalError(w, err, h.logger, "hash new password")
 		return
 	}
-
-	// Обновляем пароль пользователя
 	user.Password = hashedPassword
-
-	// Обновляем пользователя в базе данных
 	if err := h.userRepository.UpdateUser(r.Context(), user); err != nil {
 		errors.HandleInternalError(w, err, h.logger, "update user password")
 		return
 	}
+	h.history.Record(r.Context(), user.ID, hashedPassword)
+
+	// Пароль уже сменен: при ошибке отзыва сессий ответ все равно успешный, ошибка попадает в лог
+	if err := h.sessions.RevokeOthers(r.Context(), userID, userClaims.FamilyID); err != nil {
+		h.logger.Errorw("Failed to revoke other sessions after password change", "user_id", userID, "error", err)
+	}
+	if err := h.notifier.SendPasswordChanged(r.Context(), user.Email, user.UserName, time.Now()); err != nil {
+		h.logger.Errorw("Failed to send password changed event", "user_id", userID, "error", err)
+	}
 
-	// Отправка успешного ответа
+	h.logger.Infow("Password changed", "user_id", userID, "current_session_id", userClaims.FamilyID)
 	if err := httputil.JSONSuccessWithID(w, http.StatusOK, dto.MsgSuccessPasswordChanged, nil); err != nil {
 		errors.HandleInternalError(w, err, h.logger, "encode response")
 	}
+}
 
+// verifyCurrentPassword проверяет текущий пароль пользователя перед чувствительным действием.
+// Неудачные проверки учитываются блокировкой так же, как неудачные входы: украденный токен доступа
+// не позволяет подбирать пароль. При отказе отправляет ответ и возвращает false.
+func (h *UserHandler) verifyCurrentPassword(w http.ResponseWriter, r *http.Request, user *domain.User, password string) bool {
+	err := h.lockout.Check(r.Context(), user.Email, user)
+	if err == nil {
+		if crypto.VerifyPassword(user.Password, password) == nil {
+			err = h.lockout.RecordSuccess(r.Context(), user)
+			if err == nil {
+				return true
+			}
+		} else {
+			h.logger.Warnw("Wrong current password", "user_id", user.ID)
+			err = h.lockout.RecordFailure(r.Context(), user.Email, user)
+			if err == nil {
+				err = apperrors.ErrInvalidLogin
+			}
+		}
+	}
+
+	switch err {
+	case apperrors.ErrInvalidLogin:
+		httputil.JSONErrorWithID(w, http.StatusUnauthorized, dto.MsgWrongPassword)
+	case apperrors.ErrAccountLocked:
+		httputil.JSONErrorWithID(w, http.StatusLocked, dto.MsgAccountLocked)
+	default:
+		errors.HandleInternalError(w, err, h.logger, "verify current password")
+	}
+	return false
+}
+
+// confirmIdentity повторно подтверждает личность перед чувствительным действием: кодом TOTP, если он передан,
+// иначе текущим паролем, а у учетной записи без пароля - недавним входом в текущей сессии.
+// При отказе отправляет ответ и возвращает false.
+func (h *UserHandler) confirmIdentity(
+	w http.ResponseWriter,
+	r *http.Request,
+	claims *domain.UserClaims,
+	user *domain.User,
+	password, code string,
+) bool {
+	if code != "" {
+		switch err := h.mfa.Verify(r.Context(), user.ID, code); err {
+		case nil:
+			return true
+		case apperrors.ErrInvalidMFACode:
+			h.logger.Warnw("Wrong TOTP code on step-up", "user_id", user.ID)
+			httputil.JSONErrorWithID(w, http.StatusUnauthorized, dto.MsgInvalidMFACode)
+		case apperrors.ErrMFANotEnrolled:
+			httputil.JSONErrorWithID(w, http.StatusUnauthorized, dto.MsgReauthRequired)
+		default:
+			errors.HandleInternalError(w, err, h.logger, "verify totp code")
+		}
+		return false
+	}
+	if user.Password != "" {
+		return h.verifyCurrentPassword(w, r, user, password)
+	}
+
+	sessions, err := h.sessions.List(r.Context(), user.ID)
+	if err != nil {
+		errors.HandleInternalError(w, err, h.logger, "list sessions")
+		return false
+	}
+	for _, session := range sessions {
+		if session.ID == claims.FamilyID && time.Since(session.CreatedAt) < service.RecentLoginWindow {
+			return true
+		}
+	}
+	httputil.JSONErrorWithID(w, http.StatusUnauthorized, dto.MsgReauthRequired)
+	return false
+}
+
+// checkNewPassword проверяет новый пароль пользователя по политике и истории паролей.
+// user — сохраненная учетная запись с текущим хешем. При отказе отправляет ответ и возвращает false.
+func (h *UserHandler) checkNewPassword(w http.ResponseWriter, r *http.Request, user *domain.User, password string) bool {
+	err := h.policy.Check(r.Context(), password, user)
+	if err == nil {
+		err = h.history.CheckReuse(r.Context(), user, password)
+	}
+	if err == nil {
+		return true
+	}
+	if !errors.HandlePasswordPolicyError(w, err, h.logger) {
+		errors.HandleInternalError(w, err, h.logger, "check new password")
+	}
+	return false
 }
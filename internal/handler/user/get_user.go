@@ -35,8 +35,24 @@ func (h *UserHandler) GetUserInfoHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Отправляем информацию о пользователе
-	if err := httputil.JSONSuccessWithID(w, http.StatusOK, dto.MsgSuccessUserInfoRetrieved, user); err != nil {
+	remaining, err := h.recoveryCodes.Remaining(r.Context(), userID)
+	if err != nil {
+		errors.HandleDatabaseError(w, err, h.logger, "count recovery codes")
+		return
+	}
+
+	// Отправляем информацию о пользователе (без хеша пароля)
+	response := dto.UserInfoResponse{
+		ID:                     user.ID,
+		Username:               user.UserName,
+		Email:                  user.Email,
+		CreatedAt:              user.CreatedAt,
+		UpdatedAt:              user.UpdatedAt,
+		EmailVerified:          user.EmailVerified(),
+		RecoveryCodesRemaining: remaining,
+	}
+	w.Header().Set("ETag", userETag(user))
+	if err := httputil.JSONSuccessWithID(w, http.StatusOK, dto.MsgSuccessUserInfoRetrieved, response); err != nil {
 		errors.HandleInternalError(w, err, h.logger, "encode user response")
 	}
 }
@@ -0,0 +1,65 @@
+package user
+
+import (
+	"net/http"
+
+	"github.com/Alias1177/Auth/internal/dto"
+	apperrors "github.com/Alias1177/Auth/internal/errors"
+	"github.com/Alias1177/Auth/pkg/errors"
+	"github.com/Alias1177/Auth/pkg/httputil"
+	"github.com/go-chi/chi/v5"
+)
+
+// ListIdentities возвращает внешние учетные записи (Google и т.п.), привязанные к пользователю
+func (h *UserHandler) ListIdentities(w http.ResponseWriter, r *http.Request) {
+	_, userID, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	identities, err := h.identities.List(r.Context(), userID)
+	if err != nil {
+		errors.HandleDatabaseError(w, err, h.logger, "list identities")
+		return
+	}
+
+	response := make([]dto.UserIdentityResponse, 0, len(identities))
+	for _, identity := range identities {
+		response = append(response, dto.UserIdentityResponse{
+			Provider:      identity.Provider,
+			Email:         identity.Email,
+			EmailVerified: identity.EmailVerified,
+			CreatedAt:     identity.CreatedAt,
+			LastUsedAt:    identity.LastUsedAt,
+		})
+	}
+
+	if err := httputil.JSONSuccessWithID(w, http.StatusOK, dto.MsgSuccessIdentitiesRetrieved, response); err != nil {
+		errors.HandleInternalError(w, err, h.logger, "encode identities response")
+	}
+}
+
+// UnlinkIdentity отвязывает учетную запись провайдера; последний способ входа отвязать нельзя
+func (h *UserHandler) UnlinkIdentity(w http.ResponseWriter, r *http.Request) {
+	_, userID, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	provider := chi.URLParam(r, "provider")
+	if err := h.identities.Unlink(r.Context(), userID, provider); err != nil {
+		switch err {
+		case apperrors.ErrNotFound:
+			httputil.JSONErrorWithID(w, http.StatusNotFound, dto.MsgIdentityNotFound)
+		case apperrors.ErrLastLoginMethod:
+			httputil.JSONErrorWithID(w, http.StatusConflict, dto.MsgLastLoginMethod)
+		default:
+			errors.HandleInternalError(w, err, h.logger, "unlink identity")
+		}
+		return
+	}
+
+	if err := httputil.JSONSuccessWithID(w, http.StatusOK, dto.MsgSuccessIdentityUnlinked, nil); err != nil {
+		errors.HandleInternalError(w, err, h.logger, "encode response")
+	}
+}
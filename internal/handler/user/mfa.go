@@ -0,0 +1,150 @@
+package user
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Alias1177/Auth/internal/dto"
+	apperrors "github.com/Alias1177/Auth/internal/errors"
+	"github.com/Alias1177/Auth/pkg/errors"
+	"github.com/Alias1177/Auth/pkg/httputil"
+	"github.com/Alias1177/Auth/pkg/totp"
+)
+
+// Размеры PNG с QR кодом в пикселях
+const (
+	defaultQRSize = 256
+	minQRSize     = 128
+	maxQRSize     = 1024
+)
+
+type totpCodeRequest struct {
+	Code string `json:"code"`
+}
+
+// EnrollTOTP начинает регистрацию TOTP: создает секрет и возвращает его вместе с otpauth URI.
+// QR код для того же секрета отдает GET /user/mfa/totp/qr.
+func (h *UserHandler) EnrollTOTP(w http.ResponseWriter, r *http.Request) {
+	userClaims, userID, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	enrollment, err := h.mfa.Enroll(r.Context(), userID, userClaims.Email)
+	if err != nil {
+		h.handleMFAError(w, err, "enroll totp")
+		return
+	}
+
+	if err := httputil.JSONSuccessWithID(w, http.StatusOK, dto.MsgSuccessMFAEnrollmentStarted, enrollment); err != nil {
+		errors.HandleInternalError(w, err, h.logger, "encode response")
+	}
+}
+
+// TOTPQRCode отдает QR код неподтвержденного секрета в формате PNG (по умолчанию) или SVG (?format=svg)
+func (h *UserHandler) TOTPQRCode(w http.ResponseWriter, r *http.Request) {
+	userClaims, userID, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	uri, err := h.mfa.EnrollmentURI(r.Context(), userID, userClaims.Email)
+	if err != nil {
+		h.handleMFAError(w, err, "get totp uri")
+		return
+	}
+
+	var (
+		image       []byte
+		contentType string
+	)
+	switch r.URL.Query().Get("format") {
+	case "", "png":
+		size := defaultQRSize
+		if raw := r.URL.Query().Get("size"); raw != "" {
+			size, err = strconv.Atoi(raw)
+			if err != nil || size < minQRSize || size > maxQRSize {
+				httputil.JSONErrorWithID(w, http.StatusBadRequest, dto.MsgInvalidRequest)
+				return
+			}
+		}
+		image, err = totp.QRPNG(uri, size)
+		contentType = "image/png"
+	case "svg":
+		image, err = totp.QRSVG(uri)
+		contentType = "image/svg+xml"
+	default:
+		httputil.JSONErrorWithID(w, http.StatusBadRequest, dto.MsgInvalidRequest)
+		return
+	}
+	if err != nil {
+		errors.HandleInternalError(w, err, h.logger, "render totp qr code")
+		return
+	}
+
+	// Изображение содержит секрет, кэшировать его нельзя
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	w.Write(image)
+}
+
+// ConfirmTOTP включает второй фактор кодом из приложения-аутентификатора
+func (h *UserHandler) ConfirmTOTP(w http.ResponseWriter, r *http.Request) {
+	_, userID, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req totpCodeRequest
+	if err := httputil.DecodeJSON(r, &req, h.logger); err != nil {
+		httputil.JSONErrorWithID(w, http.StatusBadRequest, dto.MsgInvalidRequest)
+		return
+	}
+
+	if err := h.mfa.Confirm(r.Context(), userID, req.Code); err != nil {
+		h.handleMFAError(w, err, "confirm totp")
+		return
+	}
+
+	if err := httputil.JSONSuccessWithID(w, http.StatusOK, dto.MsgSuccessMFAEnabled, nil); err != nil {
+		errors.HandleInternalError(w, err, h.logger, "encode response")
+	}
+}
+
+// DisableTOTP отключает второй фактор; требует действующий код
+func (h *UserHandler) DisableTOTP(w http.ResponseWriter, r *http.Request) {
+	_, userID, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req totpCodeRequest
+	if err := httputil.DecodeJSON(r, &req, h.logger); err != nil {
+		httputil.JSONErrorWithID(w, http.StatusBadRequest, dto.MsgInvalidRequest)
+		return
+	}
+
+	if err := h.mfa.Disable(r.Context(), userID, req.Code); err != nil {
+		h.handleMFAError(w, err, "disable totp")
+		return
+	}
+
+	if err := httputil.JSONSuccessWithID(w, http.StatusOK, dto.MsgSuccessMFADisabled, nil); err != nil {
+		errors.HandleInternalError(w, err, h.logger, "encode response")
+	}
+}
+
+// handleMFAError преобразует ошибки сервиса второго фактора в ответы API
+func (h *UserHandler) handleMFAError(w http.ResponseWriter, err error, operation string) {
+	switch err {
+	case apperrors.ErrInvalidMFACode:
+		httputil.JSONErrorWithID(w, http.StatusUnauthorized, dto.MsgInvalidMFACode)
+	case apperrors.ErrMFANotEnrolled:
+		httputil.JSONErrorWithID(w, http.StatusNotFound, dto.MsgMFANotEnrolled)
+	case apperrors.ErrMFAAlreadyEnabled:
+		httputil.JSONErrorWithID(w, http.StatusConflict, dto.MsgMFAAlreadyEnabled)
+	default:
+		errors.HandleInternalError(w, err, h.logger, operation)
+	}
+}
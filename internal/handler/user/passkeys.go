@@ -0,0 +1,126 @@
+package user
+
+import (
+	"encoding/base64"
+	"net/http"
+
+	"github.com/Alias1177/Auth/internal/dto"
+	apperrors "github.com/Alias1177/Auth/internal/errors"
+	"github.com/Alias1177/Auth/pkg/errors"
+	"github.com/Alias1177/Auth/pkg/httputil"
+	"github.com/go-chi/chi/v5"
+)
+
+// BeginPasskeyRegistration начинает регистрацию passkey для текущего пользователя
+func (h *UserHandler) BeginPasskeyRegistration(w http.ResponseWriter, r *http.Request) {
+	_, userID, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := h.userRepository.GetUserByID(r.Context(), userID)
+	if err != nil {
+		errors.HandleDatabaseError(w, err, h.logger, "get user by ID")
+		return
+	}
+
+	creation, ceremonyID, err := h.webauthn.BeginRegistration(r.Context(), user)
+	if err != nil {
+		errors.HandleInternalError(w, err, h.logger, "begin passkey registration")
+		return
+	}
+
+	response := dto.WebAuthnOptionsResponse{CeremonyID: ceremonyID, Options: creation}
+	if err := httputil.JSONSuccessWithID(w, http.StatusOK, dto.MsgSuccessWebAuthnOptions, response); err != nil {
+		errors.HandleInternalError(w, err, h.logger, "encode response")
+	}
+}
+
+// FinishPasskeyRegistration проверяет ответ аутентификатора и сохраняет passkey
+func (h *UserHandler) FinishPasskeyRegistration(w http.ResponseWriter, r *http.Request) {
+	_, userID, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req dto.WebAuthnRegisterRequest
+	if err := httputil.DecodeJSON(r, &req, h.logger); err != nil {
+		httputil.JSONErrorWithID(w, http.StatusBadRequest, dto.MsgInvalidRequest)
+		return
+	}
+
+	user, err := h.userRepository.GetUserByID(r.Context(), userID)
+	if err != nil {
+		errors.HandleDatabaseError(w, err, h.logger, "get user by ID")
+		return
+	}
+
+	cred, err := h.webauthn.FinishRegistration(r.Context(), user, req.CeremonyID, req.Name, req.Credential)
+	if err != nil {
+		if err == apperrors.ErrWebAuthnFailed {
+			httputil.JSONErrorWithID(w, http.StatusBadRequest, dto.MsgWebAuthnFailed)
+			return
+		}
+		errors.HandleInternalError(w, err, h.logger, "finish passkey registration")
+		return
+	}
+
+	response := dto.PasskeyResponse{
+		ID:        base64.RawURLEncoding.EncodeToString(cred.ID),
+		Name:      cred.Name,
+		CreatedAt: cred.CreatedAt,
+		Synced:    cred.BackupState,
+	}
+	if err := httputil.JSONSuccessWithID(w, http.StatusCreated, dto.MsgSuccessPasskeyRegistered, response); err != nil {
+		errors.HandleInternalError(w, err, h.logger, "encode response")
+	}
+}
+
+// ListPasskeys возвращает passkeys текущего пользователя
+func (h *UserHandler) ListPasskeys(w http.ResponseWriter, r *http.Request) {
+	_, userID, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	creds, err := h.webauthn.ListCredentials(r.Context(), userID)
+	if err != nil {
+		errors.HandleDatabaseError(w, err, h.logger, "list passkeys")
+		return
+	}
+
+	response := make([]dto.PasskeyResponse, 0, len(creds))
+	for _, cred := range creds {
+		response = append(response, dto.PasskeyResponse{
+			ID:         base64.RawURLEncoding.EncodeToString(cred.ID),
+			Name:       cred.Name,
+			CreatedAt:  cred.CreatedAt,
+			LastUsedAt: cred.LastUsedAt,
+			Synced:     cred.BackupState,
+		})
+	}
+	if err := httputil.JSONSuccessWithID(w, http.StatusOK, dto.MsgSuccessPasskeysRetrieved, response); err != nil {
+		errors.HandleInternalError(w, err, h.logger, "encode response")
+	}
+}
+
+// DeletePasskey удаляет passkey текущего пользователя
+func (h *UserHandler) DeletePasskey(w http.ResponseWriter, r *http.Request) {
+	_, userID, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.webauthn.DeleteCredential(r.Context(), userID, chi.URLParam(r, "id")); err != nil {
+		if err == apperrors.ErrNotFound {
+			httputil.JSONErrorWithID(w, http.StatusNotFound, dto.MsgPasskeyNotFound)
+			return
+		}
+		errors.HandleInternalError(w, err, h.logger, "delete passkey")
+		return
+	}
+
+	if err := httputil.JSONSuccessWithID(w, http.StatusOK, dto.MsgSuccessPasskeyDeleted, nil); err != nil {
+		errors.HandleInternalError(w, err, h.logger, "encode response")
+	}
+}
@@ -0,0 +1,79 @@
+package user
+
+import (
+	"net/http"
+
+	"github.com/Alias1177/Auth/internal/dto"
+	apperrors "github.com/Alias1177/Auth/internal/errors"
+	"github.com/Alias1177/Auth/pkg/errors"
+	"github.com/Alias1177/Auth/pkg/httputil"
+)
+
+// GenerateRecoveryCodes создает первый набор кодов восстановления после повторного подтверждения личности
+func (h *UserHandler) GenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.confirmRecoveryCodesRequest(w, r)
+	if !ok {
+		return
+	}
+
+	codes, err := h.recoveryCodes.Generate(r.Context(), userID)
+	if err != nil {
+		if err == apperrors.ErrRecoveryCodesExist {
+			httputil.JSONErrorWithID(w, http.StatusConflict, dto.MsgRecoveryCodesExist)
+			return
+		}
+		errors.HandleInternalError(w, err, h.logger, "generate recovery codes")
+		return
+	}
+	h.writeRecoveryCodes(w, codes)
+}
+
+// RegenerateRecoveryCodes заменяет набор кодов новым, старые коды перестают действовать.
+// Требует повторного подтверждения личности, о выпуске кодов пользователь получает письмо.
+func (h *UserHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.confirmRecoveryCodesRequest(w, r)
+	if !ok {
+		return
+	}
+
+	codes, err := h.recoveryCodes.Regenerate(r.Context(), userID)
+	if err != nil {
+		errors.HandleInternalError(w, err, h.logger, "regenerate recovery codes")
+		return
+	}
+	h.writeRecoveryCodes(w, codes)
+}
+
+// confirmRecoveryCodesRequest проверяет пароль или код TOTP из запроса: коды восстановления позволяют
+// сменить пароль, поэтому одного access токена для их выпуска недостаточно
+func (h *UserHandler) confirmRecoveryCodesRequest(w http.ResponseWriter, r *http.Request) (int, bool) {
+	userClaims, userID, ok := h.currentUser(w, r)
+	if !ok {
+		return 0, false
+	}
+
+	var req dto.RecoveryCodesRequest
+	if err := httputil.DecodeJSON(r, &req, h.logger); err != nil {
+		httputil.JSONErrorWithID(w, http.StatusBadRequest, dto.MsgInvalidRequest)
+		return 0, false
+	}
+
+	user, err := h.userRepository.GetUserByID(r.Context(), userID)
+	if err != nil {
+		errors.HandleDatabaseError(w, err, h.logger, "get user for recovery codes")
+		return 0, false
+	}
+	if !h.confirmIdentity(w, r, userClaims, user, req.Password, req.Code) {
+		return 0, false
+	}
+	return userID, true
+}
+
+func (h *UserHandler) writeRecoveryCodes(w http.ResponseWriter, codes []string) {
+	// Коды показываются один раз и не должны оседать в кэшах
+	w.Header().Set("Cache-Control", "no-store")
+	response := dto.RecoveryCodesResponse{Codes: codes}
+	if err := httputil.JSONSuccessWithID(w, http.StatusCreated, dto.MsgSuccessRecoveryCodesGenerated, response); err != nil {
+		errors.HandleInternalError(w, err, h.logger, "encode response")
+	}
+}
@@ -0,0 +1,104 @@
+package user
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Alias1177/Auth/internal/domain"
+	"github.com/Alias1177/Auth/internal/dto"
+	apperrors "github.com/Alias1177/Auth/internal/errors"
+	"github.com/Alias1177/Auth/internal/middleware"
+	"github.com/Alias1177/Auth/pkg/errors"
+	"github.com/Alias1177/Auth/pkg/httputil"
+	"github.com/go-chi/chi/v5"
+)
+
+// ListSessions возвращает активные сессии пользователя; текущая сессия помечается флагом current.
+func (h *UserHandler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	userClaims, userID, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	sessions, err := h.sessions.List(r.Context(), userID)
+	if err != nil {
+		errors.HandleDatabaseError(w, err, h.logger, "list sessions")
+		return
+	}
+
+	response := make([]dto.SessionResponse, 0, len(sessions))
+	for _, s := range sessions {
+		response = append(response, dto.SessionResponse{
+			ID:          s.ID,
+			DeviceName:  s.DeviceName,
+			UserAgent:   s.UserAgent,
+			IPAddress:   s.IPAddress,
+			LoginMethod: s.LoginMethod,
+			CreatedAt:   s.CreatedAt,
+			LastUsedAt:  s.LastUsedAt,
+			Current:     s.ID == userClaims.FamilyID,
+		})
+	}
+
+	if err := httputil.JSONSuccessWithID(w, http.StatusOK, dto.MsgSuccessSessionsRetrieved, response); err != nil {
+		errors.HandleInternalError(w, err, h.logger, "encode sessions response")
+	}
+}
+
+// RevokeSession завершает сессию пользователя и отзывает ее токены.
+func (h *UserHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	_, userID, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	sessionID := chi.URLParam(r, "id")
+	if err := h.sessions.Revoke(r.Context(), userID, sessionID); err != nil {
+		if err == apperrors.ErrNotFound {
+			httputil.JSONErrorWithID(w, http.StatusNotFound, dto.MsgSessionNotFound)
+			return
+		}
+		errors.HandleInternalError(w, err, h.logger, "revoke session")
+		return
+	}
+
+	h.logger.Infow("Session revoked", "user_id", userID, "session_id", sessionID)
+	if err := httputil.JSONSuccessWithID(w, http.StatusOK, dto.MsgSuccessSessionRevoked, nil); err != nil {
+		errors.HandleInternalError(w, err, h.logger, "encode response")
+	}
+}
+
+// RevokeOtherSessions завершает все сессии пользователя, кроме текущей.
+func (h *UserHandler) RevokeOtherSessions(w http.ResponseWriter, r *http.Request) {
+	userClaims, userID, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.sessions.RevokeOthers(r.Context(), userID, userClaims.FamilyID); err != nil {
+		errors.HandleInternalError(w, err, h.logger, "revoke other sessions")
+		return
+	}
+
+	h.logger.Infow("Other sessions revoked", "user_id", userID, "current_session_id", userClaims.FamilyID)
+	if err := httputil.JSONSuccessWithID(w, http.StatusOK, dto.MsgSuccessOtherSessionsRevoked, nil); err != nil {
+		errors.HandleInternalError(w, err, h.logger, "encode response")
+	}
+}
+
+// currentUser извлекает claims и ID пользователя из контекста запроса
+func (h *UserHandler) currentUser(w http.ResponseWriter, r *http.Request) (*domain.UserClaims, int, bool) {
+	userClaims, ok := r.Context().Value(middleware.CtxUserKey).(*domain.UserClaims)
+	if !ok {
+		errors.HandleInternalError(w, nil, h.logger, "get user claims from context")
+		return nil, 0, false
+	}
+
+	userID, err := strconv.Atoi(userClaims.UserID)
+	if err != nil {
+		h.logger.Errorw("Invalid user ID in claims", "user_id", userClaims.UserID, "error", err)
+		httputil.JSONErrorWithID(w, http.StatusBadRequest, dto.MsgInvalidUserID)
+		return nil, 0, false
+	}
+	return userClaims, userID, true
+}
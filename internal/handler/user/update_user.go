@@ -1,7 +1,12 @@
 package user
 
 import (
+	"database/sql"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
 	"net/http"
+	"sort"
 	"strconv"
 
 	"github.com/Alias1177/Auth/internal/domain"
@@ -10,26 +15,76 @@ import (
 	"github.com/Alias1177/Auth/pkg/errors"
 	"github.com/Alias1177/Auth/pkg/httputil"
 	"github.com/Alias1177/Auth/pkg/logger"
-	crypto "github.com/Alias1177/Auth/pkg/security"
+	"github.com/Alias1177/Auth/pkg/validator"
 	"github.com/go-chi/chi/v5"
 )
 
+// updatableUserFields поля профиля, которые можно менять через PATCH; остальные (id, email, password...) отклоняются
+var updatableUserFields = map[string]bool{
+	"username": true,
+}
+
 // UserHandler управляет запросами, связанными с пользователями.
 type UserHandler struct {
 	userRepository service.UserRepository
+	sessions       service.SessionService
+	mfa            service.MFAService
+	recoveryCodes  service.RecoveryCodeService
+	webauthn       service.WebAuthnService
+	emailChange    service.EmailChangeService
+	identities     service.IdentityService
+	lockout        service.LockoutService
+	policy         service.PasswordPolicy
+	history        service.PasswordHistoryService
+	notifier       service.PasswordChangedSender
+	validator      *validator.Validator
 	logger         *logger.Logger
 }
 
-func NewUserHandler(userRepo service.UserRepository, log *logger.Logger) *UserHandler {
+func NewUserHandler(
+	userRepo service.UserRepository,
+	sessions service.SessionService,
+	mfa service.MFAService,
+	recoveryCodes service.RecoveryCodeService,
+	webauthn service.WebAuthnService,
+	emailChange service.EmailChangeService,
+	identities service.IdentityService,
+	lockout service.LockoutService,
+	policy service.PasswordPolicy,
+	history service.PasswordHistoryService,
+	notifier service.PasswordChangedSender,
+	validator *validator.Validator,
+	log *logger.Logger,
+) *UserHandler {
 	return &UserHandler{
 		userRepository: userRepo,
+		sessions:       sessions,
+		mfa:            mfa,
+		recoveryCodes:  recoveryCodes,
+		webauthn:       webauthn,
+		emailChange:    emailChange,
+		identities:     identities,
+		lockout:        lockout,
+		policy:         policy,
+		history:        history,
+		notifier:       notifier,
+		validator:      validator,
 		logger:         log,
 	}
 }
 
-// UpdateUser обновляет данные пользователя.
+// UpdateCurrentUser частично обновляет профиль текущего пользователя (PATCH /user/me)
+func (h *UserHandler) UpdateCurrentUser(w http.ResponseWriter, r *http.Request) {
+	_, userID, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+	h.updateUser(w, r, userID)
+}
+
+// UpdateUserHandler частично обновляет профиль пользователя по ID (PATCH /admin/users/{id}).
+// Маршрут доступен только с разрешением users:write; свой профиль пользователь меняет через PATCH /user/me.
 func (h *UserHandler) UpdateUserHandler(w http.ResponseWriter, r *http.Request) {
-	// Получаем ID пользователя из URL параметра
 	userIDStr := chi.URLParam(r, "id")
 	userID, err := strconv.Atoi(userIDStr)
 	if err != nil {
@@ -38,34 +93,91 @@ func (h *UserHandler) UpdateUserHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Декодирование JSON запроса
-	var user domain.User
-	if err := httputil.DecodeJSON(r, &user, h.logger); err != nil {
+	h.updateUser(w, r, userID)
+}
+
+// updateUser применяет JSON merge patch к профилю пользователя userID.
+// Если передан If-Match, он должен совпадать с текущим ETag; запись обновляется, только если
+// updated_at не изменился с момента чтения, иначе — 412.
+func (h *UserHandler) updateUser(w http.ResponseWriter, r *http.Request, userID int) {
+	var patch map[string]json.RawMessage
+	if err := httputil.DecodeJSON(r, &patch, h.logger); err != nil || patch == nil {
 		httputil.JSONErrorWithID(w, http.StatusBadRequest, dto.MsgInvalidRequest)
 		return
 	}
 
-	// Устанавливаем ID пользователя
-	user.ID = userID
+	var rejected []string
+	for field, value := range patch {
+		if !updatableUserFields[field] {
+			rejected = append(rejected, field)
+			continue
+		}
+		// null в merge patch означает удаление поля, а обязательные поля профиля удалить нельзя
+		if string(value) == "null" {
+			httputil.JSONErrorWithID(w, http.StatusBadRequest, dto.MsgInvalidRequestData)
+			return
+		}
+	}
+	if len(rejected) > 0 {
+		sort.Strings(rejected)
+		h.logger.Warnw("Rejected non-updatable user fields", "user_id", userID, "fields", rejected)
+		httputil.JSONErrorWithData(w, http.StatusBadRequest, dto.MsgFieldNotUpdatable, map[string]interface{}{
+			"fields": rejected,
+		})
+		return
+	}
 
-	// Если был передан новый пароль, хешируем его
-	if user.Password != "" {
-		hashedPassword, err := crypto.HashPassword(user.Password)
-		if err != nil {
-			errors.HandleInternalError(w, err, h.logger, "hash password")
+	var req dto.UpdateUserRequest
+	if raw, ok := patch["username"]; ok {
+		if err := json.Unmarshal(raw, &req.Username); err != nil {
+			httputil.JSONErrorWithID(w, http.StatusBadRequest, dto.MsgInvalidRequestData)
 			return
 		}
-		user.Password = hashedPassword
+	}
+	if err := h.validator.Validate(req); err != nil {
+		errors.HandleValidationError(w, err, h.logger)
+		return
 	}
 
-	// Обновляем пользователя
-	if err := h.userRepository.UpdateUser(r.Context(), &user); err != nil {
-		errors.HandleInternalError(w, err, h.logger, "update user")
+	user, err := h.userRepository.GetUserByID(r.Context(), userID)
+	if err != nil {
+		errors.HandleDatabaseError(w, err, h.logger, "get user for update")
 		return
 	}
 
-	// Отправка успешного ответа
-	if err := httputil.JSONSuccessWithID(w, http.StatusOK, dto.MsgSuccessUserUpdated, nil); err != nil {
+	if match := r.Header.Get("If-Match"); match != "" && match != "*" && match != userETag(user) {
+		httputil.JSONErrorWithID(w, http.StatusPreconditionFailed, dto.MsgPreconditionFailed)
+		return
+	}
+
+	if req.Username != nil && *req.Username != user.UserName {
+		expectedUpdatedAt := user.UpdatedAt
+		user.UserName = *req.Username
+		if err := h.userRepository.UpdateUserProfile(r.Context(), user, expectedUpdatedAt); err != nil {
+			if stderrors.Is(err, sql.ErrNoRows) {
+				httputil.JSONErrorWithID(w, http.StatusPreconditionFailed, dto.MsgPreconditionFailed)
+				return
+			}
+			errors.HandleInternalError(w, err, h.logger, "update user profile")
+			return
+		}
+		h.logger.Infow("User profile updated", "user_id", userID)
+	}
+
+	response := dto.UserDTO{
+		ID:        user.ID,
+		Username:  user.UserName,
+		Email:     user.Email,
+		CreatedAt: user.CreatedAt,
+		UpdatedAt: user.UpdatedAt,
+	}
+	w.Header().Set("ETag", userETag(user))
+	if err := httputil.JSONSuccessWithID(w, http.StatusOK, dto.MsgSuccessUserUpdated, response); err != nil {
 		errors.HandleInternalError(w, err, h.logger, "encode response")
 	}
 }
+
+// userETag версия профиля для If-Match: меняется при каждом обновлении updated_at
+func userETag(user *domain.User) string {
+	return fmt.Sprintf(`"%d-%d"`, user.ID, user.UpdatedAt.UnixMicro())
+}
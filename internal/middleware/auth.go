@@ -2,18 +2,52 @@ package middleware
 
 import (
 	"context"
-	"fmt"
 	"net/http"
 	"strings"
 
+	"github.com/Alias1177/Auth/internal/domain"
+	"github.com/Alias1177/Auth/internal/dto"
 	"github.com/Alias1177/Auth/internal/service"
+	"github.com/Alias1177/Auth/pkg/httputil"
+	"github.com/Alias1177/Auth/pkg/logger"
 )
 
 type contextKey string
 
 const CtxUserKey contextKey = "user"
 
-func JWTAuthMiddleware(manager service.TokenManager) func(http.Handler) http.Handler {
+// JWTAuthMiddleware пропускает запрос с access токеном входа в сам сервис.
+// Токены, выданные приложениям через /oauth2/token, здесь не принимаются.
+func JWTAuthMiddleware(manager service.TokenManager, denylist service.AccessTokenDenylist, log *logger.Logger) func(http.Handler) http.Handler {
+	return accessTokenAuth(manager, denylist, log, accessTokenOptions{})
+}
+
+// UserInfoAuthMiddleware пропускает запрос к /oauth2/userinfo: кроме токенов входа в сам сервис
+// принимаются токены, выданные приложениям
+func UserInfoAuthMiddleware(manager service.TokenManager, denylist service.AccessTokenDenylist, log *logger.Logger) func(http.Handler) http.Handler {
+	return accessTokenAuth(manager, denylist, log, accessTokenOptions{allowApplicationTokens: true})
+}
+
+// OptionalJWTAuthMiddleware кладет claims в контекст, если запрос пришел с действующим токеном входа,
+// а без токена или с недействительным токеном пропускает запрос без них. Нужен страницам, которые
+// сами показывают форму входа, например /oauth2/device.
+func OptionalJWTAuthMiddleware(manager service.TokenManager, denylist service.AccessTokenDenylist, log *logger.Logger) func(http.Handler) http.Handler {
+	return accessTokenAuth(manager, denylist, log, accessTokenOptions{optional: true})
+}
+
+type accessTokenOptions struct {
+	// allowApplicationTokens принимать токены, выданные приложениям через /oauth2/token
+	allowApplicationTokens bool
+	// optional пропускать запрос без claims вместо ответа 401
+	optional bool
+}
+
+func accessTokenAuth(
+	manager service.TokenManager,
+	denylist service.AccessTokenDenylist,
+	log *logger.Logger,
+	opts accessTokenOptions,
+) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			var token string
@@ -34,6 +68,10 @@ func JWTAuthMiddleware(manager service.TokenManager) func(http.Handler) http.Han
 
 			// 3️⃣ Если токен так и не нашли — отправляем ошибку
 			if token == "" {
+				if opts.optional {
+					next.ServeHTTP(w, r)
+					return
+				}
 				http.Error(w, "Unauthorized - no token", http.StatusUnauthorized)
 				return
 			}
@@ -41,14 +79,65 @@ func JWTAuthMiddleware(manager service.TokenManager) func(http.Handler) http.Han
 			// 4️⃣ Проверяем токен
 			userClaims, err := manager.ValidateAccessToken(token)
 			if err != nil {
-				fmt.Println("Ошибка валидации токена:", err)
+				log.Debugw("Access token validation failed", "error", err)
+				if opts.optional {
+					next.ServeHTTP(w, r)
+					return
+				}
+				http.Error(w, "Unauthorized - invalid token", http.StatusUnauthorized)
+				return
+			}
+			if userClaims.ClientID != "" && !opts.allowApplicationTokens {
+				log.Debugw("Application access token rejected", "client_id", userClaims.ClientID)
+				if opts.optional {
+					next.ServeHTTP(w, r)
+					return
+				}
 				http.Error(w, "Unauthorized - invalid token", http.StatusUnauthorized)
 				return
 			}
 
-			// 5️⃣ Добавляем данные пользователя в контекст
+			// 5️⃣ Проверяем, не отозван ли токен (logout)
+			revoked, err := denylist.IsRevoked(r.Context(), userClaims)
+			if err != nil {
+				log.Errorw("Failed to check access token revocation", "error", err)
+				http.Error(w, "Service unavailable", http.StatusServiceUnavailable)
+				return
+			}
+			if revoked {
+				if opts.optional {
+					next.ServeHTTP(w, r)
+					return
+				}
+				http.Error(w, "Unauthorized - token revoked", http.StatusUnauthorized)
+				return
+			}
+
+			// 6️⃣ Добавляем данные пользователя в контекст
 			ctx := context.WithValue(r.Context(), CtxUserKey, userClaims)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
+
+// RequirePermission пропускает запрос, только если в access токене есть все перечисленные разрешения.
+// Ставится после JWTAuthMiddleware, который кладет claims в контекст.
+func RequirePermission(permissions ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userClaims, ok := r.Context().Value(CtxUserKey).(*domain.UserClaims)
+			if !ok {
+				http.Error(w, "Unauthorized - no token", http.StatusUnauthorized)
+				return
+			}
+
+			for _, permission := range permissions {
+				if !userClaims.HasPermission(permission) {
+					httputil.JSONErrorWithID(w, http.StatusForbidden, dto.MsgForbidden)
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Alias1177/Auth/internal/config"
+	"github.com/Alias1177/Auth/internal/domain"
+	"github.com/Alias1177/Auth/pkg/jwt"
+	"github.com/Alias1177/Auth/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// emptyDenylist ни один токен не отозван
+type emptyDenylist struct{}
+
+func (emptyDenylist) RevokeToken(context.Context, *domain.UserClaims) error { return nil }
+func (emptyDenylist) RevokeUserTokens(context.Context, string) error        { return nil }
+func (emptyDenylist) RevokeFamilyTokens(context.Context, string) error      { return nil }
+func (emptyDenylist) IsRevoked(context.Context, *domain.UserClaims) (bool, error) {
+	return false, nil
+}
+
+func TestJWTAuthMiddleware_ApplicationTokens(t *testing.T) {
+	log, err := logger.New("error")
+	require.NoError(t, err)
+	manager, err := jwt.NewJWTTokenManager(config.JWTConfig{Secret: "secret"})
+	require.NoError(t, err)
+
+	userToken, err := manager.GenerateAccessToken(domain.UserClaims{UserID: "7", Email: "user@example.com"})
+	require.NoError(t, err)
+	appToken, err := manager.GenerateAccessToken(domain.UserClaims{UserID: "7", Email: "user@example.com", ClientID: "app", Scope: "openid"})
+	require.NoError(t, err)
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	firstParty := JWTAuthMiddleware(manager, emptyDenylist{}, log)(ok)
+	userInfo := UserInfoAuthMiddleware(manager, emptyDenylist{}, log)(ok)
+
+	tests := []struct {
+		name    string
+		handler http.Handler
+		token   string
+		want    int
+	}{
+		{name: "first-party token on first-party route", handler: firstParty, token: userToken, want: http.StatusOK},
+		{name: "application token on first-party route", handler: firstParty, token: appToken, want: http.StatusUnauthorized},
+		{name: "application token on userinfo", handler: userInfo, token: appToken, want: http.StatusOK},
+		{name: "first-party token on userinfo", handler: userInfo, token: userToken, want: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/me", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			rec := httptest.NewRecorder()
+			tt.handler.ServeHTTP(rec, req)
+			assert.Equal(t, tt.want, rec.Code)
+		})
+	}
+}
+
+func TestOptionalJWTAuthMiddleware(t *testing.T) {
+	log, err := logger.New("error")
+	require.NoError(t, err)
+	manager, err := jwt.NewJWTTokenManager(config.JWTConfig{Secret: "secret"})
+	require.NoError(t, err)
+
+	userToken, err := manager.GenerateAccessToken(domain.UserClaims{UserID: "7", Email: "user@example.com"})
+	require.NoError(t, err)
+	appToken, err := manager.GenerateAccessToken(domain.UserClaims{UserID: "7", Email: "user@example.com", ClientID: "app", Scope: "openid"})
+	require.NoError(t, err)
+
+	var signedIn bool
+	handler := OptionalJWTAuthMiddleware(manager, emptyDenylist{}, log)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, signedIn = r.Context().Value(CtxUserKey).(*domain.UserClaims)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name     string
+		token    string
+		signedIn bool
+	}{
+		{name: "first-party token", token: userToken, signedIn: true},
+		{name: "no token", token: "", signedIn: false},
+		{name: "invalid token", token: "garbage", signedIn: false},
+		{name: "application token", token: appToken, signedIn: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signedIn = false
+			req := httptest.NewRequest(http.MethodGet, "/oauth2/device", nil)
+			if tt.token != "" {
+				req.AddCookie(&http.Cookie{Name: "access-token", Value: tt.token})
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, tt.signedIn, signedIn)
+		})
+	}
+}
+
+func TestRequirePermission(t *testing.T) {
+	handler := RequirePermission(domain.PermissionUsersRead, domain.PermissionUsersWrite)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}),
+	)
+
+	tests := []struct {
+		name   string
+		claims *domain.UserClaims
+		want   int
+	}{
+		{name: "no claims", want: http.StatusUnauthorized},
+		{name: "no permissions", claims: &domain.UserClaims{UserID: "1"}, want: http.StatusForbidden},
+		{
+			name:   "partial permissions",
+			claims: &domain.UserClaims{UserID: "1", Permissions: []string{domain.PermissionUsersRead}},
+			want:   http.StatusForbidden,
+		},
+		{
+			name: "all permissions",
+			claims: &domain.UserClaims{
+				UserID:      "1",
+				Permissions: []string{domain.PermissionUsersRead, domain.PermissionUsersWrite},
+			},
+			want: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/admin/users", nil)
+			if tt.claims != nil {
+				req = req.WithContext(context.WithValue(req.Context(), CtxUserKey, tt.claims))
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			assert.Equal(t, tt.want, rec.Code)
+		})
+	}
+}
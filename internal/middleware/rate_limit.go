@@ -0,0 +1,156 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Alias1177/Auth/internal/config"
+	"github.com/Alias1177/Auth/internal/domain"
+	"github.com/Alias1177/Auth/internal/dto"
+	"github.com/Alias1177/Auth/internal/service"
+	"github.com/Alias1177/Auth/pkg/httputil"
+	"github.com/Alias1177/Auth/pkg/logger"
+)
+
+// Ручки с ограничением частоты запросов
+const (
+	RouteLogin                = "login"
+	RouteRegister             = "register"
+	RoutePasswordResetRequest = "password_reset_request"
+	RoutePasswordResetConfirm = "password_reset_confirm"
+	// RouteReauth ручки, где вошедший пользователь заново подтверждает пароль
+	RouteReauth = "reauth"
+)
+
+// maxRateLimitBody максимальный размер тела, которое читается для поиска email
+const maxRateLimitBody = 1 << 20
+
+// rateLimitRule лимит ручки и сообщение, возвращаемое при его превышении
+type rateLimitRule struct {
+	limit     config.RateLimit
+	messageID int
+}
+
+// RateLimitMiddleware ограничивает частоту запросов по IP клиента и по email из тела запроса
+type RateLimitMiddleware struct {
+	store   service.RateLimitStore
+	enabled bool
+	rules   map[string]rateLimitRule
+	log     *logger.Logger
+}
+
+// NewRateLimitMiddleware создает middleware с лимитами из конфигурации
+func NewRateLimitMiddleware(store service.RateLimitStore, cfg config.RateLimitConfig, log *logger.Logger) (*RateLimitMiddleware, error) {
+	specs := []struct {
+		route     string
+		spec      string
+		messageID int
+	}{
+		{RouteLogin, cfg.Login, dto.MsgTooManyRequests},
+		{RouteRegister, cfg.Register, dto.MsgTooManyRequests},
+		{RoutePasswordResetRequest, cfg.PasswordResetRequest, dto.MsgTooManyResetAttempts},
+		{RoutePasswordResetConfirm, cfg.PasswordResetConfirm, dto.MsgTooManyResetAttempts},
+		{RouteReauth, cfg.Reauth, dto.MsgTooManyRequests},
+	}
+
+	rules := make(map[string]rateLimitRule, len(specs))
+	for _, s := range specs {
+		limit, err := config.ParseRateLimit(s.spec)
+		if err != nil {
+			return nil, fmt.Errorf("rate limit for %s: %w", s.route, err)
+		}
+		rules[s.route] = rateLimitRule{limit: limit, messageID: s.messageID}
+	}
+
+	return &RateLimitMiddleware{
+		store:   store,
+		enabled: cfg.Enabled,
+		rules:   rules,
+		log:     log,
+	}, nil
+}
+
+// Limit возвращает middleware для ручки route
+func (m *RateLimitMiddleware) Limit(route string) func(http.Handler) http.Handler {
+	rule, ok := m.rules[route]
+	if !ok {
+		panic(fmt.Sprintf("rate limit for route %q is not configured", route))
+	}
+
+	return func(next http.Handler) http.Handler {
+		if !m.enabled {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			keys := []string{fmt.Sprintf("ratelimit:%s:ip:%s", route, httputil.ClientIP(r))}
+
+			email, err := peekEmail(r)
+			if err != nil {
+				httputil.JSONErrorWithID(w, http.StatusBadRequest, dto.MsgInvalidRequest)
+				return
+			}
+			if email != "" {
+				keys = append(keys, fmt.Sprintf("ratelimit:%s:email:%s", route, email))
+			}
+			// За JWTAuthMiddleware лимит считается и на пользователя: токен не обходит его сменой IP
+			if claims, ok := r.Context().Value(CtxUserKey).(*domain.UserClaims); ok {
+				keys = append(keys, fmt.Sprintf("ratelimit:%s:user:%s", route, claims.UserID))
+			}
+
+			for _, key := range keys {
+				allowed, retryAfter, err := m.store.Allow(r.Context(), key, rule.limit.Requests, rule.limit.Window)
+				if err != nil {
+					// Недоступность Redis не должна блокировать вход
+					m.log.Errorw("Rate limit check failed", "route", route, "error", err)
+					break
+				}
+				if !allowed {
+					m.log.Warnw("Rate limit exceeded", "route", route, "key", key)
+					w.Header().Set("Retry-After", retryAfterSeconds(retryAfter))
+					httputil.JSONErrorWithID(w, http.StatusTooManyRequests, rule.messageID)
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// peekEmail читает email из JSON тела запроса, оставляя тело доступным обработчику
+func peekEmail(r *http.Request) (string, error) {
+	if r.Body == nil {
+		return "", nil
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxRateLimitBody+1))
+	if err != nil {
+		return "", err
+	}
+	if len(body) > maxRateLimitBody {
+		return "", fmt.Errorf("request body too large")
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	var payload struct {
+		Email string `json:"email"`
+	}
+	// Некорректный JSON отклонит сам обработчик
+	_ = json.Unmarshal(body, &payload)
+	return strings.ToLower(strings.TrimSpace(payload.Email)), nil
+}
+
+// retryAfterSeconds округляет время ожидания вверх до целых секунд (не меньше одной)
+func retryAfterSeconds(d time.Duration) string {
+	seconds := int(math.Ceil(d.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	return strconv.Itoa(seconds)
+}
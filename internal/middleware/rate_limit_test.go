@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Alias1177/Auth/internal/config"
+	"github.com/Alias1177/Auth/internal/domain"
+	"github.com/Alias1177/Auth/internal/dto"
+	"github.com/Alias1177/Auth/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingStore фиксированное окно в памяти
+type countingStore struct {
+	counts map[string]int
+}
+
+func (s *countingStore) Allow(_ context.Context, key string, limit int, window time.Duration) (bool, time.Duration, error) {
+	if s.counts[key] >= limit {
+		return false, window, nil
+	}
+	s.counts[key]++
+	return true, 0, nil
+}
+
+func TestRateLimitMiddleware_Limit(t *testing.T) {
+	log, err := logger.New("error")
+	require.NoError(t, err)
+
+	cfg := config.RateLimitConfig{
+		Enabled:              true,
+		Login:                "2/1m",
+		Register:             "1/1h",
+		PasswordResetRequest: "1/90s",
+		PasswordResetConfirm: "1/1m",
+		Reauth:               "1/15m",
+	}
+	rl, err := NewRateLimitMiddleware(&countingStore{counts: map[string]int{}}, cfg, log)
+	require.NoError(t, err)
+
+	var handledBody string
+	handler := rl.Limit(RoutePasswordResetRequest)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		handledBody = string(body)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	send := func(ip, email string) *httptest.ResponseRecorder {
+		body := `{"email":"` + email + `"}`
+		req := httptest.NewRequest(http.MethodPost, "/auth/request-password-reset", strings.NewReader(body))
+		req.RemoteAddr = ip + ":40000"
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	tests := []struct {
+		name       string
+		ip         string
+		email      string
+		wantStatus int
+	}{
+		{name: "first request", ip: "10.0.0.1", email: "a@example.com", wantStatus: http.StatusOK},
+		{name: "same ip", ip: "10.0.0.1", email: "b@example.com", wantStatus: http.StatusTooManyRequests},
+		{name: "same email from another ip", ip: "10.0.0.2", email: "A@example.com", wantStatus: http.StatusTooManyRequests},
+		{name: "another ip and email", ip: "10.0.0.3", email: "c@example.com", wantStatus: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := send(tt.ip, tt.email)
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			if tt.wantStatus == http.StatusTooManyRequests {
+				assert.Equal(t, "90", rec.Header().Get("Retry-After"))
+				var resp map[string]interface{}
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+				assert.EqualValues(t, dto.MsgTooManyResetAttempts, resp["id_message"])
+			} else {
+				assert.Contains(t, handledBody, tt.email)
+			}
+		})
+	}
+}
+
+func TestRateLimitMiddleware_PerUser(t *testing.T) {
+	log, err := logger.New("error")
+	require.NoError(t, err)
+
+	cfg := config.RateLimitConfig{
+		Enabled: true, Login: "1/1m", Register: "1/1m", PasswordResetRequest: "1/1m", PasswordResetConfirm: "1/1m",
+		Reauth: "1/15m",
+	}
+	rl, err := NewRateLimitMiddleware(&countingStore{counts: map[string]int{}}, cfg, log)
+	require.NoError(t, err)
+	handler := rl.Limit(RouteReauth)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	send := func(ip, userID string) int {
+		req := httptest.NewRequest(http.MethodPost, "/user/me/password", strings.NewReader(`{}`))
+		req.RemoteAddr = ip + ":40000"
+		req = req.WithContext(context.WithValue(req.Context(), CtxUserKey, &domain.UserClaims{UserID: userID}))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusOK, send("10.0.0.1", "7"))
+	assert.Equal(t, http.StatusTooManyRequests, send("10.0.0.2", "7"), "changing IP does not reset the user limit")
+	assert.Equal(t, http.StatusOK, send("10.0.0.3", "8"))
+}
+
+func TestNewRateLimitMiddleware_InvalidConfig(t *testing.T) {
+	log, err := logger.New("error")
+	require.NoError(t, err)
+
+	_, err = NewRateLimitMiddleware(&countingStore{}, config.RateLimitConfig{Login: "ten per minute"}, log)
+	assert.Error(t, err)
+}
@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/Alias1177/Auth/pkg/httputil"
+)
+
+// RealIP подставляет в RemoteAddr адрес клиента из заголовков доверенных прокси, чтобы лимиты запросов,
+// сессии и журнал аудита видели клиента, а не прокси. Без доверенных прокси заголовки игнорируются.
+func RealIP(proxies httputil.TrustedProxies) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if len(proxies) == 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r.RemoteAddr = proxies.RealIP(r)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Alias1177/Auth/pkg/httputil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRealIP(t *testing.T) {
+	proxies, err := httputil.ParseTrustedProxies([]string{"10.0.0.0/8", "192.168.1.1"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		proxies    httputil.TrustedProxies
+		remoteAddr string
+		forwarded  string
+		realIP     string
+		want       string
+	}{
+		{name: "no trusted proxies ignores headers", remoteAddr: "203.0.113.7:5000", forwarded: "198.51.100.1", want: "203.0.113.7"},
+		{name: "untrusted peer ignores headers", proxies: proxies, remoteAddr: "203.0.113.7:5000", forwarded: "198.51.100.1", realIP: "198.51.100.2", want: "203.0.113.7"},
+		{name: "trusted proxy", proxies: proxies, remoteAddr: "10.0.0.5:5000", forwarded: "198.51.100.1", want: "198.51.100.1"},
+		{
+			name: "spoofed left-most entry", proxies: proxies, remoteAddr: "10.0.0.5:5000",
+			forwarded: "1.2.3.4, 198.51.100.1, 192.168.1.1", want: "198.51.100.1",
+		},
+		{name: "all hops trusted", proxies: proxies, remoteAddr: "10.0.0.5:5000", forwarded: "10.1.1.1, 192.168.1.1", want: "10.1.1.1"},
+		{name: "garbage hop", proxies: proxies, remoteAddr: "10.0.0.5:5000", forwarded: "198.51.100.1, not-an-ip", want: "10.0.0.5"},
+		{name: "x-real-ip from trusted proxy", proxies: proxies, remoteAddr: "192.168.1.1:5000", realIP: "198.51.100.2", want: "198.51.100.2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			handler := RealIP(tt.proxies)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = httputil.ClientIP(r)
+			}))
+
+			req := httptest.NewRequest(http.MethodPost, "/login", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			if tt.realIP != "" {
+				req.Header.Set("X-Real-IP", tt.realIP)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	_, err = httputil.ParseTrustedProxies([]string{"10.0.0.0/33"})
+	assert.Error(t, err)
+}
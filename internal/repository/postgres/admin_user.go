@@ -0,0 +1,131 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/Alias1177/Auth/internal/domain"
+)
+
+// likeEscaper экранирует спецсимволы LIKE, чтобы строка поиска сравнивалась буквально
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// SearchUsers возвращает страницу пользователей по фильтру и общее число подходящих записей
+func (r *PostgresRepository) SearchUsers(ctx context.Context, filter domain.UserFilter, limit, offset int) ([]domain.User, int64, error) {
+	var (
+		conditions []string
+		args       []interface{}
+	)
+	addCondition := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if filter.Email != "" {
+		addCondition(`email ILIKE '%%' || $%d || '%%'`, likeEscaper.Replace(filter.Email))
+	}
+	if filter.Username != "" {
+		addCondition(`username ILIKE '%%' || $%d || '%%'`, likeEscaper.Replace(filter.Username))
+	}
+	switch filter.Status {
+	case domain.UserStatusActive:
+		conditions = append(conditions, `disabled_at IS NULL AND (locked_until IS NULL OR locked_until <= NOW())`)
+	case domain.UserStatusDisabled:
+		conditions = append(conditions, `disabled_at IS NOT NULL`)
+	case domain.UserStatusLocked:
+		conditions = append(conditions, `locked_until > NOW()`)
+	case domain.UserStatusUnverified:
+		conditions = append(conditions, `email_verified_at IS NULL`)
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int64
+	if err := r.db.GetContext(ctx, &total, `SELECT COUNT(*) FROM UsersLog `+where, args...); err != nil {
+		r.log.Errorw("Failed to count users", "err", err)
+		return nil, 0, fmt.Errorf("failed to count users: %w", err)
+	}
+
+	users := []domain.User{}
+	query := fmt.Sprintf(`SELECT id, username, email, created_at, updated_at, email_verified_at,
+              failed_login_attempts, locked_until, disabled_at
+              FROM UsersLog %s ORDER BY id LIMIT $%d OFFSET $%d`, where, len(args)+1, len(args)+2)
+	if err := r.db.SelectContext(ctx, &users, query, append(args, limit, offset)...); err != nil {
+		r.log.Errorw("Failed to search users", "err", err)
+		return nil, 0, fmt.Errorf("failed to search users: %w", err)
+	}
+	return users, total, nil
+}
+
+// SetUserDisabled отключает или включает учетную запись. Возвращает sql.ErrNoRows, если пользователя нет.
+func (r *PostgresRepository) SetUserDisabled(ctx context.Context, userID int, disabled bool) error {
+	query := `UPDATE UsersLog SET disabled_at = NULL, updated_at = NOW() WHERE id = $1`
+	if disabled {
+		query = `UPDATE UsersLog SET disabled_at = COALESCE(disabled_at, NOW()), updated_at = NOW() WHERE id = $1`
+	}
+	result, err := r.db.ExecContext(ctx, query, userID)
+	if err != nil {
+		r.log.Errorw("Failed to set user disabled", "user_id", userID, "disabled", disabled, "err", err)
+		return fmt.Errorf("failed to set user disabled: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// DeleteUser удаляет пользователя; сессии, токены, ключи и прочие связанные записи удаляются каскадно.
+// Возвращает sql.ErrNoRows, если пользователя нет.
+func (r *PostgresRepository) DeleteUser(ctx context.Context, userID int) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM UsersLog WHERE id = $1`, userID)
+	if err != nil {
+		r.log.Errorw("Failed to delete user", "user_id", userID, "err", err)
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// CreateAuditRecord добавляет запись в журнал аудита
+func (r *PostgresRepository) CreateAuditRecord(ctx context.Context, record *domain.AuditRecord) error {
+	details := record.Details
+	if len(details) == 0 {
+		details = []byte("{}")
+	}
+	query := `INSERT INTO audit_log (actor_id, action, target_user_id, details, ip)
+              VALUES ($1, $2, $3, $4, $5)
+              RETURNING id, created_at`
+	err := r.db.QueryRowxContext(ctx, query,
+		record.ActorID, record.Action, record.TargetUserID, string(details), record.IP,
+	).Scan(&record.ID, &record.CreatedAt)
+	if err != nil {
+		r.log.Errorw("Failed to create audit record", "action", record.Action, "err", err)
+		return fmt.Errorf("failed to create audit record: %w", err)
+	}
+	return nil
+}
+
+// ListAuditRecords возвращает страницу записей аудита о пользователе, начиная с новых, и их общее число
+func (r *PostgresRepository) ListAuditRecords(ctx context.Context, targetUserID, limit, offset int) ([]domain.AuditRecord, int64, error) {
+	var total int64
+	if err := r.db.GetContext(ctx, &total, `SELECT COUNT(*) FROM audit_log WHERE target_user_id = $1`, targetUserID); err != nil {
+		r.log.Errorw("Failed to count audit records", "user_id", targetUserID, "err", err)
+		return nil, 0, fmt.Errorf("failed to count audit records: %w", err)
+	}
+
+	records := []domain.AuditRecord{}
+	query := `SELECT id, actor_id, action, target_user_id, details, ip, created_at
+              FROM audit_log WHERE target_user_id = $1
+              ORDER BY id DESC LIMIT $2 OFFSET $3`
+	if err := r.db.SelectContext(ctx, &records, query, targetUserID, limit, offset); err != nil {
+		r.log.Errorw("Failed to list audit records", "user_id", targetUserID, "err", err)
+		return nil, 0, fmt.Errorf("failed to list audit records: %w", err)
+	}
+	return records, total, nil
+}
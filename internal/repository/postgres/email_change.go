@@ -0,0 +1,158 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/Alias1177/Auth/internal/domain"
+	apperrors "github.com/Alias1177/Auth/internal/errors"
+	"github.com/lib/pq"
+)
+
+// uniqueViolation код ошибки PostgreSQL при нарушении уникальности
+const uniqueViolation = "23505"
+
+// SaveEmailChange сохраняет заявку на смену адреса, заменяя предыдущую заявку пользователя
+func (r *PostgresRepository) SaveEmailChange(ctx context.Context, change *domain.EmailChange) error {
+	query := `INSERT INTO email_changes (user_id, new_email, token_hash, cancel_token_hash, expires_at)
+              VALUES ($1, $2, $3, $4, $5)
+              ON CONFLICT (user_id) DO UPDATE
+              SET new_email = EXCLUDED.new_email, token_hash = EXCLUDED.token_hash,
+                  cancel_token_hash = EXCLUDED.cancel_token_hash, expires_at = EXCLUDED.expires_at,
+                  created_at = NOW()
+              RETURNING created_at`
+	err := r.db.QueryRowxContext(ctx, query,
+		change.UserID, change.NewEmail, change.TokenHash, change.CancelTokenHash, change.ExpiresAt,
+	).Scan(&change.CreatedAt)
+	if err != nil {
+		r.log.Errorw("Failed to save email change", "user_id", change.UserID, "err", err)
+		return fmt.Errorf("failed to save email change: %w", err)
+	}
+	return nil
+}
+
+// GetEmailChangeByToken возвращает действующую заявку по хешу токена подтверждения.
+// Возвращает sql.ErrNoRows, если заявки нет или она истекла.
+func (r *PostgresRepository) GetEmailChangeByToken(ctx context.Context, tokenHash string) (*domain.EmailChange, error) {
+	return r.getEmailChange(ctx, `token_hash = $1`, tokenHash)
+}
+
+// GetEmailChangeByCancelToken возвращает действующую заявку по хешу токена отмены.
+// Возвращает sql.ErrNoRows, если заявки нет или она истекла.
+func (r *PostgresRepository) GetEmailChangeByCancelToken(ctx context.Context, cancelTokenHash string) (*domain.EmailChange, error) {
+	return r.getEmailChange(ctx, `cancel_token_hash = $1`, cancelTokenHash)
+}
+
+func (r *PostgresRepository) getEmailChange(ctx context.Context, condition, hash string) (*domain.EmailChange, error) {
+	var change domain.EmailChange
+	query := `SELECT user_id, new_email, token_hash, cancel_token_hash, expires_at, created_at
+              FROM email_changes WHERE ` + condition + ` AND expires_at > NOW()`
+	if err := r.db.GetContext(ctx, &change, query, hash); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to get email change: %w", err)
+	}
+	return &change, nil
+}
+
+// DeleteEmailChange удаляет заявку пользователя на смену адреса
+func (r *PostgresRepository) DeleteEmailChange(ctx context.Context, userID int) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM email_changes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete email change: %w", err)
+	}
+	return nil
+}
+
+// ApplyEmailChange меняет адрес пользователя на подтвержденный, удаляет заявку и сохраняет
+// возможность вернуть прежний адрес. Возвращает ErrUserExists, если адрес успел занять другой пользователь.
+func (r *PostgresRepository) ApplyEmailChange(ctx context.Context, userID int, newEmail string, revert *domain.EmailChangeRevert) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE UsersLog SET email = $1, email_verified_at = NOW(), updated_at = NOW() WHERE id = $2`
+	res, err := tx.ExecContext(ctx, query, newEmail, userID)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			return apperrors.ErrUserExists
+		}
+		return fmt.Errorf("failed to update email: %w", err)
+	}
+	if err := requireAffected(res); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM email_changes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete email change: %w", err)
+	}
+
+	query = `INSERT INTO email_change_reverts (cancel_token_hash, user_id, old_email, expires_at)
+             VALUES ($1, $2, $3, $4)`
+	if _, err := tx.ExecContext(ctx, query, revert.CancelTokenHash, userID, revert.OldEmail, revert.ExpiresAt); err != nil {
+		return fmt.Errorf("failed to save email change revert: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.log.Errorw("Failed to apply email change", "user_id", userID, "err", err)
+		return fmt.Errorf("failed to commit email change: %w", err)
+	}
+	return nil
+}
+
+// GetEmailChangeRevert возвращает действующую возможность вернуть прежний адрес по хешу токена отмены.
+// Возвращает sql.ErrNoRows, если ее нет или она истекла.
+func (r *PostgresRepository) GetEmailChangeRevert(ctx context.Context, cancelTokenHash string) (*domain.EmailChangeRevert, error) {
+	var revert domain.EmailChangeRevert
+	query := `SELECT user_id, old_email, cancel_token_hash, expires_at, created_at
+              FROM email_change_reverts WHERE cancel_token_hash = $1 AND expires_at > NOW()`
+	if err := r.db.GetContext(ctx, &revert, query, cancelTokenHash); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to get email change revert: %w", err)
+	}
+	return &revert, nil
+}
+
+// RevertEmailChange возвращает пользователю прежний адрес и удаляет его заявки и остальные возвраты.
+// Возвращает ErrUserExists, если прежний адрес успел занять другой пользователь.
+func (r *PostgresRepository) RevertEmailChange(ctx context.Context, revert *domain.EmailChangeRevert) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Переход по ссылке из письма на прежний адрес подтверждает владение им
+	query := `UPDATE UsersLog SET email = $1, email_verified_at = NOW(), updated_at = NOW() WHERE id = $2`
+	res, err := tx.ExecContext(ctx, query, revert.OldEmail, revert.UserID)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			return apperrors.ErrUserExists
+		}
+		return fmt.Errorf("failed to restore email: %w", err)
+	}
+	if err := requireAffected(res); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM email_changes WHERE user_id = $1`, revert.UserID); err != nil {
+		return fmt.Errorf("failed to delete email change: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM email_change_reverts WHERE user_id = $1`, revert.UserID); err != nil {
+		return fmt.Errorf("failed to delete email change reverts: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.log.Errorw("Failed to revert email change", "user_id", revert.UserID, "err", err)
+		return fmt.Errorf("failed to commit email change revert: %w", err)
+	}
+	return nil
+}
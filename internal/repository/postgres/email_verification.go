@@ -0,0 +1,19 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+)
+
+// MarkEmailVerified отмечает адрес пользователя подтвержденным.
+// Возвращает sql.ErrNoRows, если адрес уже подтвержден или пользователь не найден.
+func (r *PostgresRepository) MarkEmailVerified(ctx context.Context, userID int) error {
+	query := `UPDATE UsersLog SET email_verified_at = NOW(), updated_at = NOW()
+              WHERE id = $1 AND email_verified_at IS NULL`
+	res, err := r.db.ExecContext(ctx, query, userID)
+	if err != nil {
+		r.log.Errorw("Failed to mark email verified", "user_id", userID, "err", err)
+		return fmt.Errorf("failed to mark email verified: %w", err)
+	}
+	return requireAffected(res)
+}
@@ -0,0 +1,50 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// RecordFailedLogin увеличивает счетчик неудачных входов и возвращает его новое значение.
+// Если с прошлой неудачи прошло больше resetAfter, счет начинается заново.
+func (r *PostgresRepository) RecordFailedLogin(ctx context.Context, userID int, resetAfter time.Duration) (int, error) {
+	query := `UPDATE UsersLog
+              SET failed_login_attempts = CASE
+                      WHEN last_failed_login_at < NOW() - make_interval(secs => $2) THEN 1
+                      ELSE failed_login_attempts + 1
+                  END,
+                  last_failed_login_at = NOW()
+              WHERE id = $1
+              RETURNING failed_login_attempts`
+	var attempts int
+	if err := r.db.QueryRowxContext(ctx, query, userID, resetAfter.Seconds()).Scan(&attempts); err != nil {
+		r.log.Errorw("Failed to record failed login", "user_id", userID, "err", err)
+		return 0, fmt.Errorf("failed to record failed login: %w", err)
+	}
+	return attempts, nil
+}
+
+// LockUser блокирует вход пользователя до until
+func (r *PostgresRepository) LockUser(ctx context.Context, userID int, until time.Time) error {
+	query := `UPDATE UsersLog SET locked_until = $2 WHERE id = $1`
+	if _, err := r.db.ExecContext(ctx, query, userID, until); err != nil {
+		r.log.Errorw("Failed to lock user", "user_id", userID, "err", err)
+		return fmt.Errorf("failed to lock user: %w", err)
+	}
+	return nil
+}
+
+// ResetFailedLogins снимает блокировку и обнуляет счетчик неудачных входов.
+// Возвращает sql.ErrNoRows, если пользователь не найден.
+func (r *PostgresRepository) ResetFailedLogins(ctx context.Context, userID int) error {
+	query := `UPDATE UsersLog
+              SET failed_login_attempts = 0, last_failed_login_at = NULL, locked_until = NULL
+              WHERE id = $1`
+	res, err := r.db.ExecContext(ctx, query, userID)
+	if err != nil {
+		r.log.Errorw("Failed to reset failed logins", "user_id", userID, "err", err)
+		return fmt.Errorf("failed to reset failed logins: %w", err)
+	}
+	return requireAffected(res)
+}
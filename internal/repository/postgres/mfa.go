@@ -0,0 +1,81 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/Alias1177/Auth/internal/domain"
+)
+
+// GetUserMFA получает настройки второго фактора; sql.ErrNoRows, если TOTP не настроен
+func (r *PostgresRepository) GetUserMFA(ctx context.Context, userID int) (*domain.UserMFA, error) {
+	var mfa domain.UserMFA
+	query := `SELECT user_id, totp_secret, enabled_at, last_used_step, created_at FROM user_mfa WHERE user_id = $1`
+	if err := r.db.GetContext(ctx, &mfa, query, userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to get user mfa: %w", err)
+	}
+	return &mfa, nil
+}
+
+// SavePendingTOTP сохраняет новый неподтвержденный секрет. Уже включенный TOTP не перезаписывается.
+// Возвращает sql.ErrNoRows, если TOTP уже включен.
+func (r *PostgresRepository) SavePendingTOTP(ctx context.Context, userID int, secret string) error {
+	query := `INSERT INTO user_mfa (user_id, totp_secret)
+              VALUES ($1, $2)
+              ON CONFLICT (user_id) DO UPDATE
+              SET totp_secret = EXCLUDED.totp_secret, last_used_step = 0, created_at = NOW()
+              WHERE user_mfa.enabled_at IS NULL`
+	res, err := r.db.ExecContext(ctx, query, userID, secret)
+	if err != nil {
+		r.log.Errorw("Failed to save TOTP secret", "user_id", userID, "err", err)
+		return fmt.Errorf("failed to save totp secret: %w", err)
+	}
+	return requireAffected(res)
+}
+
+// EnableTOTP подтверждает регистрацию TOTP, запоминая шаг кода подтверждения
+func (r *PostgresRepository) EnableTOTP(ctx context.Context, userID int, step int64) error {
+	query := `UPDATE user_mfa SET enabled_at = NOW(), last_used_step = $2
+              WHERE user_id = $1 AND enabled_at IS NULL`
+	res, err := r.db.ExecContext(ctx, query, userID, step)
+	if err != nil {
+		return fmt.Errorf("failed to enable totp: %w", err)
+	}
+	return requireAffected(res)
+}
+
+// UseTOTPStep атомарно отмечает использование кода шага step.
+// Возвращает sql.ErrNoRows, если код этого или более позднего шага уже использовался.
+func (r *PostgresRepository) UseTOTPStep(ctx context.Context, userID int, step int64) error {
+	query := `UPDATE user_mfa SET last_used_step = $2 WHERE user_id = $1 AND last_used_step < $2`
+	res, err := r.db.ExecContext(ctx, query, userID, step)
+	if err != nil {
+		return fmt.Errorf("failed to use totp step: %w", err)
+	}
+	return requireAffected(res)
+}
+
+// DeleteUserMFA отключает второй фактор
+func (r *PostgresRepository) DeleteUserMFA(ctx context.Context, userID int) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM user_mfa WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete user mfa: %w", err)
+	}
+	return nil
+}
+
+// requireAffected возвращает sql.ErrNoRows, если запрос не изменил ни одной строки
+func requireAffected(res sql.Result) error {
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Alias1177/Auth/internal/domain"
+	"github.com/lib/pq"
+)
+
+// oauthClientColumns колонки oauth_clients в порядке полей oauthClientRow
+const oauthClientColumns = `id, name, redirect_uris, scopes, created_at,
+    secret_hash, previous_secret_hash, previous_secret_expires_at, secret_rotated_at`
+
+// oauthClientRow строка oauth_clients; массивы читаются через pq.StringArray, у публичных клиентов секрета нет
+type oauthClientRow struct {
+	ID                      string         `db:"id"`
+	Name                    string         `db:"name"`
+	RedirectURIs            pq.StringArray `db:"redirect_uris"`
+	Scopes                  pq.StringArray `db:"scopes"`
+	CreatedAt               time.Time      `db:"created_at"`
+	SecretHash              sql.NullString `db:"secret_hash"`
+	PreviousSecretHash      sql.NullString `db:"previous_secret_hash"`
+	PreviousSecretExpiresAt *time.Time     `db:"previous_secret_expires_at"`
+	SecretRotatedAt         *time.Time     `db:"secret_rotated_at"`
+}
+
+func (row oauthClientRow) toDomain() domain.OAuthClient {
+	return domain.OAuthClient{
+		ID:                      row.ID,
+		Name:                    row.Name,
+		RedirectURIs:            []string(row.RedirectURIs),
+		Scopes:                  []string(row.Scopes),
+		CreatedAt:               row.CreatedAt,
+		SecretHash:              row.SecretHash.String,
+		PreviousSecretHash:      row.PreviousSecretHash.String,
+		PreviousSecretExpiresAt: row.PreviousSecretExpiresAt,
+		SecretRotatedAt:         row.SecretRotatedAt,
+	}
+}
+
+// GetOAuthClient возвращает клиента OpenID Connect. Возвращает sql.ErrNoRows, если клиента нет.
+func (r *PostgresRepository) GetOAuthClient(ctx context.Context, clientID string) (*domain.OAuthClient, error) {
+	var row oauthClientRow
+	query := `SELECT ` + oauthClientColumns + ` FROM oauth_clients WHERE id = $1`
+	if err := r.db.GetContext(ctx, &row, query, clientID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		r.log.Errorw("Failed to get oauth client", "client_id", clientID, "err", err)
+		return nil, fmt.Errorf("failed to get oauth client: %w", err)
+	}
+	client := row.toDomain()
+	return &client, nil
+}
+
+// ListOAuthClients возвращает всех зарегистрированных клиентов
+func (r *PostgresRepository) ListOAuthClients(ctx context.Context) ([]domain.OAuthClient, error) {
+	var rows []oauthClientRow
+	query := `SELECT ` + oauthClientColumns + ` FROM oauth_clients ORDER BY created_at`
+	if err := r.db.SelectContext(ctx, &rows, query); err != nil {
+		r.log.Errorw("Failed to list oauth clients", "err", err)
+		return nil, fmt.Errorf("failed to list oauth clients: %w", err)
+	}
+
+	clients := make([]domain.OAuthClient, 0, len(rows))
+	for _, row := range rows {
+		clients = append(clients, row.toDomain())
+	}
+	return clients, nil
+}
+
+// CreateOAuthClient регистрирует клиента
+func (r *PostgresRepository) CreateOAuthClient(ctx context.Context, client *domain.OAuthClient) error {
+	query := `INSERT INTO oauth_clients (id, name, redirect_uris, scopes, secret_hash)
+              VALUES ($1, $2, $3, $4, NULLIF($5, '')) RETURNING created_at`
+	err := r.db.QueryRowxContext(ctx, query,
+		client.ID, client.Name, pq.Array(client.RedirectURIs), pq.Array(client.Scopes), client.SecretHash,
+	).Scan(&client.CreatedAt)
+	if err != nil {
+		r.log.Errorw("Failed to create oauth client", "client_id", client.ID, "err", err)
+		return fmt.Errorf("failed to create oauth client: %w", err)
+	}
+	return nil
+}
+
+// RotateOAuthClientSecret заменяет секрет клиента; прежний секрет принимается до previousExpiresAt.
+// Возвращает sql.ErrNoRows, если клиента нет.
+func (r *PostgresRepository) RotateOAuthClientSecret(
+	ctx context.Context,
+	clientID, secretHash, previousHash string,
+	previousExpiresAt *time.Time,
+) error {
+	query := `UPDATE oauth_clients
+              SET secret_hash = $2, previous_secret_hash = NULLIF($3, ''),
+                  previous_secret_expires_at = $4, secret_rotated_at = NOW()
+              WHERE id = $1`
+	result, err := r.db.ExecContext(ctx, query, clientID, secretHash, previousHash, previousExpiresAt)
+	if err != nil {
+		r.log.Errorw("Failed to rotate oauth client secret", "client_id", clientID, "err", err)
+		return fmt.Errorf("failed to rotate oauth client secret: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// DeleteOAuthClient удаляет клиента. Возвращает sql.ErrNoRows, если клиента нет.
+func (r *PostgresRepository) DeleteOAuthClient(ctx context.Context, clientID string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM oauth_clients WHERE id = $1`, clientID)
+	if err != nil {
+		r.log.Errorw("Failed to delete oauth client", "client_id", clientID, "err", err)
+		return fmt.Errorf("failed to delete oauth client: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+)
+
+// GetPasswordHistory возвращает хеши последних limit паролей пользователя, начиная с самого нового
+func (r *PostgresRepository) GetPasswordHistory(ctx context.Context, userID, limit int) ([]string, error) {
+	var hashes []string
+	query := `SELECT password_hash FROM password_history WHERE user_id = $1 ORDER BY id DESC LIMIT $2`
+	if err := r.db.SelectContext(ctx, &hashes, query, userID, limit); err != nil {
+		r.log.Errorw("Failed to get password history", "user_id", userID, "err", err)
+		return nil, fmt.Errorf("failed to get password history: %w", err)
+	}
+	return hashes, nil
+}
+
+// AddPasswordHistory добавляет хеш в историю и оставляет только последние keep записей
+func (r *PostgresRepository) AddPasswordHistory(ctx context.Context, userID int, hash string, keep int) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO password_history (user_id, password_hash) VALUES ($1, $2)`
+	if _, err := tx.ExecContext(ctx, query, userID, hash); err != nil {
+		return fmt.Errorf("failed to insert password history: %w", err)
+	}
+
+	query = `DELETE FROM password_history
+             WHERE user_id = $1 AND id NOT IN (
+                 SELECT id FROM password_history WHERE user_id = $1 ORDER BY id DESC LIMIT $2
+             )`
+	if _, err := tx.ExecContext(ctx, query, userID, keep); err != nil {
+		return fmt.Errorf("failed to prune password history: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.log.Errorw("Failed to add password history", "user_id", userID, "err", err)
+		return fmt.Errorf("failed to commit password history: %w", err)
+	}
+	return nil
+}
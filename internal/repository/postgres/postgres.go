@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/Alias1177/Auth/internal/domain"
 	"github.com/Alias1177/Auth/internal/repository/redis"
@@ -31,7 +32,9 @@ func NewPostgresRepository(db *sqlx.DB, redisRepo *redis.RedisRepository, log *l
 // GetUserByID получает пользователя из базы данных по ID.
 func (r *PostgresRepository) GetUserByID(ctx context.Context, id int) (*domain.User, error) {
 	var user domain.User
-	query := `SELECT id, username, email, password FROM UsersLog WHERE id = $1`
+	query := `SELECT id, username, email, password, created_at, updated_at, email_verified_at,
+              failed_login_attempts, locked_until, disabled_at
+              FROM UsersLog WHERE id = $1`
 	err := r.db.GetContext(ctx, &user, query, id)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -46,11 +49,11 @@ func (r *PostgresRepository) GetUserByID(ctx context.Context, id int) (*domain.U
 
 // GetUserByEmail получает пользователя из базы данных по email.
 func (r *PostgresRepository) GetUserByEmail(ctx context.Context, email string) (*domain.User, error) {
-	query := "SELECT id, username, email, password FROM UsersLog WHERE email = $1"
+	query := "SELECT id, username, email, password, email_verified_at, failed_login_attempts, locked_until, disabled_at FROM UsersLog WHERE email = $1"
 	user := domain.User{}
 
 	err := r.db.QueryRowContext(ctx, query, email).
-		Scan(&user.ID, &user.UserName, &user.Email, &user.Password)
+		Scan(&user.ID, &user.UserName, &user.Email, &user.Password, &user.EmailVerifiedAt, &user.FailedLoginAttempts, &user.LockedUntil, &user.DisabledAt)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -66,20 +69,21 @@ func (r *PostgresRepository) GetUserByEmail(ctx context.Context, email string) (
 
 // CreateUser создает нового пользователя в базе данных.
 func (r *PostgresRepository) CreateUser(ctx context.Context, user *domain.User) error {
-	query := `INSERT INTO UsersLog (username, email, password) 
-             VALUES ($1, $2, $3) 
+	query := `INSERT INTO UsersLog (username, email, password, email_verified_at) 
+             VALUES ($1, $2, $3, $4) 
              RETURNING id`
-	return r.db.QueryRowxContext(ctx, query, user.UserName, user.Email, user.Password).Scan(&user.ID)
+	return r.db.QueryRowxContext(ctx, query, user.UserName, user.Email, user.Password, user.EmailVerifiedAt).Scan(&user.ID)
 }
 
 // UpdateUser обновляет данные существующего пользователя в базе данных.
 
 func (r *PostgresRepository) UpdateUser(ctx context.Context, user *domain.User) error {
+	// Адрес почты здесь не меняется: смена адреса идет только через подтверждение (ApplyEmailChange)
 	query := `UPDATE UsersLog 
-              SET username = $1, email = $2, password = $3, updated_at = NOW()
-              WHERE id = $4
+              SET username = $1, password = $2, updated_at = NOW()
+              WHERE id = $3
               RETURNING updated_at`
-	err := r.db.QueryRowxContext(ctx, query, user.UserName, user.Email, user.Password, user.ID).Scan(&user.UpdatedAt)
+	err := r.db.QueryRowxContext(ctx, query, user.UserName, user.Password, user.ID).Scan(&user.UpdatedAt)
 	if err != nil {
 		r.log.Errorw("Update err", "err", err)
 		return fmt.Errorf("failed to update user: %w", err)
@@ -96,6 +100,40 @@ func (r *PostgresRepository) UpdateUser(ctx context.Context, user *domain.User)
 	return nil
 }
 
+// UpdateUserProfile обновляет данные профиля при условии, что updated_at не изменился (оптимистичная блокировка).
+// Возвращает sql.ErrNoRows, если запись изменена другим запросом или не найдена.
+func (r *PostgresRepository) UpdateUserProfile(ctx context.Context, user *domain.User, expectedUpdatedAt time.Time) error {
+	query := `UPDATE UsersLog
+              SET username = $1, updated_at = NOW()
+              WHERE id = $2 AND updated_at = $3
+              RETURNING updated_at`
+	err := r.db.QueryRowxContext(ctx, query, user.UserName, user.ID, expectedUpdatedAt).Scan(&user.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return sql.ErrNoRows
+		}
+		r.log.Errorw("Failed to update user profile", "user_id", user.ID, "err", err)
+		return fmt.Errorf("failed to update user profile: %w", err)
+	}
+
+	if r.redisRepo != nil {
+		if err := r.redisRepo.SetUser(ctx, user); err != nil {
+			r.log.Errorw("redis set err", "user_id", user.ID, "err", err)
+		}
+	}
+	return nil
+}
+
+// UpdatePasswordHash заменяет хеш пароля, не меняя updated_at: пароль пользователя остается прежним
+func (r *PostgresRepository) UpdatePasswordHash(ctx context.Context, userID int, hash string) error {
+	query := `UPDATE UsersLog SET password = $1 WHERE id = $2`
+	if _, err := r.db.ExecContext(ctx, query, hash, userID); err != nil {
+		r.log.Errorw("Failed to update password hash", "user_id", userID, "err", err)
+		return fmt.Errorf("failed to update password hash: %w", err)
+	}
+	return nil
+}
+
 func (r *PostgresRepository) ResetPassword(ctx context.Context, user *domain.User) error {
 	query := `UPDATE UsersLog 
 		SET password = $1
@@ -0,0 +1,53 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+)
+
+// ReplaceRecoveryCodes заменяет набор кодов восстановления пользователя новым
+func (r *PostgresRepository) ReplaceRecoveryCodes(ctx context.Context, userID int, codeHashes []string) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+	for _, hash := range codeHashes {
+		query := `INSERT INTO user_recovery_codes (user_id, code_hash) VALUES ($1, $2)`
+		if _, err := tx.ExecContext(ctx, query, userID, hash); err != nil {
+			return fmt.Errorf("failed to insert recovery code: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.log.Errorw("Failed to replace recovery codes", "user_id", userID, "err", err)
+		return fmt.Errorf("failed to commit recovery codes: %w", err)
+	}
+	return nil
+}
+
+// CountUnusedRecoveryCodes возвращает число неиспользованных кодов восстановления
+func (r *PostgresRepository) CountUnusedRecoveryCodes(ctx context.Context, userID int) (int, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM user_recovery_codes WHERE user_id = $1 AND used_at IS NULL`
+	if err := r.db.GetContext(ctx, &count, query, userID); err != nil {
+		return 0, fmt.Errorf("failed to count recovery codes: %w", err)
+	}
+	return count, nil
+}
+
+// UseRecoveryCode атомарно помечает код использованным.
+// Возвращает sql.ErrNoRows, если код не найден или уже использован.
+func (r *PostgresRepository) UseRecoveryCode(ctx context.Context, userID int, codeHash string) error {
+	query := `UPDATE user_recovery_codes SET used_at = NOW()
+              WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`
+	res, err := r.db.ExecContext(ctx, query, userID, codeHash)
+	if err != nil {
+		return fmt.Errorf("failed to use recovery code: %w", err)
+	}
+	return requireAffected(res)
+}
@@ -0,0 +1,86 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/Alias1177/Auth/internal/domain"
+)
+
+const refreshTokenColumns = `id, family_id, user_id, device, client_id, scope, issued_at, expires_at, used_at, revoked_at`
+
+// CreateRefreshToken сохраняет выданный refresh токен
+func (r *PostgresRepository) CreateRefreshToken(ctx context.Context, token *domain.RefreshToken) error {
+	query := `INSERT INTO refresh_tokens (id, family_id, user_id, device, client_id, scope, expires_at)
+              VALUES ($1, $2, $3, $4, $5, $6, $7)
+              RETURNING issued_at`
+	err := r.db.QueryRowxContext(ctx, query,
+		token.ID, token.FamilyID, token.UserID, token.Device, token.ClientID, token.Scope, token.ExpiresAt,
+	).Scan(&token.IssuedAt)
+	if err != nil {
+		r.log.Errorw("Failed to create refresh token", "family_id", token.FamilyID, "err", err)
+		return fmt.Errorf("failed to create refresh token: %w", err)
+	}
+	return nil
+}
+
+// GetRefreshToken получает запись refresh токена по jti
+func (r *PostgresRepository) GetRefreshToken(ctx context.Context, id string) (*domain.RefreshToken, error) {
+	var token domain.RefreshToken
+	query := `SELECT ` + refreshTokenColumns + ` FROM refresh_tokens WHERE id = $1`
+	if err := r.db.GetContext(ctx, &token, query, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to get refresh token: %w", err)
+	}
+	return &token, nil
+}
+
+// UseRefreshToken атомарно помечает использованным действующий токен, выданный приложению clientID
+// (пусто - входу в сам сервис). Возвращает sql.ErrNoRows, если токен не найден, уже использован,
+// отозван, истек или выдан другому приложению.
+func (r *PostgresRepository) UseRefreshToken(ctx context.Context, id, clientID string) (*domain.RefreshToken, error) {
+	var token domain.RefreshToken
+	query := `UPDATE refresh_tokens
+              SET used_at = NOW()
+              WHERE id = $1 AND client_id = $2 AND used_at IS NULL AND revoked_at IS NULL AND expires_at > NOW()
+              RETURNING ` + refreshTokenColumns
+	if err := r.db.GetContext(ctx, &token, query, id, clientID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to use refresh token: %w", err)
+	}
+	return &token, nil
+}
+
+// RevokeRefreshTokenFamily отзывает все токены семейства
+func (r *PostgresRepository) RevokeRefreshTokenFamily(ctx context.Context, familyID string) error {
+	query := `UPDATE refresh_tokens SET revoked_at = NOW() WHERE family_id = $1 AND revoked_at IS NULL`
+	if _, err := r.db.ExecContext(ctx, query, familyID); err != nil {
+		return fmt.Errorf("failed to revoke refresh token family: %w", err)
+	}
+	return nil
+}
+
+// RevokeUserRefreshTokens отзывает все refresh токены пользователя, кроме семейства exceptFamilyID (если задано)
+func (r *PostgresRepository) RevokeUserRefreshTokens(ctx context.Context, userID int, exceptFamilyID string) error {
+	query := `UPDATE refresh_tokens SET revoked_at = NOW()
+              WHERE user_id = $1 AND revoked_at IS NULL AND ($2 = '' OR family_id::text <> $2)`
+	if _, err := r.db.ExecContext(ctx, query, userID, exceptFamilyID); err != nil {
+		return fmt.Errorf("failed to revoke user refresh tokens: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpiredRefreshTokens удаляет истекшие записи
+func (r *PostgresRepository) DeleteExpiredRefreshTokens(ctx context.Context) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM refresh_tokens WHERE expires_at < NOW()`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired refresh tokens: %w", err)
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,209 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/Alias1177/Auth/internal/domain"
+	apperrors "github.com/Alias1177/Auth/internal/errors"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// ListRoles возвращает все роли вместе с именами их разрешений
+func (r *PostgresRepository) ListRoles(ctx context.Context) ([]domain.Role, error) {
+	var roles []domain.Role
+	query := `SELECT id, name, description, created_at FROM roles ORDER BY name`
+	if err := r.db.SelectContext(ctx, &roles, query); err != nil {
+		r.log.Errorw("Failed to list roles", "err", err)
+		return nil, fmt.Errorf("failed to list roles: %w", err)
+	}
+
+	var grants []struct {
+		RoleID     int    `db:"role_id"`
+		Permission string `db:"name"`
+	}
+	query = `SELECT rp.role_id, p.name FROM role_permissions rp
+             JOIN permissions p ON p.id = rp.permission_id
+             ORDER BY p.name`
+	if err := r.db.SelectContext(ctx, &grants, query); err != nil {
+		r.log.Errorw("Failed to list role permissions", "err", err)
+		return nil, fmt.Errorf("failed to list role permissions: %w", err)
+	}
+
+	index := make(map[int]int, len(roles))
+	for i := range roles {
+		roles[i].Permissions = []string{}
+		index[roles[i].ID] = i
+	}
+	for _, grant := range grants {
+		if i, ok := index[grant.RoleID]; ok {
+			roles[i].Permissions = append(roles[i].Permissions, grant.Permission)
+		}
+	}
+	return roles, nil
+}
+
+// GetRoleByName возвращает роль с ее разрешениями. Возвращает sql.ErrNoRows, если роли нет.
+func (r *PostgresRepository) GetRoleByName(ctx context.Context, name string) (*domain.Role, error) {
+	var role domain.Role
+	query := `SELECT id, name, description, created_at FROM roles WHERE name = $1`
+	if err := r.db.GetContext(ctx, &role, query, name); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		r.log.Errorw("Failed to get role", "name", name, "err", err)
+		return nil, fmt.Errorf("failed to get role: %w", err)
+	}
+
+	role.Permissions = []string{}
+	query = `SELECT p.name FROM role_permissions rp
+             JOIN permissions p ON p.id = rp.permission_id
+             WHERE rp.role_id = $1 ORDER BY p.name`
+	if err := r.db.SelectContext(ctx, &role.Permissions, query, role.ID); err != nil {
+		r.log.Errorw("Failed to get role permissions", "name", name, "err", err)
+		return nil, fmt.Errorf("failed to get role permissions: %w", err)
+	}
+	return &role, nil
+}
+
+// CreateRole создает роль с указанными разрешениями. Возвращает ErrRoleExists, если имя занято.
+func (r *PostgresRepository) CreateRole(ctx context.Context, role *domain.Role) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO roles (name, description) VALUES ($1, $2) RETURNING id, created_at`
+	if err := tx.QueryRowxContext(ctx, query, role.Name, role.Description).Scan(&role.ID, &role.CreatedAt); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			return apperrors.ErrRoleExists
+		}
+		r.log.Errorw("Failed to create role", "name", role.Name, "err", err)
+		return fmt.Errorf("failed to create role: %w", err)
+	}
+
+	if err := setRolePermissions(ctx, tx, role.ID, role.Permissions); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.log.Errorw("Failed to commit role", "name", role.Name, "err", err)
+		return fmt.Errorf("failed to commit role: %w", err)
+	}
+	return nil
+}
+
+// SetRolePermissions заменяет набор разрешений роли
+func (r *PostgresRepository) SetRolePermissions(ctx context.Context, roleID int, permissions []string) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM role_permissions WHERE role_id = $1`, roleID); err != nil {
+		return fmt.Errorf("failed to clear role permissions: %w", err)
+	}
+	if err := setRolePermissions(ctx, tx, roleID, permissions); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.log.Errorw("Failed to set role permissions", "role_id", roleID, "err", err)
+		return fmt.Errorf("failed to commit role permissions: %w", err)
+	}
+	return nil
+}
+
+// setRolePermissions выдает роли разрешения по именам; неизвестные имена пропускаются
+func setRolePermissions(ctx context.Context, tx *sqlx.Tx, roleID int, permissions []string) error {
+	if len(permissions) == 0 {
+		return nil
+	}
+	query := `INSERT INTO role_permissions (role_id, permission_id)
+              SELECT $1, id FROM permissions WHERE name = ANY($2)
+              ON CONFLICT DO NOTHING`
+	if _, err := tx.ExecContext(ctx, query, roleID, pq.Array(permissions)); err != nil {
+		return fmt.Errorf("failed to grant role permissions: %w", err)
+	}
+	return nil
+}
+
+// DeleteRole удаляет роль; назначения пользователям удаляются каскадно
+func (r *PostgresRepository) DeleteRole(ctx context.Context, roleID int) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM roles WHERE id = $1`, roleID)
+	if err != nil {
+		r.log.Errorw("Failed to delete role", "role_id", roleID, "err", err)
+		return fmt.Errorf("failed to delete role: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// ListPermissions возвращает все известные разрешения
+func (r *PostgresRepository) ListPermissions(ctx context.Context) ([]domain.Permission, error) {
+	var permissions []domain.Permission
+	query := `SELECT id, name, description FROM permissions ORDER BY name`
+	if err := r.db.SelectContext(ctx, &permissions, query); err != nil {
+		r.log.Errorw("Failed to list permissions", "err", err)
+		return nil, fmt.Errorf("failed to list permissions: %w", err)
+	}
+	return permissions, nil
+}
+
+// GetUserRoles возвращает имена ролей пользователя
+func (r *PostgresRepository) GetUserRoles(ctx context.Context, userID int) ([]string, error) {
+	roles := []string{}
+	query := `SELECT ro.name FROM user_roles ur
+              JOIN roles ro ON ro.id = ur.role_id
+              WHERE ur.user_id = $1 ORDER BY ro.name`
+	if err := r.db.SelectContext(ctx, &roles, query, userID); err != nil {
+		r.log.Errorw("Failed to get user roles", "user_id", userID, "err", err)
+		return nil, fmt.Errorf("failed to get user roles: %w", err)
+	}
+	return roles, nil
+}
+
+// GetUserPermissions возвращает имена разрешений всех ролей пользователя без повторов
+func (r *PostgresRepository) GetUserPermissions(ctx context.Context, userID int) ([]string, error) {
+	permissions := []string{}
+	query := `SELECT DISTINCT p.name FROM user_roles ur
+              JOIN role_permissions rp ON rp.role_id = ur.role_id
+              JOIN permissions p ON p.id = rp.permission_id
+              WHERE ur.user_id = $1 ORDER BY p.name`
+	if err := r.db.SelectContext(ctx, &permissions, query, userID); err != nil {
+		r.log.Errorw("Failed to get user permissions", "user_id", userID, "err", err)
+		return nil, fmt.Errorf("failed to get user permissions: %w", err)
+	}
+	return permissions, nil
+}
+
+// AssignRole назначает роль пользователю; повторное назначение ничего не меняет
+func (r *PostgresRepository) AssignRole(ctx context.Context, userID, roleID int) error {
+	query := `INSERT INTO user_roles (user_id, role_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`
+	if _, err := r.db.ExecContext(ctx, query, userID, roleID); err != nil {
+		r.log.Errorw("Failed to assign role", "user_id", userID, "role_id", roleID, "err", err)
+		return fmt.Errorf("failed to assign role: %w", err)
+	}
+	return nil
+}
+
+// RemoveRole снимает роль с пользователя. Возвращает sql.ErrNoRows, если роль не была назначена.
+func (r *PostgresRepository) RemoveRole(ctx context.Context, userID, roleID int) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM user_roles WHERE user_id = $1 AND role_id = $2`, userID, roleID)
+	if err != nil {
+		r.log.Errorw("Failed to remove role", "user_id", userID, "role_id", roleID, "err", err)
+		return fmt.Errorf("failed to remove role: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Alias1177/Auth/internal/domain"
+)
+
+// CreateSession сохраняет новую сессию
+func (r *PostgresRepository) CreateSession(ctx context.Context, session *domain.Session) error {
+	query := `INSERT INTO user_sessions (id, user_id, device_name, user_agent, ip_address, login_method)
+              VALUES ($1, $2, $3, $4, $5, $6)
+              RETURNING created_at, last_used_at`
+	err := r.db.QueryRowxContext(ctx, query,
+		session.ID, session.UserID, session.DeviceName, session.UserAgent, session.IPAddress, session.LoginMethod,
+	).Scan(&session.CreatedAt, &session.LastUsedAt)
+	if err != nil {
+		r.log.Errorw("Failed to create session", "user_id", session.UserID, "err", err)
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+	return nil
+}
+
+// ListActiveSessions возвращает сессии пользователя, у которых есть действующий refresh токен
+func (r *PostgresRepository) ListActiveSessions(ctx context.Context, userID int) ([]domain.Session, error) {
+	sessions := []domain.Session{}
+	query := `SELECT s.id, s.user_id, s.device_name, s.user_agent, s.ip_address, s.login_method,
+                     s.created_at, s.last_used_at, s.revoked_at
+              FROM user_sessions s
+              WHERE s.user_id = $1 AND s.revoked_at IS NULL
+                AND EXISTS (
+                    SELECT 1 FROM refresh_tokens t
+                    WHERE t.family_id = s.id AND t.revoked_at IS NULL AND t.expires_at > NOW()
+                )
+              ORDER BY s.last_used_at DESC`
+	if err := r.db.SelectContext(ctx, &sessions, query, userID); err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	return sessions, nil
+}
+
+// TouchSession обновляет время последнего использования сессии
+func (r *PostgresRepository) TouchSession(ctx context.Context, sessionID string) error {
+	query := `UPDATE user_sessions SET last_used_at = NOW() WHERE id = $1`
+	if _, err := r.db.ExecContext(ctx, query, sessionID); err != nil {
+		return fmt.Errorf("failed to touch session: %w", err)
+	}
+	return nil
+}
+
+// RevokeSession помечает сессию пользователя отозванной.
+// Возвращает sql.ErrNoRows, если сессия не найдена или принадлежит другому пользователю.
+func (r *PostgresRepository) RevokeSession(ctx context.Context, userID int, sessionID string) error {
+	query := `UPDATE user_sessions SET revoked_at = NOW()
+              WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL`
+	res, err := r.db.ExecContext(ctx, query, sessionID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	return requireAffected(res)
+}
+
+// RevokeUserSessions отзывает все сессии пользователя, кроме exceptID (если он задан),
+// и возвращает идентификаторы отозванных сессий
+func (r *PostgresRepository) RevokeUserSessions(ctx context.Context, userID int, exceptID string) ([]string, error) {
+	ids := []string{}
+	query := `UPDATE user_sessions SET revoked_at = NOW()
+              WHERE user_id = $1 AND revoked_at IS NULL AND ($2 = '' OR id::text <> $2)
+              RETURNING id`
+	if err := r.db.SelectContext(ctx, &ids, query, userID, exceptID); err != nil {
+		return nil, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	return ids, nil
+}
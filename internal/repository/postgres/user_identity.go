@@ -0,0 +1,86 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/Alias1177/Auth/internal/domain"
+	apperrors "github.com/Alias1177/Auth/internal/errors"
+	"github.com/lib/pq"
+)
+
+const userIdentityColumns = `id, user_id, provider, provider_user_id, email, email_verified, created_at, last_used_at`
+
+// GetUserIdentity находит привязку по идентификатору пользователя у провайдера; sql.ErrNoRows, если ее нет
+func (r *PostgresRepository) GetUserIdentity(ctx context.Context, provider, providerUserID string) (*domain.UserIdentity, error) {
+	var identity domain.UserIdentity
+	query := `SELECT ` + userIdentityColumns + ` FROM user_identities WHERE provider = $1 AND provider_user_id = $2`
+	if err := r.db.GetContext(ctx, &identity, query, provider, providerUserID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, sql.ErrNoRows
+		}
+		r.log.Errorw("Failed to get user identity", "provider", provider, "err", err)
+		return nil, fmt.Errorf("failed to get user identity: %w", err)
+	}
+	return &identity, nil
+}
+
+// ListUserIdentities возвращает внешние учетные записи пользователя
+func (r *PostgresRepository) ListUserIdentities(ctx context.Context, userID int) ([]domain.UserIdentity, error) {
+	identities := []domain.UserIdentity{}
+	query := `SELECT ` + userIdentityColumns + ` FROM user_identities WHERE user_id = $1 ORDER BY created_at`
+	if err := r.db.SelectContext(ctx, &identities, query, userID); err != nil {
+		return nil, fmt.Errorf("failed to list user identities: %w", err)
+	}
+	return identities, nil
+}
+
+// CreateUserIdentity привязывает внешнюю учетную запись к пользователю.
+// Возвращает ErrIdentityLinked, если учетная запись уже привязана или у пользователя уже есть запись этого провайдера.
+func (r *PostgresRepository) CreateUserIdentity(ctx context.Context, identity *domain.UserIdentity) error {
+	query := `INSERT INTO user_identities (user_id, provider, provider_user_id, email, email_verified, last_used_at)
+              VALUES ($1, $2, $3, $4, $5, NOW())
+              RETURNING id, created_at, last_used_at`
+	err := r.db.QueryRowxContext(ctx, query,
+		identity.UserID, identity.Provider, identity.ProviderUserID, identity.Email, identity.EmailVerified,
+	).Scan(&identity.ID, &identity.CreatedAt, &identity.LastUsedAt)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			return apperrors.ErrIdentityLinked
+		}
+		r.log.Errorw("Failed to create user identity", "user_id", identity.UserID, "provider", identity.Provider, "err", err)
+		return fmt.Errorf("failed to create user identity: %w", err)
+	}
+	return nil
+}
+
+// UpdateUserIdentityLogin сохраняет адрес, сообщенный провайдером при входе, и время входа
+func (r *PostgresRepository) UpdateUserIdentityLogin(ctx context.Context, id int, email string, emailVerified bool) error {
+	query := `UPDATE user_identities SET email = $2, email_verified = $3, last_used_at = NOW() WHERE id = $1`
+	if _, err := r.db.ExecContext(ctx, query, id, email, emailVerified); err != nil {
+		r.log.Errorw("Failed to update user identity", "id", id, "err", err)
+		return fmt.Errorf("failed to update user identity: %w", err)
+	}
+	return nil
+}
+
+// DeleteUserIdentity отвязывает учетную запись провайдера от пользователя; sql.ErrNoRows, если привязки нет
+func (r *PostgresRepository) DeleteUserIdentity(ctx context.Context, userID int, provider string) error {
+	query := `DELETE FROM user_identities WHERE user_id = $1 AND provider = $2`
+	result, err := r.db.ExecContext(ctx, query, userID, provider)
+	if err != nil {
+		r.log.Errorw("Failed to delete user identity", "user_id", userID, "provider", provider, "err", err)
+		return fmt.Errorf("failed to delete user identity: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to delete user identity: %w", err)
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
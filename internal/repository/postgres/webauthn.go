@@ -0,0 +1,73 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/Alias1177/Auth/internal/domain"
+)
+
+const webAuthnCredentialColumns = `id, user_id, name, public_key, attestation_type, transports, aaguid,
+       sign_count, backup_eligible, backup_state, created_at, last_used_at`
+
+// CreateWebAuthnCredential сохраняет зарегистрированный ключ WebAuthn
+func (r *PostgresRepository) CreateWebAuthnCredential(ctx context.Context, cred *domain.WebAuthnCredential) error {
+	query := `INSERT INTO webauthn_credentials
+                  (id, user_id, name, public_key, attestation_type, transports, aaguid, sign_count, backup_eligible, backup_state)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+              RETURNING created_at`
+	err := r.db.QueryRowxContext(ctx, query,
+		cred.ID, cred.UserID, cred.Name, cred.PublicKey, cred.AttestationType, cred.Transports,
+		cred.AAGUID, cred.SignCount, cred.BackupEligible, cred.BackupState,
+	).Scan(&cred.CreatedAt)
+	if err != nil {
+		r.log.Errorw("Failed to create webauthn credential", "user_id", cred.UserID, "err", err)
+		return fmt.Errorf("failed to create webauthn credential: %w", err)
+	}
+	return nil
+}
+
+// ListWebAuthnCredentials возвращает ключи WebAuthn пользователя
+func (r *PostgresRepository) ListWebAuthnCredentials(ctx context.Context, userID int) ([]domain.WebAuthnCredential, error) {
+	creds := []domain.WebAuthnCredential{}
+	query := `SELECT ` + webAuthnCredentialColumns + ` FROM webauthn_credentials WHERE user_id = $1 ORDER BY created_at`
+	if err := r.db.SelectContext(ctx, &creds, query, userID); err != nil {
+		return nil, fmt.Errorf("failed to list webauthn credentials: %w", err)
+	}
+	return creds, nil
+}
+
+// GetWebAuthnCredential получает ключ по идентификатору; sql.ErrNoRows, если ключ не найден
+func (r *PostgresRepository) GetWebAuthnCredential(ctx context.Context, id []byte) (*domain.WebAuthnCredential, error) {
+	var cred domain.WebAuthnCredential
+	query := `SELECT ` + webAuthnCredentialColumns + ` FROM webauthn_credentials WHERE id = $1`
+	if err := r.db.GetContext(ctx, &cred, query, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to get webauthn credential: %w", err)
+	}
+	return &cred, nil
+}
+
+// UpdateWebAuthnCredentialUsage сохраняет счетчик подписей и время последнего входа ключом
+func (r *PostgresRepository) UpdateWebAuthnCredentialUsage(ctx context.Context, id []byte, signCount int64, backupState bool) error {
+	query := `UPDATE webauthn_credentials
+              SET sign_count = $2, backup_state = $3, last_used_at = NOW()
+              WHERE id = $1`
+	if _, err := r.db.ExecContext(ctx, query, id, signCount, backupState); err != nil {
+		return fmt.Errorf("failed to update webauthn credential: %w", err)
+	}
+	return nil
+}
+
+// DeleteWebAuthnCredential удаляет ключ пользователя; sql.ErrNoRows, если ключ не найден
+func (r *PostgresRepository) DeleteWebAuthnCredential(ctx context.Context, userID int, id []byte) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM webauthn_credentials WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete webauthn credential: %w", err)
+	}
+	return requireAffected(res)
+}
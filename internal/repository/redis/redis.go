@@ -9,6 +9,7 @@ import (
 
 	"github.com/Alias1177/Auth/internal/domain"
 	"github.com/Alias1177/Auth/pkg/logger"
+	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -61,6 +62,11 @@ func (r *RedisRepository) SaveUser(ctx context.Context, user *domain.User) error
 	return r.client.Set(ctx, key, jsonData, 0).Err()
 }
 
+// DeleteUser удаляет данные пользователя из Redis.
+func (r *RedisRepository) DeleteUser(ctx context.Context, id int) error {
+	return r.client.Del(ctx, fmt.Sprintf("user:%d", id)).Err()
+}
+
 // SetUser сохраняет данные пользователя в Redis (альтернативный метод).
 func (r *RedisRepository) SetUser(ctx context.Context, user *domain.User) error {
 	key := fmt.Sprintf("user:%d", user.ID)
@@ -120,3 +126,49 @@ func (r *RedisRepository) SetWithTTL(ctx context.Context, key string, value stri
 func (r *RedisRepository) Delete(ctx context.Context, key string) error {
 	return r.client.Del(ctx, key).Err()
 }
+
+// incrementScript увеличивает счетчик и назначает TTL одной операцией.
+// TTL ставится и ключу без срока жизни: такой счетчик иначе блокировал бы действие навсегда.
+var incrementScript = redis.NewScript(`
+local count = redis.call('INCR', KEYS[1])
+if count == 1 or redis.call('PTTL', KEYS[1]) < 0 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+return count
+`)
+
+// Increment атомарно увеличивает счетчик; при создании ключа ему назначается TTL
+func (r *RedisRepository) Increment(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	return incrementScript.Run(ctx, r.client, []string{key}, ttl.Milliseconds()).Int64()
+}
+
+// slidingWindowScript учитывает запрос в окне, если в нем меньше limit запросов.
+// Возвращает {1, 0} при успехе или {0, мс до освобождения места}.
+var slidingWindowScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now - window)
+if redis.call('ZCARD', KEYS[1]) < limit then
+	redis.call('ZADD', KEYS[1], now, ARGV[4])
+	redis.call('PEXPIRE', KEYS[1], window)
+	return {1, 0}
+end
+local oldest = redis.call('ZRANGE', KEYS[1], 0, 0, 'WITHSCORES')
+return {0, tonumber(oldest[2]) + window - now}
+`)
+
+// Allow атомарно учитывает запрос в скользящем окне (sorted set с временем запросов)
+func (r *RedisRepository) Allow(ctx context.Context, key string, limit int, window time.Duration) (bool, time.Duration, error) {
+	now := time.Now()
+	member := fmt.Sprintf("%d-%s", now.UnixNano(), uuid.NewString())
+	res, err := slidingWindowScript.Run(ctx, r.client, []string{key},
+		now.UnixMilli(), window.Milliseconds(), limit, member).Int64Slice()
+	if err != nil {
+		return false, 0, err
+	}
+	if res[0] == 1 {
+		return true, 0, nil
+	}
+	return false, time.Duration(res[1]) * time.Millisecond, nil
+}
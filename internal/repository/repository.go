@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/Alias1177/Auth/internal/domain"
 	"github.com/Alias1177/Auth/internal/service"
@@ -85,3 +86,13 @@ func (r *Repository) UpdateUser(ctx context.Context, user *domain.User) error {
 func (r *Repository) ResetPassword(ctx context.Context, user *domain.User) error {
 	return r.postgres.ResetPassword(ctx, user)
 }
+
+// UpdateUserProfile обновление профиля с проверкой версии записи (прямая прокси без изменения логики)
+func (r *Repository) UpdateUserProfile(ctx context.Context, user *domain.User, expectedUpdatedAt time.Time) error {
+	return r.postgres.UpdateUserProfile(ctx, user, expectedUpdatedAt)
+}
+
+// UpdatePasswordHash заменяет хеш пароля без изменения данных профиля (пересчет хеша при входе)
+func (r *Repository) UpdatePasswordHash(ctx context.Context, userID int, hash string) error {
+	return r.postgres.UpdatePasswordHash(ctx, userID, hash)
+}
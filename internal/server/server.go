@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/Alias1177/Auth/internal/app/container"
+	"github.com/Alias1177/Auth/internal/domain"
 	"github.com/Alias1177/Auth/internal/dto"
 	"github.com/Alias1177/Auth/internal/middleware"
 	"github.com/go-chi/chi/v5"
@@ -37,6 +38,9 @@ func New(container *container.Container) (*Server, error) {
 func (s *Server) setupMiddleware() {
 	logger := s.container.GetLogger()
 
+	// Адрес клиента за доверенными прокси; до остальных middleware, чтобы логи и лимиты видели клиента
+	s.router.Use(middleware.RealIP(s.container.GetTrustedProxies()))
+
 	// CORS middleware
 	s.router.Use(cors.Handler(cors.Options{
 		AllowedOrigins: []string{}, // Пустой список (разрешим динамически)
@@ -44,7 +48,8 @@ func (s *Server) setupMiddleware() {
 			return true
 		},
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"},
-		AllowedHeaders:   []string{"Content-Type", "Authorization"},
+		AllowedHeaders:   []string{"Content-Type", "Authorization", "X-Device-Name", "If-Match"},
+		ExposedHeaders:   []string{"Retry-After", "ETag"},
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
@@ -64,27 +69,144 @@ func (s *Server) setupRoutes() {
 	registrationHandler := s.container.GetRegistrationHandler()
 	userHandler := s.container.GetUserHandler()
 	tokenManager := s.container.GetTokenManager()
+	tokenDenylist := s.container.GetTokenDenylist()
 	passwordResetHandler := s.container.GetPasswordResetHandler()
 	oauthHandler := s.container.GetOAuthHandler()
+	recoveryHandler := s.container.GetRecoveryHandler()
+	verificationHandler := s.container.GetEmailVerificationHandler()
+	unlockHandler := s.container.GetUnlockHandler()
+	emailChangeHandler := s.container.GetEmailChangeHandler()
+	roleHandler := s.container.GetRoleHandler()
+	adminUserHandler := s.container.GetAdminUserHandler()
+	clientHandler := s.container.GetClientHandler()
+	oidcHandler := s.container.GetOIDCHandler()
+	rateLimit := s.container.GetRateLimitMiddleware()
 
 	// Публичные маршруты
 	s.router.Get("/health", s.healthCheck)
-	s.router.Post("/login", authHandler.Login)
-	s.router.Post("/register", registrationHandler.Register)
+	s.router.Get("/.well-known/jwks.json", authHandler.JWKS)
+	s.router.Get("/.well-known/openid-configuration", oidcHandler.Discovery)
+	s.router.With(rateLimit.Limit(middleware.RouteLogin)).Post("/login", authHandler.Login)
+	s.router.Post("/login/mfa", authHandler.LoginMFA)
+	s.router.Post("/login/webauthn/begin", authHandler.BeginWebAuthnLogin)
+	s.router.Post("/login/webauthn/finish", authHandler.FinishWebAuthnLogin)
+	s.router.With(rateLimit.Limit(middleware.RouteRegister)).Post("/register", registrationHandler.Register)
 	s.router.Post("/refresh-token", authHandler.Refresh)
 	s.router.Get("/auth/{provider}/callback", oauthHandler.GetCallback)
 	s.router.Get("/logout/{provider}", oauthHandler.GetLogout)
 	s.router.Get("/auth/{provider}", oauthHandler.GetAuth)
 
 	// Новые безопасные ручки для сброса пароля
-	s.router.Post("/auth/request-password-reset", passwordResetHandler.RequestPasswordReset)
-	s.router.Post("/auth/confirm-password-reset", passwordResetHandler.ConfirmPasswordReset)
+	s.router.With(rateLimit.Limit(middleware.RoutePasswordResetRequest)).
+		Post("/auth/request-password-reset", passwordResetHandler.RequestPasswordReset)
+	s.router.With(rateLimit.Limit(middleware.RoutePasswordResetConfirm)).
+		Post("/auth/confirm-password-reset", passwordResetHandler.ConfirmPasswordReset)
+
+	// Восстановление доступа кодом восстановления, не зависит от почты
+	s.router.Post("/auth/recover", recoveryHandler.Recover)
+
+	// Подтверждение почты
+	s.router.Post("/auth/verify-email", verificationHandler.VerifyEmail)
+	s.router.Post("/auth/resend-verification", verificationHandler.ResendVerification)
+
+	// Подтверждение смены адреса (ссылка на новый адрес) и отмена (ссылка на старый)
+	s.router.Post("/auth/confirm-email-change", emailChangeHandler.ConfirmEmailChange)
+	s.router.Post("/auth/cancel-email-change", emailChangeHandler.CancelEmailChange)
+
+	// Снятие блокировки входа по ссылке из письма
+	s.router.Post("/auth/unlock", unlockHandler.Unlock)
 
 	// Защищённые маршруты
+	authMiddleware := middleware.JWTAuthMiddleware(tokenManager, tokenDenylist, s.container.GetLogger())
+	// Токены, выданные приложениям, действуют только на /oauth2/userinfo
+	userInfoAuth := middleware.UserInfoAuthMiddleware(tokenManager, tokenDenylist, s.container.GetLogger())
+	// Страница подтверждения устройства сама предлагает войти, если куки нет
+	optionalAuth := middleware.OptionalJWTAuthMiddleware(tokenManager, tokenDenylist, s.container.GetLogger())
+
+	// Провайдер OpenID Connect: вход в другие приложения через этот сервис
+	s.router.Route("/oauth2", func(r chi.Router) {
+		r.Get("/authorize", oidcHandler.Authorize)
+		r.With(rateLimit.Limit(middleware.RouteLogin)).Post("/authorize", oidcHandler.AuthorizeSubmit)
+		r.Post("/token", oidcHandler.Token)
+		r.Post("/introspect", oidcHandler.Introspect)
+		r.Post("/revoke", oidcHandler.Revoke)
+		r.Post("/device_authorization", oidcHandler.DeviceAuthorization)
+		r.With(optionalAuth, rateLimit.Limit(middleware.RouteLogin)).Get("/device", oidcHandler.Device)
+		r.With(optionalAuth, rateLimit.Limit(middleware.RouteLogin)).Post("/device", oidcHandler.DeviceSubmit)
+		r.With(userInfoAuth).Get("/userinfo", oidcHandler.UserInfo)
+		r.With(userInfoAuth).Post("/userinfo", oidcHandler.UserInfo)
+	})
+
+	s.router.With(authMiddleware).Post("/logout", authHandler.Logout)
+	s.router.With(authMiddleware).Post("/logout-all", authHandler.LogoutAll)
+
 	s.router.Route("/user", func(r chi.Router) {
-		r.Use(middleware.JWTAuthMiddleware(tokenManager))
-		r.Patch("/{id}", userHandler.UpdateUserHandler)
+		r.Use(authMiddleware)
 		r.Get("/me", userHandler.GetUserInfoHandler)
+		r.Patch("/me", userHandler.UpdateCurrentUser)
+		r.With(rateLimit.Limit(middleware.RouteReauth)).Post("/me/password", userHandler.ChangePassword)
+		r.Post("/me/email", userHandler.RequestEmailChange)
+		r.Get("/sessions", userHandler.ListSessions)
+		r.Delete("/sessions", userHandler.RevokeOtherSessions)
+		r.Delete("/sessions/{id}", userHandler.RevokeSession)
+
+		r.With(rateLimit.Limit(middleware.RouteReauth)).Post("/recovery-codes", userHandler.GenerateRecoveryCodes)
+		r.With(rateLimit.Limit(middleware.RouteReauth)).Post("/recovery-codes/regenerate", userHandler.RegenerateRecoveryCodes)
+
+		r.Route("/identities", func(r chi.Router) {
+			r.Get("/", userHandler.ListIdentities)
+			r.Post("/{provider}", oauthHandler.StartIdentityLink)
+			r.Delete("/{provider}", userHandler.UnlinkIdentity)
+		})
+
+		r.Route("/webauthn", func(r chi.Router) {
+			r.Post("/register/begin", userHandler.BeginPasskeyRegistration)
+			r.Post("/register/finish", userHandler.FinishPasskeyRegistration)
+			r.Get("/credentials", userHandler.ListPasskeys)
+			r.Delete("/credentials/{id}", userHandler.DeletePasskey)
+		})
+
+		r.Route("/mfa/totp", func(r chi.Router) {
+			r.Post("/", userHandler.EnrollTOTP)
+			r.Get("/qr", userHandler.TOTPQRCode)
+			r.Post("/verify", userHandler.ConfirmTOTP)
+			r.Delete("/", userHandler.DisableTOTP)
+		})
+	})
+
+	// Администрирование: доступ по разрешениям из access токена
+	s.router.Route("/admin", func(r chi.Router) {
+		r.Use(authMiddleware)
+
+		r.With(middleware.RequirePermission(domain.PermissionRolesRead)).Get("/roles", roleHandler.ListRoles)
+		r.With(middleware.RequirePermission(domain.PermissionRolesWrite)).Post("/roles", roleHandler.CreateRole)
+		r.With(middleware.RequirePermission(domain.PermissionRolesWrite)).Put("/roles/{name}/permissions", roleHandler.SetRolePermissions)
+		r.With(middleware.RequirePermission(domain.PermissionRolesWrite)).Delete("/roles/{name}", roleHandler.DeleteRole)
+		r.With(middleware.RequirePermission(domain.PermissionRolesRead)).Get("/permissions", roleHandler.ListPermissions)
+
+		r.With(middleware.RequirePermission(domain.PermissionRolesRead)).Get("/users/{id}/roles", roleHandler.ListUserRoles)
+		r.With(middleware.RequirePermission(domain.PermissionRolesWrite)).Put("/users/{id}/roles/{role}", roleHandler.AssignRole)
+		r.With(middleware.RequirePermission(domain.PermissionRolesWrite)).Delete("/users/{id}/roles/{role}", roleHandler.RemoveRole)
+
+		r.Route("/users", func(r chi.Router) {
+			r.With(middleware.RequirePermission(domain.PermissionUsersRead)).Get("/", adminUserHandler.SearchUsers)
+			r.With(middleware.RequirePermission(domain.PermissionUsersWrite)).Post("/", adminUserHandler.CreateUser)
+			r.With(middleware.RequirePermission(domain.PermissionUsersRead)).Get("/{id}", adminUserHandler.GetUser)
+			r.With(middleware.RequirePermission(domain.PermissionUsersWrite)).Patch("/{id}", userHandler.UpdateUserHandler)
+			r.With(middleware.RequirePermission(domain.PermissionUsersWrite)).Delete("/{id}", adminUserHandler.DeleteUser)
+			r.With(middleware.RequirePermission(domain.PermissionUsersWrite)).Post("/{id}/disable", adminUserHandler.DisableUser)
+			r.With(middleware.RequirePermission(domain.PermissionUsersWrite)).Post("/{id}/enable", adminUserHandler.EnableUser)
+			r.With(middleware.RequirePermission(domain.PermissionUsersWrite)).Post("/{id}/password-reset", adminUserHandler.ForcePasswordReset)
+			r.With(middleware.RequirePermission(domain.PermissionUsersWrite)).Delete("/{id}/sessions", adminUserHandler.RevokeSessions)
+			r.With(middleware.RequirePermission(domain.PermissionUsersRead)).Get("/{id}/audit", adminUserHandler.AuditLog)
+		})
+
+		r.Route("/clients", func(r chi.Router) {
+			r.With(middleware.RequirePermission(domain.PermissionClientsRead)).Get("/", clientHandler.ListClients)
+			r.With(middleware.RequirePermission(domain.PermissionClientsWrite)).Post("/", clientHandler.CreateClient)
+			r.With(middleware.RequirePermission(domain.PermissionClientsWrite)).Post("/{id}/secret", clientHandler.RotateSecret)
+			r.With(middleware.RequirePermission(domain.PermissionClientsWrite)).Delete("/{id}", clientHandler.DeleteClient)
+		})
 	})
 }
 
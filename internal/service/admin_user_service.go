@@ -0,0 +1,296 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	stderrors "errors"
+	"time"
+
+	"github.com/Alias1177/Auth/internal/domain"
+	"github.com/Alias1177/Auth/internal/errors"
+	"github.com/Alias1177/Auth/pkg/logger"
+	crypto "github.com/Alias1177/Auth/pkg/security"
+)
+
+// AdminUserRepository операции над учетными записями, доступные только администратору
+type AdminUserRepository interface {
+	SearchUsers(ctx context.Context, filter domain.UserFilter, limit, offset int) ([]domain.User, int64, error)
+	SetUserDisabled(ctx context.Context, userID int, disabled bool) error
+	DeleteUser(ctx context.Context, userID int) error
+}
+
+// AuditRepository журнал аудита действий администраторов
+type AuditRepository interface {
+	CreateAuditRecord(ctx context.Context, record *domain.AuditRecord) error
+	ListAuditRecords(ctx context.Context, targetUserID, limit, offset int) ([]domain.AuditRecord, int64, error)
+}
+
+// AdminUserService управление учетными записями администратором.
+// Каждое изменяющее действие записывается в журнал аудита.
+type AdminUserService interface {
+	SearchUsers(ctx context.Context, filter domain.UserFilter, page, limit int) ([]domain.User, int64, error)
+	GetUser(ctx context.Context, userID int) (*domain.User, error)
+	CreateUser(ctx context.Context, actor domain.AuditActor, username, email, password string, emailVerified bool) (*domain.User, error)
+	DisableUser(ctx context.Context, actor domain.AuditActor, userID int) error
+	EnableUser(ctx context.Context, actor domain.AuditActor, userID int) error
+	ForcePasswordReset(ctx context.Context, actor domain.AuditActor, userID int) error
+	RevokeSessions(ctx context.Context, actor domain.AuditActor, userID int) error
+	DeleteUser(ctx context.Context, actor domain.AuditActor, userID int) error
+	AuditLog(ctx context.Context, userID, page, limit int) ([]domain.AuditRecord, int64, error)
+}
+
+// AdminUserServiceImpl реализация сервиса управления учетными записями
+type AdminUserServiceImpl struct {
+	repo     AdminUserRepository
+	audit    AuditRepository
+	userRepo UserRepository
+	cache    UserCache
+	sessions SessionService
+	policy   PasswordPolicy
+	history  PasswordHistoryService
+	resets   PasswordResetService
+	logger   *logger.Logger
+}
+
+// NewAdminUserService создает новый экземпляр сервиса управления учетными записями
+func NewAdminUserService(
+	repo AdminUserRepository,
+	audit AuditRepository,
+	userRepo UserRepository,
+	cache UserCache,
+	sessions SessionService,
+	policy PasswordPolicy,
+	history PasswordHistoryService,
+	resets PasswordResetService,
+	logger *logger.Logger,
+) *AdminUserServiceImpl {
+	return &AdminUserServiceImpl{
+		repo:     repo,
+		audit:    audit,
+		userRepo: userRepo,
+		cache:    cache,
+		sessions: sessions,
+		policy:   policy,
+		history:  history,
+		resets:   resets,
+		logger:   logger,
+	}
+}
+
+// SearchUsers возвращает страницу page (с 1) пользователей по фильтру и общее число найденных
+func (s *AdminUserServiceImpl) SearchUsers(ctx context.Context, filter domain.UserFilter, page, limit int) ([]domain.User, int64, error) {
+	return s.repo.SearchUsers(ctx, filter, limit, (page-1)*limit)
+}
+
+// GetUser возвращает пользователя или ErrUserNotFound
+func (s *AdminUserServiceImpl) GetUser(ctx context.Context, userID int) (*domain.User, error) {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		if stderrors.Is(err, sql.ErrNoRows) {
+			return nil, errors.ErrUserNotFound
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+// CreateUser создает учетную запись; пароль проходит ту же политику, что и при регистрации
+func (s *AdminUserServiceImpl) CreateUser(
+	ctx context.Context,
+	actor domain.AuditActor,
+	username, email, password string,
+	emailVerified bool,
+) (*domain.User, error) {
+	if _, err := s.userRepo.GetUserByEmail(ctx, email); err == nil {
+		return nil, errors.ErrUserExists
+	} else if !stderrors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	user := &domain.User{UserName: username, Email: email}
+	if err := s.policy.Check(ctx, password, user); err != nil {
+		return nil, err
+	}
+
+	hash, err := crypto.HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+	user.Password = hash
+	if emailVerified {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+	}
+	if err := s.userRepo.CreateUser(ctx, user); err != nil {
+		return nil, err
+	}
+	s.history.Record(ctx, user.ID, hash)
+
+	s.record(ctx, actor, domain.AuditActionUserCreate, user.ID, map[string]interface{}{
+		"email":          email,
+		"username":       username,
+		"email_verified": emailVerified,
+	})
+	return user, nil
+}
+
+// DisableUser отключает учетную запись и завершает все ее сессии.
+// Отключить собственную учетную запись нельзя, чтобы администратор не потерял доступ по ошибке.
+func (s *AdminUserServiceImpl) DisableUser(ctx context.Context, actor domain.AuditActor, userID int) error {
+	if actor.UserID == userID {
+		return errors.ErrForbidden
+	}
+	if err := s.setDisabled(ctx, userID, true); err != nil {
+		return err
+	}
+	if err := s.sessions.RevokeAll(ctx, userID); err != nil {
+		return err
+	}
+
+	s.record(ctx, actor, domain.AuditActionUserDisable, userID, nil)
+	return nil
+}
+
+// EnableUser снова разрешает вход в отключенную учетную запись
+func (s *AdminUserServiceImpl) EnableUser(ctx context.Context, actor domain.AuditActor, userID int) error {
+	if err := s.setDisabled(ctx, userID, false); err != nil {
+		return err
+	}
+
+	s.record(ctx, actor, domain.AuditActionUserEnable, userID, nil)
+	return nil
+}
+
+// ForcePasswordReset заменяет пароль недоступным значением, завершает все сессии и отправляет письмо сброса.
+// Войти по паролю можно будет только после сброса по коду из письма.
+func (s *AdminUserServiceImpl) ForcePasswordReset(ctx context.Context, actor domain.AuditActor, userID int) error {
+	user, err := s.GetUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return err
+	}
+	hash, err := crypto.HashPassword(base64.RawURLEncoding.EncodeToString(secret))
+	if err != nil {
+		return err
+	}
+	if err := s.userRepo.UpdatePasswordHash(ctx, userID, hash); err != nil {
+		return err
+	}
+	if err := s.sessions.RevokeAll(ctx, userID); err != nil {
+		return err
+	}
+	if err := s.resets.RequestReset(ctx, user.Email); err != nil {
+		return err
+	}
+
+	s.record(ctx, actor, domain.AuditActionUserPasswordReset, userID, nil)
+	return nil
+}
+
+// RevokeSessions завершает все сессии пользователя
+func (s *AdminUserServiceImpl) RevokeSessions(ctx context.Context, actor domain.AuditActor, userID int) error {
+	if _, err := s.GetUser(ctx, userID); err != nil {
+		return err
+	}
+	if err := s.sessions.RevokeAll(ctx, userID); err != nil {
+		return err
+	}
+
+	s.record(ctx, actor, domain.AuditActionUserRevokeSession, userID, nil)
+	return nil
+}
+
+// DeleteUser удаляет учетную запись. Сессии отзываются заранее, чтобы выданные access токены перестали действовать.
+func (s *AdminUserServiceImpl) DeleteUser(ctx context.Context, actor domain.AuditActor, userID int) error {
+	if actor.UserID == userID {
+		return errors.ErrForbidden
+	}
+	user, err := s.GetUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.sessions.RevokeAll(ctx, userID); err != nil {
+		return err
+	}
+	if err := s.repo.DeleteUser(ctx, userID); err != nil {
+		if stderrors.Is(err, sql.ErrNoRows) {
+			return errors.ErrUserNotFound
+		}
+		return err
+	}
+	if err := s.cache.DeleteUser(ctx, userID); err != nil {
+		s.logger.Errorw("Failed to invalidate user cache", "user_id", userID, "error", err)
+	}
+
+	// Адрес сохраняется в журнале: после удаления по ID уже не узнать, чья это была учетная запись
+	s.record(ctx, actor, domain.AuditActionUserDelete, userID, map[string]interface{}{
+		"email":    user.Email,
+		"username": user.UserName,
+	})
+	return nil
+}
+
+// AuditLog возвращает страницу записей аудита о пользователе, начиная с новых
+func (s *AdminUserServiceImpl) AuditLog(ctx context.Context, userID, page, limit int) ([]domain.AuditRecord, int64, error) {
+	return s.audit.ListAuditRecords(ctx, userID, limit, (page-1)*limit)
+}
+
+func (s *AdminUserServiceImpl) setDisabled(ctx context.Context, userID int, disabled bool) error {
+	if err := s.repo.SetUserDisabled(ctx, userID, disabled); err != nil {
+		if stderrors.Is(err, sql.ErrNoRows) {
+			return errors.ErrUserNotFound
+		}
+		return err
+	}
+	if err := s.cache.DeleteUser(ctx, userID); err != nil {
+		s.logger.Errorw("Failed to invalidate user cache", "user_id", userID, "error", err)
+	}
+	return nil
+}
+
+// record пишет действие в журнал аудита
+func (s *AdminUserServiceImpl) record(ctx context.Context, actor domain.AuditActor, action string, userID int, details map[string]interface{}) {
+	writeAuditRecord(ctx, s.audit, s.logger, actor, action, userID, details)
+}
+
+// writeAuditRecord пишет действие в журнал аудита. Действие к этому моменту уже выполнено,
+// поэтому ошибка записи не отменяет его, а попадает в лог вместе с деталями.
+func writeAuditRecord(
+	ctx context.Context,
+	audit AuditRepository,
+	log *logger.Logger,
+	actor domain.AuditActor,
+	action string,
+	userID int,
+	details map[string]interface{},
+) {
+	record := &domain.AuditRecord{
+		Action:       action,
+		TargetUserID: &userID,
+		IP:           actor.IP,
+	}
+	if !actor.System() {
+		record.ActorID = &actor.UserID
+	}
+	if details != nil {
+		data, err := json.Marshal(details)
+		if err != nil {
+			log.Errorw("Failed to encode audit details", "action", action, "error", err)
+		}
+		record.Details = data
+	}
+
+	if err := audit.CreateAuditRecord(ctx, record); err != nil {
+		log.Errorw("Failed to write audit record",
+			"action", action, "actor_id", actor.UserID, "user_id", userID, "ip", actor.IP, "error", err)
+		return
+	}
+	log.Infow("Admin action", "action", action, "actor_id", actor.UserID, "user_id", userID)
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/Alias1177/Auth/internal/domain"
+	"github.com/Alias1177/Auth/internal/errors"
+	"github.com/Alias1177/Auth/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryAdminUsers учетные записи и журнал аудита в памяти
+type memoryAdminUsers struct {
+	disabled map[int]bool
+	deleted  map[int]bool
+	records  []domain.AuditRecord
+}
+
+func (m *memoryAdminUsers) SearchUsers(context.Context, domain.UserFilter, int, int) ([]domain.User, int64, error) {
+	return nil, 0, nil
+}
+
+func (m *memoryAdminUsers) SetUserDisabled(_ context.Context, userID int, disabled bool) error {
+	if m.deleted[userID] {
+		return sql.ErrNoRows
+	}
+	m.disabled[userID] = disabled
+	return nil
+}
+
+func (m *memoryAdminUsers) DeleteUser(_ context.Context, userID int) error {
+	if m.deleted[userID] {
+		return sql.ErrNoRows
+	}
+	m.deleted[userID] = true
+	return nil
+}
+
+func (m *memoryAdminUsers) CreateAuditRecord(_ context.Context, record *domain.AuditRecord) error {
+	m.records = append(m.records, *record)
+	return nil
+}
+
+func (m *memoryAdminUsers) ListAuditRecords(context.Context, int, int, int) ([]domain.AuditRecord, int64, error) {
+	return m.records, int64(len(m.records)), nil
+}
+
+func TestAdminUserService(t *testing.T) {
+	ctx := context.Background()
+	log, err := logger.New("error")
+	require.NoError(t, err)
+
+	target := &domain.User{ID: 7, Email: "user@example.com", UserName: "user"}
+	userRepo := new(MockUserRepository)
+	userRepo.On("GetUserByID", mock.Anything, target.ID).Return(target, nil)
+	userRepo.On("GetUserByID", mock.Anything, mock.Anything).Return(nil, sql.ErrNoRows)
+
+	repo := &memoryAdminUsers{disabled: map[int]bool{}, deleted: map[int]bool{}}
+	cache := &memoryCache{values: map[string]string{}}
+	cache.On("DeleteUser", mock.Anything, mock.Anything).Return(nil)
+	sessions := &revokingSessions{}
+	svc := NewAdminUserService(repo, repo, userRepo, cache, sessions, nil, nil, nil, log)
+
+	admin := domain.AuditActor{UserID: 1, IP: "10.0.0.1"}
+
+	tests := []struct {
+		name    string
+		action  func(ctx context.Context, actor domain.AuditActor, userID int) error
+		userID  int
+		wantErr error
+	}{
+		{name: "disable self", action: svc.DisableUser, userID: admin.UserID, wantErr: errors.ErrForbidden},
+		{name: "delete self", action: svc.DeleteUser, userID: admin.UserID, wantErr: errors.ErrForbidden},
+		{name: "revoke sessions of missing user", action: svc.RevokeSessions, userID: 404, wantErr: errors.ErrUserNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorIs(t, tt.action(ctx, admin, tt.userID), tt.wantErr)
+		})
+	}
+	assert.Empty(t, repo.records, "rejected actions must not be audited")
+
+	require.NoError(t, svc.DisableUser(ctx, admin, target.ID))
+	assert.True(t, repo.disabled[target.ID])
+	assert.Equal(t, []int{target.ID}, sessions.revoked)
+
+	require.NoError(t, svc.EnableUser(ctx, admin, target.ID))
+	assert.False(t, repo.disabled[target.ID])
+
+	require.NoError(t, svc.DeleteUser(ctx, admin, target.ID))
+	assert.True(t, repo.deleted[target.ID])
+
+	require.Len(t, repo.records, 3)
+	actions := []string{repo.records[0].Action, repo.records[1].Action, repo.records[2].Action}
+	assert.Equal(t, []string{domain.AuditActionUserDisable, domain.AuditActionUserEnable, domain.AuditActionUserDelete}, actions)
+	for _, record := range repo.records {
+		assert.Equal(t, admin.UserID, *record.ActorID)
+		assert.Equal(t, target.ID, *record.TargetUserID)
+		assert.Equal(t, admin.IP, record.IP)
+	}
+	assert.JSONEq(t, `{"email":"user@example.com","username":"user"}`, string(repo.records[2].Details))
+}
@@ -2,35 +2,111 @@ package service
 
 import (
 	"context"
+	"database/sql"
+	stderrors "errors"
+	"strconv"
 
 	"github.com/Alias1177/Auth/internal/domain"
 	"github.com/Alias1177/Auth/internal/errors"
+	"github.com/Alias1177/Auth/pkg/logger"
 	crypto "github.com/Alias1177/Auth/pkg/security"
 )
 
 type AuthServiceImpl struct {
-	userRepo     UserRepository
-	tokenManager TokenManager
+	userRepo      UserRepository
+	tokenManager  TokenManager
+	refreshTokens RefreshTokenService
+	sessions      SessionService
+	denylist      AccessTokenDenylist
+	lockout       LockoutService
+	verification  EmailVerificationService
+	logger        *logger.Logger
 }
 
-func NewAuthService(userRepo UserRepository, tokenManager TokenManager) *AuthServiceImpl {
+func NewAuthService(
+	userRepo UserRepository,
+	tokenManager TokenManager,
+	refreshTokens RefreshTokenService,
+	sessions SessionService,
+	denylist AccessTokenDenylist,
+	lockout LockoutService,
+	verification EmailVerificationService,
+	logger *logger.Logger,
+) *AuthServiceImpl {
 	return &AuthServiceImpl{
-		userRepo:     userRepo,
-		tokenManager: tokenManager,
+		userRepo:      userRepo,
+		tokenManager:  tokenManager,
+		refreshTokens: refreshTokens,
+		sessions:      sessions,
+		denylist:      denylist,
+		lockout:       lockout,
+		verification:  verification,
+		logger:        logger,
 	}
 }
 
+// Login проверяет email и пароль для всех мест входа по паролю: /login и страницы входа OpenID Connect.
+// Неизвестный адрес обрабатывается как неверный пароль (ErrInvalidLogin), чтобы ответ не раскрывал наличие аккаунта.
+// Заблокированный вход отклоняется даже с верным паролем (ErrAccountLocked), а об отключении учетной записи
+// и неподтвержденном адресе (ErrAccountDisabled, ErrEmailNotVerified) сообщается только после верного пароля.
 func (s *AuthServiceImpl) Login(ctx context.Context, email, password string) (*domain.User, error) {
 	user, err := s.userRepo.GetUserByEmail(ctx, email)
 	if err != nil {
+		if !stderrors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		user = nil
+	}
+
+	if err := s.lockout.Check(ctx, email, user); err != nil {
 		return nil, err
 	}
-	if err := crypto.VerifyPassword(user.Password, password); err != nil {
+	if verifyLoginPassword(user, password) != nil {
+		if err := s.lockout.RecordFailure(ctx, email, user); err != nil {
+			return nil, err
+		}
+		return nil, errors.ErrInvalidLogin
+	}
+	if err := s.lockout.RecordSuccess(ctx, user); err != nil {
 		return nil, err
 	}
+
+	// Хеш устаревшего алгоритма или с прежними параметрами пересчитываем, пока известен пароль
+	if crypto.NeedsRehash(user.Password) {
+		s.rehashPassword(ctx, user.ID, password)
+	}
+
+	if user.Disabled() {
+		return nil, errors.ErrAccountDisabled
+	}
+	if s.verification.Required() && !user.EmailVerified() {
+		return nil, errors.ErrEmailNotVerified
+	}
 	return user, nil
 }
 
+// verifyLoginPassword проверяет пароль пользователя. Для неизвестного адреса и учетной записи без пароля
+// пароль проверяется по фиктивному хешу: время ответа не раскрывает, зарегистрирован ли адрес.
+func verifyLoginPassword(user *domain.User, password string) error {
+	if user == nil || user.Password == "" {
+		return crypto.VerifyDummyPassword(password)
+	}
+	return crypto.VerifyPassword(user.Password, password)
+}
+
+// rehashPassword сохраняет хеш пароля, посчитанный текущим алгоритмом. Ошибка не мешает входу.
+func (s *AuthServiceImpl) rehashPassword(ctx context.Context, userID int, password string) {
+	hash, err := crypto.HashPassword(password)
+	if err == nil {
+		err = s.userRepo.UpdatePasswordHash(ctx, userID, hash)
+	}
+	if err != nil {
+		s.logger.Warnw("Failed to rehash password", "user_id", userID, "error", err)
+		return
+	}
+	s.logger.Infow("Password hash upgraded", "user_id", userID)
+}
+
 func (s *AuthServiceImpl) Register(ctx context.Context, user *domain.User) error {
 	existing, err := s.userRepo.GetUserByEmail(ctx, user.Email)
 	if err == nil && existing != nil {
@@ -44,19 +120,33 @@ func (s *AuthServiceImpl) Register(ctx context.Context, user *domain.User) error
 	return s.userRepo.CreateUser(ctx, user)
 }
 
-func (s *AuthServiceImpl) RefreshToken(ctx context.Context, refreshToken string) (string, error) {
-	claims, err := s.tokenManager.ValidateRefreshToken(refreshToken)
-	if err != nil {
-		return "", err
+// Logout завершает текущий вход: отзывает сессию с ее refresh токенами и сам access токен
+func (s *AuthServiceImpl) Logout(ctx context.Context, claims *domain.UserClaims) error {
+	if claims.FamilyID != "" {
+		userID, err := strconv.Atoi(claims.UserID)
+		if err != nil {
+			return errors.ErrInvalidToken
+		}
+		err = s.sessions.Revoke(ctx, userID, claims.FamilyID)
+		if err == errors.ErrNotFound {
+			// Вход выполнен до появления сессий: отзываем только семейство токенов
+			err = s.refreshTokens.RevokeFamily(ctx, claims.FamilyID)
+		}
+		if err != nil {
+			return err
+		}
 	}
-	accessToken, err := s.tokenManager.GenerateAccessToken(*claims)
-	if err != nil {
-		return "", err
-	}
-	return accessToken, nil
+	return s.denylist.RevokeToken(ctx, claims)
 }
 
-func (s *AuthServiceImpl) Logout(ctx context.Context, userID string) error {
-	// Здесь может быть логика blacklist токенов или удаление refresh токена
-	return nil // no-op
+// LogoutAll завершает все входы пользователя на всех устройствах
+func (s *AuthServiceImpl) LogoutAll(ctx context.Context, claims *domain.UserClaims) error {
+	userID, err := strconv.Atoi(claims.UserID)
+	if err != nil {
+		return errors.ErrInvalidToken
+	}
+	if err := s.sessions.RevokeAll(ctx, userID); err != nil {
+		return err
+	}
+	return s.denylist.RevokeToken(ctx, claims)
 }
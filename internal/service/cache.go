@@ -10,8 +10,18 @@ import (
 type UserCache interface {
 	GetUser(ctx context.Context, id int) (*domain.User, error)
 	SaveUser(ctx context.Context, user *domain.User) error
+	// DeleteUser удаляет запись пользователя из кэша, следующее чтение возьмет ее из базы
+	DeleteUser(ctx context.Context, id int) error
 	// Методы для работы с произвольными ключами и TTL
 	Get(ctx context.Context, key string) (string, error)
 	SetWithTTL(ctx context.Context, key string, value string, ttl time.Duration) error
 	Delete(ctx context.Context, key string) error
+	// Increment атомарно увеличивает счетчик; TTL задается при создании ключа
+	Increment(ctx context.Context, key string, ttl time.Duration) (int64, error)
+}
+
+// RateLimitStore счетчик запросов в скользящем окне.
+// Allow учитывает запрос, если лимит не исчерпан, иначе возвращает время до освобождения места в окне.
+type RateLimitStore interface {
+	Allow(ctx context.Context, key string, limit int, window time.Duration) (bool, time.Duration, error)
 }
@@ -0,0 +1,307 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	stderrors "errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Alias1177/Auth/internal/config"
+	"github.com/Alias1177/Auth/internal/domain"
+	"github.com/Alias1177/Auth/internal/errors"
+	"github.com/Alias1177/Auth/pkg/logger"
+	crypto "github.com/Alias1177/Auth/pkg/security"
+)
+
+// Ограничение числа заявок на смену адреса от одного пользователя
+const (
+	maxEmailChangeRequests   = 5
+	emailChangeRequestWindow = time.Hour
+)
+
+// RecentLoginWindow в течение какого времени после входа пользователь без пароля
+// может подтвердить чувствительное действие без кода TOTP
+const RecentLoginWindow = 10 * time.Minute
+
+// EmailChangeRepository хранилище заявок на смену адреса
+type EmailChangeRepository interface {
+	SaveEmailChange(ctx context.Context, change *domain.EmailChange) error
+	GetEmailChangeByToken(ctx context.Context, tokenHash string) (*domain.EmailChange, error)
+	GetEmailChangeByCancelToken(ctx context.Context, cancelTokenHash string) (*domain.EmailChange, error)
+	DeleteEmailChange(ctx context.Context, userID int) error
+	ApplyEmailChange(ctx context.Context, userID int, newEmail string, revert *domain.EmailChangeRevert) error
+	GetEmailChangeRevert(ctx context.Context, cancelTokenHash string) (*domain.EmailChangeRevert, error)
+	RevertEmailChange(ctx context.Context, revert *domain.EmailChangeRevert) error
+}
+
+// EmailChangeSender отправка писем о смене адреса
+type EmailChangeSender interface {
+	SendEmailChangeConfirmation(ctx context.Context, newEmail, username, token string) error
+	SendEmailChangeNotice(ctx context.Context, oldEmail, username, newEmail, cancelToken string) error
+}
+
+// EmailChangeService смена адреса почты с подтверждением нового адреса.
+// Старый адрес получает уведомление со ссылкой отмены, которая заодно завершает все сессии.
+// После подтверждения ссылка еще ChangeTTL возвращает прежний адрес.
+type EmailChangeService interface {
+	Request(ctx context.Context, userID int, sessionID, newEmail, password, code string) error
+	Confirm(ctx context.Context, token string) error
+	Cancel(ctx context.Context, token string) error
+}
+
+// EmailChangeServiceImpl реализация сервиса смены адреса
+type EmailChangeServiceImpl struct {
+	repo     EmailChangeRepository
+	userRepo UserRepository
+	cache    UserCache
+	sessions SessionService
+	mfa      MFAService
+	sender   EmailChangeSender
+	cfg      config.EmailVerificationConfig
+	logger   *logger.Logger
+}
+
+// NewEmailChangeService создает новый экземпляр сервиса смены адреса
+func NewEmailChangeService(
+	repo EmailChangeRepository,
+	userRepo UserRepository,
+	cache UserCache,
+	sessions SessionService,
+	mfa MFAService,
+	sender EmailChangeSender,
+	cfg config.EmailVerificationConfig,
+	logger *logger.Logger,
+) *EmailChangeServiceImpl {
+	return &EmailChangeServiceImpl{
+		repo:     repo,
+		userRepo: userRepo,
+		cache:    cache,
+		sessions: sessions,
+		mfa:      mfa,
+		sender:   sender,
+		cfg:      cfg,
+		logger:   logger,
+	}
+}
+
+// Request создает заявку на смену адреса и отправляет письма на новый и старый адреса.
+// Пользователь с паролем подтверждает заявку текущим паролем, без пароля - кодом TOTP или недавним входом
+// в сессии sessionID.
+func (s *EmailChangeServiceImpl) Request(ctx context.Context, userID int, sessionID, newEmail, password, code string) error {
+	attempts, err := s.cache.Increment(ctx, emailChangeRequestsKey(userID), emailChangeRequestWindow)
+	if err != nil {
+		return err
+	}
+	if attempts > maxEmailChangeRequests {
+		return errors.ErrTooManyRequests
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.reauthenticate(ctx, user, sessionID, password, code); err != nil {
+		return err
+	}
+
+	newEmail = strings.TrimSpace(newEmail)
+	if strings.EqualFold(newEmail, user.Email) {
+		return errors.ErrValidation
+	}
+	if err := s.ensureEmailAvailable(ctx, userID, newEmail); err != nil {
+		return err
+	}
+
+	token, err := newEmailChangeToken()
+	if err != nil {
+		return err
+	}
+	cancelToken, err := newEmailChangeToken()
+	if err != nil {
+		return err
+	}
+
+	change := &domain.EmailChange{
+		UserID:          userID,
+		NewEmail:        newEmail,
+		TokenHash:       hashVerificationToken(token),
+		CancelTokenHash: hashVerificationToken(cancelToken),
+		ExpiresAt:       time.Now().Add(s.cfg.ChangeTTL),
+	}
+	if err := s.repo.SaveEmailChange(ctx, change); err != nil {
+		return err
+	}
+
+	if err := s.sender.SendEmailChangeConfirmation(ctx, newEmail, user.UserName, token); err != nil {
+		return err
+	}
+	if err := s.sender.SendEmailChangeNotice(ctx, user.Email, user.UserName, newEmail, cancelToken); err != nil {
+		// Подтверждение уже отправлено; без уведомления старый адрес не сможет отменить смену, поэтому заявка удаляется
+		if delErr := s.repo.DeleteEmailChange(ctx, userID); delErr != nil {
+			s.logger.Errorw("Failed to delete email change", "user_id", userID, "error", delErr)
+		}
+		return err
+	}
+
+	s.logger.Infow("Email change requested", "user_id", userID)
+	return nil
+}
+
+// Confirm применяет смену адреса по токену из письма на новый адрес.
+// Занятость адреса проверяется повторно: его могли зарегистрировать после создания заявки.
+func (s *EmailChangeServiceImpl) Confirm(ctx context.Context, token string) error {
+	change, err := s.repo.GetEmailChangeByToken(ctx, hashVerificationToken(token))
+	if err != nil {
+		if stderrors.Is(err, sql.ErrNoRows) {
+			return errors.ErrInvalidToken
+		}
+		return err
+	}
+
+	if err := s.ensureEmailAvailable(ctx, change.UserID, change.NewEmail); err != nil {
+		if err == errors.ErrUserExists {
+			if delErr := s.repo.DeleteEmailChange(ctx, change.UserID); delErr != nil {
+				s.logger.Errorw("Failed to delete email change", "user_id", change.UserID, "error", delErr)
+			}
+		}
+		return err
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, change.UserID)
+	if err != nil {
+		return err
+	}
+	// Ссылка отмены из уведомления на старый адрес продолжает действовать и после подтверждения
+	revert := &domain.EmailChangeRevert{
+		UserID:          change.UserID,
+		OldEmail:        user.Email,
+		CancelTokenHash: change.CancelTokenHash,
+		ExpiresAt:       time.Now().Add(s.cfg.ChangeTTL),
+	}
+	if err := s.repo.ApplyEmailChange(ctx, change.UserID, change.NewEmail, revert); err != nil {
+		if stderrors.Is(err, sql.ErrNoRows) {
+			return errors.ErrInvalidToken
+		}
+		return err
+	}
+	if err := s.cache.DeleteUser(ctx, change.UserID); err != nil {
+		s.logger.Errorw("Failed to invalidate user cache", "user_id", change.UserID, "error", err)
+	}
+
+	s.logger.Infow("Email changed", "user_id", change.UserID)
+	return nil
+}
+
+// Cancel отменяет заявку по ссылке из уведомления на старый адрес и завершает все сессии:
+// заявку мог создать тот, кто завладел сессией и паролем. Если смена уже подтверждена,
+// ссылка возвращает прежний адрес.
+func (s *EmailChangeServiceImpl) Cancel(ctx context.Context, token string) error {
+	tokenHash := hashVerificationToken(token)
+	change, err := s.repo.GetEmailChangeByCancelToken(ctx, tokenHash)
+	if err != nil {
+		if !stderrors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		return s.revert(ctx, tokenHash)
+	}
+
+	if err := s.repo.DeleteEmailChange(ctx, change.UserID); err != nil {
+		return err
+	}
+	if err := s.sessions.RevokeAll(ctx, change.UserID); err != nil {
+		return err
+	}
+
+	s.logger.Warnw("Email change cancelled from old address, all sessions revoked", "user_id", change.UserID)
+	return nil
+}
+
+// revert возвращает прежний адрес после подтвержденной смены и завершает все сессии
+func (s *EmailChangeServiceImpl) revert(ctx context.Context, tokenHash string) error {
+	revert, err := s.repo.GetEmailChangeRevert(ctx, tokenHash)
+	if err != nil {
+		if stderrors.Is(err, sql.ErrNoRows) {
+			return errors.ErrInvalidToken
+		}
+		return err
+	}
+
+	if err := s.repo.RevertEmailChange(ctx, revert); err != nil {
+		if stderrors.Is(err, sql.ErrNoRows) {
+			return errors.ErrInvalidToken
+		}
+		return err
+	}
+	if err := s.cache.DeleteUser(ctx, revert.UserID); err != nil {
+		s.logger.Errorw("Failed to invalidate user cache", "user_id", revert.UserID, "error", err)
+	}
+	if err := s.sessions.RevokeAll(ctx, revert.UserID); err != nil {
+		return err
+	}
+
+	s.logger.Warnw("Email change reverted from old address, all sessions revoked", "user_id", revert.UserID)
+	return nil
+}
+
+// reauthenticate подтверждает, что заявку создает владелец учетной записи, а не тот, кто завладел токеном.
+// Пользователь без пароля (вход через провайдера) подтверждает заявку кодом TOTP или входом не раньше
+// RecentLoginWindow назад.
+func (s *EmailChangeServiceImpl) reauthenticate(ctx context.Context, user *domain.User, sessionID, password, code string) error {
+	if user.Password != "" {
+		if crypto.VerifyPassword(user.Password, password) != nil {
+			return errors.ErrInvalidLogin
+		}
+		return nil
+	}
+
+	if code != "" {
+		err := s.mfa.Verify(ctx, user.ID, code)
+		if err == errors.ErrMFANotEnrolled {
+			return errors.ErrReauthRequired
+		}
+		return err
+	}
+
+	sessions, err := s.sessions.List(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		if session.ID == sessionID && time.Since(session.CreatedAt) < RecentLoginWindow {
+			return nil
+		}
+	}
+	return errors.ErrReauthRequired
+}
+
+// ensureEmailAvailable возвращает ErrUserExists, если адрес принадлежит другому пользователю
+func (s *EmailChangeServiceImpl) ensureEmailAvailable(ctx context.Context, userID int, email string) error {
+	owner, err := s.userRepo.GetUserByEmail(ctx, email)
+	if err != nil {
+		if stderrors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+	if owner.ID != userID {
+		return errors.ErrUserExists
+	}
+	return nil
+}
+
+// newEmailChangeToken случайный токен для ссылки в письме
+func newEmailChangeToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func emailChangeRequestsKey(userID int) string {
+	return fmt.Sprintf("email_change_requests:%d", userID)
+}
@@ -0,0 +1,255 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/Alias1177/Auth/internal/config"
+	"github.com/Alias1177/Auth/internal/domain"
+	"github.com/Alias1177/Auth/internal/errors"
+	"github.com/Alias1177/Auth/pkg/logger"
+	crypto "github.com/Alias1177/Auth/pkg/security"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryEmailChanges хранилище заявок на смену адреса в памяти
+type memoryEmailChanges struct {
+	changes map[int]domain.EmailChange
+	applied map[int]string
+	reverts []domain.EmailChangeRevert
+}
+
+func (m *memoryEmailChanges) SaveEmailChange(_ context.Context, change *domain.EmailChange) error {
+	m.changes[change.UserID] = *change
+	return nil
+}
+
+func (m *memoryEmailChanges) find(match func(domain.EmailChange) bool) (*domain.EmailChange, error) {
+	for _, change := range m.changes {
+		if match(change) && change.ExpiresAt.After(time.Now()) {
+			return &change, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (m *memoryEmailChanges) GetEmailChangeByToken(_ context.Context, tokenHash string) (*domain.EmailChange, error) {
+	return m.find(func(c domain.EmailChange) bool { return c.TokenHash == tokenHash })
+}
+
+func (m *memoryEmailChanges) GetEmailChangeByCancelToken(_ context.Context, cancelTokenHash string) (*domain.EmailChange, error) {
+	return m.find(func(c domain.EmailChange) bool { return c.CancelTokenHash == cancelTokenHash })
+}
+
+func (m *memoryEmailChanges) DeleteEmailChange(_ context.Context, userID int) error {
+	delete(m.changes, userID)
+	return nil
+}
+
+func (m *memoryEmailChanges) ApplyEmailChange(_ context.Context, userID int, newEmail string, revert *domain.EmailChangeRevert) error {
+	m.applied[userID] = newEmail
+	delete(m.changes, userID)
+	m.reverts = append(m.reverts, *revert)
+	return nil
+}
+
+func (m *memoryEmailChanges) GetEmailChangeRevert(_ context.Context, cancelTokenHash string) (*domain.EmailChangeRevert, error) {
+	for _, revert := range m.reverts {
+		if revert.CancelTokenHash == cancelTokenHash && revert.ExpiresAt.After(time.Now()) {
+			return &revert, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (m *memoryEmailChanges) RevertEmailChange(_ context.Context, revert *domain.EmailChangeRevert) error {
+	m.applied[revert.UserID] = revert.OldEmail
+	delete(m.changes, revert.UserID)
+	kept := m.reverts[:0]
+	for _, r := range m.reverts {
+		if r.UserID != revert.UserID {
+			kept = append(kept, r)
+		}
+	}
+	m.reverts = kept
+	return nil
+}
+
+// recordingEmailChangeSender запоминает токены из писем на новый и старый адреса
+type recordingEmailChangeSender struct {
+	confirmTokens []string
+	cancelTokens  []string
+}
+
+func (s *recordingEmailChangeSender) SendEmailChangeConfirmation(_ context.Context, _, _, token string) error {
+	s.confirmTokens = append(s.confirmTokens, token)
+	return nil
+}
+
+func (s *recordingEmailChangeSender) SendEmailChangeNotice(_ context.Context, _, _, _, cancelToken string) error {
+	s.cancelTokens = append(s.cancelTokens, cancelToken)
+	return nil
+}
+
+// revokingSessions запоминает пользователей, у которых завершены все сессии
+type revokingSessions struct {
+	SessionService
+	sessions []domain.Session
+	revoked  []int
+}
+
+func (s *revokingSessions) List(_ context.Context, userID int) ([]domain.Session, error) {
+	result := []domain.Session{}
+	for _, session := range s.sessions {
+		if session.UserID == userID {
+			result = append(result, session)
+		}
+	}
+	return result, nil
+}
+
+func (s *revokingSessions) RevokeAll(_ context.Context, userID int) error {
+	s.revoked = append(s.revoked, userID)
+	return nil
+}
+
+func TestEmailChangeService(t *testing.T) {
+	ctx := context.Background()
+	log, err := logger.New("error")
+	require.NoError(t, err)
+	crypto.SetDefaultHasher(crypto.NewPasswordHasher(crypto.NewBcryptHasher(4)))
+
+	hash, err := crypto.HashPassword("Current#Pass1")
+	require.NoError(t, err)
+	user := &domain.User{ID: 7, Email: "old@example.com", UserName: "user", Password: hash}
+
+	userRepo := new(MockUserRepository)
+	userRepo.On("GetUserByID", mock.Anything, user.ID).Return(user, nil)
+	userRepo.On("GetUserByEmail", mock.Anything, "taken@example.com").Return(&domain.User{ID: 8}, nil)
+	userRepo.On("GetUserByEmail", mock.Anything, mock.Anything).Return(nil, sql.ErrNoRows).Once()
+
+	repo := &memoryEmailChanges{changes: map[int]domain.EmailChange{}, applied: map[int]string{}}
+	cache := &memoryCache{values: map[string]string{}}
+	cache.On("DeleteUser", mock.Anything, user.ID).Return(nil)
+	sessions := &revokingSessions{}
+	sender := &recordingEmailChangeSender{}
+	svc := NewEmailChangeService(repo, userRepo, cache, sessions, nil, sender,
+		config.EmailVerificationConfig{ChangeTTL: time.Hour}, log)
+
+	requestTests := []struct {
+		name     string
+		newEmail string
+		password string
+		wantErr  error
+	}{
+		{name: "wrong password", newEmail: "new@example.com", password: "wrong", wantErr: errors.ErrInvalidLogin},
+		{name: "same address", newEmail: "OLD@example.com", password: "Current#Pass1", wantErr: errors.ErrValidation},
+		{name: "address taken", newEmail: "taken@example.com", password: "Current#Pass1", wantErr: errors.ErrUserExists},
+		{name: "valid request", newEmail: "new@example.com", password: "Current#Pass1"},
+	}
+	for _, tt := range requestTests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantErr, svc.Request(ctx, user.ID, "", tt.newEmail, tt.password, ""))
+		})
+	}
+	require.Len(t, sender.confirmTokens, 1)
+	require.Len(t, sender.cancelTokens, 1)
+
+	// Токен отмены не подтверждает смену, и наоборот
+	assert.Equal(t, errors.ErrInvalidToken, svc.Confirm(ctx, sender.cancelTokens[0]))
+	assert.Equal(t, errors.ErrInvalidToken, svc.Cancel(ctx, sender.confirmTokens[0]))
+
+	// Адрес заняли после создания заявки: подтверждение отклоняется, заявка удаляется
+	userRepo.On("GetUserByEmail", mock.Anything, "new@example.com").Return(&domain.User{ID: 9}, nil).Once()
+	assert.Equal(t, errors.ErrUserExists, svc.Confirm(ctx, sender.confirmTokens[0]))
+	assert.Empty(t, repo.changes)
+
+	// Отмена со старого адреса завершает все сессии
+	userRepo.On("GetUserByEmail", mock.Anything, mock.Anything).Return(nil, sql.ErrNoRows)
+	require.NoError(t, svc.Request(ctx, user.ID, "", "new@example.com", "Current#Pass1", ""))
+	require.NoError(t, svc.Cancel(ctx, sender.cancelTokens[1]))
+	assert.Equal(t, []int{user.ID}, sessions.revoked)
+	assert.Equal(t, errors.ErrInvalidToken, svc.Confirm(ctx, sender.confirmTokens[1]))
+
+	// Заявки, в том числе с неверным паролем, ограничены по частоте
+	assert.Equal(t, errors.ErrTooManyRequests, svc.Request(ctx, user.ID, "", "new@example.com", "Current#Pass1", ""))
+	delete(cache.values, emailChangeRequestsKey(user.ID))
+
+	// Подтверждение меняет адрес и сбрасывает кэш пользователя
+	require.NoError(t, svc.Request(ctx, user.ID, "", "new@example.com", "Current#Pass1", ""))
+	require.NoError(t, svc.Confirm(ctx, sender.confirmTokens[2]))
+	assert.Equal(t, "new@example.com", repo.applied[user.ID])
+	cache.AssertCalled(t, "DeleteUser", mock.Anything, user.ID)
+	assert.Equal(t, errors.ErrInvalidToken, svc.Confirm(ctx, sender.confirmTokens[2]))
+
+	// После подтверждения ссылка со старого адреса возвращает его и завершает все сессии
+	require.NoError(t, svc.Cancel(ctx, sender.cancelTokens[2]))
+	assert.Equal(t, "old@example.com", repo.applied[user.ID])
+	assert.Equal(t, []int{user.ID, user.ID}, sessions.revoked)
+	assert.Equal(t, errors.ErrInvalidToken, svc.Cancel(ctx, sender.cancelTokens[2]))
+}
+
+// codeMFA принимает единственный код TOTP
+type codeMFA struct {
+	MFAService
+	code string
+}
+
+func (m codeMFA) Verify(_ context.Context, _ int, code string) error {
+	if m.code == "" {
+		return errors.ErrMFANotEnrolled
+	}
+	if code != m.code {
+		return errors.ErrInvalidMFACode
+	}
+	return nil
+}
+
+func TestEmailChangeService_RequestWithoutPassword(t *testing.T) {
+	ctx := context.Background()
+	log, err := logger.New("error")
+	require.NoError(t, err)
+
+	withTOTP := &domain.User{ID: 7, Email: "totp@example.com"}
+	withoutTOTP := &domain.User{ID: 8, Email: "oauth@example.com"}
+	userRepo := new(MockUserRepository)
+	userRepo.On("GetUserByID", mock.Anything, withTOTP.ID).Return(withTOTP, nil)
+	userRepo.On("GetUserByID", mock.Anything, withoutTOTP.ID).Return(withoutTOTP, nil)
+	userRepo.On("GetUserByEmail", mock.Anything, mock.Anything).Return(nil, sql.ErrNoRows)
+
+	sessions := &revokingSessions{sessions: []domain.Session{
+		{ID: "fresh", UserID: withoutTOTP.ID, CreatedAt: time.Now().Add(-time.Minute)},
+		{ID: "stale", UserID: withoutTOTP.ID, CreatedAt: time.Now().Add(-time.Hour)},
+	}}
+	newService := func(mfa MFAService) *EmailChangeServiceImpl {
+		repo := &memoryEmailChanges{changes: map[int]domain.EmailChange{}, applied: map[int]string{}}
+		return NewEmailChangeService(repo, userRepo, &memoryCache{values: map[string]string{}}, sessions, mfa,
+			&recordingEmailChangeSender{}, config.EmailVerificationConfig{ChangeTTL: time.Hour}, log)
+	}
+
+	tests := []struct {
+		name      string
+		user      *domain.User
+		mfa       MFAService
+		sessionID string
+		code      string
+		wantErr   error
+	}{
+		{name: "valid totp code", user: withTOTP, mfa: codeMFA{code: "123456"}, code: "123456"},
+		{name: "wrong totp code", user: withTOTP, mfa: codeMFA{code: "123456"}, code: "654321", wantErr: errors.ErrInvalidMFACode},
+		{name: "access token alone", user: withTOTP, mfa: codeMFA{code: "123456"}, wantErr: errors.ErrReauthRequired},
+		{name: "code without totp", user: withoutTOTP, mfa: codeMFA{}, code: "123456", wantErr: errors.ErrReauthRequired},
+		{name: "recent sign-in", user: withoutTOTP, mfa: codeMFA{}, sessionID: "fresh"},
+		{name: "old sign-in", user: withoutTOTP, mfa: codeMFA{}, sessionID: "stale", wantErr: errors.ErrReauthRequired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := newService(tt.mfa).Request(ctx, tt.user.ID, tt.sessionID, "new@example.com", "", tt.code)
+			assert.Equal(t, tt.wantErr, err)
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	stderrors "errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Alias1177/Auth/internal/config"
+	"github.com/Alias1177/Auth/internal/domain"
+	"github.com/Alias1177/Auth/internal/errors"
+	"github.com/Alias1177/Auth/pkg/jwt"
+	"github.com/Alias1177/Auth/pkg/logger"
+)
+
+// Ограничение повторной отправки письма подтверждения на один адрес
+const (
+	maxVerificationEmails   = 3
+	verificationEmailWindow = time.Hour
+)
+
+// EmailVerificationRepository хранилище отметки о подтверждении почты
+type EmailVerificationRepository interface {
+	MarkEmailVerified(ctx context.Context, userID int) error
+}
+
+// EmailVerificationSender отправка письма с токеном подтверждения
+type EmailVerificationSender interface {
+	SendEmailVerification(ctx context.Context, email, username, token string) error
+}
+
+// EmailVerificationService подтверждение адреса почты новых пользователей
+type EmailVerificationService interface {
+	Required() bool
+	Send(ctx context.Context, user *domain.User) error
+	Resend(ctx context.Context, email string) error
+	Verify(ctx context.Context, token string) error
+}
+
+// EmailVerificationServiceImpl реализация на подписанных JWT.
+// Действует только последний отправленный токен: его хеш хранится в Redis и удаляется при подтверждении.
+type EmailVerificationServiceImpl struct {
+	repo         EmailVerificationRepository
+	userRepo     UserRepository
+	cache        UserCache
+	tokenManager TokenManager
+	sender       EmailVerificationSender
+	cfg          config.EmailVerificationConfig
+	logger       *logger.Logger
+}
+
+// NewEmailVerificationService создает новый экземпляр сервиса подтверждения почты
+func NewEmailVerificationService(
+	repo EmailVerificationRepository,
+	userRepo UserRepository,
+	cache UserCache,
+	tokenManager TokenManager,
+	sender EmailVerificationSender,
+	cfg config.EmailVerificationConfig,
+	logger *logger.Logger,
+) *EmailVerificationServiceImpl {
+	return &EmailVerificationServiceImpl{
+		repo:         repo,
+		userRepo:     userRepo,
+		cache:        cache,
+		tokenManager: tokenManager,
+		sender:       sender,
+		cfg:          cfg,
+		logger:       logger,
+	}
+}
+
+// Required сообщает, запрещен ли вход до подтверждения почты
+func (s *EmailVerificationServiceImpl) Required() bool {
+	return s.cfg.Required
+}
+
+// Send выпускает новый токен подтверждения и отправляет письмо; ранее отправленные токены перестают действовать
+func (s *EmailVerificationServiceImpl) Send(ctx context.Context, user *domain.User) error {
+	claims := domain.UserClaims{
+		UserID: strconv.Itoa(user.ID),
+		Email:  user.Email,
+	}
+	token, err := s.tokenManager.GenerateScopedToken(claims, jwt.TokenTypeEmailVerification, s.cfg.TokenTTL)
+	if err != nil {
+		return err
+	}
+	if err := s.cache.SetWithTTL(ctx, emailVerificationKey(user.ID), hashVerificationToken(token), s.cfg.TokenTTL); err != nil {
+		return err
+	}
+	if err := s.sender.SendEmailVerification(ctx, user.Email, user.UserName, token); err != nil {
+		return err
+	}
+	s.logger.Infow("Email verification sent", "user_id", user.ID)
+	return nil
+}
+
+// Resend повторно отправляет письмо. Для неизвестного или уже подтвержденного адреса
+// ничего не делает и не сообщает об этом, чтобы не раскрывать наличие аккаунта.
+func (s *EmailVerificationServiceImpl) Resend(ctx context.Context, email string) error {
+	email = strings.TrimSpace(email)
+	attempts, err := s.cache.Increment(ctx, verificationResendKey(strings.ToLower(email)), verificationEmailWindow)
+	if err != nil {
+		return err
+	}
+	if attempts > maxVerificationEmails {
+		return errors.ErrTooManyRequests
+	}
+
+	user, err := s.userRepo.GetUserByEmail(ctx, email)
+	if err != nil {
+		if stderrors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+	if user.EmailVerified() {
+		return nil
+	}
+	return s.Send(ctx, user)
+}
+
+// Verify подтверждает адрес по токену из письма. Токен одноразовый.
+func (s *EmailVerificationServiceImpl) Verify(ctx context.Context, token string) error {
+	claims, err := s.tokenManager.ValidateScopedToken(token, jwt.TokenTypeEmailVerification)
+	if err != nil {
+		return errors.ErrInvalidToken
+	}
+	userID, err := strconv.Atoi(claims.UserID)
+	if err != nil {
+		return errors.ErrInvalidToken
+	}
+
+	stored, err := s.cache.Get(ctx, emailVerificationKey(userID))
+	if err != nil || stored != hashVerificationToken(token) {
+		return errors.ErrInvalidToken
+	}
+	if err := s.cache.Delete(ctx, emailVerificationKey(userID)); err != nil {
+		return err
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		if stderrors.Is(err, sql.ErrNoRows) {
+			return errors.ErrInvalidToken
+		}
+		return err
+	}
+	// Токен выпущен для прежнего адреса
+	if !strings.EqualFold(user.Email, claims.Email) {
+		return errors.ErrInvalidToken
+	}
+
+	if err := s.repo.MarkEmailVerified(ctx, userID); err != nil && !stderrors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	s.logger.Infow("Email verified", "user_id", userID)
+	return nil
+}
+
+func hashVerificationToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func emailVerificationKey(userID int) string {
+	return fmt.Sprintf("email_verification:%d", userID)
+}
+
+func verificationResendKey(email string) string {
+	return fmt.Sprintf("email_verification:resend:%s", email)
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/Alias1177/Auth/internal/config"
+	"github.com/Alias1177/Auth/internal/domain"
+	"github.com/Alias1177/Auth/internal/errors"
+	"github.com/Alias1177/Auth/pkg/jwt"
+	"github.com/Alias1177/Auth/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingSender запоминает отправленные токены
+type recordingSender struct {
+	tokens []string
+}
+
+func (s *recordingSender) SendEmailVerification(_ context.Context, _, _, token string) error {
+	s.tokens = append(s.tokens, token)
+	return nil
+}
+
+// verifiedUsers отмечает подтвержденных пользователей
+type verifiedUsers map[int]bool
+
+func (v verifiedUsers) MarkEmailVerified(_ context.Context, userID int) error {
+	if v[userID] {
+		return sql.ErrNoRows
+	}
+	v[userID] = true
+	return nil
+}
+
+func TestEmailVerificationService_Verify(t *testing.T) {
+	ctx := context.Background()
+	log, err := logger.New("error")
+	require.NoError(t, err)
+	tokenManager, err := jwt.NewJWTTokenManager(config.JWTConfig{Secret: "secret"})
+	require.NoError(t, err)
+
+	user := &domain.User{ID: 7, Email: "user@example.com", UserName: "user"}
+	userRepo := new(MockUserRepository)
+	userRepo.On("GetUserByID", mock.Anything, user.ID).Return(user, nil)
+	userRepo.On("GetUserByEmail", mock.Anything, user.Email).Return(user, nil)
+	userRepo.On("GetUserByEmail", mock.Anything, mock.Anything).Return(nil, sql.ErrNoRows)
+
+	repo := verifiedUsers{}
+	sender := &recordingSender{}
+	cache := &memoryCache{values: map[string]string{}}
+	svc := NewEmailVerificationService(repo, userRepo, cache, tokenManager, sender,
+		config.EmailVerificationConfig{TokenTTL: time.Hour}, log)
+
+	require.NoError(t, svc.Send(ctx, user))
+	require.NoError(t, svc.Resend(ctx, user.Email))
+	require.NoError(t, svc.Resend(ctx, "unknown@example.com"))
+	require.Len(t, sender.tokens, 2)
+
+	mfaToken, err := tokenManager.GenerateScopedToken(domain.UserClaims{UserID: "7", Email: user.Email}, jwt.TokenTypeMFAPending, time.Minute)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{name: "token of another type", token: mfaToken, wantErr: errors.ErrInvalidToken},
+		{name: "superseded token", token: sender.tokens[0], wantErr: errors.ErrInvalidToken},
+		{name: "latest token", token: sender.tokens[1]},
+		{name: "token is single use", token: sender.tokens[1], wantErr: errors.ErrInvalidToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantErr, svc.Verify(ctx, tt.token))
+		})
+	}
+	assert.True(t, repo[user.ID])
+}
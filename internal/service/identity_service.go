@@ -0,0 +1,285 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Alias1177/Auth/internal/config"
+	"github.com/Alias1177/Auth/internal/domain"
+	"github.com/Alias1177/Auth/internal/errors"
+	"github.com/Alias1177/Auth/pkg/logger"
+)
+
+// identityLinkTTL время, за которое пользователь должен пройти вход у провайдера при привязке
+const identityLinkTTL = 10 * time.Minute
+
+// IdentityRepository хранилище привязанных внешних учетных записей
+type IdentityRepository interface {
+	GetUserIdentity(ctx context.Context, provider, providerUserID string) (*domain.UserIdentity, error)
+	ListUserIdentities(ctx context.Context, userID int) ([]domain.UserIdentity, error)
+	CreateUserIdentity(ctx context.Context, identity *domain.UserIdentity) error
+	UpdateUserIdentityLogin(ctx context.Context, id int, email string, emailVerified bool) error
+	DeleteUserIdentity(ctx context.Context, userID int, provider string) error
+}
+
+// IdentityService вход через внешних провайдеров и привязка их учетных записей.
+// Пользователь находится по идентификатору у провайдера; совпадение адреса почты само по себе
+// учетные записи не объединяет.
+type IdentityService interface {
+	SignIn(ctx context.Context, external domain.ExternalIdentity) (*domain.User, error)
+	StartLink(ctx context.Context, userID int, provider string) (string, error)
+	CompleteLink(ctx context.Context, linkToken string, external domain.ExternalIdentity) (*domain.UserIdentity, error)
+	List(ctx context.Context, userID int) ([]domain.UserIdentity, error)
+	Unlink(ctx context.Context, userID int, provider string) error
+}
+
+// IdentityServiceImpl реализация сервиса внешних учетных записей
+type IdentityServiceImpl struct {
+	repo        IdentityRepository
+	userRepo    UserRepository
+	credentials WebAuthnRepository
+	cache       UserCache
+	cfg         config.IdentityConfig
+	logger      *logger.Logger
+}
+
+// NewIdentityService создает новый экземпляр сервиса внешних учетных записей
+func NewIdentityService(
+	repo IdentityRepository,
+	userRepo UserRepository,
+	credentials WebAuthnRepository,
+	cache UserCache,
+	cfg config.IdentityConfig,
+	logger *logger.Logger,
+) *IdentityServiceImpl {
+	return &IdentityServiceImpl{
+		repo:        repo,
+		userRepo:    userRepo,
+		credentials: credentials,
+		cache:       cache,
+		cfg:         cfg,
+		logger:      logger,
+	}
+}
+
+// identityLink заявка на привязку учетной записи провайдера, хранится в Redis до возврата от провайдера
+type identityLink struct {
+	UserID   int    `json:"user_id"`
+	Provider string `json:"provider"`
+}
+
+// SignIn возвращает пользователя внешней учетной записи. При первом входе учетная запись привязывается
+// к пользователю с тем же адресом, только если адрес подтвержден и провайдером, и у нас, и это разрешено
+// настройками; иначе возвращается ErrAccountLinkRequired. Для нового адреса создается пользователь.
+func (s *IdentityServiceImpl) SignIn(ctx context.Context, external domain.ExternalIdentity) (*domain.User, error) {
+	if external.Provider == "" || external.ProviderUserID == "" {
+		return nil, errors.ErrValidation
+	}
+
+	identity, err := s.repo.GetUserIdentity(ctx, external.Provider, external.ProviderUserID)
+	if err == nil {
+		if err := s.repo.UpdateUserIdentityLogin(ctx, identity.ID, external.Email, external.EmailVerified); err != nil {
+			s.logger.Errorw("Failed to update identity login", "user_id", identity.UserID, "provider", identity.Provider, "error", err)
+		}
+		return s.userRepo.GetUserByID(ctx, identity.UserID)
+	}
+	if !stderrors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	if external.Email == "" {
+		// Без адреса нельзя ни создать пользователя, ни найти существующего
+		return nil, errors.ErrValidation
+	}
+	user, err := s.userRepo.GetUserByEmail(ctx, external.Email)
+	if err == nil {
+		if !s.canAutoLink(user, external) {
+			s.logger.Warnw("External sign-in matches an existing account, explicit linking required",
+				"user_id", user.ID, "provider", external.Provider, "provider_email_verified", external.EmailVerified)
+			return nil, errors.ErrAccountLinkRequired
+		}
+		if err := s.link(ctx, user.ID, external); err != nil {
+			return nil, err
+		}
+		s.logger.Infow("External account linked by verified email", "user_id", user.ID, "provider", external.Provider)
+		return user, nil
+	}
+	if !stderrors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	user = &domain.User{
+		Email:    external.Email,
+		UserName: external.UserName,
+		// Пароль не нужен: пользователь входит через провайдера
+	}
+	if external.EmailVerified {
+		verifiedAt := time.Now()
+		user.EmailVerifiedAt = &verifiedAt
+	}
+	if err := s.userRepo.CreateUser(ctx, user); err != nil {
+		return nil, err
+	}
+	if err := s.link(ctx, user.ID, external); err != nil {
+		return nil, err
+	}
+
+	s.logger.Infow("User created from external account", "user_id", user.ID, "provider", external.Provider)
+	return user, nil
+}
+
+// StartLink начинает привязку учетной записи провайдера к вошедшему пользователю.
+// Возвращает одноразовый токен, который нужно предъявить после возврата от провайдера.
+func (s *IdentityServiceImpl) StartLink(ctx context.Context, userID int, provider string) (string, error) {
+	if provider == "" {
+		return "", errors.ErrValidation
+	}
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+
+	data, err := json.Marshal(identityLink{UserID: userID, Provider: provider})
+	if err != nil {
+		return "", err
+	}
+	if err := s.cache.SetWithTTL(ctx, identityLinkKey(token), string(data), identityLinkTTL); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// CompleteLink привязывает учетную запись, с которой пользователь вернулся от провайдера.
+// Адрес почты не проверяется: владение учетной записью подтверждено входом у провайдера.
+func (s *IdentityServiceImpl) CompleteLink(
+	ctx context.Context,
+	linkToken string,
+	external domain.ExternalIdentity,
+) (*domain.UserIdentity, error) {
+	key := identityLinkKey(linkToken)
+	data, err := s.cache.Get(ctx, key)
+	if err != nil {
+		return nil, errors.ErrInvalidToken
+	}
+	// Токен одноразовый, даже если два возврата от провайдера пришли одновременно
+	uses, err := s.cache.Increment(ctx, key+":used", identityLinkTTL)
+	if err != nil {
+		return nil, err
+	}
+	if uses > 1 {
+		return nil, errors.ErrInvalidToken
+	}
+	if err := s.cache.Delete(ctx, key); err != nil {
+		return nil, err
+	}
+
+	var link identityLink
+	if err := json.Unmarshal([]byte(data), &link); err != nil {
+		return nil, err
+	}
+	if link.Provider != external.Provider || external.ProviderUserID == "" {
+		return nil, errors.ErrInvalidToken
+	}
+
+	identity, err := s.repo.GetUserIdentity(ctx, external.Provider, external.ProviderUserID)
+	if err == nil {
+		if identity.UserID != link.UserID {
+			s.logger.Warnw("External account is linked to another user", "user_id", link.UserID, "provider", external.Provider)
+			return nil, errors.ErrIdentityLinked
+		}
+		return identity, nil
+	}
+	if !stderrors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	identity = newUserIdentity(link.UserID, external)
+	if err := s.repo.CreateUserIdentity(ctx, identity); err != nil {
+		return nil, err
+	}
+
+	s.logger.Infow("External account linked", "user_id", link.UserID, "provider", external.Provider)
+	return identity, nil
+}
+
+// List возвращает внешние учетные записи пользователя
+func (s *IdentityServiceImpl) List(ctx context.Context, userID int) ([]domain.UserIdentity, error) {
+	return s.repo.ListUserIdentities(ctx, userID)
+}
+
+// Unlink отвязывает учетную запись провайдера. Последний способ входа отвязать нельзя:
+// пользователь без пароля и passkeys потерял бы доступ к учетной записи.
+func (s *IdentityServiceImpl) Unlink(ctx context.Context, userID int, provider string) error {
+	identities, err := s.repo.ListUserIdentities(ctx, userID)
+	if err != nil {
+		return err
+	}
+	linked := false
+	for _, identity := range identities {
+		if identity.Provider == provider {
+			linked = true
+		}
+	}
+	if !linked {
+		return errors.ErrNotFound
+	}
+
+	if len(identities) == 1 {
+		user, err := s.userRepo.GetUserByID(ctx, userID)
+		if err != nil {
+			return err
+		}
+		creds, err := s.credentials.ListWebAuthnCredentials(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if user.Password == "" && len(creds) == 0 {
+			return errors.ErrLastLoginMethod
+		}
+	}
+
+	if err := s.repo.DeleteUserIdentity(ctx, userID, provider); err != nil {
+		if stderrors.Is(err, sql.ErrNoRows) {
+			return errors.ErrNotFound
+		}
+		return err
+	}
+
+	s.logger.Infow("External account unlinked", "user_id", userID, "provider", provider)
+	return nil
+}
+
+// canAutoLink разрешает привязку по адресу, только если обе стороны подтвердили владение им:
+// иначе учетной записью завладел бы тот, кто указал чужой адрес у провайдера или при регистрации у нас
+func (s *IdentityServiceImpl) canAutoLink(user *domain.User, external domain.ExternalIdentity) bool {
+	return s.cfg.AutoLinkVerifiedEmail &&
+		external.EmailVerified &&
+		user.EmailVerified() &&
+		strings.EqualFold(user.Email, external.Email)
+}
+
+func (s *IdentityServiceImpl) link(ctx context.Context, userID int, external domain.ExternalIdentity) error {
+	return s.repo.CreateUserIdentity(ctx, newUserIdentity(userID, external))
+}
+
+func newUserIdentity(userID int, external domain.ExternalIdentity) *domain.UserIdentity {
+	return &domain.UserIdentity{
+		UserID:         userID,
+		Provider:       external.Provider,
+		ProviderUserID: external.ProviderUserID,
+		Email:          external.Email,
+		EmailVerified:  external.EmailVerified,
+	}
+}
+
+func identityLinkKey(token string) string {
+	return fmt.Sprintf("identity_link:%s", hashVerificationToken(token))
+}
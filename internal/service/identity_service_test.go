@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/Alias1177/Auth/internal/config"
+	"github.com/Alias1177/Auth/internal/domain"
+	"github.com/Alias1177/Auth/internal/errors"
+	"github.com/Alias1177/Auth/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryIdentities хранилище привязанных учетных записей в памяти
+type memoryIdentities struct {
+	identities []domain.UserIdentity
+}
+
+func (m *memoryIdentities) GetUserIdentity(_ context.Context, provider, providerUserID string) (*domain.UserIdentity, error) {
+	for _, identity := range m.identities {
+		if identity.Provider == provider && identity.ProviderUserID == providerUserID {
+			return &identity, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (m *memoryIdentities) ListUserIdentities(_ context.Context, userID int) ([]domain.UserIdentity, error) {
+	result := []domain.UserIdentity{}
+	for _, identity := range m.identities {
+		if identity.UserID == userID {
+			result = append(result, identity)
+		}
+	}
+	return result, nil
+}
+
+func (m *memoryIdentities) CreateUserIdentity(_ context.Context, identity *domain.UserIdentity) error {
+	for _, existing := range m.identities {
+		if existing.Provider == identity.Provider &&
+			(existing.ProviderUserID == identity.ProviderUserID || existing.UserID == identity.UserID) {
+			return errors.ErrIdentityLinked
+		}
+	}
+	identity.ID = len(m.identities) + 1
+	m.identities = append(m.identities, *identity)
+	return nil
+}
+
+func (m *memoryIdentities) UpdateUserIdentityLogin(_ context.Context, id int, email string, emailVerified bool) error {
+	for i := range m.identities {
+		if m.identities[i].ID == id {
+			m.identities[i].Email = email
+			m.identities[i].EmailVerified = emailVerified
+		}
+	}
+	return nil
+}
+
+func (m *memoryIdentities) DeleteUserIdentity(_ context.Context, userID int, provider string) error {
+	for i, identity := range m.identities {
+		if identity.UserID == userID && identity.Provider == provider {
+			m.identities = append(m.identities[:i], m.identities[i+1:]...)
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+func TestIdentityService_SignIn(t *testing.T) {
+	ctx := context.Background()
+	log, err := logger.New("error")
+	require.NoError(t, err)
+
+	verifiedAt := time.Now()
+	verified := &domain.User{ID: 1, Email: "verified@example.com", Password: "hash", EmailVerifiedAt: &verifiedAt}
+	unverified := &domain.User{ID: 2, Email: "unverified@example.com", Password: "hash"}
+
+	userRepo := new(MockUserRepository)
+	userRepo.On("GetUserByID", mock.Anything, verified.ID).Return(verified, nil)
+	userRepo.On("GetUserByEmail", mock.Anything, verified.Email).Return(verified, nil)
+	userRepo.On("GetUserByEmail", mock.Anything, unverified.Email).Return(unverified, nil)
+	userRepo.On("GetUserByEmail", mock.Anything, mock.Anything).Return(nil, sql.ErrNoRows)
+	userRepo.On("CreateUser", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		args.Get(1).(*domain.User).ID = 3
+	}).Return(nil)
+
+	google := func(id, email string, emailVerified bool) domain.ExternalIdentity {
+		return domain.ExternalIdentity{Provider: "google", ProviderUserID: id, Email: email, EmailVerified: emailVerified}
+	}
+
+	tests := []struct {
+		name     string
+		autoLink bool
+		external domain.ExternalIdentity
+		wantUser int
+		wantErr  error
+	}{
+		{name: "verified on both sides", autoLink: true, external: google("g-1", verified.Email, true), wantUser: verified.ID},
+		{name: "auto-linking disabled", autoLink: false, external: google("g-1", verified.Email, true), wantErr: errors.ErrAccountLinkRequired},
+		{name: "provider did not verify email", autoLink: true, external: google("g-1", verified.Email, false), wantErr: errors.ErrAccountLinkRequired},
+		{name: "local email not verified", autoLink: true, external: google("g-2", unverified.Email, true), wantErr: errors.ErrAccountLinkRequired},
+		{name: "new email creates user", autoLink: true, external: google("g-3", "new@example.com", true), wantUser: 3},
+		{name: "no email", autoLink: true, external: google("g-4", "", true), wantErr: errors.ErrValidation},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &memoryIdentities{}
+			svc := NewIdentityService(repo, userRepo, &memoryWebAuthnRepository{}, nil, config.IdentityConfig{AutoLinkVerifiedEmail: tt.autoLink}, log)
+
+			user, err := svc.SignIn(ctx, tt.external)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Empty(t, repo.identities, "rejected sign-in must not link the account")
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantUser, user.ID)
+			require.Len(t, repo.identities, 1)
+			assert.Equal(t, tt.wantUser, repo.identities[0].UserID)
+		})
+	}
+
+	t.Run("linked identity wins over email", func(t *testing.T) {
+		repo := &memoryIdentities{identities: []domain.UserIdentity{{ID: 1, UserID: verified.ID, Provider: "google", ProviderUserID: "g-1"}}}
+		svc := NewIdentityService(repo, userRepo, &memoryWebAuthnRepository{}, nil, config.IdentityConfig{}, log)
+
+		user, err := svc.SignIn(ctx, google("g-1", unverified.Email, false))
+		require.NoError(t, err)
+		assert.Equal(t, verified.ID, user.ID)
+		assert.Equal(t, unverified.Email, repo.identities[0].Email)
+	})
+}
+
+func TestIdentityService_LinkAndUnlink(t *testing.T) {
+	ctx := context.Background()
+	log, err := logger.New("error")
+	require.NoError(t, err)
+
+	withPassword := &domain.User{ID: 1, Email: "user@example.com", Password: "hash"}
+	passwordless := &domain.User{ID: 2, Email: "oauth@example.com"}
+	userRepo := new(MockUserRepository)
+	userRepo.On("GetUserByID", mock.Anything, withPassword.ID).Return(withPassword, nil)
+	userRepo.On("GetUserByID", mock.Anything, passwordless.ID).Return(passwordless, nil)
+
+	repo := &memoryIdentities{identities: []domain.UserIdentity{{ID: 1, UserID: passwordless.ID, Provider: "google", ProviderUserID: "g-2"}}}
+	credentials := &memoryWebAuthnRepository{}
+	svc := NewIdentityService(repo, userRepo, credentials, &memoryCache{values: map[string]string{}}, config.IdentityConfig{}, log)
+
+	// Учетная запись привязывается по одноразовому токену, выданному вошедшему пользователю
+	token, err := svc.StartLink(ctx, withPassword.ID, "google")
+	require.NoError(t, err)
+	_, err = svc.CompleteLink(ctx, token, domain.ExternalIdentity{Provider: "github", ProviderUserID: "g-1"})
+	assert.ErrorIs(t, err, errors.ErrInvalidToken, "token is bound to the provider")
+
+	token, err = svc.StartLink(ctx, withPassword.ID, "google")
+	require.NoError(t, err)
+	identity, err := svc.CompleteLink(ctx, token, domain.ExternalIdentity{Provider: "google", ProviderUserID: "g-1"})
+	require.NoError(t, err)
+	assert.Equal(t, withPassword.ID, identity.UserID)
+	_, err = svc.CompleteLink(ctx, token, domain.ExternalIdentity{Provider: "google", ProviderUserID: "g-1"})
+	assert.ErrorIs(t, err, errors.ErrInvalidToken, "token is single-use")
+
+	// Учетная запись другого пользователя не перепривязывается
+	token, err = svc.StartLink(ctx, withPassword.ID, "google")
+	require.NoError(t, err)
+	_, err = svc.CompleteLink(ctx, token, domain.ExternalIdentity{Provider: "google", ProviderUserID: "g-2"})
+	assert.ErrorIs(t, err, errors.ErrIdentityLinked)
+
+	assert.ErrorIs(t, svc.Unlink(ctx, passwordless.ID, "google"), errors.ErrLastLoginMethod)
+	assert.ErrorIs(t, svc.Unlink(ctx, withPassword.ID, "github"), errors.ErrNotFound)
+	require.NoError(t, svc.Unlink(ctx, withPassword.ID, "google"))
+
+	// Passkey остается способом входа без пароля
+	credentials.creds = append(credentials.creds, domain.WebAuthnCredential{ID: []byte("key"), UserID: passwordless.ID})
+	require.NoError(t, svc.Unlink(ctx, passwordless.ID, "google"))
+	identities, err := svc.List(ctx, passwordless.ID)
+	require.NoError(t, err)
+	assert.Empty(t, identities)
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"time"
 
 	"github.com/Alias1177/Auth/internal/domain"
 )
@@ -19,8 +20,8 @@ type UserService interface {
 type AuthService interface {
 	Login(ctx context.Context, email, password string) (*domain.User, error)
 	Register(ctx context.Context, user *domain.User) error
-	RefreshToken(ctx context.Context, refreshToken string) (string, error)
-	Logout(ctx context.Context, userID string) error
+	Logout(ctx context.Context, claims *domain.UserClaims) error
+	LogoutAll(ctx context.Context, claims *domain.UserClaims) error
 }
 
 // TokenService интерфейс для работы с токенами
@@ -31,6 +32,11 @@ type TokenService interface {
 	ValidateRefreshToken(token string) (*domain.UserClaims, error)
 }
 
+// PasswordChangedSender уведомление пользователя о смене пароля
+type PasswordChangedSender interface {
+	SendPasswordChanged(ctx context.Context, email, username string, changedAt time.Time) error
+}
+
 // PasswordService интерфейс для работы с паролями
 type PasswordService interface {
 	HashPassword(password string) (string, error)
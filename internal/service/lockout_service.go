@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	stderrors "errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Alias1177/Auth/internal/config"
+	"github.com/Alias1177/Auth/internal/domain"
+	"github.com/Alias1177/Auth/internal/errors"
+	"github.com/Alias1177/Auth/pkg/jwt"
+	"github.com/Alias1177/Auth/pkg/logger"
+)
+
+// LockoutRepository хранилище счетчиков неудачных входов
+type LockoutRepository interface {
+	RecordFailedLogin(ctx context.Context, userID int, resetAfter time.Duration) (int, error)
+	LockUser(ctx context.Context, userID int, until time.Time) error
+	ResetFailedLogins(ctx context.Context, userID int) error
+}
+
+// AccountUnlockSender отправка письма со ссылкой разблокировки
+type AccountUnlockSender interface {
+	SendAccountUnlock(ctx context.Context, email, username, token string, lockedUntil time.Time) error
+}
+
+// LockoutService блокировка входа по паролю после серии неудачных попыток.
+// Для несуществующих адресов ведется такой же учет в Redis, чтобы ответы не раскрывали наличие аккаунта.
+type LockoutService interface {
+	Check(ctx context.Context, email string, user *domain.User) error
+	RecordFailure(ctx context.Context, email string, user *domain.User) error
+	RecordSuccess(ctx context.Context, user *domain.User) error
+	Unlock(ctx context.Context, token string) error
+	UnlockUser(ctx context.Context, userID int) error
+}
+
+// LockoutServiceImpl реализация сервиса блокировки
+type LockoutServiceImpl struct {
+	repo         LockoutRepository
+	cache        UserCache
+	tokenManager TokenManager
+	sender       AccountUnlockSender
+	cfg          config.LockoutConfig
+	logger       *logger.Logger
+}
+
+// NewLockoutService создает новый экземпляр сервиса блокировки
+func NewLockoutService(
+	repo LockoutRepository,
+	cache UserCache,
+	tokenManager TokenManager,
+	sender AccountUnlockSender,
+	cfg config.LockoutConfig,
+	logger *logger.Logger,
+) *LockoutServiceImpl {
+	return &LockoutServiceImpl{
+		repo:         repo,
+		cache:        cache,
+		tokenManager: tokenManager,
+		sender:       sender,
+		cfg:          cfg,
+		logger:       logger,
+	}
+}
+
+// Check возвращает ErrAccountLocked, если вход для адреса заблокирован
+func (s *LockoutServiceImpl) Check(ctx context.Context, email string, user *domain.User) error {
+	if user != nil {
+		if user.IsLocked(time.Now()) {
+			return errors.ErrAccountLocked
+		}
+		return nil
+	}
+	if _, err := s.cache.Get(ctx, unknownLockKey(email)); err == nil {
+		return errors.ErrAccountLocked
+	}
+	return nil
+}
+
+// RecordFailure учитывает неудачный вход. Возвращает ErrAccountLocked, если эта попытка привела к блокировке.
+func (s *LockoutServiceImpl) RecordFailure(ctx context.Context, email string, user *domain.User) error {
+	if user == nil {
+		return s.recordUnknownFailure(ctx, email)
+	}
+
+	attempts, err := s.repo.RecordFailedLogin(ctx, user.ID, s.cfg.ResetAfter)
+	if err != nil {
+		return err
+	}
+	duration := lockDuration(attempts, s.cfg)
+	if duration == 0 {
+		return nil
+	}
+
+	until := time.Now().Add(duration)
+	if err := s.repo.LockUser(ctx, user.ID, until); err != nil {
+		return err
+	}
+	s.logger.Warnw("Account locked after failed logins", "user_id", user.ID, "attempts", attempts, "locked_until", until)
+
+	// Без письма блокировка все равно снимется по времени
+	if err := s.sendUnlock(ctx, user, until); err != nil {
+		s.logger.Errorw("Failed to send account unlock email", "user_id", user.ID, "error", err)
+	}
+	return errors.ErrAccountLocked
+}
+
+// RecordSuccess обнуляет счетчик после успешного входа
+func (s *LockoutServiceImpl) RecordSuccess(ctx context.Context, user *domain.User) error {
+	if user.FailedLoginAttempts == 0 && user.LockedUntil == nil {
+		return nil
+	}
+	return s.repo.ResetFailedLogins(ctx, user.ID)
+}
+
+// Unlock снимает блокировку по токену из письма. Токен одноразовый.
+func (s *LockoutServiceImpl) Unlock(ctx context.Context, token string) error {
+	claims, err := s.tokenManager.ValidateScopedToken(token, jwt.TokenTypeAccountUnlock)
+	if err != nil {
+		return errors.ErrInvalidToken
+	}
+	userID, err := strconv.Atoi(claims.UserID)
+	if err != nil {
+		return errors.ErrInvalidToken
+	}
+
+	stored, err := s.cache.Get(ctx, unlockTokenKey(userID))
+	if err != nil || stored != claims.TokenID {
+		return errors.ErrInvalidToken
+	}
+	if err := s.cache.Delete(ctx, unlockTokenKey(userID)); err != nil {
+		return err
+	}
+	return s.UnlockUser(ctx, userID)
+}
+
+// UnlockUser снимает блокировку пользователя (администратором); ErrUserNotFound, если пользователя нет
+func (s *LockoutServiceImpl) UnlockUser(ctx context.Context, userID int) error {
+	if err := s.repo.ResetFailedLogins(ctx, userID); err != nil {
+		if stderrors.Is(err, sql.ErrNoRows) {
+			return errors.ErrUserNotFound
+		}
+		return err
+	}
+	s.logger.Infow("Account unlocked", "user_id", userID)
+	return nil
+}
+
+// sendUnlock выпускает токен разблокировки и отправляет письмо
+func (s *LockoutServiceImpl) sendUnlock(ctx context.Context, user *domain.User, until time.Time) error {
+	claims := domain.UserClaims{UserID: strconv.Itoa(user.ID), Email: user.Email}
+	token, err := s.tokenManager.GenerateScopedToken(claims, jwt.TokenTypeAccountUnlock, s.cfg.UnlockTokenTTL)
+	if err != nil {
+		return err
+	}
+	issued, err := s.tokenManager.ValidateScopedToken(token, jwt.TokenTypeAccountUnlock)
+	if err != nil {
+		return err
+	}
+	if err := s.cache.SetWithTTL(ctx, unlockTokenKey(user.ID), issued.TokenID, s.cfg.UnlockTokenTTL); err != nil {
+		return err
+	}
+	return s.sender.SendAccountUnlock(ctx, user.Email, user.UserName, token, until)
+}
+
+// recordUnknownFailure повторяет расписание блокировок для адреса без аккаунта
+func (s *LockoutServiceImpl) recordUnknownFailure(ctx context.Context, email string) error {
+	attempts, err := s.cache.Increment(ctx, unknownAttemptsKey(email), s.cfg.ResetAfter)
+	if err != nil {
+		return err
+	}
+	duration := lockDuration(int(attempts), s.cfg)
+	if duration == 0 {
+		return nil
+	}
+	if err := s.cache.SetWithTTL(ctx, unknownLockKey(email), "1", duration); err != nil {
+		return err
+	}
+	return errors.ErrAccountLocked
+}
+
+// lockDuration срок блокировки после attempts неудач подряд: 0 до порога, затем удваивается до MaxDuration
+func lockDuration(attempts int, cfg config.LockoutConfig) time.Duration {
+	if cfg.Threshold <= 0 || attempts < cfg.Threshold {
+		return 0
+	}
+	duration := cfg.BaseDuration
+	for i := cfg.Threshold; i < attempts && duration < cfg.MaxDuration; i++ {
+		duration *= 2
+	}
+	if duration > cfg.MaxDuration {
+		duration = cfg.MaxDuration
+	}
+	return duration
+}
+
+func unlockTokenKey(userID int) string {
+	return fmt.Sprintf("lockout:unlock:%d", userID)
+}
+
+func unknownAttemptsKey(email string) string {
+	return fmt.Sprintf("lockout:unknown:attempts:%s", strings.ToLower(email))
+}
+
+func unknownLockKey(email string) string {
+	return fmt.Sprintf("lockout:unknown:locked:%s", strings.ToLower(email))
+}
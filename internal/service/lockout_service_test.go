@@ -0,0 +1,52 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Alias1177/Auth/internal/config"
+	"github.com/Alias1177/Auth/internal/errors"
+	"github.com/Alias1177/Auth/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockDuration(t *testing.T) {
+	cfg := config.LockoutConfig{Threshold: 5, BaseDuration: time.Minute, MaxDuration: 10 * time.Minute}
+
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{attempts: 1, want: 0},
+		{attempts: 4, want: 0},
+		{attempts: 5, want: time.Minute},
+		{attempts: 6, want: 2 * time.Minute},
+		{attempts: 8, want: 8 * time.Minute},
+		{attempts: 9, want: 10 * time.Minute},
+		{attempts: 1000, want: 10 * time.Minute},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, lockDuration(tt.attempts, cfg), "attempts=%d", tt.attempts)
+	}
+
+	assert.Zero(t, lockDuration(100, config.LockoutConfig{}), "lockout disabled")
+}
+
+func TestLockoutService_UnknownEmail(t *testing.T) {
+	ctx := context.Background()
+	log, err := logger.New("error")
+	require.NoError(t, err)
+
+	cfg := config.LockoutConfig{Threshold: 3, BaseDuration: time.Minute, MaxDuration: time.Hour, ResetAfter: time.Hour}
+	svc := NewLockoutService(nil, &memoryCache{values: map[string]string{}}, nil, nil, cfg, log)
+
+	// Адрес без аккаунта блокируется по тому же расписанию, что и существующий
+	require.NoError(t, svc.Check(ctx, "ghost@example.com", nil))
+	require.NoError(t, svc.RecordFailure(ctx, "ghost@example.com", nil))
+	require.NoError(t, svc.RecordFailure(ctx, "ghost@example.com", nil))
+	assert.Equal(t, errors.ErrAccountLocked, svc.RecordFailure(ctx, "ghost@example.com", nil))
+	assert.Equal(t, errors.ErrAccountLocked, svc.Check(ctx, "Ghost@example.com", nil))
+	assert.NoError(t, svc.Check(ctx, "other@example.com", nil))
+}
@@ -0,0 +1,239 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	stderrors "errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Alias1177/Auth/internal/domain"
+	"github.com/Alias1177/Auth/internal/errors"
+	"github.com/Alias1177/Auth/pkg/jwt"
+	"github.com/Alias1177/Auth/pkg/logger"
+	"github.com/Alias1177/Auth/pkg/totp"
+)
+
+// maxMFAAttempts число попыток ввода кода на один mfa_pending токен
+const maxMFAAttempts = 5
+
+// MFARepository хранилище настроек второго фактора
+type MFARepository interface {
+	GetUserMFA(ctx context.Context, userID int) (*domain.UserMFA, error)
+	SavePendingTOTP(ctx context.Context, userID int, secret string) error
+	EnableTOTP(ctx context.Context, userID int, step int64) error
+	UseTOTPStep(ctx context.Context, userID int, step int64) error
+	DeleteUserMFA(ctx context.Context, userID int) error
+}
+
+// MFAService регистрация TOTP и проверка второго фактора при входе
+type MFAService interface {
+	IsEnabled(ctx context.Context, userID int) (bool, error)
+	Enroll(ctx context.Context, userID int, email string) (*domain.TOTPEnrollment, error)
+	EnrollmentURI(ctx context.Context, userID int, email string) (string, error)
+	Confirm(ctx context.Context, userID int, code string) error
+	Disable(ctx context.Context, userID int, code string) error
+	Verify(ctx context.Context, userID int, code string) error
+	StartChallenge(claims domain.UserClaims) (string, error)
+	CompleteChallenge(ctx context.Context, mfaToken, code string) (*domain.UserClaims, error)
+}
+
+// MFAServiceImpl реализация сервиса второго фактора
+type MFAServiceImpl struct {
+	repo         MFARepository
+	cache        UserCache
+	tokenManager TokenManager
+	issuer       string
+	logger       *logger.Logger
+}
+
+// NewMFAService создает новый экземпляр сервиса второго фактора
+func NewMFAService(
+	repo MFARepository,
+	cache UserCache,
+	tokenManager TokenManager,
+	issuer string,
+	logger *logger.Logger,
+) *MFAServiceImpl {
+	return &MFAServiceImpl{
+		repo:         repo,
+		cache:        cache,
+		tokenManager: tokenManager,
+		issuer:       issuer,
+		logger:       logger,
+	}
+}
+
+// IsEnabled сообщает, включен ли у пользователя второй фактор
+func (s *MFAServiceImpl) IsEnabled(ctx context.Context, userID int) (bool, error) {
+	mfa, err := s.repo.GetUserMFA(ctx, userID)
+	if err != nil {
+		if stderrors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	return mfa.Enabled(), nil
+}
+
+// Enroll создает новый секрет TOTP. До подтверждения кодом второй фактор не действует,
+// повторный вызов заменяет неподтвержденный секрет.
+func (s *MFAServiceImpl) Enroll(ctx context.Context, userID int, email string) (*domain.TOTPEnrollment, error) {
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.SavePendingTOTP(ctx, userID, secret); err != nil {
+		if stderrors.Is(err, sql.ErrNoRows) {
+			return nil, errors.ErrMFAAlreadyEnabled
+		}
+		return nil, err
+	}
+	return &domain.TOTPEnrollment{
+		Secret: secret,
+		URI:    totp.URI(s.issuer, email, secret),
+	}, nil
+}
+
+// EnrollmentURI возвращает otpauth URI неподтвержденного секрета для отрисовки QR кода
+func (s *MFAServiceImpl) EnrollmentURI(ctx context.Context, userID int, email string) (string, error) {
+	mfa, err := s.getMFA(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	if mfa.Enabled() {
+		// Секрет включенного TOTP повторно не показывается
+		return "", errors.ErrMFAAlreadyEnabled
+	}
+	return totp.URI(s.issuer, email, mfa.TOTPSecret), nil
+}
+
+// Confirm включает второй фактор после ввода первого кода из приложения
+func (s *MFAServiceImpl) Confirm(ctx context.Context, userID int, code string) error {
+	mfa, err := s.getMFA(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if mfa.Enabled() {
+		return errors.ErrMFAAlreadyEnabled
+	}
+
+	step, err := totp.Validate(mfa.TOTPSecret, code, time.Now())
+	if err != nil {
+		return errors.ErrInvalidMFACode
+	}
+	if err := s.repo.EnableTOTP(ctx, userID, step); err != nil {
+		if stderrors.Is(err, sql.ErrNoRows) {
+			return errors.ErrMFAAlreadyEnabled
+		}
+		return err
+	}
+	s.logger.Infow("TOTP enabled", "user_id", userID)
+	return nil
+}
+
+// Disable отключает второй фактор; для включенного TOTP требуется действующий код
+func (s *MFAServiceImpl) Disable(ctx context.Context, userID int, code string) error {
+	mfa, err := s.getMFA(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if mfa.Enabled() {
+		if err := s.verify(ctx, mfa, code); err != nil {
+			return err
+		}
+	}
+	if err := s.repo.DeleteUserMFA(ctx, userID); err != nil {
+		return err
+	}
+	s.logger.Infow("TOTP disabled", "user_id", userID)
+	return nil
+}
+
+// Verify проверяет код включенного TOTP для подтверждения чувствительного действия
+func (s *MFAServiceImpl) Verify(ctx context.Context, userID int, code string) error {
+	mfa, err := s.getMFA(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !mfa.Enabled() {
+		return errors.ErrMFANotEnrolled
+	}
+	return s.verify(ctx, mfa, code)
+}
+
+// StartChallenge выпускает mfa_pending токен, подтверждающий, что пароль уже проверен
+func (s *MFAServiceImpl) StartChallenge(claims domain.UserClaims) (string, error) {
+	return s.tokenManager.GenerateScopedToken(claims, jwt.TokenTypeMFAPending, jwt.MFAPendingTokenTTL)
+}
+
+// CompleteChallenge проверяет код для mfa_pending токена и возвращает claims для выдачи токенов.
+// Токен одноразовый и допускает ограниченное число попыток ввода кода.
+func (s *MFAServiceImpl) CompleteChallenge(ctx context.Context, mfaToken, code string) (*domain.UserClaims, error) {
+	claims, err := s.tokenManager.ValidateScopedToken(mfaToken, jwt.TokenTypeMFAPending)
+	if err != nil || claims.TokenID == "" {
+		return nil, errors.ErrInvalidToken
+	}
+	userID, err := strconv.Atoi(claims.UserID)
+	if err != nil {
+		return nil, errors.ErrInvalidToken
+	}
+
+	attempts, err := s.cache.Increment(ctx, mfaAttemptsKey(claims.TokenID), jwt.MFAPendingTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+	if attempts > maxMFAAttempts {
+		// Токен исчерпан или уже обменян на пару токенов
+		return nil, errors.ErrInvalidToken
+	}
+
+	mfa, err := s.getMFA(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !mfa.Enabled() {
+		return nil, errors.ErrMFANotEnrolled
+	}
+	if err := s.verify(ctx, mfa, code); err != nil {
+		s.logger.Warnw("Invalid MFA code", "user_id", userID, "attempt", attempts)
+		return nil, err
+	}
+
+	// Сжигаем токен, чтобы его нельзя было обменять повторно
+	if err := s.cache.SetWithTTL(ctx, mfaAttemptsKey(claims.TokenID), strconv.Itoa(maxMFAAttempts+1), jwt.MFAPendingTokenTTL); err != nil {
+		return nil, err
+	}
+	return &domain.UserClaims{UserID: claims.UserID, Email: claims.Email}, nil
+}
+
+// verify проверяет код и отклоняет повторное использование кода того же шага
+func (s *MFAServiceImpl) verify(ctx context.Context, mfa *domain.UserMFA, code string) error {
+	step, err := totp.Validate(mfa.TOTPSecret, code, time.Now())
+	if err != nil {
+		return errors.ErrInvalidMFACode
+	}
+	if err := s.repo.UseTOTPStep(ctx, mfa.UserID, step); err != nil {
+		if stderrors.Is(err, sql.ErrNoRows) {
+			return errors.ErrInvalidMFACode
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *MFAServiceImpl) getMFA(ctx context.Context, userID int) (*domain.UserMFA, error) {
+	mfa, err := s.repo.GetUserMFA(ctx, userID)
+	if err != nil {
+		if stderrors.Is(err, sql.ErrNoRows) {
+			return nil, errors.ErrMFANotEnrolled
+		}
+		return nil, err
+	}
+	return mfa, nil
+}
+
+func mfaAttemptsKey(jti string) string {
+	return fmt.Sprintf("mfa:attempts:%s", jti)
+}
@@ -42,6 +42,21 @@ func (m *MockUserRepository) UpdateUser(ctx context.Context, user *domain.User)
 	return args.Error(0)
 }
 
+func (m *MockUserRepository) ResetPassword(ctx context.Context, user *domain.User) error {
+	args := m.Called(ctx, user)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) UpdatePasswordHash(ctx context.Context, userID int, hash string) error {
+	args := m.Called(ctx, userID, hash)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) UpdateUserProfile(ctx context.Context, user *domain.User, expectedUpdatedAt time.Time) error {
+	args := m.Called(ctx, user, expectedUpdatedAt)
+	return args.Error(0)
+}
+
 // --- MockTokenManager ---
 type MockTokenManager struct {
 	mock.Mock
@@ -112,6 +127,11 @@ func (m *MockUserCache) SaveUser(ctx context.Context, user *domain.User) error {
 	return args.Error(0)
 }
 
+func (m *MockUserCache) DeleteUser(ctx context.Context, id int) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
 func (m *MockUserCache) Get(ctx context.Context, key string) (string, error) {
 	args := m.Called(ctx, key)
 	return args.String(0), args.Error(1)
@@ -127,6 +147,11 @@ func (m *MockUserCache) Delete(ctx context.Context, key string) error {
 	return args.Error(0)
 }
 
+func (m *MockUserCache) Increment(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	args := m.Called(ctx, key, ttl)
+	return args.Get(0).(int64), args.Error(1)
+}
+
 // --- MockKafkaProducer ---
 type MockKafkaProducer struct {
 	mock.Mock
@@ -0,0 +1,247 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	stderrors "errors"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"time"
+
+	"github.com/Alias1177/Auth/internal/domain"
+	"github.com/Alias1177/Auth/internal/errors"
+	"github.com/Alias1177/Auth/pkg/logger"
+	"github.com/google/uuid"
+)
+
+// OAuthClientRepository хранилище приложений, зарегистрированных для входа через OpenID Connect
+type OAuthClientRepository interface {
+	GetOAuthClient(ctx context.Context, clientID string) (*domain.OAuthClient, error)
+	ListOAuthClients(ctx context.Context) ([]domain.OAuthClient, error)
+	CreateOAuthClient(ctx context.Context, client *domain.OAuthClient) error
+	RotateOAuthClientSecret(ctx context.Context, clientID, secretHash, previousHash string, previousExpiresAt *time.Time) error
+	DeleteOAuthClient(ctx context.Context, clientID string) error
+}
+
+// OAuthClientService регистрация приложений OpenID Connect и сервисов, получающих токены по client_credentials.
+// Секрет клиента возвращается только при создании и ротации; хранится лишь его хеш.
+type OAuthClientService interface {
+	RegisterClient(ctx context.Context, spec domain.OAuthClientSpec) (*domain.OAuthClient, string, error)
+	ListClients(ctx context.Context) ([]domain.OAuthClient, error)
+	// RotateSecret выдает новый секрет; прежний продолжает действовать в течение grace
+	RotateSecret(ctx context.Context, clientID string, grace time.Duration) (string, error)
+	DeleteClient(ctx context.Context, clientID string) error
+}
+
+// scopeTokenPattern допустимое имя области доступа (RFC 6749, раздел 3.3)
+var scopeTokenPattern = regexp.MustCompile(`^[\x21\x23-\x5B\x5D-\x7E]+$`)
+
+// OAuthClientServiceImpl реализация сервиса приложений
+type OAuthClientServiceImpl struct {
+	repo   OAuthClientRepository
+	logger *logger.Logger
+}
+
+// NewOAuthClientService создает новый экземпляр сервиса приложений
+func NewOAuthClientService(repo OAuthClientRepository, logger *logger.Logger) *OAuthClientServiceImpl {
+	return &OAuthClientServiceImpl{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// RegisterClient регистрирует клиента и выдает ему client_id. Публичному клиенту нужны адреса возврата,
+// конфиденциальному выдается секрет; без адресов возврата он получает токены только по client_credentials.
+func (s *OAuthClientServiceImpl) RegisterClient(ctx context.Context, spec domain.OAuthClientSpec) (*domain.OAuthClient, string, error) {
+	if spec.Name == "" {
+		return nil, "", fmt.Errorf("%w: client name is required", errors.ErrValidation)
+	}
+	if !spec.Confidential && len(spec.RedirectURIs) == 0 {
+		return nil, "", fmt.Errorf("%w: at least one redirect URI is required for a public client", errors.ErrValidation)
+	}
+	if !spec.Confidential && len(spec.Scopes) > 0 {
+		return nil, "", fmt.Errorf("%w: scopes can only be granted to a confidential client", errors.ErrValidation)
+	}
+	for _, uri := range spec.RedirectURIs {
+		if err := checkRedirectURI(uri); err != nil {
+			return nil, "", err
+		}
+	}
+	for _, scope := range spec.Scopes {
+		if !scopeTokenPattern.MatchString(scope) {
+			return nil, "", fmt.Errorf("%w: invalid scope %q", errors.ErrValidation, scope)
+		}
+	}
+
+	client := &domain.OAuthClient{
+		ID:           uuid.NewString(),
+		Name:         spec.Name,
+		RedirectURIs: nonNilStrings(spec.RedirectURIs),
+		Scopes:       nonNilStrings(spec.Scopes),
+	}
+	var secret string
+	if spec.Confidential {
+		var err error
+		if secret, err = generateClientSecret(); err != nil {
+			return nil, "", err
+		}
+		client.SecretHash = hashVerificationToken(secret)
+	}
+	if err := s.repo.CreateOAuthClient(ctx, client); err != nil {
+		return nil, "", err
+	}
+
+	s.logger.Infow("OAuth client registered", "client_id", client.ID, "name", spec.Name, "confidential", spec.Confidential)
+	return client, secret, nil
+}
+
+// ListClients возвращает все зарегистрированные приложения
+func (s *OAuthClientServiceImpl) ListClients(ctx context.Context) ([]domain.OAuthClient, error) {
+	return s.repo.ListOAuthClients(ctx)
+}
+
+// RotateSecret выдает конфиденциальному клиенту новый секрет. Прежний принимается еще grace, чтобы сервис
+// успел перейти на новый без простоя; секрет, действовавший до прежнего, перестает приниматься сразу.
+func (s *OAuthClientServiceImpl) RotateSecret(ctx context.Context, clientID string, grace time.Duration) (string, error) {
+	if grace < 0 {
+		return "", fmt.Errorf("%w: grace period must not be negative", errors.ErrValidation)
+	}
+	client, err := s.repo.GetOAuthClient(ctx, clientID)
+	if err != nil {
+		if stderrors.Is(err, sql.ErrNoRows) {
+			return "", errors.ErrOAuthClientNotFound
+		}
+		return "", err
+	}
+	if !client.Confidential() {
+		return "", fmt.Errorf("%w: public client has no secret", errors.ErrValidation)
+	}
+
+	secret, err := generateClientSecret()
+	if err != nil {
+		return "", err
+	}
+	var (
+		previousHash      string
+		previousExpiresAt *time.Time
+	)
+	if grace > 0 {
+		expiresAt := time.Now().Add(grace)
+		previousHash, previousExpiresAt = client.SecretHash, &expiresAt
+	}
+	if err := s.repo.RotateOAuthClientSecret(ctx, clientID, hashVerificationToken(secret), previousHash, previousExpiresAt); err != nil {
+		if stderrors.Is(err, sql.ErrNoRows) {
+			return "", errors.ErrOAuthClientNotFound
+		}
+		return "", err
+	}
+
+	s.logger.Infow("OAuth client secret rotated", "client_id", clientID, "grace", grace)
+	return secret, nil
+}
+
+// DeleteClient удаляет приложение; выданные ему коды авторизации больше не обменять на токены
+func (s *OAuthClientServiceImpl) DeleteClient(ctx context.Context, clientID string) error {
+	if err := s.repo.DeleteOAuthClient(ctx, clientID); err != nil {
+		if stderrors.Is(err, sql.ErrNoRows) {
+			return errors.ErrOAuthClientNotFound
+		}
+		return err
+	}
+
+	s.logger.Infow("OAuth client deleted", "client_id", clientID)
+	return nil
+}
+
+// checkRedirectURI допускает абсолютные https адреса без фрагмента,
+// а http - только для loopback адресов нативных приложений (RFC 8252, раздел 7.3)
+func checkRedirectURI(raw string) error {
+	uri, err := url.Parse(raw)
+	if err != nil || uri.Host == "" || uri.Fragment != "" {
+		return fmt.Errorf("%w: redirect URI %q must be an absolute URL without fragment", errors.ErrValidation, raw)
+	}
+	switch uri.Scheme {
+	case "https":
+		return nil
+	case "http":
+		host := uri.Hostname()
+		if ip := net.ParseIP(host); host == "localhost" || (ip != nil && ip.IsLoopback()) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: redirect URI %q must use https (http is allowed for loopback only)", errors.ErrValidation, raw)
+}
+
+// findOAuthClient возвращает клиента протокола OAuth; неизвестный клиент - ошибка invalid_client
+func findOAuthClient(ctx context.Context, clients OAuthClientRepository, clientID string) (*domain.OAuthClient, error) {
+	if clientID == "" {
+		return nil, errors.NewOAuthError(errors.OAuthInvalidClient, "client_id is required")
+	}
+	client, err := clients.GetOAuthClient(ctx, clientID)
+	if err != nil {
+		if stderrors.Is(err, sql.ErrNoRows) {
+			return nil, errors.NewOAuthError(errors.OAuthInvalidClient, "unknown client")
+		}
+		return nil, err
+	}
+	return client, nil
+}
+
+// authenticateOAuthClient проверяет клиента на ручках /oauth2: конфиденциальный обязан предъявить
+// действующий секрет, публичный не должен предъявлять никакого
+func authenticateOAuthClient(
+	ctx context.Context,
+	clients OAuthClientRepository,
+	clientID, clientSecret string,
+	log *logger.Logger,
+) (*domain.OAuthClient, error) {
+	client, err := findOAuthClient(ctx, clients, clientID)
+	if err != nil {
+		return nil, err
+	}
+	if client.Confidential() {
+		if !verifyClientSecret(client, clientSecret, time.Now()) {
+			log.Warnw("Client authentication failed", "client_id", client.ID)
+			return nil, errors.NewOAuthError(errors.OAuthInvalidClient, "client authentication failed")
+		}
+	} else if clientSecret != "" {
+		return nil, errors.NewOAuthError(errors.OAuthInvalidClient, "public client must not send a secret")
+	}
+	return client, nil
+}
+
+// verifyClientSecret сверяет секрет с текущим и, до истечения срока, с прежним секретом клиента
+func verifyClientSecret(client *domain.OAuthClient, secret string, now time.Time) bool {
+	if secret == "" || !client.Confidential() {
+		return false
+	}
+	hash := []byte(hashVerificationToken(secret))
+	if subtle.ConstantTimeCompare(hash, []byte(client.SecretHash)) == 1 {
+		return true
+	}
+	return client.PreviousSecretHash != "" &&
+		client.PreviousSecretExpiresAt != nil && now.Before(*client.PreviousSecretExpiresAt) &&
+		subtle.ConstantTimeCompare(hash, []byte(client.PreviousSecretHash)) == 1
+}
+
+// generateClientSecret создает случайный секрет клиента
+func generateClientSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// nonNilStrings заменяет nil пустым срезом: колонки-массивы в базе NOT NULL
+func nonNilStrings(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
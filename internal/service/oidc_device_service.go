@@ -0,0 +1,283 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Alias1177/Auth/internal/domain"
+	"github.com/Alias1177/Auth/internal/errors"
+)
+
+// userCodeAlphabet согласные без гласных и похожих символов (RFC 8628, раздел 6.1):
+// код удобно вводить с экрана телевизора, и из него не складываются слова
+const userCodeAlphabet = "BCDFGHJKLMNPQRSTVWXZ"
+
+// userCodeLength длина user_code без разделителя; 20^8 вариантов при ограничении частоты подтверждений
+const userCodeLength = 8
+
+// StartDeviceAuthorization выдает устройству device_code для опроса и user_code для ввода пользователем.
+// Публичные клиенты (CLI) допускаются; неизвестные области доступа отбрасываются, openid не обязателен.
+func (s *OIDCServiceImpl) StartDeviceAuthorization(
+	ctx context.Context,
+	clientID, clientSecret, scope string,
+	device domain.ClientInfo,
+) (*domain.DeviceCode, error) {
+	client, err := authenticateOAuthClient(ctx, s.clients, clientID, clientSecret, s.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+	deviceCode := base64.RawURLEncoding.EncodeToString(buf)
+	userCode, err := s.newUserCode(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	authorization := domain.DeviceAuthorization{
+		ClientID:  client.ID,
+		Scope:     strings.Join(grantedScopes(scope), " "),
+		UserCode:  userCode,
+		Status:    domain.DeviceAuthorizationPending,
+		UserAgent: device.UserAgent,
+		IPAddress: device.IPAddress,
+		ExpiresAt: time.Now().Add(s.cfg.DeviceCodeTTL).Unix(),
+	}
+	key := deviceCodeKey(deviceCode)
+	if err := s.saveDeviceAuthorization(ctx, key, &authorization); err != nil {
+		return nil, err
+	}
+	if err := s.cache.SetWithTTL(ctx, userCodeKey(userCode), key, s.cfg.DeviceCodeTTL); err != nil {
+		return nil, err
+	}
+
+	s.logger.Infow("Device authorization started", "client_id", client.ID, "scope", authorization.Scope)
+	return &domain.DeviceCode{
+		DeviceCode: deviceCode,
+		UserCode:   FormatUserCode(userCode),
+		ExpiresIn:  int(s.cfg.DeviceCodeTTL.Seconds()),
+		Interval:   int(s.cfg.DevicePollInterval.Seconds()),
+	}, nil
+}
+
+// DeviceAuthorization возвращает ожидающий подтверждения запрос по user_code и приложение, которое его создало.
+// Возвращает ErrNotFound для неизвестного, истекшего или уже подтвержденного кода.
+func (s *OIDCServiceImpl) DeviceAuthorization(ctx context.Context, userCode string) (*domain.DeviceAuthorization, *domain.OAuthClient, error) {
+	_, authorization, err := s.pendingDeviceAuthorization(ctx, userCode)
+	if err != nil {
+		return nil, nil, err
+	}
+	client, err := findOAuthClient(ctx, s.clients, authorization.ClientID)
+	if err != nil {
+		// Приложение удалено, пока пользователь вводил код
+		return nil, nil, errors.ErrNotFound
+	}
+	return authorization, client, nil
+}
+
+// CompleteDeviceAuthorization подтверждает или отклоняет запрос устройства от имени вошедшего пользователя.
+// user_code одноразовый: после решения он больше не находится.
+func (s *OIDCServiceImpl) CompleteDeviceAuthorization(ctx context.Context, userCode string, userID int, approve bool) error {
+	key, authorization, err := s.pendingDeviceAuthorization(ctx, userCode)
+	if err != nil {
+		return err
+	}
+	codeKey := userCodeKey(authorization.UserCode)
+	uses, err := s.cache.Increment(ctx, codeKey+":used", s.cfg.DeviceCodeTTL)
+	if err != nil {
+		return err
+	}
+	if uses > 1 {
+		return errors.ErrNotFound
+	}
+	if err := s.cache.Delete(ctx, codeKey); err != nil {
+		return err
+	}
+
+	authorization.Status = domain.DeviceAuthorizationDenied
+	if approve {
+		authorization.Status = domain.DeviceAuthorizationApproved
+		authorization.UserID = userID
+		authorization.AuthTime = time.Now().Unix()
+	}
+	if err := s.saveDeviceAuthorization(ctx, key, authorization); err != nil {
+		return err
+	}
+
+	s.logger.Infow("Device authorization completed", "client_id", authorization.ClientID, "user_id", userID, "status", authorization.Status)
+	return nil
+}
+
+// ExchangeDeviceCode отвечает на опрос устройства: authorization_pending, пока пользователь не решил,
+// slow_down при опросе чаще интервала, access_denied при отказе, expired_token после истечения кода.
+// Подтвержденный запрос обменивается на токены один раз.
+func (s *OIDCServiceImpl) ExchangeDeviceCode(ctx context.Context, clientID, clientSecret, deviceCode string) (*domain.OAuthTokens, error) {
+	client, err := authenticateOAuthClient(ctx, s.clients, clientID, clientSecret, s.logger)
+	if err != nil {
+		return nil, err
+	}
+	if deviceCode == "" {
+		return nil, errors.NewOAuthError(errors.OAuthInvalidRequest, "device_code is required")
+	}
+
+	key := deviceCodeKey(deviceCode)
+	authorization, err := s.loadDeviceAuthorization(ctx, key)
+	if err != nil {
+		return nil, errors.NewOAuthError(errors.OAuthExpiredToken, "device code is invalid or expired")
+	}
+	if authorization.ClientID != client.ID {
+		s.logger.Warnw("Device code presented by another client", "client_id", client.ID)
+		return nil, errors.NewOAuthError(errors.OAuthInvalidGrant, "device code was issued to another client")
+	}
+
+	polls, err := s.cache.Increment(ctx, key+":poll", s.cfg.DevicePollInterval)
+	if err != nil {
+		return nil, err
+	}
+	if polls > 1 {
+		return nil, errors.NewOAuthError(errors.OAuthSlowDown, "polling too frequently, increase the interval by 5 seconds")
+	}
+
+	switch authorization.Status {
+	case domain.DeviceAuthorizationPending:
+		return nil, errors.NewOAuthError(errors.OAuthAuthorizationPending, "user has not yet approved the device")
+	case domain.DeviceAuthorizationDenied:
+		if err := s.cache.Delete(ctx, key); err != nil {
+			return nil, err
+		}
+		return nil, errors.NewOAuthError(errors.OAuthAccessDenied, "user denied the device")
+	}
+
+	// Как и код авторизации, подтвержденный device_code обменивается только один раз
+	uses, err := s.cache.Increment(ctx, key+":used", s.cfg.DeviceCodeTTL)
+	if err != nil {
+		return nil, err
+	}
+	if uses > 1 {
+		return nil, errors.NewOAuthError(errors.OAuthExpiredToken, "device code is invalid or expired")
+	}
+	if err := s.cache.Delete(ctx, key); err != nil {
+		return nil, err
+	}
+
+	tokens, err := s.issueUserTokens(ctx, client, authorization.UserID, authorization.Scope, "", authorization.AuthTime, domain.ClientInfo{
+		UserAgent:   authorization.UserAgent,
+		IPAddress:   authorization.IPAddress,
+		LoginMethod: domain.LoginMethodDevice,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Infow("Device code exchanged", "client_id", client.ID, "user_id", authorization.UserID)
+	return tokens, nil
+}
+
+// pendingDeviceAuthorization находит ожидающий подтверждения запрос по user_code
+func (s *OIDCServiceImpl) pendingDeviceAuthorization(ctx context.Context, userCode string) (string, *domain.DeviceAuthorization, error) {
+	normalized := normalizeUserCode(userCode)
+	if len(normalized) != userCodeLength {
+		return "", nil, errors.ErrNotFound
+	}
+	key, err := s.cache.Get(ctx, userCodeKey(normalized))
+	if err != nil {
+		return "", nil, errors.ErrNotFound
+	}
+	authorization, err := s.loadDeviceAuthorization(ctx, key)
+	if err != nil || authorization.Status != domain.DeviceAuthorizationPending {
+		return "", nil, errors.ErrNotFound
+	}
+	return key, authorization, nil
+}
+
+func (s *OIDCServiceImpl) loadDeviceAuthorization(ctx context.Context, key string) (*domain.DeviceAuthorization, error) {
+	data, err := s.cache.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	var authorization domain.DeviceAuthorization
+	if err := json.Unmarshal([]byte(data), &authorization); err != nil {
+		return nil, err
+	}
+	return &authorization, nil
+}
+
+// saveDeviceAuthorization сохраняет запрос до его исходного срока истечения
+func (s *OIDCServiceImpl) saveDeviceAuthorization(ctx context.Context, key string, authorization *domain.DeviceAuthorization) error {
+	ttl := time.Until(time.Unix(authorization.ExpiresAt, 0))
+	if ttl <= 0 {
+		return errors.ErrNotFound
+	}
+	data, err := json.Marshal(authorization)
+	if err != nil {
+		return err
+	}
+	return s.cache.SetWithTTL(ctx, key, string(data), ttl)
+}
+
+// newUserCode создает user_code, не занятый другим ожидающим запросом
+func (s *OIDCServiceImpl) newUserCode(ctx context.Context) (string, error) {
+	for attempt := 0; attempt < 3; attempt++ {
+		code, err := randomUserCode()
+		if err != nil {
+			return "", err
+		}
+		if _, err := s.cache.Get(ctx, userCodeKey(code)); err != nil {
+			return code, nil
+		}
+	}
+	return "", fmt.Errorf("failed to generate unique user code")
+}
+
+// randomUserCode выбирает символы алфавита равномерно, отбрасывая байты за последним полным кругом алфавита
+func randomUserCode() (string, error) {
+	limit := byte(256 - 256%len(userCodeAlphabet))
+	code := make([]byte, 0, userCodeLength)
+	buf := make([]byte, userCodeLength*2)
+	for len(code) < userCodeLength {
+		if _, err := rand.Read(buf); err != nil {
+			return "", err
+		}
+		for _, b := range buf {
+			if b < limit && len(code) < userCodeLength {
+				code = append(code, userCodeAlphabet[int(b)%len(userCodeAlphabet)])
+			}
+		}
+	}
+	return string(code), nil
+}
+
+// FormatUserCode показывает user_code пользователю в виде XXXX-XXXX
+func FormatUserCode(code string) string {
+	if len(code) != userCodeLength {
+		return code
+	}
+	return code[:userCodeLength/2] + "-" + code[userCodeLength/2:]
+}
+
+// normalizeUserCode приводит введенный пользователем код к хранимому виду: без регистра, дефисов и пробелов
+func normalizeUserCode(input string) string {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(input) {
+		if strings.ContainsRune(userCodeAlphabet, r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func deviceCodeKey(deviceCode string) string {
+	return fmt.Sprintf("oidc_device:%s", hashVerificationToken(deviceCode))
+}
+
+func userCodeKey(userCode string) string {
+	return fmt.Sprintf("oidc_user_code:%s", userCode)
+}
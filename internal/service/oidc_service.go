@@ -0,0 +1,376 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Alias1177/Auth/internal/config"
+	"github.com/Alias1177/Auth/internal/domain"
+	"github.com/Alias1177/Auth/internal/errors"
+	"github.com/Alias1177/Auth/pkg/jwt"
+	"github.com/Alias1177/Auth/pkg/logger"
+)
+
+// codeChallengeMethodS256 единственный поддерживаемый метод PKCE: plain не защищает от перехвата кода
+const codeChallengeMethodS256 = "S256"
+
+// pkcePattern допустимые code_challenge и code_verifier (RFC 7636, раздел 4.1)
+var pkcePattern = regexp.MustCompile(`^[A-Za-z0-9\-._~]{43,128}$`)
+
+// SupportedScopes области доступа, которые провайдер выдает приложениям
+var SupportedScopes = []string{domain.ScopeOpenID, domain.ScopeProfile, domain.ScopeEmail}
+
+// OIDCService провайдер OpenID Connect: коды авторизации с PKCE и их обмен на токены.
+// Токены выдаются через сессии, поэтому вход в приложение виден в списке сессий пользователя и отзывается так же.
+// Конфиденциальные клиенты на /oauth2/token предъявляют секрет, публичные - только client_id.
+type OIDCService interface {
+	// Client возвращает приложение, если адрес возврата зарегистрирован для него.
+	// При ошибке ответ нельзя отправлять на адрес возврата.
+	Client(ctx context.Context, clientID, redirectURI string) (*domain.OAuthClient, error)
+	// ValidateRequest проверяет параметры запроса авторизации и возвращает выдаваемые области доступа
+	ValidateRequest(req domain.AuthorizationRequest) (string, error)
+	IssueCode(ctx context.Context, req domain.AuthorizationRequest, scope string, userID int, client domain.ClientInfo) (string, error)
+	ExchangeCode(ctx context.Context, clientID, clientSecret, code, redirectURI, codeVerifier string) (*domain.OAuthTokens, error)
+	Refresh(ctx context.Context, clientID, clientSecret, refreshToken string) (*domain.OAuthTokens, error)
+	// ClientCredentials выдает конфиденциальному клиенту access токен от его собственного имени
+	ClientCredentials(ctx context.Context, clientID, clientSecret, scope string) (*domain.OAuthTokens, error)
+
+	// Вход на устройствах без браузера (RFC 8628): устройство получает коды, пользователь подтверждает
+	// user_code на странице провайдера, устройство опрашивает ручку токенов
+	StartDeviceAuthorization(ctx context.Context, clientID, clientSecret, scope string, device domain.ClientInfo) (*domain.DeviceCode, error)
+	DeviceAuthorization(ctx context.Context, userCode string) (*domain.DeviceAuthorization, *domain.OAuthClient, error)
+	CompleteDeviceAuthorization(ctx context.Context, userCode string, userID int, approve bool) error
+	ExchangeDeviceCode(ctx context.Context, clientID, clientSecret, deviceCode string) (*domain.OAuthTokens, error)
+}
+
+// OIDCServiceImpl реализация провайдера OpenID Connect
+type OIDCServiceImpl struct {
+	clients      OAuthClientRepository
+	userRepo     UserRepository
+	cache        UserCache
+	sessions     SessionService
+	tokenManager TokenManager
+	cfg          config.OIDCConfig
+	logger       *logger.Logger
+}
+
+// NewOIDCService создает новый экземпляр провайдера OpenID Connect
+func NewOIDCService(
+	clients OAuthClientRepository,
+	userRepo UserRepository,
+	cache UserCache,
+	sessions SessionService,
+	tokenManager TokenManager,
+	cfg config.OIDCConfig,
+	logger *logger.Logger,
+) *OIDCServiceImpl {
+	return &OIDCServiceImpl{
+		clients:      clients,
+		userRepo:     userRepo,
+		cache:        cache,
+		sessions:     sessions,
+		tokenManager: tokenManager,
+		cfg:          cfg,
+		logger:       logger,
+	}
+}
+
+// Client возвращает приложение и проверяет адрес возврата по списку зарегистрированных
+func (s *OIDCServiceImpl) Client(ctx context.Context, clientID, redirectURI string) (*domain.OAuthClient, error) {
+	client, err := findOAuthClient(ctx, s.clients, clientID)
+	if err != nil {
+		return nil, err
+	}
+	if !client.AllowsRedirectURI(redirectURI) {
+		return nil, errors.NewOAuthError(errors.OAuthInvalidRequest, "redirect_uri is not registered for the client")
+	}
+	return client, nil
+}
+
+// ValidateRequest требует response_type=code, область openid и PKCE с методом S256.
+// Неизвестные области доступа отбрасываются, как предписывает OpenID Connect.
+func (s *OIDCServiceImpl) ValidateRequest(req domain.AuthorizationRequest) (string, error) {
+	if req.ResponseType != "code" {
+		return "", errors.NewOAuthError(errors.OAuthUnsupportedResponseType, "only response_type=code is supported")
+	}
+	if req.CodeChallengeMethod != codeChallengeMethodS256 || !pkcePattern.MatchString(req.CodeChallenge) {
+		return "", errors.NewOAuthError(errors.OAuthInvalidRequest, "PKCE code_challenge with code_challenge_method=S256 is required")
+	}
+
+	granted := grantedScopes(req.Scope)
+	if len(granted) == 0 || granted[0] != domain.ScopeOpenID {
+		return "", errors.NewOAuthError(errors.OAuthInvalidScope, "openid scope is required")
+	}
+	return strings.Join(granted, " "), nil
+}
+
+// IssueCode выдает одноразовый код авторизации для пользователя, прошедшего вход на странице провайдера
+func (s *OIDCServiceImpl) IssueCode(
+	ctx context.Context,
+	req domain.AuthorizationRequest,
+	scope string,
+	userID int,
+	client domain.ClientInfo,
+) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	code := base64.RawURLEncoding.EncodeToString(buf)
+
+	data, err := json.Marshal(domain.AuthorizationCode{
+		ClientID:      req.ClientID,
+		RedirectURI:   req.RedirectURI,
+		UserID:        userID,
+		Scope:         scope,
+		Nonce:         req.Nonce,
+		CodeChallenge: req.CodeChallenge,
+		UserAgent:     client.UserAgent,
+		IPAddress:     client.IPAddress,
+		LoginMethod:   client.LoginMethod,
+		AuthTime:      time.Now().Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+	if err := s.cache.SetWithTTL(ctx, authorizationCodeKey(code), string(data), s.cfg.CodeTTL); err != nil {
+		return "", err
+	}
+
+	s.logger.Infow("Authorization code issued", "client_id", req.ClientID, "user_id", userID, "scope", scope)
+	return code, nil
+}
+
+// ExchangeCode обменивает код авторизации на access, refresh и ID токены.
+// Код одноразовый, привязан к приложению и адресу возврата и требует code_verifier из PKCE.
+func (s *OIDCServiceImpl) ExchangeCode(
+	ctx context.Context,
+	clientID, clientSecret, code, redirectURI, codeVerifier string,
+) (*domain.OAuthTokens, error) {
+	client, err := authenticateOAuthClient(ctx, s.clients, clientID, clientSecret, s.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	grant, err := s.takeCode(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	if grant.ClientID != client.ID || grant.RedirectURI != redirectURI {
+		s.logger.Warnw("Authorization code presented by another client or redirect URI", "client_id", clientID)
+		return nil, errors.NewOAuthError(errors.OAuthInvalidGrant, "authorization code was issued to another client or redirect_uri")
+	}
+	if !verifyCodeChallenge(grant.CodeChallenge, codeVerifier) {
+		return nil, errors.NewOAuthError(errors.OAuthInvalidGrant, "code_verifier does not match code_challenge")
+	}
+
+	// Сессия запоминает браузер, в котором пользователь вошел
+	tokens, err := s.issueUserTokens(ctx, client, grant.UserID, grant.Scope, grant.Nonce, grant.AuthTime, domain.ClientInfo{
+		UserAgent:   grant.UserAgent,
+		IPAddress:   grant.IPAddress,
+		LoginMethod: grant.LoginMethod,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Infow("Authorization code exchanged", "client_id", client.ID, "user_id", grant.UserID)
+	return tokens, nil
+}
+
+// issueUserTokens начинает сессию пользователя в приложении и выдает access, refresh и, при области openid,
+// ID токен. Сессия называется по приложению. Access токен привязан к приложению (aud = client_id), несет
+// выданные области доступа вместо ролей и принимается только на /oauth2/userinfo.
+func (s *OIDCServiceImpl) issueUserTokens(
+	ctx context.Context,
+	client *domain.OAuthClient,
+	userID int,
+	scope, nonce string,
+	authTime int64,
+	info domain.ClientInfo,
+) (*domain.OAuthTokens, error) {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		if stderrors.Is(err, sql.ErrNoRows) {
+			return nil, errors.NewOAuthError(errors.OAuthInvalidGrant, "user no longer exists")
+		}
+		return nil, err
+	}
+
+	claims := domain.UserClaims{UserID: strconv.Itoa(user.ID), Email: user.Email, ClientID: client.ID, Scope: scope}
+	info.DeviceName = client.Name
+	pair, err := s.sessions.Start(ctx, claims, info)
+	if err != nil {
+		return nil, s.grantError(err)
+	}
+	tokens := &domain.OAuthTokens{
+		AccessToken:  pair.AccessToken,
+		RefreshToken: pair.RefreshToken,
+		ExpiresIn:    int(jwt.AccessTokenTTL.Seconds()),
+		Scope:        scope,
+	}
+
+	scopes := strings.Fields(scope)
+	if len(scopes) == 0 || scopes[0] != domain.ScopeOpenID {
+		return tokens, nil
+	}
+	idClaims := domain.IDTokenClaims{
+		Issuer:   s.cfg.Issuer,
+		Subject:  claims.UserID,
+		Audience: client.ID,
+		Nonce:    nonce,
+		AuthTime: authTime,
+	}
+	for _, scope := range scopes {
+		switch scope {
+		case domain.ScopeEmail:
+			idClaims.Email = user.Email
+			idClaims.EmailVerified = user.EmailVerified()
+		case domain.ScopeProfile:
+			idClaims.Username = user.UserName
+		}
+	}
+	if tokens.IDToken, err = s.tokenManager.GenerateIDToken(idClaims, s.cfg.IDTokenTTL); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// Refresh обменивает refresh токен приложения на новую пару с ротацией, как /refresh-token.
+// Токен, выданный другому приложению или входу в сам сервис, отклоняется с invalid_grant.
+func (s *OIDCServiceImpl) Refresh(ctx context.Context, clientID, clientSecret, refreshToken string) (*domain.OAuthTokens, error) {
+	client, err := authenticateOAuthClient(ctx, s.clients, clientID, clientSecret, s.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	pair, err := s.sessions.Refresh(ctx, refreshToken, client.ID)
+	if err != nil {
+		return nil, s.grantError(err)
+	}
+	tokens := &domain.OAuthTokens{
+		AccessToken:  pair.AccessToken,
+		RefreshToken: pair.RefreshToken,
+		ExpiresIn:    int(jwt.AccessTokenTTL.Seconds()),
+	}
+	if claims, err := s.tokenManager.ValidateAccessToken(pair.AccessToken); err == nil {
+		tokens.Scope = claims.Scope
+	}
+	return tokens, nil
+}
+
+// ClientCredentials выдает access токен с sub = client_id (RFC 6749, раздел 4.4). Без scope выдаются все
+// разрешенные клиенту области; запрос области сверх разрешенных отклоняется целиком. Refresh токен не выдается:
+// сервис просто запрашивает новый токен по секрету.
+func (s *OIDCServiceImpl) ClientCredentials(ctx context.Context, clientID, clientSecret, scope string) (*domain.OAuthTokens, error) {
+	client, err := authenticateOAuthClient(ctx, s.clients, clientID, clientSecret, s.logger)
+	if err != nil {
+		return nil, err
+	}
+	if !client.Confidential() {
+		return nil, errors.NewOAuthError(errors.OAuthUnauthorizedClient, "client_credentials grant requires a confidential client")
+	}
+
+	scopes := strings.Fields(scope)
+	if len(scopes) == 0 {
+		scopes = client.Scopes
+	} else if !client.AllowsScopes(scopes) {
+		s.logger.Warnw("Client requested scope beyond allowed", "client_id", client.ID, "scope", scope)
+		return nil, errors.NewOAuthError(errors.OAuthInvalidScope, "requested scope is not allowed for the client")
+	}
+
+	token, err := s.tokenManager.GenerateClientToken(domain.ClientClaims{ClientID: client.ID, Scopes: scopes}, s.cfg.ClientTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Infow("Client credentials token issued", "client_id", client.ID, "scope", strings.Join(scopes, " "))
+	return &domain.OAuthTokens{
+		AccessToken: token,
+		ExpiresIn:   int(s.cfg.ClientTokenTTL.Seconds()),
+		Scope:       strings.Join(scopes, " "),
+	}, nil
+}
+
+// takeCode достает код авторизации и сжигает его. Счетчик в Redis не дает обменять код дважды
+// при одновременных запросах, а сама запись удаляется сразу после первого обмена.
+func (s *OIDCServiceImpl) takeCode(ctx context.Context, code string) (*domain.AuthorizationCode, error) {
+	invalid := errors.NewOAuthError(errors.OAuthInvalidGrant, "authorization code is invalid or expired")
+	if code == "" {
+		return nil, invalid
+	}
+
+	key := authorizationCodeKey(code)
+	data, err := s.cache.Get(ctx, key)
+	if err != nil {
+		return nil, invalid
+	}
+	uses, err := s.cache.Increment(ctx, key+":used", s.cfg.CodeTTL)
+	if err != nil {
+		return nil, err
+	}
+	if uses > 1 {
+		s.logger.Warnw("Authorization code replay rejected")
+		return nil, invalid
+	}
+	if err := s.cache.Delete(ctx, key); err != nil {
+		return nil, err
+	}
+
+	var grant domain.AuthorizationCode
+	if err := json.Unmarshal([]byte(data), &grant); err != nil {
+		return nil, invalid
+	}
+	return &grant, nil
+}
+
+// grantError переводит отказ в выдаче токенов сессией в ошибку протокола OAuth
+func (s *OIDCServiceImpl) grantError(err error) error {
+	switch err {
+	case errors.ErrAccountDisabled:
+		return errors.NewOAuthError(errors.OAuthInvalidGrant, "account disabled")
+	case errors.ErrInvalidToken, errors.ErrTokenReused:
+		return errors.NewOAuthError(errors.OAuthInvalidGrant, "refresh token is invalid or revoked")
+	}
+	return err
+}
+
+// grantedScopes оставляет из запрошенных областей поддерживаемые, в порядке SupportedScopes
+func grantedScopes(requested string) []string {
+	granted := make([]string, 0, len(SupportedScopes))
+	fields := strings.Fields(requested)
+	for _, scope := range SupportedScopes {
+		for _, r := range fields {
+			if r == scope {
+				granted = append(granted, scope)
+				break
+			}
+		}
+	}
+	return granted
+}
+
+// verifyCodeChallenge проверяет code_verifier: BASE64URL(SHA256(code_verifier)) == code_challenge
+func verifyCodeChallenge(challenge, verifier string) bool {
+	if !pkcePattern.MatchString(verifier) {
+		return false
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	expected := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(challenge)) == 1
+}
+
+func authorizationCodeKey(code string) string {
+	return fmt.Sprintf("oidc_code:%s", hashVerificationToken(code))
+}
@@ -0,0 +1,420 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	stderrors "errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Alias1177/Auth/internal/config"
+	"github.com/Alias1177/Auth/internal/domain"
+	"github.com/Alias1177/Auth/internal/errors"
+	"github.com/Alias1177/Auth/pkg/jwt"
+	"github.com/Alias1177/Auth/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryOAuthClients хранилище приложений в памяти
+type memoryOAuthClients map[string]domain.OAuthClient
+
+func (m memoryOAuthClients) GetOAuthClient(_ context.Context, clientID string) (*domain.OAuthClient, error) {
+	client, ok := m[clientID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &client, nil
+}
+
+func (m memoryOAuthClients) ListOAuthClients(context.Context) ([]domain.OAuthClient, error) {
+	return nil, nil
+}
+
+func (m memoryOAuthClients) CreateOAuthClient(_ context.Context, client *domain.OAuthClient) error {
+	m[client.ID] = *client
+	return nil
+}
+
+func (m memoryOAuthClients) RotateOAuthClientSecret(
+	_ context.Context,
+	clientID, secretHash, previousHash string,
+	previousExpiresAt *time.Time,
+) error {
+	client, ok := m[clientID]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	client.SecretHash, client.PreviousSecretHash, client.PreviousSecretExpiresAt = secretHash, previousHash, previousExpiresAt
+	m[clientID] = client
+	return nil
+}
+
+func (m memoryOAuthClients) DeleteOAuthClient(_ context.Context, clientID string) error {
+	delete(m, clientID)
+	return nil
+}
+
+// startingSessions запоминает сведения о клиенте начатых сессий
+type startingSessions struct {
+	SessionService
+	started []domain.ClientInfo
+}
+
+func (s *startingSessions) Start(_ context.Context, claims domain.UserClaims, client domain.ClientInfo) (*domain.TokenPair, error) {
+	s.started = append(s.started, client)
+	return &domain.TokenPair{AccessToken: "access-" + claims.UserID, RefreshToken: "refresh-" + claims.UserID}, nil
+}
+
+// rotatingSessions выдает и обменивает токены настоящим сервисом refresh токенов, без учета сессий
+type rotatingSessions struct {
+	SessionService
+	refreshTokens RefreshTokenService
+}
+
+func (s rotatingSessions) Start(ctx context.Context, claims domain.UserClaims, client domain.ClientInfo) (*domain.TokenPair, error) {
+	return s.refreshTokens.Issue(ctx, claims, "family-"+client.DeviceName, client.DeviceName)
+}
+
+func (s rotatingSessions) Refresh(ctx context.Context, refreshToken, clientID string) (*domain.TokenPair, error) {
+	return s.refreshTokens.Rotate(ctx, refreshToken, clientID)
+}
+
+// adminAuthorizer назначает каждому пользователю роль администратора
+type adminAuthorizer struct{}
+
+func (adminAuthorizer) Authorization(context.Context, int) ([]string, []string, error) {
+	return []string{domain.RoleAdmin}, []string{domain.PermissionUsersWrite}, nil
+}
+
+func oauthErrorCode(err error) string {
+	var oauthErr *errors.OAuthError
+	if stderrors.As(err, &oauthErr) {
+		return oauthErr.Code
+	}
+	return ""
+}
+
+func TestOIDCService_ValidateRequest(t *testing.T) {
+	svc := NewOIDCService(nil, nil, nil, nil, nil, config.OIDCConfig{}, nil)
+	challenge := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+
+	tests := []struct {
+		name      string
+		req       domain.AuthorizationRequest
+		wantScope string
+		wantCode  string
+	}{
+		{
+			name:      "valid request drops unknown scopes",
+			req:       domain.AuthorizationRequest{ResponseType: "code", Scope: "email custom openid", CodeChallenge: challenge, CodeChallengeMethod: "S256"},
+			wantScope: "openid email",
+		},
+		{
+			name:     "implicit flow",
+			req:      domain.AuthorizationRequest{ResponseType: "token", Scope: "openid", CodeChallenge: challenge, CodeChallengeMethod: "S256"},
+			wantCode: errors.OAuthUnsupportedResponseType,
+		},
+		{
+			name:     "plain pkce",
+			req:      domain.AuthorizationRequest{ResponseType: "code", Scope: "openid", CodeChallenge: challenge, CodeChallengeMethod: "plain"},
+			wantCode: errors.OAuthInvalidRequest,
+		},
+		{
+			name:     "missing pkce",
+			req:      domain.AuthorizationRequest{ResponseType: "code", Scope: "openid"},
+			wantCode: errors.OAuthInvalidRequest,
+		},
+		{
+			name:     "missing openid scope",
+			req:      domain.AuthorizationRequest{ResponseType: "code", Scope: "profile", CodeChallenge: challenge, CodeChallengeMethod: "S256"},
+			wantCode: errors.OAuthInvalidScope,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scope, err := svc.ValidateRequest(tt.req)
+			if tt.wantCode != "" {
+				assert.Equal(t, tt.wantCode, oauthErrorCode(err))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantScope, scope)
+		})
+	}
+}
+
+func TestOIDCService_CodeFlow(t *testing.T) {
+	ctx := context.Background()
+	log, err := logger.New("error")
+	require.NoError(t, err)
+	tokenManager, err := jwt.NewJWTTokenManager(config.JWTConfig{Secret: "secret"})
+	require.NoError(t, err)
+
+	now := time.Now()
+	user := &domain.User{ID: 7, Email: "user@example.com", UserName: "user", EmailVerifiedAt: &now}
+	userRepo := new(MockUserRepository)
+	userRepo.On("GetUserByID", mock.Anything, user.ID).Return(user, nil)
+
+	clients := memoryOAuthClients{"app": {ID: "app", Name: "App", RedirectURIs: []string{"https://app.example.com/callback"}}}
+	cache := &memoryCache{values: map[string]string{}}
+	sessions := &startingSessions{}
+	svc := NewOIDCService(clients, userRepo, cache, sessions, tokenManager,
+		config.OIDCConfig{Issuer: "https://auth.example.com", CodeTTL: time.Minute, IDTokenTTL: time.Hour}, log)
+
+	_, err = svc.Client(ctx, "app", "https://app.example.com/callback?next=/")
+	assert.Equal(t, errors.OAuthInvalidRequest, oauthErrorCode(err), "redirect URI must match exactly")
+	_, err = svc.Client(ctx, "unknown", "https://app.example.com/callback")
+	assert.Equal(t, errors.OAuthInvalidClient, oauthErrorCode(err))
+
+	verifier := "dBjftJeZ4CVP-mJ0kqzCHm91OSWCq6ZDBYiF6xnvXN4-"
+	sum := sha256.Sum256([]byte(verifier))
+	req := domain.AuthorizationRequest{
+		ClientID:      "app",
+		RedirectURI:   "https://app.example.com/callback",
+		Nonce:         "nonce",
+		CodeChallenge: base64.RawURLEncoding.EncodeToString(sum[:]),
+	}
+	client := domain.ClientInfo{UserAgent: "browser", IPAddress: "10.0.0.1", LoginMethod: domain.LoginMethodPassword}
+
+	issue := func(t *testing.T) string {
+		code, err := svc.IssueCode(ctx, req, "openid email", user.ID, client)
+		require.NoError(t, err)
+		return code
+	}
+
+	rejectTests := []struct {
+		name        string
+		clientID    string
+		redirectURI string
+		verifier    string
+		wantCode    string
+	}{
+		{name: "wrong verifier", clientID: "app", redirectURI: req.RedirectURI, verifier: verifier[:43] + "x", wantCode: errors.OAuthInvalidGrant},
+		{name: "wrong redirect uri", clientID: "app", redirectURI: "https://app.example.com/other", verifier: verifier, wantCode: errors.OAuthInvalidGrant},
+		{name: "unknown client", clientID: "other", redirectURI: req.RedirectURI, verifier: verifier, wantCode: errors.OAuthInvalidClient},
+	}
+	for _, tt := range rejectTests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.ExchangeCode(ctx, tt.clientID, "", issue(t), tt.redirectURI, tt.verifier)
+			assert.Equal(t, tt.wantCode, oauthErrorCode(err))
+		})
+	}
+
+	code := issue(t)
+	tokens, err := svc.ExchangeCode(ctx, "app", "", code, req.RedirectURI, verifier)
+	require.NoError(t, err)
+	assert.Equal(t, "access-7", tokens.AccessToken)
+	assert.Equal(t, "openid email", tokens.Scope)
+	assert.NotEmpty(t, tokens.IDToken)
+	require.NotEmpty(t, sessions.started)
+	assert.Equal(t, domain.ClientInfo{DeviceName: "App", UserAgent: "browser", IPAddress: "10.0.0.1", LoginMethod: domain.LoginMethodPassword},
+		sessions.started[len(sessions.started)-1])
+
+	// ID токен не принимается вместо access токена
+	_, err = tokenManager.ValidateAccessToken(tokens.IDToken)
+	assert.Error(t, err)
+
+	// Код одноразовый
+	_, err = svc.ExchangeCode(ctx, "app", "", code, req.RedirectURI, verifier)
+	assert.Equal(t, errors.OAuthInvalidGrant, oauthErrorCode(err))
+}
+
+func TestOIDCService_ClientCredentials(t *testing.T) {
+	ctx := context.Background()
+	log, err := logger.New("error")
+	require.NoError(t, err)
+	tokenManager, err := jwt.NewJWTTokenManager(config.JWTConfig{Secret: "secret"})
+	require.NoError(t, err)
+
+	clients := memoryOAuthClients{
+		"app": {ID: "app", Name: "App", RedirectURIs: []string{"https://app.example.com/callback"}},
+	}
+	registry := NewOAuthClientService(clients, log)
+	svc := NewOIDCService(clients, nil, nil, nil, tokenManager, config.OIDCConfig{ClientTokenTTL: time.Minute}, log)
+
+	billing, secret, err := registry.RegisterClient(ctx, domain.OAuthClientSpec{
+		Name:         "Billing",
+		Scopes:       []string{"invoices:read", "invoices:write"},
+		Confidential: true,
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, secret)
+
+	tests := []struct {
+		name      string
+		clientID  string
+		secret    string
+		scope     string
+		wantScope string
+		wantCode  string
+	}{
+		{name: "all allowed scopes by default", clientID: billing.ID, secret: secret, wantScope: "invoices:read invoices:write"},
+		{name: "narrowed scope", clientID: billing.ID, secret: secret, scope: "invoices:read", wantScope: "invoices:read"},
+		{name: "scope beyond allowed", clientID: billing.ID, secret: secret, scope: "invoices:read users:write", wantCode: errors.OAuthInvalidScope},
+		{name: "wrong secret", clientID: billing.ID, secret: "wrong", wantCode: errors.OAuthInvalidClient},
+		{name: "missing secret", clientID: billing.ID, wantCode: errors.OAuthInvalidClient},
+		{name: "public client", clientID: "app", wantCode: errors.OAuthUnauthorizedClient},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, err := svc.ClientCredentials(ctx, tt.clientID, tt.secret, tt.scope)
+			if tt.wantCode != "" {
+				assert.Equal(t, tt.wantCode, oauthErrorCode(err))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantScope, tokens.Scope)
+			assert.Empty(t, tokens.RefreshToken)
+
+			claims, err := tokenManager.ValidateClientToken(tokens.AccessToken)
+			require.NoError(t, err)
+			assert.Equal(t, billing.ID, claims.ClientID)
+			assert.Equal(t, tt.wantScope, strings.Join(claims.Scopes, " "))
+		})
+	}
+
+	// После ротации прежний секрет действует в течение grace, а без grace перестает сразу
+	rotated, err := registry.RotateSecret(ctx, billing.ID, time.Hour)
+	require.NoError(t, err)
+	for _, s := range []string{secret, rotated} {
+		_, err = svc.ClientCredentials(ctx, billing.ID, s, "")
+		assert.NoError(t, err)
+	}
+	latest, err := registry.RotateSecret(ctx, billing.ID, 0)
+	require.NoError(t, err)
+	_, err = svc.ClientCredentials(ctx, billing.ID, rotated, "")
+	assert.Equal(t, errors.OAuthInvalidClient, oauthErrorCode(err))
+	_, err = svc.ClientCredentials(ctx, billing.ID, latest, "")
+	assert.NoError(t, err)
+
+	_, err = registry.RotateSecret(ctx, "app", time.Hour)
+	assert.ErrorIs(t, err, errors.ErrValidation)
+}
+
+func TestOIDCService_DeviceFlow(t *testing.T) {
+	ctx := context.Background()
+	log, err := logger.New("error")
+	require.NoError(t, err)
+	tokenManager, err := jwt.NewJWTTokenManager(config.JWTConfig{Secret: "secret"})
+	require.NoError(t, err)
+
+	user := &domain.User{ID: 7, Email: "user@example.com", UserName: "user"}
+	userRepo := new(MockUserRepository)
+	userRepo.On("GetUserByID", mock.Anything, user.ID).Return(user, nil)
+
+	clients := memoryOAuthClients{
+		"cli":   {ID: "cli", Name: "CLI", RedirectURIs: []string{"http://127.0.0.1/callback"}},
+		"other": {ID: "other", Name: "Other", RedirectURIs: []string{"http://127.0.0.1/callback"}},
+	}
+	cache := &memoryCache{values: map[string]string{}}
+	sessions := &startingSessions{}
+	svc := NewOIDCService(clients, userRepo, cache, sessions, tokenManager, config.OIDCConfig{
+		Issuer: "https://auth.example.com", IDTokenTTL: time.Hour, DeviceCodeTTL: 10 * time.Minute, DevicePollInterval: 5 * time.Second,
+	}, log)
+	device := domain.ClientInfo{UserAgent: "cli/1.0", IPAddress: "10.0.0.2"}
+
+	// poll опрашивает ручку токенов, как будто интервал опроса уже прошел
+	poll := func(clientID, deviceCode string) (*domain.OAuthTokens, error) {
+		delete(cache.values, deviceCodeKey(deviceCode)+":poll")
+		return svc.ExchangeDeviceCode(ctx, clientID, "", deviceCode)
+	}
+
+	code, err := svc.StartDeviceAuthorization(ctx, "cli", "", "openid profile offline_access", device)
+	require.NoError(t, err)
+	assert.Regexp(t, `^[B-Z]{4}-[B-Z]{4}$`, code.UserCode)
+	assert.Equal(t, 5, code.Interval)
+
+	_, err = poll("cli", code.DeviceCode)
+	assert.Equal(t, errors.OAuthAuthorizationPending, oauthErrorCode(err))
+	_, err = svc.ExchangeDeviceCode(ctx, "cli", "", code.DeviceCode)
+	assert.Equal(t, errors.OAuthSlowDown, oauthErrorCode(err), "polling faster than the interval")
+	_, err = poll("other", code.DeviceCode)
+	assert.Equal(t, errors.OAuthInvalidGrant, oauthErrorCode(err))
+
+	// Код вводится без учета регистра и дефиса
+	authorization, client, err := svc.DeviceAuthorization(ctx, strings.ToLower(strings.ReplaceAll(code.UserCode, "-", "")))
+	require.NoError(t, err)
+	assert.Equal(t, "CLI", client.Name)
+	assert.Equal(t, "openid profile", authorization.Scope)
+
+	require.NoError(t, svc.CompleteDeviceAuthorization(ctx, code.UserCode, user.ID, true))
+	assert.Equal(t, errors.ErrNotFound, svc.CompleteDeviceAuthorization(ctx, code.UserCode, 8, true), "user code is single use")
+
+	tokens, err := poll("cli", code.DeviceCode)
+	require.NoError(t, err)
+	assert.Equal(t, "access-7", tokens.AccessToken)
+	assert.NotEmpty(t, tokens.IDToken)
+	assert.Equal(t, domain.ClientInfo{DeviceName: "CLI", UserAgent: "cli/1.0", IPAddress: "10.0.0.2", LoginMethod: domain.LoginMethodDevice},
+		sessions.started[len(sessions.started)-1])
+
+	_, err = poll("cli", code.DeviceCode)
+	assert.Equal(t, errors.OAuthExpiredToken, oauthErrorCode(err), "device code is single use")
+
+	denied, err := svc.StartDeviceAuthorization(ctx, "cli", "", "", device)
+	require.NoError(t, err)
+	require.NoError(t, svc.CompleteDeviceAuthorization(ctx, denied.UserCode, user.ID, false))
+	_, err = poll("cli", denied.DeviceCode)
+	assert.Equal(t, errors.OAuthAccessDenied, oauthErrorCode(err))
+}
+
+func TestOIDCService_ApplicationTokens(t *testing.T) {
+	ctx := context.Background()
+	log, err := logger.New("error")
+	require.NoError(t, err)
+	tokenManager, err := jwt.NewJWTTokenManager(config.JWTConfig{Secret: "secret"})
+	require.NoError(t, err)
+
+	user := &domain.User{ID: 7, Email: "user@example.com", UserName: "user"}
+	userRepo := new(MockUserRepository)
+	userRepo.On("GetUserByID", mock.Anything, user.ID).Return(user, nil)
+
+	clients := memoryOAuthClients{
+		"app":   {ID: "app", Name: "App", RedirectURIs: []string{"https://app.example.com/callback"}},
+		"other": {ID: "other", Name: "Other", RedirectURIs: []string{"https://other.example.com/callback"}},
+	}
+	refreshTokens := NewRefreshTokenService(memoryRefreshTokens{}, tokenManager, userRepo, adminAuthorizer{}, log)
+	svc := NewOIDCService(clients, userRepo, &memoryCache{values: map[string]string{}}, rotatingSessions{refreshTokens: refreshTokens},
+		tokenManager, config.OIDCConfig{Issuer: "https://auth.example.com", IDTokenTTL: time.Hour}, log)
+
+	app := clients["app"]
+	tokens, err := svc.issueUserTokens(ctx, &app, user.ID, "openid email", "", 0, domain.ClientInfo{})
+	require.NoError(t, err)
+
+	// Access токен приложения привязан к нему и не несет ролей пользователя
+	claims, err := tokenManager.ValidateAccessToken(tokens.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, "app", claims.ClientID)
+	assert.Equal(t, "openid email", claims.Scope)
+	assert.Empty(t, claims.Roles)
+	assert.Empty(t, claims.Permissions)
+
+	// Refresh токен обменивает только приложение, которому он выдан; чужая попытка не отзывает семейство
+	_, err = svc.Refresh(ctx, "other", "", tokens.RefreshToken)
+	assert.Equal(t, errors.OAuthInvalidGrant, oauthErrorCode(err))
+	_, err = refreshTokens.Rotate(ctx, tokens.RefreshToken, "")
+	assert.Equal(t, errors.ErrInvalidToken, err, "application token is not accepted at /refresh-token")
+
+	refreshed, err := svc.Refresh(ctx, "app", "", tokens.RefreshToken)
+	require.NoError(t, err)
+	assert.Equal(t, "openid email", refreshed.Scope)
+	claims, err = tokenManager.ValidateAccessToken(refreshed.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, "app", claims.ClientID)
+	assert.Empty(t, claims.Roles)
+
+	// Токены входа в сам сервис не принимаются на /oauth2/token
+	pair, err := refreshTokens.Issue(ctx, domain.UserClaims{UserID: "7", Email: user.Email}, "family-web", "browser")
+	require.NoError(t, err)
+	claims, err = tokenManager.ValidateAccessToken(pair.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, []string{domain.RoleAdmin}, claims.Roles)
+	_, err = svc.Refresh(ctx, "app", "", pair.RefreshToken)
+	assert.Equal(t, errors.OAuthInvalidGrant, oauthErrorCode(err))
+	_, err = refreshTokens.Rotate(ctx, pair.RefreshToken, "")
+	assert.NoError(t, err)
+}
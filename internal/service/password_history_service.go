@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+
+	"github.com/Alias1177/Auth/internal/domain"
+	"github.com/Alias1177/Auth/internal/errors"
+	"github.com/Alias1177/Auth/pkg/logger"
+	crypto "github.com/Alias1177/Auth/pkg/security"
+)
+
+// PasswordRuleReused пароль совпадает с текущим или одним из недавних
+const PasswordRuleReused = "reused"
+
+// PasswordHistoryRepository хранилище хешей прежних паролей
+type PasswordHistoryRepository interface {
+	GetPasswordHistory(ctx context.Context, userID, limit int) ([]string, error)
+	AddPasswordHistory(ctx context.Context, userID int, hash string, keep int) error
+}
+
+// PasswordHistoryService запрет повторного использования последних паролей
+type PasswordHistoryService interface {
+	// CheckReuse возвращает *errors.PasswordPolicyError, если пароль совпадает с текущим паролем user
+	// или с одним из последних сохраненных
+	CheckReuse(ctx context.Context, user *domain.User, password string) error
+	// Record сохраняет хеш только что установленного пароля
+	Record(ctx context.Context, userID int, hash string)
+}
+
+// PasswordHistoryServiceImpl реализация истории паролей; при size <= 0 история не ведется
+type PasswordHistoryServiceImpl struct {
+	repo   PasswordHistoryRepository
+	size   int
+	logger *logger.Logger
+}
+
+// NewPasswordHistoryService создает сервис истории паролей, хранящий size последних паролей
+func NewPasswordHistoryService(repo PasswordHistoryRepository, size int, logger *logger.Logger) *PasswordHistoryServiceImpl {
+	return &PasswordHistoryServiceImpl{
+		repo:   repo,
+		size:   size,
+		logger: logger,
+	}
+}
+
+// CheckReuse проверяет пароль по текущему хешу и истории
+func (s *PasswordHistoryServiceImpl) CheckReuse(ctx context.Context, user *domain.User, password string) error {
+	if s.size <= 0 {
+		return nil
+	}
+
+	hashes, err := s.repo.GetPasswordHistory(ctx, user.ID, s.size)
+	if err != nil {
+		return err
+	}
+	// Текущий пароль мог быть установлен до появления истории
+	if user.Password != "" {
+		hashes = append(hashes, user.Password)
+	}
+
+	for _, hash := range hashes {
+		if crypto.VerifyPassword(hash, password) == nil {
+			s.logger.Infow("Password reuse rejected", "user_id", user.ID)
+			return &errors.PasswordPolicyError{Violations: []errors.PasswordViolation{{
+				Rule:    PasswordRuleReused,
+				Message: "Password must differ from your recent passwords",
+			}}}
+		}
+	}
+	return nil
+}
+
+// Record добавляет хеш в историю и удаляет записи сверх size.
+// Пароль к этому моменту уже сменен, поэтому ошибка только записывается в лог.
+func (s *PasswordHistoryServiceImpl) Record(ctx context.Context, userID int, hash string) {
+	if s.size <= 0 {
+		return
+	}
+	if err := s.repo.AddPasswordHistory(ctx, userID, hash, s.size); err != nil {
+		s.logger.Errorw("Failed to record password history", "user_id", userID, "error", err)
+	}
+}
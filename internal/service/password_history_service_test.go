@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Alias1177/Auth/internal/domain"
+	"github.com/Alias1177/Auth/internal/errors"
+	"github.com/Alias1177/Auth/pkg/logger"
+	crypto "github.com/Alias1177/Auth/pkg/security"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryPasswordHistory хранит историю в памяти с той же обрезкой, что и PostgreSQL
+type memoryPasswordHistory struct {
+	hashes map[int][]string // от новых к старым
+}
+
+func (m *memoryPasswordHistory) GetPasswordHistory(_ context.Context, userID, limit int) ([]string, error) {
+	hashes := m.hashes[userID]
+	if len(hashes) > limit {
+		hashes = hashes[:limit]
+	}
+	return hashes, nil
+}
+
+func (m *memoryPasswordHistory) AddPasswordHistory(_ context.Context, userID int, hash string, keep int) error {
+	hashes := append([]string{hash}, m.hashes[userID]...)
+	if len(hashes) > keep {
+		hashes = hashes[:keep]
+	}
+	m.hashes[userID] = hashes
+	return nil
+}
+
+func TestPasswordHistoryService(t *testing.T) {
+	ctx := context.Background()
+	log, err := logger.New("error")
+	require.NoError(t, err)
+	crypto.SetDefaultHasher(crypto.NewPasswordHasher(crypto.NewBcryptHasher(4)))
+
+	repo := &memoryPasswordHistory{hashes: map[int][]string{}}
+	history := NewPasswordHistoryService(repo, 2, log)
+	user := &domain.User{ID: 7}
+
+	setPassword := func(password string) {
+		hash, err := crypto.HashPassword(password)
+		require.NoError(t, err)
+		user.Password = hash
+		history.Record(ctx, user.ID, hash)
+	}
+	assertReused := func(password string, want bool) {
+		err := history.CheckReuse(ctx, user, password)
+		if !want {
+			assert.NoError(t, err, password)
+			return
+		}
+		var policyErr *errors.PasswordPolicyError
+		require.ErrorAs(t, err, &policyErr, password)
+		assert.Equal(t, PasswordRuleReused, policyErr.Violations[0].Rule)
+	}
+
+	setPassword("First#Pass1")
+	setPassword("Second#Pass2")
+	assertReused("Second#Pass2", true)
+	assertReused("First#Pass1", true)
+
+	// Третий пароль вытесняет первый из истории размером 2
+	setPassword("Third#Pass3")
+	assertReused("First#Pass1", false)
+	assertReused("Second#Pass2", true)
+	assert.Len(t, repo.hashes[user.ID], 2)
+
+	// Если история отключена, повтор не проверяется даже для текущего пароля
+	disabled := NewPasswordHistoryService(repo, 0, log)
+	assert.NoError(t, disabled.CheckReuse(ctx, user, "Third#Pass3"))
+}
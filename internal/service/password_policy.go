@@ -0,0 +1,202 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Alias1177/Auth/internal/config"
+	"github.com/Alias1177/Auth/internal/domain"
+	"github.com/Alias1177/Auth/internal/errors"
+	"github.com/Alias1177/Auth/pkg/logger"
+	crypto "github.com/Alias1177/Auth/pkg/security"
+)
+
+// Правила политики паролей, возвращаются клиенту в списке нарушений
+const (
+	PasswordRuleMinLength        = "min_length"
+	PasswordRuleMaxLength        = "max_length"
+	PasswordRuleUppercase        = "uppercase"
+	PasswordRuleLowercase        = "lowercase"
+	PasswordRuleDigit            = "digit"
+	PasswordRuleSpecial          = "special"
+	PasswordRuleContainsEmail    = "contains_email"
+	PasswordRuleContainsUsername = "contains_username"
+	PasswordRuleDictionaryWord   = "dictionary_word"
+	PasswordRuleBreached         = "breached"
+)
+
+// minUserInfoLength короче этой длины имя и адрес не проверяются: иначе запрещались бы случайные совпадения
+const minUserInfoLength = 3
+
+// commonPasswords встроенный список самых распространенных паролей и слов из них
+var commonPasswords = []string{
+	"password", "passw0rd", "qwerty", "qwertyuiop", "letmein", "welcome", "admin",
+	"administrator", "login", "master", "monkey", "dragon", "football", "baseball",
+	"iloveyou", "sunshine", "princess", "shadow", "superman", "trustno1", "abc123",
+	"123456", "12345678", "123456789", "1234567890", "111111", "000000", "654321",
+	"changeme", "secret", "default", "guest", "root", "test", "user",
+}
+
+// PasswordPolicy проверка пароля по политике.
+// Единственное место, где решается, подходит ли пароль: регистрация, смена, сброс и установка администратором.
+type PasswordPolicy interface {
+	// Check возвращает *errors.PasswordPolicyError со всеми нарушенными правилами.
+	// user может быть nil или заполнен частично: проверяются только известные поля.
+	Check(ctx context.Context, password string, user *domain.User) error
+}
+
+// PasswordPolicyImpl реализация политики паролей на основе конфигурации
+type PasswordPolicyImpl struct {
+	cfg        config.PasswordPolicyConfig
+	dictionary map[string]struct{}
+	breach     crypto.BreachChecker
+	failOpen   bool
+	logger     *logger.Logger
+}
+
+// NewPasswordPolicy создает политику паролей; словарь из cfg.DictionaryPath дополняет встроенный список.
+// breach может быть nil — тогда пароли по базе утечек не проверяются. При failOpen недоступность
+// базы утечек не мешает установить пароль, иначе Check возвращает ошибку проверки.
+func NewPasswordPolicy(
+	cfg config.PasswordPolicyConfig,
+	breach crypto.BreachChecker,
+	failOpen bool,
+	logger *logger.Logger,
+) (*PasswordPolicyImpl, error) {
+	if cfg.MinLength < 1 {
+		return nil, fmt.Errorf("password min length must be positive, got %d", cfg.MinLength)
+	}
+	if cfg.MaxLength < cfg.MinLength {
+		return nil, fmt.Errorf("password max length %d is less than min length %d", cfg.MaxLength, cfg.MinLength)
+	}
+
+	dictionary := make(map[string]struct{}, len(commonPasswords))
+	for _, word := range commonPasswords {
+		dictionary[word] = struct{}{}
+	}
+	if cfg.DictionaryPath != "" {
+		if err := loadPasswordDictionary(cfg.DictionaryPath, dictionary); err != nil {
+			return nil, err
+		}
+	}
+
+	return &PasswordPolicyImpl{
+		cfg:        cfg,
+		dictionary: dictionary,
+		breach:     breach,
+		failOpen:   failOpen,
+		logger:     logger,
+	}, nil
+}
+
+// Check проверяет пароль по всем правилам политики
+func (p *PasswordPolicyImpl) Check(ctx context.Context, password string, user *domain.User) error {
+	var violations []errors.PasswordViolation
+	violate := func(rule, format string, args ...interface{}) {
+		violations = append(violations, errors.PasswordViolation{Rule: rule, Message: fmt.Sprintf(format, args...)})
+	}
+
+	length := utf8.RuneCountInString(password)
+	if length < p.cfg.MinLength {
+		violate(PasswordRuleMinLength, "Password must be at least %d characters long", p.cfg.MinLength)
+	}
+	if length > p.cfg.MaxLength {
+		violate(PasswordRuleMaxLength, "Password must be at most %d characters long", p.cfg.MaxLength)
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSpecial bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case !unicode.IsLetter(r) && !unicode.IsSpace(r):
+			hasSpecial = true
+		}
+	}
+	if p.cfg.RequireUpper && !hasUpper {
+		violate(PasswordRuleUppercase, "Password must contain an uppercase letter")
+	}
+	if p.cfg.RequireLower && !hasLower {
+		violate(PasswordRuleLowercase, "Password must contain a lowercase letter")
+	}
+	if p.cfg.RequireDigit && !hasDigit {
+		violate(PasswordRuleDigit, "Password must contain a digit")
+	}
+	if p.cfg.RequireSpecial && !hasSpecial {
+		violate(PasswordRuleSpecial, "Password must contain a special character")
+	}
+
+	lowered := strings.ToLower(password)
+	if p.cfg.DisallowUser && user != nil {
+		local, _, _ := strings.Cut(strings.ToLower(user.Email), "@")
+		if len(local) >= minUserInfoLength && strings.Contains(lowered, local) {
+			violate(PasswordRuleContainsEmail, "Password must not contain your email address")
+		}
+		username := strings.ToLower(user.UserName)
+		if len(username) >= minUserInfoLength && strings.Contains(lowered, username) {
+			violate(PasswordRuleContainsUsername, "Password must not contain your username")
+		}
+	}
+
+	if p.isDictionaryWord(lowered) {
+		violate(PasswordRuleDictionaryWord, "Password is too common")
+	}
+
+	if p.breach != nil {
+		breached, err := p.breach.IsBreached(ctx, password)
+		switch {
+		case err != nil && p.failOpen:
+			p.logger.Warnw("Breached password check unavailable, skipping", "error", err)
+		case err != nil:
+			return fmt.Errorf("breached password check failed: %w", err)
+		case breached:
+			violate(PasswordRuleBreached, "Password has appeared in a data breach")
+		}
+	}
+
+	if len(violations) > 0 {
+		return &errors.PasswordPolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// isDictionaryWord проверяет пароль целиком и без цифр и знаков по краям: "Password1!" считается словом "password"
+func (p *PasswordPolicyImpl) isDictionaryWord(lowered string) bool {
+	if _, ok := p.dictionary[lowered]; ok {
+		return true
+	}
+	core := strings.TrimFunc(lowered, func(r rune) bool { return !unicode.IsLetter(r) })
+	_, ok := p.dictionary[core]
+	return ok
+}
+
+// loadPasswordDictionary добавляет в словарь слова из файла, по одному в строке; пустые строки и # комментарии пропускаются
+func loadPasswordDictionary(path string, dictionary map[string]struct{}) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open password dictionary: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		word := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		if word == "" || strings.HasPrefix(word, "#") {
+			continue
+		}
+		dictionary[word] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read password dictionary: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"crypto/sha1"
+	stderrors "errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Alias1177/Auth/internal/config"
+	"github.com/Alias1177/Auth/internal/domain"
+	"github.com/Alias1177/Auth/internal/errors"
+	crypto "github.com/Alias1177/Auth/pkg/security"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPasswordPolicy_Check(t *testing.T) {
+	dictionary := filepath.Join(t.TempDir(), "words.txt")
+	require.NoError(t, os.WriteFile(dictionary, []byte("# local words\nKangaroo\n\n"), 0o600))
+
+	breached := crypto.NewBloomFilter(10, 0.001)
+	breached.Add(sha1.Sum([]byte("Leaked#Pass99")))
+
+	policy, err := NewPasswordPolicy(config.PasswordPolicyConfig{
+		MinLength:      8,
+		MaxLength:      20,
+		RequireUpper:   true,
+		RequireLower:   true,
+		RequireDigit:   true,
+		RequireSpecial: true,
+		DisallowUser:   true,
+		DictionaryPath: dictionary,
+	}, breached, false, nil)
+	require.NoError(t, err)
+
+	user := &domain.User{Email: "alice.smith@example.com", UserName: "wonderland"}
+
+	tests := []struct {
+		name     string
+		password string
+		want     []string
+	}{
+		{name: "valid", password: "Tr0ub4dor&3x", want: nil},
+		{name: "too short", password: "Ab1!", want: []string{PasswordRuleMinLength}},
+		{name: "too long", password: "Ab1!Ab1!Ab1!Ab1!Ab1!x", want: []string{PasswordRuleMaxLength}},
+		{name: "missing classes", password: "lowercaseonly", want: []string{PasswordRuleUppercase, PasswordRuleDigit, PasswordRuleSpecial}},
+		{name: "contains email", password: "Alice.Smith#42", want: []string{PasswordRuleContainsEmail}},
+		{name: "contains username", password: "xWonderland9!", want: []string{PasswordRuleContainsUsername}},
+		{name: "builtin dictionary", password: "Password1!", want: []string{PasswordRuleDictionaryWord}},
+		{name: "file dictionary", password: "!Kangaroo77", want: []string{PasswordRuleDictionaryWord}},
+		{name: "breached", password: "Leaked#Pass99", want: []string{PasswordRuleBreached}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.Check(context.Background(), tt.password, user)
+			if tt.want == nil {
+				assert.NoError(t, err)
+				return
+			}
+
+			var policyErr *errors.PasswordPolicyError
+			require.ErrorAs(t, err, &policyErr)
+			assert.True(t, stderrors.Is(err, errors.ErrInvalidPassword))
+
+			rules := make([]string, 0, len(policyErr.Violations))
+			for _, v := range policyErr.Violations {
+				rules = append(rules, v.Rule)
+				assert.NotEmpty(t, v.Message)
+			}
+			assert.Equal(t, tt.want, rules)
+		})
+	}
+}
+
+func TestNewPasswordPolicy_InvalidConfig(t *testing.T) {
+	_, err := NewPasswordPolicy(config.PasswordPolicyConfig{MinLength: 12, MaxLength: 8}, nil, false, nil)
+	assert.Error(t, err)
+
+	_, err = NewPasswordPolicy(config.PasswordPolicyConfig{MinLength: 8, MaxLength: 64, DictionaryPath: "/nonexistent/words.txt"}, nil, false, nil)
+	assert.Error(t, err)
+}
@@ -21,6 +21,8 @@ type PasswordResetService interface {
 type PasswordResetServiceImpl struct {
 	userRepo           UserRepository
 	userCache          UserCache
+	policy             PasswordPolicy
+	history            PasswordHistoryService
 	logger             *logger.Logger
 	kafkaProducer      *kafka.Producer
 	notificationClient *notification.NotificationClient
@@ -30,6 +32,8 @@ type PasswordResetServiceImpl struct {
 func NewPasswordResetService(
 	userRepo UserRepository,
 	userCache UserCache,
+	policy PasswordPolicy,
+	history PasswordHistoryService,
 	logger *logger.Logger,
 	kafkaProducer *kafka.Producer,
 	notificationClient *notification.NotificationClient,
@@ -37,6 +41,8 @@ func NewPasswordResetService(
 	return &PasswordResetServiceImpl{
 		userRepo:           userRepo,
 		userCache:          userCache,
+		policy:             policy,
+		history:            history,
 		logger:             logger,
 		kafkaProducer:      kafkaProducer,
 		notificationClient: notificationClient,
@@ -83,6 +89,13 @@ func (s *PasswordResetServiceImpl) ConfirmReset(ctx context.Context, email, code
 		return errors.ErrUserNotFound
 	}
 
+	if err := s.policy.Check(ctx, newPassword, user); err != nil {
+		return err
+	}
+	if err := s.history.CheckReuse(ctx, user, newPassword); err != nil {
+		return err
+	}
+
 	// Хешируем новый пароль
 	hashedPassword, err := crypto.HashPassword(newPassword)
 	if err != nil {
@@ -97,6 +110,8 @@ func (s *PasswordResetServiceImpl) ConfirmReset(ctx context.Context, email, code
 		return errors.ErrInternal
 	}
 
+	s.history.Record(ctx, user.ID, hashedPassword)
+
 	s.logger.Infow("Password successfully reset", "email", email, "user_id", user.ID)
 	return nil
 }
@@ -0,0 +1,249 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	stderrors "errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Alias1177/Auth/internal/domain"
+	"github.com/Alias1177/Auth/internal/errors"
+	"github.com/Alias1177/Auth/pkg/logger"
+	crypto "github.com/Alias1177/Auth/pkg/security"
+)
+
+// Параметры кодов восстановления
+const (
+	recoveryCodeCount  = 10
+	recoveryCodeLength = 12 // символов без дефисов, 60 бит энтропии
+	recoveryGroupSize  = 4
+	// Алфавит без похожих символов (0/o, 1/l/i)
+	recoveryAlphabet = "23456789abcdefghjkmnpqrstuvwxyz"
+
+	maxRecoveryAttempts   = 5
+	recoveryAttemptWindow = 15 * time.Minute
+)
+
+// RecoveryCodeRepository хранилище хешей кодов восстановления
+type RecoveryCodeRepository interface {
+	ReplaceRecoveryCodes(ctx context.Context, userID int, codeHashes []string) error
+	CountUnusedRecoveryCodes(ctx context.Context, userID int) (int, error)
+	UseRecoveryCode(ctx context.Context, userID int, codeHash string) error
+}
+
+// RecoveryCodesSender уведомление пользователя о выпуске нового набора кодов
+type RecoveryCodesSender interface {
+	SendRecoveryCodesRegenerated(ctx context.Context, email, username string, regeneratedAt time.Time) error
+}
+
+// RecoveryCodeService одноразовые коды восстановления доступа, не зависящие от почты
+type RecoveryCodeService interface {
+	Generate(ctx context.Context, userID int) ([]string, error)
+	Regenerate(ctx context.Context, userID int) ([]string, error)
+	Remaining(ctx context.Context, userID int) (int, error)
+	Redeem(ctx context.Context, email, code, newPassword string) error
+}
+
+// RecoveryCodeServiceImpl реализация сервиса кодов восстановления
+type RecoveryCodeServiceImpl struct {
+	repo     RecoveryCodeRepository
+	userRepo UserRepository
+	mfa      MFARepository
+	cache    UserCache
+	sessions SessionService
+	policy   PasswordPolicy
+	history  PasswordHistoryService
+	notifier RecoveryCodesSender
+	logger   *logger.Logger
+}
+
+// NewRecoveryCodeService создает новый экземпляр сервиса кодов восстановления
+func NewRecoveryCodeService(
+	repo RecoveryCodeRepository,
+	userRepo UserRepository,
+	mfa MFARepository,
+	cache UserCache,
+	sessions SessionService,
+	policy PasswordPolicy,
+	history PasswordHistoryService,
+	notifier RecoveryCodesSender,
+	logger *logger.Logger,
+) *RecoveryCodeServiceImpl {
+	return &RecoveryCodeServiceImpl{
+		repo:     repo,
+		userRepo: userRepo,
+		mfa:      mfa,
+		cache:    cache,
+		sessions: sessions,
+		policy:   policy,
+		history:  history,
+		notifier: notifier,
+		logger:   logger,
+	}
+}
+
+// Generate создает первый набор кодов; если неиспользованные коды уже есть, возвращает ErrRecoveryCodesExist
+func (s *RecoveryCodeServiceImpl) Generate(ctx context.Context, userID int) ([]string, error) {
+	remaining, err := s.repo.CountUnusedRecoveryCodes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if remaining > 0 {
+		return nil, errors.ErrRecoveryCodesExist
+	}
+	return s.replace(ctx, userID)
+}
+
+// Regenerate создает новый набор кодов, делая недействительным предыдущий, и уведомляет пользователя письмом.
+// Коды возвращаются в открытом виде только один раз.
+func (s *RecoveryCodeServiceImpl) Regenerate(ctx context.Context, userID int) ([]string, error) {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	codes, err := s.replace(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Коды уже выпущены: при ошибке отправки ответ все равно успешный, ошибка попадает в лог
+	if err := s.notifier.SendRecoveryCodesRegenerated(ctx, user.Email, user.UserName, time.Now()); err != nil {
+		s.logger.Errorw("Failed to send recovery codes regenerated event", "user_id", userID, "error", err)
+	}
+	return codes, nil
+}
+
+// replace сохраняет хеши нового набора кодов вместо прежнего
+func (s *RecoveryCodeServiceImpl) replace(ctx context.Context, userID int) ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		code, err := newRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes[i] = code
+		hashes[i] = hashRecoveryCode(userID, code)
+	}
+
+	if err := s.repo.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+	s.logger.Infow("Recovery codes generated", "user_id", userID)
+	return codes, nil
+}
+
+// Remaining возвращает число неиспользованных кодов
+func (s *RecoveryCodeServiceImpl) Remaining(ctx context.Context, userID int) (int, error) {
+	return s.repo.CountUnusedRecoveryCodes(ctx, userID)
+}
+
+// Redeem погашает код восстановления: устанавливает новый пароль, отключает второй фактор
+// и завершает все сессии пользователя. Для неизвестного email и неверного кода возвращается одна и та же ошибка.
+func (s *RecoveryCodeServiceImpl) Redeem(ctx context.Context, email, code, newPassword string) error {
+	attempts, err := s.cache.Increment(ctx, recoveryAttemptsKey(email), recoveryAttemptWindow)
+	if err != nil {
+		return err
+	}
+	if attempts > maxRecoveryAttempts {
+		return errors.ErrTooManyRequests
+	}
+
+	user, err := s.userRepo.GetUserByEmail(ctx, email)
+	if err != nil && !stderrors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	// Политика проверяется до погашения кода, чтобы слабый пароль не сжигал код;
+	// для неизвестного адреса проверка та же, ответ не раскрывает наличие аккаунта
+	policyUser := user
+	if policyUser == nil {
+		policyUser = &domain.User{Email: email}
+	}
+	if err := s.policy.Check(ctx, newPassword, policyUser); err != nil {
+		return err
+	}
+	if user == nil {
+		return errors.ErrInvalidRecoveryCode
+	}
+
+	if err := s.repo.UseRecoveryCode(ctx, user.ID, hashRecoveryCode(user.ID, code)); err != nil {
+		if stderrors.Is(err, sql.ErrNoRows) {
+			s.logger.Warnw("Invalid recovery code", "user_id", user.ID, "attempt", attempts)
+			return errors.ErrInvalidRecoveryCode
+		}
+		return err
+	}
+
+	hashedPassword, err := crypto.HashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+	user.Password = hashedPassword
+	if err := s.userRepo.UpdateUser(ctx, user); err != nil {
+		return err
+	}
+
+	// Повтор прежнего пароля здесь не проверяется: проверка до погашения кода
+	// позволила бы без кода узнавать, совпадает ли пароль с текущим
+	s.history.Record(ctx, user.ID, hashedPassword)
+
+	// Код восстановления нужен тому, кто потерял устройство с TOTP: иначе вход остановится на втором факторе.
+	// Включить второй фактор заново можно после входа.
+	if err := s.mfa.DeleteUserMFA(ctx, user.ID); err != nil {
+		return err
+	}
+	if err := s.sessions.RevokeAll(ctx, user.ID); err != nil {
+		return err
+	}
+	if err := s.cache.Delete(ctx, recoveryAttemptsKey(email)); err != nil {
+		s.logger.Warnw("Failed to reset recovery attempts", "user_id", user.ID, "error", err)
+	}
+
+	s.logger.Infow("Account recovered with recovery code", "user_id", user.ID)
+	return nil
+}
+
+// newRecoveryCode генерирует код вида xxxx-xxxx-xxxx
+func newRecoveryCode() (string, error) {
+	// Байты за пределами кратного длине алфавита отбрасываются, чтобы символы были равновероятны
+	limit := byte(256 / len(recoveryAlphabet) * len(recoveryAlphabet))
+	chars := make([]byte, 0, recoveryCodeLength)
+	buf := make([]byte, recoveryCodeLength)
+	for len(chars) < recoveryCodeLength {
+		if _, err := rand.Read(buf); err != nil {
+			return "", err
+		}
+		for _, v := range buf {
+			if v < limit && len(chars) < recoveryCodeLength {
+				chars = append(chars, recoveryAlphabet[int(v)%len(recoveryAlphabet)])
+			}
+		}
+	}
+
+	var b strings.Builder
+	for i, c := range chars {
+		if i > 0 && i%recoveryGroupSize == 0 {
+			b.WriteByte('-')
+		}
+		b.WriteByte(c)
+	}
+	return b.String(), nil
+}
+
+// hashRecoveryCode нормализует код (регистр, дефисы, пробелы) и хеширует его вместе с ID пользователя
+func hashRecoveryCode(userID int, code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(strconv.Itoa(userID) + ":" + normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+func recoveryAttemptsKey(email string) string {
+	return fmt.Sprintf("recovery:attempts:%s", strings.ToLower(email))
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/Alias1177/Auth/internal/config"
+	"github.com/Alias1177/Auth/internal/domain"
+	"github.com/Alias1177/Auth/internal/errors"
+	"github.com/Alias1177/Auth/pkg/logger"
+	crypto "github.com/Alias1177/Auth/pkg/security"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryRecoveryCodes хеши неиспользованных кодов в памяти
+type memoryRecoveryCodes map[int]map[string]bool
+
+func (m memoryRecoveryCodes) ReplaceRecoveryCodes(_ context.Context, userID int, codeHashes []string) error {
+	m[userID] = map[string]bool{}
+	for _, hash := range codeHashes {
+		m[userID][hash] = true
+	}
+	return nil
+}
+
+func (m memoryRecoveryCodes) CountUnusedRecoveryCodes(_ context.Context, userID int) (int, error) {
+	return len(m[userID]), nil
+}
+
+func (m memoryRecoveryCodes) UseRecoveryCode(_ context.Context, userID int, codeHash string) error {
+	if !m[userID][codeHash] {
+		return sql.ErrNoRows
+	}
+	delete(m[userID], codeHash)
+	return nil
+}
+
+// memoryMFA запоминает пользователей, у которых включен второй фактор
+type memoryMFA struct {
+	MFARepository
+	enabled map[int]bool
+}
+
+func (m *memoryMFA) DeleteUserMFA(_ context.Context, userID int) error {
+	delete(m.enabled, userID)
+	return nil
+}
+
+// recordingRecoveryNotifier запоминает адреса, получившие уведомление о новых кодах
+type recordingRecoveryNotifier struct {
+	emails []string
+}
+
+func (n *recordingRecoveryNotifier) SendRecoveryCodesRegenerated(_ context.Context, email, _ string, _ time.Time) error {
+	n.emails = append(n.emails, email)
+	return nil
+}
+
+func TestNewRecoveryCode(t *testing.T) {
+	format := regexp.MustCompile(`^[` + recoveryAlphabet + `]{4}-[` + recoveryAlphabet + `]{4}-[` + recoveryAlphabet + `]{4}$`)
+	seen := map[string]bool{}
+	for i := 0; i < 100; i++ {
+		code, err := newRecoveryCode()
+		require.NoError(t, err)
+		assert.Regexp(t, format, code)
+		assert.False(t, seen[code], "duplicate code %s", code)
+		seen[code] = true
+	}
+}
+
+func TestHashRecoveryCode(t *testing.T) {
+	tests := []struct {
+		name  string
+		code  string
+		equal bool
+	}{
+		{name: "same code", code: "abcd-efgh-jkmn", equal: true},
+		{name: "upper case", code: "ABCD-EFGH-JKMN", equal: true},
+		{name: "without dashes", code: "abcdefghjkmn", equal: true},
+		{name: "with spaces", code: " abcd efgh jkmn ", equal: true},
+		{name: "other code", code: "abcd-efgh-jkmp", equal: false},
+	}
+
+	expected := hashRecoveryCode(1, "abcd-efgh-jkmn")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.equal, hashRecoveryCode(1, tt.code) == expected)
+		})
+	}
+
+	// Один и тот же код у разных пользователей дает разные хеши
+	assert.NotEqual(t, expected, hashRecoveryCode(2, "abcd-efgh-jkmn"))
+}
+
+func TestRecoveryCodeService_Redeem(t *testing.T) {
+	ctx := context.Background()
+	log, err := logger.New("error")
+	require.NoError(t, err)
+	crypto.SetDefaultHasher(crypto.NewPasswordHasher(crypto.NewBcryptHasher(4)))
+
+	user := &domain.User{ID: 7, Email: "user@example.com", UserName: "user", Password: "old-hash"}
+	userRepo := new(MockUserRepository)
+	userRepo.On("GetUserByID", mock.Anything, user.ID).Return(user, nil)
+	userRepo.On("GetUserByEmail", mock.Anything, user.Email).Return(user, nil)
+	userRepo.On("UpdateUser", mock.Anything, mock.Anything).Return(nil)
+
+	policy, err := NewPasswordPolicy(config.PasswordPolicyConfig{MinLength: 8, MaxLength: 64}, nil, false, log)
+	require.NoError(t, err)
+	history := NewPasswordHistoryService(&memoryPasswordHistory{hashes: map[int][]string{}}, 0, log)
+
+	codes := memoryRecoveryCodes{}
+	mfa := &memoryMFA{enabled: map[int]bool{user.ID: true}}
+	sessions := &revokingSessions{}
+	notifier := &recordingRecoveryNotifier{}
+	svc := NewRecoveryCodeService(codes, userRepo, mfa, &memoryCache{values: map[string]string{}}, sessions,
+		policy, history, notifier, log)
+
+	// Первый набор выпускается без уведомления, новый набор - с уведомлением
+	_, err = svc.Generate(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Empty(t, notifier.emails)
+	issued, err := svc.Regenerate(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{user.Email}, notifier.emails)
+
+	assert.Equal(t, errors.ErrInvalidRecoveryCode, svc.Redeem(ctx, user.Email, "aaaa-bbbb-cccc", "New#Password1"))
+	assert.True(t, mfa.enabled[user.ID], "invalid code must not disable MFA")
+
+	// Погашенный код отключает TOTP: иначе вход остановится на втором факторе
+	require.NoError(t, svc.Redeem(ctx, user.Email, issued[0], "New#Password1"))
+	assert.False(t, mfa.enabled[user.ID])
+	assert.Equal(t, []int{user.ID}, sessions.revoked)
+	assert.NoError(t, crypto.VerifyPassword(user.Password, "New#Password1"))
+	assert.Equal(t, errors.ErrInvalidRecoveryCode, svc.Redeem(ctx, user.Email, issued[0], "New#Password2"))
+}
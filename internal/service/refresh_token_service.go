@@ -0,0 +1,207 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	stderrors "errors"
+	"strconv"
+	"time"
+
+	"github.com/Alias1177/Auth/internal/domain"
+	"github.com/Alias1177/Auth/internal/errors"
+	"github.com/Alias1177/Auth/pkg/jwt"
+	"github.com/Alias1177/Auth/pkg/logger"
+	"github.com/google/uuid"
+)
+
+// maxDeviceLength ограничение длины описания устройства (размер колонки device)
+const maxDeviceLength = 255
+
+// RefreshTokenRepository хранилище выданных refresh токенов
+type RefreshTokenRepository interface {
+	CreateRefreshToken(ctx context.Context, token *domain.RefreshToken) error
+	GetRefreshToken(ctx context.Context, id string) (*domain.RefreshToken, error)
+	UseRefreshToken(ctx context.Context, id, clientID string) (*domain.RefreshToken, error)
+	RevokeRefreshTokenFamily(ctx context.Context, familyID string) error
+	RevokeUserRefreshTokens(ctx context.Context, userID int, exceptFamilyID string) error
+	DeleteExpiredRefreshTokens(ctx context.Context) (int64, error)
+}
+
+// RefreshTokenService выдача и одноразовая ротация refresh токенов.
+// Семейство, выданное приложению (claims.ClientID), обменивается только этим приложением.
+type RefreshTokenService interface {
+	Issue(ctx context.Context, claims domain.UserClaims, familyID, device string) (*domain.TokenPair, error)
+	Rotate(ctx context.Context, refreshToken, clientID string) (*domain.TokenPair, error)
+	RevokeFamily(ctx context.Context, familyID string) error
+	RevokeAllForUser(ctx context.Context, userID int, exceptFamilyID string) error
+}
+
+// RefreshTokenServiceImpl реализация сервиса refresh токенов
+type RefreshTokenServiceImpl struct {
+	repo         RefreshTokenRepository
+	tokenManager TokenManager
+	users        UserRepository
+	authorizer   UserAuthorizer
+	logger       *logger.Logger
+}
+
+// NewRefreshTokenService создает новый экземпляр сервиса refresh токенов.
+// Роли и разрешения из authorizer записываются в каждый выдаваемый access токен;
+// отключенным учетным записям токены не выдаются.
+func NewRefreshTokenService(
+	repo RefreshTokenRepository,
+	tokenManager TokenManager,
+	users UserRepository,
+	authorizer UserAuthorizer,
+	logger *logger.Logger,
+) *RefreshTokenServiceImpl {
+	return &RefreshTokenServiceImpl{
+		repo:         repo,
+		tokenManager: tokenManager,
+		users:        users,
+		authorizer:   authorizer,
+		logger:       logger,
+	}
+}
+
+// Issue выдает пару токенов для нового входа, начиная семейство refresh токенов familyID
+func (s *RefreshTokenServiceImpl) Issue(ctx context.Context, claims domain.UserClaims, familyID, device string) (*domain.TokenPair, error) {
+	return s.issue(ctx, claims, familyID, device)
+}
+
+// Rotate обменивает refresh токен на новую пару. clientID - приложение, предъявившее токен на /oauth2/token,
+// или пусто для /refresh-token; токен, выданный не этому предъявителю, недействителен.
+// Каждый токен одноразовый: повторное предъявление уже использованного токена означает его кражу,
+// поэтому все семейство отзывается и возвращается ErrTokenReused.
+func (s *RefreshTokenServiceImpl) Rotate(ctx context.Context, refreshToken, clientID string) (*domain.TokenPair, error) {
+	claims, err := s.tokenManager.ValidateRefreshToken(refreshToken)
+	if err != nil {
+		return nil, errors.ErrInvalidToken
+	}
+	if claims.TokenID == "" {
+		// Токены, выпущенные до появления хранилища, не обмениваются
+		return nil, errors.ErrInvalidToken
+	}
+
+	record, err := s.repo.UseRefreshToken(ctx, claims.TokenID, clientID)
+	if err != nil {
+		if !stderrors.Is(err, sql.ErrNoRows) {
+			s.logger.Errorw("Failed to use refresh token", "jti", claims.TokenID, "error", err)
+			return nil, errors.ErrInternal
+		}
+		return nil, s.rejectToken(ctx, claims.TokenID, clientID)
+	}
+
+	claims.UserID = strconv.Itoa(record.UserID)
+	claims.ClientID, claims.Scope = record.ClientID, record.Scope
+	return s.issue(ctx, *claims, record.FamilyID, record.Device)
+}
+
+// rejectToken определяет причину отказа и при повторном использовании отзывает семейство.
+// Токен чужого предъявителя просто отклоняется: отзыв семейства по нему позволил бы другому
+// приложению завершать сессии пользователя.
+func (s *RefreshTokenServiceImpl) rejectToken(ctx context.Context, tokenID, clientID string) error {
+	record, err := s.repo.GetRefreshToken(ctx, tokenID)
+	if err != nil {
+		return errors.ErrInvalidToken
+	}
+	if record.ClientID != clientID {
+		s.logger.Warnw("Refresh token presented by another client",
+			"jti", record.ID, "client_id", clientID, "token_client_id", record.ClientID)
+		return errors.ErrInvalidToken
+	}
+	if record.UsedAt == nil {
+		// Токен отозван или истек
+		return errors.ErrInvalidToken
+	}
+
+	s.logger.Warnw("Refresh token reuse detected, revoking family",
+		"jti", record.ID, "family_id", record.FamilyID, "user_id", record.UserID)
+	if err := s.repo.RevokeRefreshTokenFamily(ctx, record.FamilyID); err != nil {
+		s.logger.Errorw("Failed to revoke refresh token family", "family_id", record.FamilyID, "error", err)
+		return errors.ErrInternal
+	}
+	return errors.ErrTokenReused
+}
+
+// RevokeFamily отзывает все refresh токены одного входа
+func (s *RefreshTokenServiceImpl) RevokeFamily(ctx context.Context, familyID string) error {
+	return s.repo.RevokeRefreshTokenFamily(ctx, familyID)
+}
+
+// RevokeAllForUser отзывает все refresh токены пользователя, кроме семейства exceptFamilyID
+func (s *RefreshTokenServiceImpl) RevokeAllForUser(ctx context.Context, userID int, exceptFamilyID string) error {
+	return s.repo.RevokeUserRefreshTokens(ctx, userID, exceptFamilyID)
+}
+
+// PruneExpired удаляет истекшие refresh токены
+func (s *RefreshTokenServiceImpl) PruneExpired(ctx context.Context) {
+	deleted, err := s.repo.DeleteExpiredRefreshTokens(ctx)
+	if err != nil {
+		s.logger.Errorw("Failed to prune expired refresh tokens", "error", err)
+		return
+	}
+	if deleted > 0 {
+		s.logger.Infow("Pruned expired refresh tokens", "count", deleted)
+	}
+}
+
+// issue выпускает access и refresh токены в рамках семейства и сохраняет refresh токен
+func (s *RefreshTokenServiceImpl) issue(ctx context.Context, claims domain.UserClaims, familyID, device string) (*domain.TokenPair, error) {
+	userID, err := strconv.Atoi(claims.UserID)
+	if err != nil {
+		return nil, errors.ErrInvalidToken
+	}
+
+	// Все способы входа и обмен refresh токена проходят здесь, поэтому отключение проверяется в одном месте
+	user, err := s.users.GetUserByID(ctx, userID)
+	if err != nil {
+		if stderrors.Is(err, sql.ErrNoRows) {
+			return nil, errors.ErrInvalidToken
+		}
+		return nil, err
+	}
+	if user.Disabled() {
+		return nil, errors.ErrAccountDisabled
+	}
+
+	claims.FamilyID = familyID
+	claims.TokenID = uuid.NewString()
+	if claims.ClientID != "" {
+		// Токены приложения не дают его владельцу прав пользователя в самом сервисе
+		claims.Roles, claims.Permissions = nil, nil
+	} else {
+		// Роли читаются заново при каждой выдаче, поэтому ротация подхватывает изменения назначений
+		claims.Roles, claims.Permissions, err = s.authorizer.Authorization(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	accessToken, err := s.tokenManager.GenerateAccessToken(claims)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, err := s.tokenManager.GenerateRefreshToken(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	record := &domain.RefreshToken{
+		ID:        claims.TokenID,
+		FamilyID:  familyID,
+		UserID:    userID,
+		Device:    truncate(device, maxDeviceLength),
+		ClientID:  claims.ClientID,
+		Scope:     claims.Scope,
+		ExpiresAt: time.Now().Add(jwt.RefreshTokenTTL),
+	}
+	if err := s.repo.CreateRefreshToken(ctx, record); err != nil {
+		return nil, err
+	}
+
+	return &domain.TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	}, nil
+}
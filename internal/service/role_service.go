@@ -0,0 +1,292 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	stderrors "errors"
+	"sort"
+	"strconv"
+
+	"github.com/Alias1177/Auth/internal/domain"
+	"github.com/Alias1177/Auth/internal/errors"
+	"github.com/Alias1177/Auth/pkg/logger"
+)
+
+// RoleRepository хранилище ролей, разрешений и назначений ролей пользователям
+type RoleRepository interface {
+	ListRoles(ctx context.Context) ([]domain.Role, error)
+	GetRoleByName(ctx context.Context, name string) (*domain.Role, error)
+	CreateRole(ctx context.Context, role *domain.Role) error
+	SetRolePermissions(ctx context.Context, roleID int, permissions []string) error
+	DeleteRole(ctx context.Context, roleID int) error
+	ListPermissions(ctx context.Context) ([]domain.Permission, error)
+	GetUserRoles(ctx context.Context, userID int) ([]string, error)
+	GetUserPermissions(ctx context.Context, userID int) ([]string, error)
+	AssignRole(ctx context.Context, userID, roleID int) error
+	RemoveRole(ctx context.Context, userID, roleID int) error
+}
+
+// UserAuthorizer роли и разрешения пользователя, которые записываются в access токен
+type UserAuthorizer interface {
+	Authorization(ctx context.Context, userID int) (roles, permissions []string, err error)
+}
+
+// RoleService управление ролями и их назначением пользователям.
+// Новые роли попадают в токены при следующей выдаче, то есть не позже обмена refresh токена;
+// при снятии роли выданные access токены пользователя отзываются сразу.
+type RoleService interface {
+	UserAuthorizer
+	ListRoles(ctx context.Context) ([]domain.Role, error)
+	CreateRole(ctx context.Context, name, description string, permissions []string) (*domain.Role, error)
+	SetRolePermissions(ctx context.Context, name string, permissions []string) (*domain.Role, error)
+	DeleteRole(ctx context.Context, name string) error
+	ListPermissions(ctx context.Context) ([]domain.Permission, error)
+	UserRoles(ctx context.Context, userID int) ([]string, error)
+	AssignRole(ctx context.Context, actor domain.AuditActor, userID int, role string) error
+	RemoveRole(ctx context.Context, actor domain.AuditActor, userID int, role string) error
+}
+
+// RoleServiceImpl реализация сервиса ролей
+type RoleServiceImpl struct {
+	repo     RoleRepository
+	userRepo UserRepository
+	audit    AuditRepository
+	denylist AccessTokenDenylist
+	logger   *logger.Logger
+}
+
+// NewRoleService создает новый экземпляр сервиса ролей
+func NewRoleService(
+	repo RoleRepository,
+	userRepo UserRepository,
+	audit AuditRepository,
+	denylist AccessTokenDenylist,
+	logger *logger.Logger,
+) *RoleServiceImpl {
+	return &RoleServiceImpl{
+		repo:     repo,
+		userRepo: userRepo,
+		audit:    audit,
+		denylist: denylist,
+		logger:   logger,
+	}
+}
+
+// Authorization возвращает роли пользователя и объединение разрешений этих ролей
+func (s *RoleServiceImpl) Authorization(ctx context.Context, userID int) ([]string, []string, error) {
+	roles, err := s.repo.GetUserRoles(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(roles) == 0 {
+		return nil, nil, nil
+	}
+	permissions, err := s.repo.GetUserPermissions(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return roles, permissions, nil
+}
+
+// ListRoles возвращает все роли с разрешениями
+func (s *RoleServiceImpl) ListRoles(ctx context.Context) ([]domain.Role, error) {
+	return s.repo.ListRoles(ctx)
+}
+
+// CreateRole создает роль; все разрешения должны быть известны сервису
+func (s *RoleServiceImpl) CreateRole(ctx context.Context, name, description string, permissions []string) (*domain.Role, error) {
+	permissions, err := s.checkPermissions(ctx, permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	role := &domain.Role{Name: name, Description: description, Permissions: permissions}
+	if err := s.repo.CreateRole(ctx, role); err != nil {
+		return nil, err
+	}
+
+	s.logger.Infow("Role created", "role", name, "permissions", permissions)
+	return role, nil
+}
+
+// SetRolePermissions заменяет разрешения роли. Встроенную роль admin менять нельзя.
+func (s *RoleServiceImpl) SetRolePermissions(ctx context.Context, name string, permissions []string) (*domain.Role, error) {
+	if name == domain.RoleAdmin {
+		return nil, errors.ErrProtectedRole
+	}
+	role, err := s.getRole(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	permissions, err = s.checkPermissions(ctx, permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.SetRolePermissions(ctx, role.ID, permissions); err != nil {
+		return nil, err
+	}
+	role.Permissions = permissions
+
+	s.logger.Infow("Role permissions changed", "role", name, "permissions", permissions)
+	return role, nil
+}
+
+// DeleteRole удаляет роль вместе с ее назначениями. Встроенную роль admin удалить нельзя.
+func (s *RoleServiceImpl) DeleteRole(ctx context.Context, name string) error {
+	if name == domain.RoleAdmin {
+		return errors.ErrProtectedRole
+	}
+	role, err := s.getRole(ctx, name)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.DeleteRole(ctx, role.ID); err != nil {
+		if stderrors.Is(err, sql.ErrNoRows) {
+			return errors.ErrRoleNotFound
+		}
+		return err
+	}
+
+	s.logger.Infow("Role deleted", "role", name)
+	return nil
+}
+
+// ListPermissions возвращает все разрешения
+func (s *RoleServiceImpl) ListPermissions(ctx context.Context) ([]domain.Permission, error) {
+	return s.repo.ListPermissions(ctx)
+}
+
+// UserRoles возвращает роли существующего пользователя
+func (s *RoleServiceImpl) UserRoles(ctx context.Context, userID int) ([]string, error) {
+	if err := s.checkUser(ctx, userID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetUserRoles(ctx, userID)
+}
+
+// AssignRole назначает роль пользователю. Роль admin назначает только администратор,
+// остальные роли - только тот, у кого есть все их разрешения.
+func (s *RoleServiceImpl) AssignRole(ctx context.Context, actor domain.AuditActor, userID int, name string) error {
+	if err := s.checkUser(ctx, userID); err != nil {
+		return err
+	}
+	role, err := s.getRole(ctx, name)
+	if err != nil {
+		return err
+	}
+	if err := s.checkGrant(ctx, actor, role); err != nil {
+		return err
+	}
+	if err := s.repo.AssignRole(ctx, userID, role.ID); err != nil {
+		return err
+	}
+
+	writeAuditRecord(ctx, s.audit, s.logger, actor, domain.AuditActionRoleAssign, userID, map[string]interface{}{"role": name})
+	return nil
+}
+
+// RemoveRole снимает роль с пользователя с теми же ограничениями, что и назначение.
+// Выданные пользователю access токены отзываются: иначе снятые разрешения действовали бы до их истечения.
+func (s *RoleServiceImpl) RemoveRole(ctx context.Context, actor domain.AuditActor, userID int, name string) error {
+	if err := s.checkUser(ctx, userID); err != nil {
+		return err
+	}
+	role, err := s.getRole(ctx, name)
+	if err != nil {
+		return err
+	}
+	if err := s.checkGrant(ctx, actor, role); err != nil {
+		return err
+	}
+	if err := s.repo.RemoveRole(ctx, userID, role.ID); err != nil {
+		if stderrors.Is(err, sql.ErrNoRows) {
+			return errors.ErrRoleNotFound
+		}
+		return err
+	}
+	if err := s.denylist.RevokeUserTokens(ctx, strconv.Itoa(userID)); err != nil {
+		return err
+	}
+
+	writeAuditRecord(ctx, s.audit, s.logger, actor, domain.AuditActionRoleRemove, userID, map[string]interface{}{"role": name})
+	return nil
+}
+
+// checkGrant запрещает выдавать и снимать права, которых нет у самого администратора.
+// Административной утилите разрешено все: ей назначается первый администратор.
+func (s *RoleServiceImpl) checkGrant(ctx context.Context, actor domain.AuditActor, role *domain.Role) error {
+	if actor.System() {
+		return nil
+	}
+	roles, permissions, err := s.Authorization(ctx, actor.UserID)
+	if err != nil {
+		return err
+	}
+	if role.Name == domain.RoleAdmin {
+		for _, held := range roles {
+			if held == domain.RoleAdmin {
+				return nil
+			}
+		}
+		return errors.ErrForbidden
+	}
+
+	held := make(map[string]bool, len(permissions))
+	for _, permission := range permissions {
+		held[permission] = true
+	}
+	for _, permission := range role.Permissions {
+		if !held[permission] {
+			return errors.ErrForbidden
+		}
+	}
+	return nil
+}
+
+func (s *RoleServiceImpl) getRole(ctx context.Context, name string) (*domain.Role, error) {
+	role, err := s.repo.GetRoleByName(ctx, name)
+	if err != nil {
+		if stderrors.Is(err, sql.ErrNoRows) {
+			return nil, errors.ErrRoleNotFound
+		}
+		return nil, err
+	}
+	return role, nil
+}
+
+func (s *RoleServiceImpl) checkUser(ctx context.Context, userID int) error {
+	if _, err := s.userRepo.GetUserByID(ctx, userID); err != nil {
+		if stderrors.Is(err, sql.ErrNoRows) {
+			return errors.ErrUserNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// checkPermissions возвращает отсортированный список без повторов или ErrUnknownPermission
+func (s *RoleServiceImpl) checkPermissions(ctx context.Context, permissions []string) ([]string, error) {
+	known, err := s.repo.ListPermissions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]bool, len(known))
+	for _, permission := range known {
+		names[permission.Name] = true
+	}
+
+	seen := make(map[string]bool, len(permissions))
+	result := make([]string, 0, len(permissions))
+	for _, permission := range permissions {
+		if !names[permission] {
+			return nil, errors.ErrUnknownPermission
+		}
+		if !seen[permission] {
+			seen[permission] = true
+			result = append(result, permission)
+		}
+	}
+	sort.Strings(result)
+	return result, nil
+}
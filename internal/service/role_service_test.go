@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/Alias1177/Auth/internal/domain"
+	"github.com/Alias1177/Auth/internal/errors"
+	"github.com/Alias1177/Auth/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryRoles роли и их назначения в памяти
+type memoryRoles struct {
+	RoleRepository
+	roles       []domain.Role
+	assignments map[int][]int
+}
+
+func (m *memoryRoles) GetRoleByName(_ context.Context, name string) (*domain.Role, error) {
+	for _, role := range m.roles {
+		if role.Name == name {
+			return &role, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (m *memoryRoles) GetUserRoles(_ context.Context, userID int) ([]string, error) {
+	names := []string{}
+	for _, roleID := range m.assignments[userID] {
+		names = append(names, m.roles[roleID-1].Name)
+	}
+	return names, nil
+}
+
+func (m *memoryRoles) GetUserPermissions(_ context.Context, userID int) ([]string, error) {
+	permissions := []string{}
+	for _, roleID := range m.assignments[userID] {
+		permissions = append(permissions, m.roles[roleID-1].Permissions...)
+	}
+	return permissions, nil
+}
+
+func (m *memoryRoles) AssignRole(_ context.Context, userID, roleID int) error {
+	m.assignments[userID] = append(m.assignments[userID], roleID)
+	return nil
+}
+
+func (m *memoryRoles) RemoveRole(_ context.Context, userID, roleID int) error {
+	for i, assigned := range m.assignments[userID] {
+		if assigned == roleID {
+			m.assignments[userID] = append(m.assignments[userID][:i], m.assignments[userID][i+1:]...)
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+func TestRoleService_AssignAndRemove(t *testing.T) {
+	ctx := context.Background()
+	log, err := logger.New("error")
+	require.NoError(t, err)
+
+	userRepo := new(MockUserRepository)
+	userRepo.On("GetUserByID", mock.Anything, mock.Anything).Return(&domain.User{ID: 7}, nil)
+
+	roles := &memoryRoles{
+		roles: []domain.Role{
+			{ID: 1, Name: domain.RoleAdmin, Permissions: []string{domain.PermissionRolesWrite, domain.PermissionUsersRead, domain.PermissionUsersWrite}},
+			{ID: 2, Name: "role-manager", Permissions: []string{domain.PermissionRolesWrite, domain.PermissionUsersRead}},
+			{ID: 3, Name: "support", Permissions: []string{domain.PermissionUsersRead}},
+			{ID: 4, Name: "operator", Permissions: []string{domain.PermissionUsersWrite}},
+		},
+		assignments: map[int][]int{1: {1}, 2: {2}},
+	}
+	audit := &memoryAdminUsers{}
+	denylist := memoryDenylist{}
+	svc := NewRoleService(roles, userRepo, audit, denylist, log)
+
+	admin := domain.AuditActor{UserID: 1, IP: "10.0.0.1"}
+	manager := domain.AuditActor{UserID: 2, IP: "10.0.0.2"}
+
+	// Менеджер ролей не выдает права, которых нет у него самого
+	assert.ErrorIs(t, svc.AssignRole(ctx, manager, 7, domain.RoleAdmin), errors.ErrForbidden)
+	assert.ErrorIs(t, svc.AssignRole(ctx, manager, 7, "operator"), errors.ErrForbidden)
+	require.NoError(t, svc.AssignRole(ctx, manager, 7, "support"))
+
+	require.NoError(t, svc.AssignRole(ctx, admin, 7, "operator"))
+	assert.ErrorIs(t, svc.RemoveRole(ctx, manager, 7, "operator"), errors.ErrForbidden)
+	assert.Empty(t, denylist, "rejected removal must not revoke tokens")
+
+	require.NoError(t, svc.RemoveRole(ctx, admin, 7, "operator"))
+	assert.True(t, denylist["uid:7"], "removing a role revokes issued access tokens")
+
+	// Административная утилита назначает первого администратора
+	require.NoError(t, svc.AssignRole(ctx, domain.AuditActor{}, 7, domain.RoleAdmin))
+
+	require.Len(t, audit.records, 4)
+	assert.Equal(t, domain.AuditActionRoleAssign, audit.records[0].Action)
+	assert.Equal(t, manager.UserID, *audit.records[0].ActorID)
+	assert.JSONEq(t, `{"role":"support"}`, string(audit.records[0].Details))
+	assert.Equal(t, domain.AuditActionRoleRemove, audit.records[2].Action)
+	assert.Nil(t, audit.records[3].ActorID, "command-line actions have no actor user")
+}
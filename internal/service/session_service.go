@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	stderrors "errors"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Alias1177/Auth/internal/domain"
+	"github.com/Alias1177/Auth/internal/errors"
+	"github.com/Alias1177/Auth/pkg/logger"
+	"github.com/google/uuid"
+)
+
+// Ограничения длины полей сессии (размеры колонок user_sessions)
+const (
+	maxUserAgentLength = 512
+	maxIPAddressLength = 64
+)
+
+// SessionRepository хранилище сессий пользователей
+type SessionRepository interface {
+	CreateSession(ctx context.Context, session *domain.Session) error
+	ListActiveSessions(ctx context.Context, userID int) ([]domain.Session, error)
+	TouchSession(ctx context.Context, sessionID string) error
+	RevokeSession(ctx context.Context, userID int, sessionID string) error
+	RevokeUserSessions(ctx context.Context, userID int, exceptID string) ([]string, error)
+}
+
+// SessionService учет входов пользователя с разных устройств.
+// Сессия соответствует семейству refresh токенов, поэтому ее отзыв делает недействительными все токены входа.
+type SessionService interface {
+	Start(ctx context.Context, claims domain.UserClaims, client domain.ClientInfo) (*domain.TokenPair, error)
+	// Refresh обменивает refresh токен; clientID - приложение на /oauth2/token или пусто для входа в сам сервис
+	Refresh(ctx context.Context, refreshToken, clientID string) (*domain.TokenPair, error)
+	List(ctx context.Context, userID int) ([]domain.Session, error)
+	Revoke(ctx context.Context, userID int, sessionID string) error
+	RevokeOthers(ctx context.Context, userID int, currentID string) error
+	RevokeAll(ctx context.Context, userID int) error
+}
+
+// SessionServiceImpl реализация сервиса сессий
+type SessionServiceImpl struct {
+	repo          SessionRepository
+	refreshTokens RefreshTokenService
+	tokenManager  TokenManager
+	denylist      AccessTokenDenylist
+	logger        *logger.Logger
+}
+
+// NewSessionService создает новый экземпляр сервиса сессий
+func NewSessionService(
+	repo SessionRepository,
+	refreshTokens RefreshTokenService,
+	tokenManager TokenManager,
+	denylist AccessTokenDenylist,
+	logger *logger.Logger,
+) *SessionServiceImpl {
+	return &SessionServiceImpl{
+		repo:          repo,
+		refreshTokens: refreshTokens,
+		tokenManager:  tokenManager,
+		denylist:      denylist,
+		logger:        logger,
+	}
+}
+
+// Start регистрирует новую сессию и выдает для нее пару токенов
+func (s *SessionServiceImpl) Start(ctx context.Context, claims domain.UserClaims, client domain.ClientInfo) (*domain.TokenPair, error) {
+	userID, err := strconv.Atoi(claims.UserID)
+	if err != nil {
+		return nil, errors.ErrInvalidToken
+	}
+
+	session := &domain.Session{
+		ID:          uuid.NewString(),
+		UserID:      userID,
+		DeviceName:  truncate(client.DeviceName, maxDeviceLength),
+		UserAgent:   truncate(client.UserAgent, maxUserAgentLength),
+		IPAddress:   truncate(client.IPAddress, maxIPAddressLength),
+		LoginMethod: client.LoginMethod,
+	}
+	if err := s.repo.CreateSession(ctx, session); err != nil {
+		return nil, err
+	}
+
+	device := client.DeviceName
+	if device == "" {
+		device = client.UserAgent
+	}
+	return s.refreshTokens.Issue(ctx, claims, session.ID, device)
+}
+
+// Refresh обменивает refresh токен и отмечает время последнего использования сессии
+func (s *SessionServiceImpl) Refresh(ctx context.Context, refreshToken, clientID string) (*domain.TokenPair, error) {
+	tokens, err := s.refreshTokens.Rotate(ctx, refreshToken, clientID)
+	if err != nil {
+		if err == errors.ErrTokenReused {
+			// Семейство уже отозвано, дополнительно гасим выданные в нем access токены
+			if claims, vErr := s.tokenManager.ValidateRefreshToken(refreshToken); vErr == nil && claims.FamilyID != "" {
+				if dErr := s.denylist.RevokeFamilyTokens(ctx, claims.FamilyID); dErr != nil {
+					s.logger.Errorw("Failed to revoke session access tokens", "session_id", claims.FamilyID, "error", dErr)
+				}
+			}
+		}
+		return nil, err
+	}
+
+	claims, err := s.tokenManager.ValidateAccessToken(tokens.AccessToken)
+	if err == nil && claims.FamilyID != "" {
+		if err := s.repo.TouchSession(ctx, claims.FamilyID); err != nil {
+			// Не критично для обмена токенов
+			s.logger.Warnw("Failed to update session last use", "session_id", claims.FamilyID, "error", err)
+		}
+	}
+	return tokens, nil
+}
+
+// List возвращает действующие сессии пользователя
+func (s *SessionServiceImpl) List(ctx context.Context, userID int) ([]domain.Session, error) {
+	return s.repo.ListActiveSessions(ctx, userID)
+}
+
+// Revoke завершает сессию пользователя; возвращает ErrNotFound, если сессия не найдена
+func (s *SessionServiceImpl) Revoke(ctx context.Context, userID int, sessionID string) error {
+	if _, err := uuid.Parse(sessionID); err != nil {
+		return errors.ErrNotFound
+	}
+	if err := s.repo.RevokeSession(ctx, userID, sessionID); err != nil {
+		if stderrors.Is(err, sql.ErrNoRows) {
+			return errors.ErrNotFound
+		}
+		return err
+	}
+	return s.revokeTokens(ctx, []string{sessionID})
+}
+
+// RevokeOthers завершает все сессии пользователя, кроме текущей
+func (s *SessionServiceImpl) RevokeOthers(ctx context.Context, userID int, currentID string) error {
+	revoked, err := s.repo.RevokeUserSessions(ctx, userID, currentID)
+	if err != nil {
+		return err
+	}
+	// Отзываем и токены, выданные до появления сессий
+	if err := s.refreshTokens.RevokeAllForUser(ctx, userID, currentID); err != nil {
+		return err
+	}
+	return s.revokeTokens(ctx, revoked)
+}
+
+// RevokeAll завершает все сессии пользователя, включая текущую
+func (s *SessionServiceImpl) RevokeAll(ctx context.Context, userID int) error {
+	if _, err := s.repo.RevokeUserSessions(ctx, userID, ""); err != nil {
+		return err
+	}
+	if err := s.refreshTokens.RevokeAllForUser(ctx, userID, ""); err != nil {
+		return err
+	}
+	return s.denylist.RevokeUserTokens(ctx, strconv.Itoa(userID))
+}
+
+// revokeTokens отзывает refresh и access токены перечисленных сессий
+func (s *SessionServiceImpl) revokeTokens(ctx context.Context, sessionIDs []string) error {
+	for _, id := range sessionIDs {
+		if err := s.refreshTokens.RevokeFamily(ctx, id); err != nil {
+			return err
+		}
+		if err := s.denylist.RevokeFamilyTokens(ctx, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// truncate обрезает строку до max символов: VARCHAR(n) считает символы, а разрез посреди
+// многобайтного символа дал бы строку, которую Postgres отклоняет как неверный UTF-8.
+// Неверные последовательности из заголовков клиента отбрасываются по той же причине.
+func truncate(value string, max int) string {
+	value = strings.ToValidUTF8(value, "")
+	if utf8.RuneCountInString(value) <= max {
+		return value
+	}
+	return string([]rune(value)[:max])
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/Alias1177/Auth/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingSessions запоминает созданные сессии
+type recordingSessions struct {
+	SessionRepository
+	created []domain.Session
+}
+
+func (r *recordingSessions) CreateSession(_ context.Context, session *domain.Session) error {
+	r.created = append(r.created, *session)
+	return nil
+}
+
+// issuingRefreshTokens выдает пару токенов, не обращаясь к хранилищу
+type issuingRefreshTokens struct {
+	RefreshTokenService
+}
+
+func (issuingRefreshTokens) Issue(context.Context, domain.UserClaims, string, string) (*domain.TokenPair, error) {
+	return &domain.TokenPair{AccessToken: "access", RefreshToken: "refresh"}, nil
+}
+
+func TestSessionService_StartTruncatesClientInfo(t *testing.T) {
+	repo := &recordingSessions{}
+	svc := NewSessionService(repo, issuingRefreshTokens{}, nil, nil, nil)
+
+	// Двухбайтные символы: обрезка по байтам разрезала бы последний символ
+	userAgent := strings.Repeat("ж", maxUserAgentLength+10)
+	device := "📱" + strings.Repeat("д", maxDeviceLength)
+	_, err := svc.Start(context.Background(), domain.UserClaims{UserID: "7"}, domain.ClientInfo{
+		DeviceName: device,
+		UserAgent:  userAgent + "\xff",
+		IPAddress:  "10.0.0.1",
+	})
+	require.NoError(t, err)
+
+	require.Len(t, repo.created, 1)
+	session := repo.created[0]
+	assert.True(t, utf8.ValidString(session.UserAgent))
+	assert.Equal(t, strings.Repeat("ж", maxUserAgentLength), session.UserAgent)
+	assert.True(t, utf8.ValidString(session.DeviceName))
+	assert.Equal(t, maxDeviceLength, utf8.RuneCountInString(session.DeviceName))
+	assert.True(t, strings.HasPrefix(session.DeviceName, "📱"))
+	assert.Equal(t, "10.0.0.1", session.IPAddress)
+}
@@ -1,7 +1,10 @@
 package service
 
 import (
+	"time"
+
 	"github.com/Alias1177/Auth/internal/domain"
+	"github.com/Alias1177/Auth/pkg/jwt"
 )
 
 // TokenManager interface реализация по JWT
@@ -9,6 +12,11 @@ type TokenManager interface {
 	GenerateAccessToken(userClaims domain.UserClaims) (string, error)
 	ValidateAccessToken(token string) (*domain.UserClaims, error)
 	GenerateRefreshToken(userClaims domain.UserClaims) (string, error)
-	RefreshTokens(refreshToken string) (string, string, error)
 	ValidateRefreshToken(token string) (*domain.UserClaims, error)
+	GenerateScopedToken(userClaims domain.UserClaims, tokenType string, ttl time.Duration) (string, error)
+	ValidateScopedToken(token, tokenType string) (*domain.UserClaims, error)
+	GenerateIDToken(claims domain.IDTokenClaims, ttl time.Duration) (string, error)
+	GenerateClientToken(claims domain.ClientClaims, ttl time.Duration) (string, error)
+	ValidateClientToken(token string) (*domain.ClientClaims, error)
+	JWKS() jwt.JWKS
 }
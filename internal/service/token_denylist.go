@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Alias1177/Auth/internal/domain"
+	"github.com/Alias1177/Auth/pkg/jwt"
+	"github.com/redis/go-redis/v9"
+)
+
+// AccessTokenDenylist список отозванных access токенов.
+// Записи живут в Redis не дольше самих токенов, поэтому список не растет бесконечно.
+type AccessTokenDenylist interface {
+	RevokeToken(ctx context.Context, claims *domain.UserClaims) error
+	RevokeUserTokens(ctx context.Context, userID string) error
+	RevokeFamilyTokens(ctx context.Context, familyID string) error
+	IsRevoked(ctx context.Context, claims *domain.UserClaims) (bool, error)
+}
+
+// AccessTokenDenylistImpl реализация поверх Redis
+type AccessTokenDenylistImpl struct {
+	cache UserCache
+}
+
+// NewAccessTokenDenylist создает новый список отозванных токенов
+func NewAccessTokenDenylist(cache UserCache) *AccessTokenDenylistImpl {
+	return &AccessTokenDenylistImpl{cache: cache}
+}
+
+func tokenDenylistKey(jti string) string {
+	return fmt.Sprintf("denylist:access:%s", jti)
+}
+
+func userDenylistKey(userID string) string {
+	return fmt.Sprintf("denylist:user:%s", userID)
+}
+
+func familyDenylistKey(familyID string) string {
+	return fmt.Sprintf("denylist:family:%s", familyID)
+}
+
+// RevokeToken отзывает конкретный access токен до его естественного истечения
+func (d *AccessTokenDenylistImpl) RevokeToken(ctx context.Context, claims *domain.UserClaims) error {
+	if claims.TokenID == "" {
+		return nil
+	}
+
+	ttl := time.Until(time.Unix(claims.ExpiresAt, 0))
+	if ttl <= 0 {
+		return nil
+	}
+	return d.cache.SetWithTTL(ctx, tokenDenylistKey(claims.TokenID), "1", ttl)
+}
+
+// RevokeUserTokens отзывает все access токены пользователя, выпущенные до текущего момента.
+// Граница хранится в миллисекундах: токен, выданный в ту же секунду до отзыва, тоже отзывается,
+// а выданный сразу после (новая сессия после восстановления доступа) - нет.
+func (d *AccessTokenDenylistImpl) RevokeUserTokens(ctx context.Context, userID string) error {
+	cutoff := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	return d.cache.SetWithTTL(ctx, userDenylistKey(userID), cutoff, jwt.AccessTokenTTL)
+}
+
+// RevokeFamilyTokens отзывает все access токены одной сессии (семейства refresh токенов)
+func (d *AccessTokenDenylistImpl) RevokeFamilyTokens(ctx context.Context, familyID string) error {
+	return d.cache.SetWithTTL(ctx, familyDenylistKey(familyID), "1", jwt.AccessTokenTTL)
+}
+
+// IsRevoked проверяет, отозван ли токен сам по себе, вместе со своей сессией или со всеми токенами пользователя
+func (d *AccessTokenDenylistImpl) IsRevoked(ctx context.Context, claims *domain.UserClaims) (bool, error) {
+	if claims.TokenID != "" {
+		revoked, err := d.exists(ctx, tokenDenylistKey(claims.TokenID))
+		if err != nil || revoked {
+			return revoked, err
+		}
+	}
+	if claims.FamilyID != "" {
+		revoked, err := d.exists(ctx, familyDenylistKey(claims.FamilyID))
+		if err != nil || revoked {
+			return revoked, err
+		}
+	}
+	if claims.UserID == "" {
+		// Токен сервиса (client_credentials) отзывается только по jti
+		return false, nil
+	}
+
+	value, err := d.cache.Get(ctx, userDenylistKey(claims.UserID))
+	if err != nil {
+		if stderrors.Is(err, redis.Nil) {
+			return false, nil
+		}
+		return false, err
+	}
+	cutoff, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return false, fmt.Errorf("invalid user revocation cutoff: %w", err)
+	}
+	return claims.IssuedAtMilli < cutoff, nil
+}
+
+func (d *AccessTokenDenylistImpl) exists(ctx context.Context, key string) (bool, error) {
+	if _, err := d.cache.Get(ctx, key); err != nil {
+		if stderrors.Is(err, redis.Nil) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
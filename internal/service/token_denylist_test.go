@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/Alias1177/Auth/internal/domain"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// redisLikeCache отвечает на отсутствующий ключ redis.Nil, как RedisRepository
+type redisLikeCache struct {
+	memoryCache
+}
+
+func (c *redisLikeCache) Get(ctx context.Context, key string) (string, error) {
+	value, err := c.memoryCache.Get(ctx, key)
+	if err != nil {
+		return "", redis.Nil
+	}
+	return value, nil
+}
+
+func TestAccessTokenDenylist_RevokeUserTokensSameSecond(t *testing.T) {
+	ctx := context.Background()
+	cache := &redisLikeCache{memoryCache{values: map[string]string{}}}
+	denylist := NewAccessTokenDenylist(cache)
+
+	require.NoError(t, denylist.RevokeUserTokens(ctx, "7"))
+	cutoff, err := strconv.ParseInt(cache.values[userDenylistKey("7")], 10, 64)
+	require.NoError(t, err)
+
+	before := &domain.UserClaims{UserID: "7", IssuedAt: cutoff / 1000, IssuedAtMilli: cutoff - 1}
+	after := &domain.UserClaims{UserID: "7", IssuedAt: cutoff / 1000, IssuedAtMilli: cutoff}
+
+	revoked, err := denylist.IsRevoked(ctx, before)
+	require.NoError(t, err)
+	assert.True(t, revoked, "token issued in the same second before logout-all is revoked")
+
+	revoked, err = denylist.IsRevoked(ctx, after)
+	require.NoError(t, err)
+	assert.False(t, revoked, "token issued right after logout-all stays valid")
+}
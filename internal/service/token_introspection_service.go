@@ -0,0 +1,261 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	stderrors "errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Alias1177/Auth/internal/domain"
+	"github.com/Alias1177/Auth/internal/errors"
+	"github.com/Alias1177/Auth/pkg/logger"
+)
+
+// TokenIntrospectionService интроспекция (RFC 7662) и отзыв (RFC 7009) токенов для приложений и шлюзов.
+// Кроме подписи и срока действия учитывается серверное состояние: список отозванных access токенов,
+// хранилище refresh токенов и существование клиента, которому выдан токен сервиса.
+type TokenIntrospectionService interface {
+	Introspect(ctx context.Context, clientID, clientSecret, token, tokenTypeHint string) (*domain.TokenIntrospection, error)
+	Revoke(ctx context.Context, clientID, clientSecret, token, tokenTypeHint string) error
+}
+
+// TokenIntrospectionServiceImpl реализация интроспекции и отзыва токенов
+type TokenIntrospectionServiceImpl struct {
+	clients       OAuthClientRepository
+	refreshTokens RefreshTokenRepository
+	sessions      SessionService
+	denylist      AccessTokenDenylist
+	tokenManager  TokenManager
+	logger        *logger.Logger
+}
+
+// NewTokenIntrospectionService создает новый экземпляр сервиса интроспекции и отзыва токенов
+func NewTokenIntrospectionService(
+	clients OAuthClientRepository,
+	refreshTokens RefreshTokenRepository,
+	sessions SessionService,
+	denylist AccessTokenDenylist,
+	tokenManager TokenManager,
+	logger *logger.Logger,
+) *TokenIntrospectionServiceImpl {
+	return &TokenIntrospectionServiceImpl{
+		clients:       clients,
+		refreshTokens: refreshTokens,
+		sessions:      sessions,
+		denylist:      denylist,
+		tokenManager:  tokenManager,
+		logger:        logger,
+	}
+}
+
+// Introspect сообщает, действителен ли токен, и его основные claims. Спрашивать могут только
+// конфиденциальные клиенты: иначе ручка позволяла бы кому угодно проверять украденные токены.
+func (s *TokenIntrospectionServiceImpl) Introspect(
+	ctx context.Context,
+	clientID, clientSecret, token, tokenTypeHint string,
+) (*domain.TokenIntrospection, error) {
+	client, err := authenticateOAuthClient(ctx, s.clients, clientID, clientSecret, s.logger)
+	if err != nil {
+		return nil, err
+	}
+	if !client.Confidential() {
+		return nil, errors.NewOAuthError(errors.OAuthInvalidClient, "token introspection requires a confidential client")
+	}
+
+	for _, tokenType := range tokenTypeOrder(tokenTypeHint) {
+		var result *domain.TokenIntrospection
+		if tokenType == domain.TokenTypeHintRefreshToken {
+			result, err = s.introspectRefreshToken(ctx, token)
+		} else {
+			result, err = s.introspectAccessToken(ctx, token)
+		}
+		if err != nil || result != nil {
+			return result, err
+		}
+	}
+	return &domain.TokenIntrospection{Active: false}, nil
+}
+
+// Revoke отзывает токен. Access токен попадает в список отозванных, refresh токен завершает всю
+// сессию вместе с ее access токенами. Недействительный токен не считается ошибкой (RFC 7009, раздел 2.2).
+// Отозвать можно только токен, выданный самому клиенту: чужой токен сервиса или пользователя
+// (в том числе входа в сам сервис) не отзывается, но ответ тот же, что при успехе, иначе по ответу
+// можно было бы узнать, чей это токен.
+func (s *TokenIntrospectionServiceImpl) Revoke(ctx context.Context, clientID, clientSecret, token, tokenTypeHint string) error {
+	client, err := authenticateOAuthClient(ctx, s.clients, clientID, clientSecret, s.logger)
+	if err != nil {
+		return err
+	}
+
+	for _, tokenType := range tokenTypeOrder(tokenTypeHint) {
+		if tokenType == domain.TokenTypeHintRefreshToken {
+			if claims, err := s.tokenManager.ValidateRefreshToken(token); err == nil {
+				owned, err := s.ownsRefreshToken(ctx, client.ID, claims)
+				if err != nil || !owned {
+					return err
+				}
+				return s.revokeSession(ctx, claims)
+			}
+			continue
+		}
+
+		if claims, err := s.tokenManager.ValidateAccessToken(token); err == nil {
+			if claims.ClientID != client.ID {
+				s.logger.Warnw("Client tried to revoke a user token issued to another party",
+					"client_id", client.ID, "token_client_id", claims.ClientID)
+				return nil
+			}
+			s.logger.Infow("Access token revoked by client", "client_id", client.ID, "user_id", claims.UserID)
+			return s.denylist.RevokeToken(ctx, claims)
+		}
+		if claims, err := s.tokenManager.ValidateClientToken(token); err == nil {
+			if claims.ClientID != client.ID {
+				s.logger.Warnw("Client tried to revoke a token of another client", "client_id", client.ID, "token_client_id", claims.ClientID)
+				return nil
+			}
+			return s.denylist.RevokeToken(ctx, &domain.UserClaims{TokenID: claims.TokenID, ExpiresAt: claims.ExpiresAt})
+		}
+	}
+	return nil
+}
+
+// introspectAccessToken проверяет access токен пользователя или сервиса; nil, если токен не access
+func (s *TokenIntrospectionServiceImpl) introspectAccessToken(ctx context.Context, token string) (*domain.TokenIntrospection, error) {
+	if claims, err := s.tokenManager.ValidateAccessToken(token); err == nil {
+		revoked, err := s.denylist.IsRevoked(ctx, claims)
+		if err != nil {
+			return nil, err
+		}
+		if revoked {
+			return &domain.TokenIntrospection{Active: false}, nil
+		}
+		return &domain.TokenIntrospection{
+			Active:    true,
+			TokenType: domain.TokenTypeHintAccessToken,
+			Subject:   claims.UserID,
+			ClientID:  claims.ClientID,
+			Scope:     claims.Scope,
+			TokenID:   claims.TokenID,
+			IssuedAt:  claims.IssuedAt,
+			ExpiresAt: claims.ExpiresAt,
+		}, nil
+	}
+
+	claims, err := s.tokenManager.ValidateClientToken(token)
+	if err != nil {
+		return nil, nil
+	}
+	revoked, err := s.denylist.IsRevoked(ctx, &domain.UserClaims{TokenID: claims.TokenID})
+	if err != nil {
+		return nil, err
+	}
+	// Токены удаленного клиента или клиента, лишенного секрета, больше не действуют
+	client, err := s.clients.GetOAuthClient(ctx, claims.ClientID)
+	if err != nil && !stderrors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if revoked || client == nil || !client.Confidential() {
+		return &domain.TokenIntrospection{Active: false}, nil
+	}
+	return &domain.TokenIntrospection{
+		Active:    true,
+		TokenType: domain.TokenTypeHintAccessToken,
+		Subject:   claims.ClientID,
+		ClientID:  claims.ClientID,
+		Scope:     strings.Join(claims.Scopes, " "),
+		TokenID:   claims.TokenID,
+		IssuedAt:  claims.IssuedAt,
+		ExpiresAt: claims.ExpiresAt,
+	}, nil
+}
+
+// introspectRefreshToken проверяет refresh токен по хранилищу: использованный при ротации, отозванный
+// или истекший токен недействителен. nil, если токен не refresh.
+func (s *TokenIntrospectionServiceImpl) introspectRefreshToken(ctx context.Context, token string) (*domain.TokenIntrospection, error) {
+	claims, err := s.tokenManager.ValidateRefreshToken(token)
+	if err != nil {
+		return nil, nil
+	}
+	inactive := &domain.TokenIntrospection{Active: false}
+	if claims.TokenID == "" {
+		// Токены, выпущенные до появления хранилища, не обмениваются и считаются недействительными
+		return inactive, nil
+	}
+
+	record, err := s.refreshTokens.GetRefreshToken(ctx, claims.TokenID)
+	if err != nil {
+		if stderrors.Is(err, sql.ErrNoRows) {
+			return inactive, nil
+		}
+		return nil, err
+	}
+	if record.UsedAt != nil || record.RevokedAt != nil || !record.ExpiresAt.After(time.Now()) {
+		return inactive, nil
+	}
+	return &domain.TokenIntrospection{
+		Active:    true,
+		TokenType: domain.TokenTypeHintRefreshToken,
+		Subject:   strconv.Itoa(record.UserID),
+		ClientID:  record.ClientID,
+		Scope:     record.Scope,
+		TokenID:   record.ID,
+		IssuedAt:  record.IssuedAt.Unix(),
+		ExpiresAt: record.ExpiresAt.Unix(),
+	}, nil
+}
+
+// ownsRefreshToken сообщает, выдано ли семейство refresh токена приложению clientID.
+// Привязка берется из хранилища; неизвестный токен никому не принадлежит.
+func (s *TokenIntrospectionServiceImpl) ownsRefreshToken(ctx context.Context, clientID string, claims *domain.UserClaims) (bool, error) {
+	if claims.TokenID == "" {
+		return false, nil
+	}
+	record, err := s.refreshTokens.GetRefreshToken(ctx, claims.TokenID)
+	if err != nil {
+		if stderrors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	if record.ClientID != clientID {
+		s.logger.Warnw("Client tried to revoke a user token issued to another party",
+			"client_id", clientID, "token_client_id", record.ClientID)
+		return false, nil
+	}
+	return true, nil
+}
+
+// revokeSession завершает сессию refresh токена, как выход пользователя
+func (s *TokenIntrospectionServiceImpl) revokeSession(ctx context.Context, claims *domain.UserClaims) error {
+	if claims.FamilyID == "" {
+		return nil
+	}
+	userID, err := strconv.Atoi(claims.UserID)
+	if err != nil {
+		return nil
+	}
+
+	err = s.sessions.Revoke(ctx, userID, claims.FamilyID)
+	if err == errors.ErrNotFound {
+		// Вход выполнен до появления сессий: отзываем семейство токенов напрямую
+		if err = s.refreshTokens.RevokeRefreshTokenFamily(ctx, claims.FamilyID); err == nil {
+			err = s.denylist.RevokeFamilyTokens(ctx, claims.FamilyID)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	s.logger.Infow("Session revoked by client", "user_id", userID, "session_id", claims.FamilyID)
+	return nil
+}
+
+// tokenTypeOrder порядок проверки типов токена: сначала подсказанный клиентом (RFC 7009, раздел 2.1)
+func tokenTypeOrder(hint string) []string {
+	if hint == domain.TokenTypeHintRefreshToken {
+		return []string{domain.TokenTypeHintRefreshToken, domain.TokenTypeHintAccessToken}
+	}
+	return []string{domain.TokenTypeHintAccessToken, domain.TokenTypeHintRefreshToken}
+}
@@ -0,0 +1,198 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/Alias1177/Auth/internal/config"
+	"github.com/Alias1177/Auth/internal/domain"
+	"github.com/Alias1177/Auth/internal/errors"
+	"github.com/Alias1177/Auth/pkg/jwt"
+	"github.com/Alias1177/Auth/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryRefreshTokens хранилище refresh токенов в памяти
+type memoryRefreshTokens map[string]domain.RefreshToken
+
+func (m memoryRefreshTokens) CreateRefreshToken(_ context.Context, token *domain.RefreshToken) error {
+	m[token.ID] = *token
+	return nil
+}
+
+func (m memoryRefreshTokens) GetRefreshToken(_ context.Context, id string) (*domain.RefreshToken, error) {
+	token, ok := m[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &token, nil
+}
+
+func (m memoryRefreshTokens) UseRefreshToken(_ context.Context, id, clientID string) (*domain.RefreshToken, error) {
+	token, ok := m[id]
+	if !ok || token.ClientID != clientID || token.UsedAt != nil || token.RevokedAt != nil || !token.ExpiresAt.After(time.Now()) {
+		return nil, sql.ErrNoRows
+	}
+	now := time.Now()
+	token.UsedAt = &now
+	m[id] = token
+	return &token, nil
+}
+
+func (m memoryRefreshTokens) RevokeRefreshTokenFamily(_ context.Context, familyID string) error {
+	now := time.Now()
+	for id, token := range m {
+		if token.FamilyID == familyID {
+			token.RevokedAt = &now
+			m[id] = token
+		}
+	}
+	return nil
+}
+
+func (m memoryRefreshTokens) RevokeUserRefreshTokens(context.Context, int, string) error {
+	return nil
+}
+
+func (m memoryRefreshTokens) DeleteExpiredRefreshTokens(context.Context) (int64, error) {
+	return 0, nil
+}
+
+// memoryDenylist список отозванных токенов и сессий в памяти
+type memoryDenylist map[string]bool
+
+func (m memoryDenylist) RevokeToken(_ context.Context, claims *domain.UserClaims) error {
+	m["jti:"+claims.TokenID] = true
+	return nil
+}
+
+func (m memoryDenylist) RevokeUserTokens(_ context.Context, userID string) error {
+	m["uid:"+userID] = true
+	return nil
+}
+
+func (m memoryDenylist) RevokeFamilyTokens(_ context.Context, familyID string) error {
+	m["fid:"+familyID] = true
+	return nil
+}
+
+func (m memoryDenylist) IsRevoked(_ context.Context, claims *domain.UserClaims) (bool, error) {
+	return m["jti:"+claims.TokenID] || (claims.FamilyID != "" && m["fid:"+claims.FamilyID]), nil
+}
+
+// sessionlessSessions отвечает, что сессии нет, как для входа до появления сессий
+type sessionlessSessions struct {
+	SessionService
+}
+
+func (s sessionlessSessions) Revoke(context.Context, int, string) error {
+	return errors.ErrNotFound
+}
+
+func TestTokenIntrospectionService(t *testing.T) {
+	ctx := context.Background()
+	log, err := logger.New("error")
+	require.NoError(t, err)
+	tokenManager, err := jwt.NewJWTTokenManager(config.JWTConfig{Secret: "secret"})
+	require.NoError(t, err)
+
+	clients := memoryOAuthClients{
+		"app": {ID: "app", Name: "App", RedirectURIs: []string{"https://app.example.com/callback"}},
+	}
+	registry := NewOAuthClientService(clients, log)
+	gateway, gatewaySecret, err := registry.RegisterClient(ctx, domain.OAuthClientSpec{Name: "Gateway", Confidential: true})
+	require.NoError(t, err)
+	billing, billingSecret, err := registry.RegisterClient(ctx, domain.OAuthClientSpec{
+		Name: "Billing", Scopes: []string{"invoices:read"}, Confidential: true,
+	})
+	require.NoError(t, err)
+
+	refreshTokens := memoryRefreshTokens{}
+	denylist := memoryDenylist{}
+	svc := NewTokenIntrospectionService(clients, refreshTokens, sessionlessSessions{}, denylist, tokenManager, log)
+
+	user := domain.UserClaims{UserID: "7", Email: "user@example.com", TokenID: "rt-1", FamilyID: "family-1"}
+	refreshToken, err := tokenManager.GenerateRefreshToken(user)
+	require.NoError(t, err)
+	require.NoError(t, refreshTokens.CreateRefreshToken(ctx, &domain.RefreshToken{
+		ID: "rt-1", FamilyID: "family-1", UserID: 7, IssuedAt: time.Now(), ExpiresAt: time.Now().Add(time.Hour),
+	}))
+	accessToken, err := tokenManager.GenerateAccessToken(user)
+	require.NoError(t, err)
+	clientToken, err := tokenManager.GenerateClientToken(domain.ClientClaims{ClientID: billing.ID, Scopes: []string{"invoices:read"}}, time.Minute)
+	require.NoError(t, err)
+
+	introspect := func(t *testing.T, token, hint string) *domain.TokenIntrospection {
+		result, err := svc.Introspect(ctx, gateway.ID, gatewaySecret, token, hint)
+		require.NoError(t, err)
+		return result
+	}
+
+	_, err = svc.Introspect(ctx, "app", "", accessToken, "")
+	assert.Equal(t, errors.OAuthInvalidClient, oauthErrorCode(err), "public client cannot introspect")
+	_, err = svc.Introspect(ctx, gateway.ID, "wrong", accessToken, "")
+	assert.Equal(t, errors.OAuthInvalidClient, oauthErrorCode(err))
+
+	access := introspect(t, accessToken, "")
+	assert.True(t, access.Active)
+	assert.Equal(t, domain.TokenTypeHintAccessToken, access.TokenType)
+	assert.Equal(t, "7", access.Subject)
+
+	refresh := introspect(t, refreshToken, domain.TokenTypeHintAccessToken)
+	assert.True(t, refresh.Active, "wrong hint falls back to other token types")
+	assert.Equal(t, domain.TokenTypeHintRefreshToken, refresh.TokenType)
+
+	serviceToken := introspect(t, clientToken, "")
+	assert.True(t, serviceToken.Active)
+	assert.Equal(t, billing.ID, serviceToken.Subject)
+	assert.Equal(t, billing.ID, serviceToken.ClientID)
+	assert.Equal(t, "invoices:read", serviceToken.Scope)
+
+	assert.False(t, introspect(t, "not-a-token", "").Active)
+	assert.NoError(t, svc.Revoke(ctx, "app", "", "not-a-token", ""), "invalid token revocation is not an error")
+
+	// Токены входа в сам сервис приложение не отзывает, но ответ не отличается от успешного
+	require.NoError(t, svc.Revoke(ctx, "app", "", refreshToken, domain.TokenTypeHintRefreshToken))
+	require.NoError(t, svc.Revoke(ctx, "app", "", accessToken, ""))
+	assert.True(t, introspect(t, refreshToken, "").Active)
+	assert.True(t, introspect(t, accessToken, "").Active)
+
+	appUser := domain.UserClaims{UserID: "7", Email: "user@example.com", TokenID: "rt-2", FamilyID: "family-2", ClientID: "app", Scope: "openid"}
+	appRefreshToken, err := tokenManager.GenerateRefreshToken(appUser)
+	require.NoError(t, err)
+	require.NoError(t, refreshTokens.CreateRefreshToken(ctx, &domain.RefreshToken{
+		ID: "rt-2", FamilyID: "family-2", UserID: 7, ClientID: "app", Scope: "openid", IssuedAt: time.Now(), ExpiresAt: time.Now().Add(time.Hour),
+	}))
+	appAccessToken, err := tokenManager.GenerateAccessToken(appUser)
+	require.NoError(t, err)
+	appAccess := introspect(t, appAccessToken, "")
+	assert.Equal(t, "app", appAccess.ClientID)
+	assert.Equal(t, "openid", appAccess.Scope)
+
+	// Токены пользователя в приложении отзывает только это приложение
+	require.NoError(t, svc.Revoke(ctx, gateway.ID, gatewaySecret, appRefreshToken, domain.TokenTypeHintRefreshToken))
+	require.NoError(t, svc.Revoke(ctx, gateway.ID, gatewaySecret, appAccessToken, ""))
+	assert.True(t, introspect(t, appRefreshToken, "").Active)
+	assert.True(t, introspect(t, appAccessToken, "").Active)
+
+	// Отзыв refresh токена завершает сессию вместе с ее access токенами
+	require.NoError(t, svc.Revoke(ctx, "app", "", appRefreshToken, domain.TokenTypeHintRefreshToken))
+	assert.False(t, introspect(t, appRefreshToken, "").Active)
+	assert.False(t, introspect(t, appAccessToken, "").Active)
+	assert.True(t, introspect(t, accessToken, "").Active, "other sessions are not affected")
+
+	// Токен сервиса отзывает только клиент, которому он выдан
+	require.NoError(t, svc.Revoke(ctx, gateway.ID, gatewaySecret, clientToken, ""), "response does not reveal the owner")
+	assert.True(t, introspect(t, clientToken, "").Active)
+	require.NoError(t, svc.Revoke(ctx, billing.ID, billingSecret, clientToken, ""))
+	assert.False(t, introspect(t, clientToken, "").Active)
+
+	// Токены удаленного клиента недействительны
+	otherToken, err := tokenManager.GenerateClientToken(domain.ClientClaims{ClientID: billing.ID}, time.Minute)
+	require.NoError(t, err)
+	require.NoError(t, registry.DeleteClient(ctx, billing.ID))
+	assert.False(t, introspect(t, otherToken, "").Active)
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"time"
 
 	"github.com/Alias1177/Auth/internal/domain"
 )
@@ -12,4 +13,8 @@ type UserRepository interface {
 	GetUserByEmail(ctx context.Context, email string) (*domain.User, error)
 	UpdateUser(ctx context.Context, user *domain.User) error
 	ResetPassword(ctx context.Context, user *domain.User) error
+	UpdatePasswordHash(ctx context.Context, userID int, hash string) error
+	// UpdateUserProfile обновляет данные профиля, если запись не менялась с expectedUpdatedAt;
+	// иначе возвращает sql.ErrNoRows
+	UpdateUserProfile(ctx context.Context, user *domain.User, expectedUpdatedAt time.Time) error
 }
@@ -0,0 +1,355 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Alias1177/Auth/internal/config"
+	"github.com/Alias1177/Auth/internal/domain"
+	"github.com/Alias1177/Auth/internal/errors"
+	"github.com/Alias1177/Auth/pkg/logger"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+// webAuthnCeremonyTTL время, за которое клиент должен завершить церемонию
+const webAuthnCeremonyTTL = 5 * time.Minute
+
+// WebAuthnRepository хранилище ключей WebAuthn
+type WebAuthnRepository interface {
+	CreateWebAuthnCredential(ctx context.Context, cred *domain.WebAuthnCredential) error
+	ListWebAuthnCredentials(ctx context.Context, userID int) ([]domain.WebAuthnCredential, error)
+	GetWebAuthnCredential(ctx context.Context, id []byte) (*domain.WebAuthnCredential, error)
+	UpdateWebAuthnCredentialUsage(ctx context.Context, id []byte, signCount int64, backupState bool) error
+	DeleteWebAuthnCredential(ctx context.Context, userID int, id []byte) error
+}
+
+// WebAuthnService регистрация passkeys и вход по ним без пароля
+type WebAuthnService interface {
+	BeginRegistration(ctx context.Context, user *domain.User) (*protocol.CredentialCreation, string, error)
+	FinishRegistration(ctx context.Context, user *domain.User, ceremonyID, name string, credential json.RawMessage) (*domain.WebAuthnCredential, error)
+	ListCredentials(ctx context.Context, userID int) ([]domain.WebAuthnCredential, error)
+	DeleteCredential(ctx context.Context, userID int, credentialID string) error
+	BeginLogin(ctx context.Context) (*protocol.CredentialAssertion, string, error)
+	FinishLogin(ctx context.Context, ceremonyID string, credential json.RawMessage) (*domain.User, error)
+}
+
+// WebAuthnServiceImpl реализация на go-webauthn. Состояние церемоний хранится в Redis.
+type WebAuthnServiceImpl struct {
+	webauthn *webauthn.WebAuthn
+	repo     WebAuthnRepository
+	userRepo UserRepository
+	cache    UserCache
+	logger   *logger.Logger
+}
+
+// NewWebAuthnService создает сервис WebAuthn для relying party из конфигурации
+func NewWebAuthnService(
+	cfg config.WebAuthnConfig,
+	repo WebAuthnRepository,
+	userRepo UserRepository,
+	cache UserCache,
+	logger *logger.Logger,
+) (*WebAuthnServiceImpl, error) {
+	wa, err := webauthn.New(&webauthn.Config{
+		RPID:          cfg.RPID,
+		RPDisplayName: cfg.RPDisplayName,
+		RPOrigins:     cfg.RPOrigins,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid webauthn configuration: %w", err)
+	}
+	return &WebAuthnServiceImpl{
+		webauthn: wa,
+		repo:     repo,
+		userRepo: userRepo,
+		cache:    cache,
+		logger:   logger,
+	}, nil
+}
+
+// ceremony состояние незавершенной церемонии
+type ceremony struct {
+	UserID  int                  `json:"user_id,omitempty"`
+	Session webauthn.SessionData `json:"session"`
+}
+
+// BeginRegistration начинает регистрацию нового ключа для пользователя.
+// Ключ создается как discoverable credential, чтобы по нему можно было войти без ввода email.
+func (s *WebAuthnServiceImpl) BeginRegistration(ctx context.Context, user *domain.User) (*protocol.CredentialCreation, string, error) {
+	waUser, err := s.loadUser(ctx, user)
+	if err != nil {
+		return nil, "", err
+	}
+
+	exclusions := make([]protocol.CredentialDescriptor, 0, len(waUser.credentials))
+	for _, cred := range waUser.credentials {
+		exclusions = append(exclusions, cred.Descriptor())
+	}
+
+	creation, session, err := s.webauthn.BeginRegistration(waUser,
+		webauthn.WithAuthenticatorSelection(protocol.AuthenticatorSelection{
+			ResidentKey:        protocol.ResidentKeyRequirementRequired,
+			RequireResidentKey: protocol.ResidentKeyRequired(),
+			UserVerification:   protocol.VerificationRequired,
+		}),
+		webauthn.WithExclusions(exclusions),
+	)
+	if err != nil {
+		return nil, "", err
+	}
+
+	ceremonyID, err := s.saveCeremony(ctx, ceremony{UserID: user.ID, Session: *session})
+	if err != nil {
+		return nil, "", err
+	}
+	return creation, ceremonyID, nil
+}
+
+// FinishRegistration проверяет ответ аутентификатора и сохраняет новый ключ
+func (s *WebAuthnServiceImpl) FinishRegistration(
+	ctx context.Context,
+	user *domain.User,
+	ceremonyID, name string,
+	credential json.RawMessage,
+) (*domain.WebAuthnCredential, error) {
+	state, err := s.takeCeremony(ctx, ceremonyID)
+	if err != nil {
+		return nil, err
+	}
+	if state.UserID != user.ID {
+		return nil, errors.ErrWebAuthnFailed
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(credential)
+	if err != nil {
+		s.logger.Warnw("Invalid webauthn registration response", "user_id", user.ID, "error", err)
+		return nil, errors.ErrWebAuthnFailed
+	}
+
+	waUser, err := s.loadUser(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	created, err := s.webauthn.CreateCredential(waUser, state.Session, parsed)
+	if err != nil {
+		s.logger.Warnw("WebAuthn registration failed", "user_id", user.ID, "error", err)
+		return nil, errors.ErrWebAuthnFailed
+	}
+
+	transports := make([]string, 0, len(created.Transport))
+	for _, t := range created.Transport {
+		transports = append(transports, string(t))
+	}
+	cred := &domain.WebAuthnCredential{
+		ID:              created.ID,
+		UserID:          user.ID,
+		Name:            truncate(strings.TrimSpace(name), maxDeviceLength),
+		PublicKey:       created.PublicKey,
+		AttestationType: created.AttestationType,
+		Transports:      strings.Join(transports, ","),
+		AAGUID:          created.Authenticator.AAGUID,
+		SignCount:       int64(created.Authenticator.SignCount),
+		BackupEligible:  created.Flags.BackupEligible,
+		BackupState:     created.Flags.BackupState,
+	}
+	if err := s.repo.CreateWebAuthnCredential(ctx, cred); err != nil {
+		return nil, err
+	}
+	s.logger.Infow("WebAuthn credential registered", "user_id", user.ID)
+	return cred, nil
+}
+
+// ListCredentials возвращает ключи пользователя
+func (s *WebAuthnServiceImpl) ListCredentials(ctx context.Context, userID int) ([]domain.WebAuthnCredential, error) {
+	return s.repo.ListWebAuthnCredentials(ctx, userID)
+}
+
+// DeleteCredential удаляет ключ пользователя по идентификатору в base64url
+func (s *WebAuthnServiceImpl) DeleteCredential(ctx context.Context, userID int, credentialID string) error {
+	id, err := base64.RawURLEncoding.DecodeString(credentialID)
+	if err != nil {
+		return errors.ErrNotFound
+	}
+	if err := s.repo.DeleteWebAuthnCredential(ctx, userID, id); err != nil {
+		if stderrors.Is(err, sql.ErrNoRows) {
+			return errors.ErrNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// BeginLogin начинает вход по discoverable credential: пользователь определяется по выбранному ключу
+func (s *WebAuthnServiceImpl) BeginLogin(ctx context.Context) (*protocol.CredentialAssertion, string, error) {
+	assertion, session, err := s.webauthn.BeginDiscoverableLogin(
+		webauthn.WithUserVerification(protocol.VerificationRequired),
+	)
+	if err != nil {
+		return nil, "", err
+	}
+
+	ceremonyID, err := s.saveCeremony(ctx, ceremony{Session: *session})
+	if err != nil {
+		return nil, "", err
+	}
+	return assertion, ceremonyID, nil
+}
+
+// FinishLogin проверяет подпись аутентификатора и возвращает владельца ключа
+func (s *WebAuthnServiceImpl) FinishLogin(ctx context.Context, ceremonyID string, credential json.RawMessage) (*domain.User, error) {
+	state, err := s.takeCeremony(ctx, ceremonyID)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(credential)
+	if err != nil {
+		s.logger.Warnw("Invalid webauthn login response", "error", err)
+		return nil, errors.ErrWebAuthnFailed
+	}
+
+	var owner *webAuthnUser
+	handler := func(rawID, userHandle []byte) (webauthn.User, error) {
+		stored, err := s.repo.GetWebAuthnCredential(ctx, rawID)
+		if err != nil {
+			return nil, err
+		}
+		if string(userHandle) != strconv.Itoa(stored.UserID) {
+			return nil, fmt.Errorf("user handle does not match credential owner")
+		}
+		user, err := s.userRepo.GetUserByID(ctx, stored.UserID)
+		if err != nil {
+			return nil, err
+		}
+		owner, err = s.loadUser(ctx, user)
+		return owner, err
+	}
+
+	validated, err := s.webauthn.ValidateDiscoverableLogin(handler, state.Session, parsed)
+	if err != nil {
+		s.logger.Warnw("WebAuthn login failed", "error", err)
+		return nil, errors.ErrWebAuthnFailed
+	}
+	if validated.Authenticator.CloneWarning {
+		// Счетчик подписей не вырос: ключ мог быть скопирован
+		s.logger.Warnw("WebAuthn sign counter did not increase, possible cloned authenticator", "user_id", owner.user.ID)
+		return nil, errors.ErrWebAuthnFailed
+	}
+
+	if err := s.repo.UpdateWebAuthnCredentialUsage(ctx, validated.ID, int64(validated.Authenticator.SignCount), validated.Flags.BackupState); err != nil {
+		return nil, err
+	}
+	return owner.user, nil
+}
+
+// loadUser собирает пользователя WebAuthn вместе с его ключами
+func (s *WebAuthnServiceImpl) loadUser(ctx context.Context, user *domain.User) (*webAuthnUser, error) {
+	stored, err := s.repo.ListWebAuthnCredentials(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	waUser := &webAuthnUser{user: user}
+	for _, cred := range stored {
+		var transports []protocol.AuthenticatorTransport
+		for _, t := range strings.Split(cred.Transports, ",") {
+			if t != "" {
+				transports = append(transports, protocol.AuthenticatorTransport(t))
+			}
+		}
+		waUser.credentials = append(waUser.credentials, webauthn.Credential{
+			ID:              cred.ID,
+			PublicKey:       cred.PublicKey,
+			AttestationType: cred.AttestationType,
+			Transport:       transports,
+			Flags: webauthn.CredentialFlags{
+				UserPresent:    true,
+				UserVerified:   true,
+				BackupEligible: cred.BackupEligible,
+				BackupState:    cred.BackupState,
+			},
+			Authenticator: webauthn.Authenticator{
+				AAGUID:    cred.AAGUID,
+				SignCount: uint32(cred.SignCount),
+			},
+		})
+	}
+	return waUser, nil
+}
+
+// saveCeremony сохраняет состояние церемонии и возвращает ее идентификатор
+func (s *WebAuthnServiceImpl) saveCeremony(ctx context.Context, state ceremony) (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	id := base64.RawURLEncoding.EncodeToString(buf)
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return "", err
+	}
+	if err := s.cache.SetWithTTL(ctx, webAuthnCeremonyKey(id), string(data), webAuthnCeremonyTTL); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// takeCeremony достает состояние церемонии и удаляет его: каждый challenge одноразовый
+func (s *WebAuthnServiceImpl) takeCeremony(ctx context.Context, id string) (*ceremony, error) {
+	if id == "" {
+		return nil, errors.ErrWebAuthnFailed
+	}
+	data, err := s.cache.Get(ctx, webAuthnCeremonyKey(id))
+	if err != nil {
+		return nil, errors.ErrWebAuthnFailed
+	}
+	if err := s.cache.Delete(ctx, webAuthnCeremonyKey(id)); err != nil {
+		return nil, err
+	}
+
+	var state ceremony
+	if err := json.Unmarshal([]byte(data), &state); err != nil {
+		return nil, errors.ErrWebAuthnFailed
+	}
+	return &state, nil
+}
+
+func webAuthnCeremonyKey(id string) string {
+	return fmt.Sprintf("webauthn:ceremony:%s", id)
+}
+
+// webAuthnUser адаптер пользователя для go-webauthn.
+// В качестве user handle используется ID пользователя: он не содержит персональных данных.
+type webAuthnUser struct {
+	user        *domain.User
+	credentials []webauthn.Credential
+}
+
+func (u *webAuthnUser) WebAuthnID() []byte {
+	return []byte(strconv.Itoa(u.user.ID))
+}
+
+func (u *webAuthnUser) WebAuthnName() string {
+	return u.user.Email
+}
+
+func (u *webAuthnUser) WebAuthnDisplayName() string {
+	if u.user.UserName != "" {
+		return u.user.UserName
+	}
+	return u.user.Email
+}
+
+func (u *webAuthnUser) WebAuthnCredentials() []webauthn.Credential {
+	return u.credentials
+}
@@ -0,0 +1,276 @@
+package service
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Alias1177/Auth/internal/config"
+	"github.com/Alias1177/Auth/internal/domain"
+	"github.com/Alias1177/Auth/internal/errors"
+	"github.com/Alias1177/Auth/pkg/logger"
+	"github.com/fxamacker/cbor/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const testWebAuthnOrigin = "http://localhost:3000"
+
+// memoryCache кэш в памяти для тестов церемоний
+type memoryCache struct {
+	MockUserCache
+	values map[string]string
+}
+
+func (c *memoryCache) Get(_ context.Context, key string) (string, error) {
+	value, ok := c.values[key]
+	if !ok {
+		return "", sql.ErrNoRows
+	}
+	return value, nil
+}
+
+func (c *memoryCache) SetWithTTL(_ context.Context, key string, value string, _ time.Duration) error {
+	c.values[key] = value
+	return nil
+}
+
+func (c *memoryCache) Delete(_ context.Context, key string) error {
+	delete(c.values, key)
+	return nil
+}
+
+func (c *memoryCache) Increment(_ context.Context, key string, _ time.Duration) (int64, error) {
+	count, _ := strconv.ParseInt(c.values[key], 10, 64)
+	count++
+	c.values[key] = strconv.FormatInt(count, 10)
+	return count, nil
+}
+
+// memoryWebAuthnRepository хранилище ключей в памяти
+type memoryWebAuthnRepository struct {
+	creds []domain.WebAuthnCredential
+}
+
+func (r *memoryWebAuthnRepository) CreateWebAuthnCredential(_ context.Context, cred *domain.WebAuthnCredential) error {
+	cred.CreatedAt = time.Now()
+	r.creds = append(r.creds, *cred)
+	return nil
+}
+
+func (r *memoryWebAuthnRepository) ListWebAuthnCredentials(_ context.Context, userID int) ([]domain.WebAuthnCredential, error) {
+	var result []domain.WebAuthnCredential
+	for _, cred := range r.creds {
+		if cred.UserID == userID {
+			result = append(result, cred)
+		}
+	}
+	return result, nil
+}
+
+func (r *memoryWebAuthnRepository) GetWebAuthnCredential(_ context.Context, id []byte) (*domain.WebAuthnCredential, error) {
+	for _, cred := range r.creds {
+		if string(cred.ID) == string(id) {
+			return &cred, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *memoryWebAuthnRepository) UpdateWebAuthnCredentialUsage(_ context.Context, id []byte, signCount int64, backupState bool) error {
+	for i := range r.creds {
+		if string(r.creds[i].ID) == string(id) {
+			r.creds[i].SignCount = signCount
+			r.creds[i].BackupState = backupState
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+func (r *memoryWebAuthnRepository) DeleteWebAuthnCredential(_ context.Context, userID int, id []byte) error {
+	for i, cred := range r.creds {
+		if cred.UserID == userID && string(cred.ID) == string(id) {
+			r.creds = append(r.creds[:i], r.creds[i+1:]...)
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+// softAuthenticator программный аутентификатор с ключом ES256
+type softAuthenticator struct {
+	key       *ecdsa.PrivateKey
+	id        []byte
+	rpID      string
+	signCount uint32
+}
+
+func newSoftAuthenticator(t *testing.T, rpID string) *softAuthenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	id := make([]byte, 16)
+	_, err = rand.Read(id)
+	require.NoError(t, err)
+	return &softAuthenticator{key: key, id: id, rpID: rpID}
+}
+
+func (a *softAuthenticator) authData(flags byte, attested []byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(a.rpID))
+	data := append([]byte{}, rpIDHash[:]...)
+	data = append(data, flags)
+	data = binary.BigEndian.AppendUint32(data, a.signCount)
+	return append(data, attested...)
+}
+
+func clientDataJSON(t *testing.T, ceremonyType, challenge string) []byte {
+	data, err := json.Marshal(map[string]string{
+		"type":      ceremonyType,
+		"challenge": challenge,
+		"origin":    testWebAuthnOrigin,
+	})
+	require.NoError(t, err)
+	return data
+}
+
+// register формирует ответ navigator.credentials.create с attestation "none"
+func (a *softAuthenticator) register(t *testing.T, challenge string) json.RawMessage {
+	coseKey, err := cbor.Marshal(map[int]interface{}{
+		1:  2,
+		3:  -7,
+		-1: 1,
+		-2: a.key.PublicKey.X.FillBytes(make([]byte, 32)),
+		-3: a.key.PublicKey.Y.FillBytes(make([]byte, 32)),
+	})
+	require.NoError(t, err)
+
+	attested := make([]byte, 16) // AAGUID
+	attested = binary.BigEndian.AppendUint16(attested, uint16(len(a.id)))
+	attested = append(attested, a.id...)
+	attested = append(attested, coseKey...)
+
+	attestation, err := cbor.Marshal(map[string]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[string]interface{}{},
+		"authData": a.authData(0x01|0x04|0x40, attested),
+	})
+	require.NoError(t, err)
+
+	return a.response(t, map[string]string{
+		"clientDataJSON":    base64.RawURLEncoding.EncodeToString(clientDataJSON(t, "webauthn.create", challenge)),
+		"attestationObject": base64.RawURLEncoding.EncodeToString(attestation),
+	})
+}
+
+// assert формирует ответ navigator.credentials.get
+func (a *softAuthenticator) assert(t *testing.T, challenge string, userHandle []byte) json.RawMessage {
+	authData := a.authData(0x01|0x04, nil)
+	clientData := clientDataJSON(t, "webauthn.get", challenge)
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	require.NoError(t, err)
+
+	return a.response(t, map[string]string{
+		"clientDataJSON":    base64.RawURLEncoding.EncodeToString(clientData),
+		"authenticatorData": base64.RawURLEncoding.EncodeToString(authData),
+		"signature":         base64.RawURLEncoding.EncodeToString(signature),
+		"userHandle":        base64.RawURLEncoding.EncodeToString(userHandle),
+	})
+}
+
+func (a *softAuthenticator) response(t *testing.T, response map[string]string) json.RawMessage {
+	id := base64.RawURLEncoding.EncodeToString(a.id)
+	data, err := json.Marshal(map[string]interface{}{
+		"id":       id,
+		"rawId":    id,
+		"type":     "public-key",
+		"response": response,
+	})
+	require.NoError(t, err)
+	return data
+}
+
+func TestWebAuthnService_RegisterAndLogin(t *testing.T) {
+	ctx := context.Background()
+	log, err := logger.New("error")
+	require.NoError(t, err)
+
+	user := &domain.User{ID: 42, Email: "user@example.com", UserName: "user"}
+	userRepo := new(MockUserRepository)
+	userRepo.On("GetUserByID", mock.Anything, user.ID).Return(user, nil)
+	repo := &memoryWebAuthnRepository{}
+
+	svc, err := NewWebAuthnService(config.WebAuthnConfig{
+		RPID:          "localhost",
+		RPDisplayName: "Auth",
+		RPOrigins:     []string{testWebAuthnOrigin},
+	}, repo, userRepo, &memoryCache{values: map[string]string{}}, log)
+	require.NoError(t, err)
+
+	authenticator := newSoftAuthenticator(t, "localhost")
+
+	// Регистрация
+	creation, ceremonyID, err := svc.BeginRegistration(ctx, user)
+	require.NoError(t, err)
+	cred, err := svc.FinishRegistration(ctx, user, ceremonyID, "Laptop", authenticator.register(t, creation.Response.Challenge.String()))
+	require.NoError(t, err)
+	assert.Equal(t, authenticator.id, cred.ID)
+	assert.Equal(t, "Laptop", cred.Name)
+
+	// Церемония одноразовая
+	_, err = svc.FinishRegistration(ctx, user, ceremonyID, "Laptop", authenticator.register(t, creation.Response.Challenge.String()))
+	assert.Equal(t, errors.ErrWebAuthnFailed, err)
+
+	// Повторная регистрация того же ключа исключается
+	creation, _, err = svc.BeginRegistration(ctx, user)
+	require.NoError(t, err)
+	require.Len(t, creation.Response.CredentialExcludeList, 1)
+
+	// Вход
+	authenticator.signCount = 1
+	assertion, ceremonyID, err := svc.BeginLogin(ctx)
+	require.NoError(t, err)
+	response := authenticator.assert(t, assertion.Response.Challenge.String(), []byte("42"))
+	loggedIn, err := svc.FinishLogin(ctx, ceremonyID, response)
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, loggedIn.ID)
+	assert.Equal(t, int64(1), repo.creds[0].SignCount)
+
+	// Повтор того же ответа отклоняется
+	_, err = svc.FinishLogin(ctx, ceremonyID, response)
+	assert.Equal(t, errors.ErrWebAuthnFailed, err)
+
+	tests := []struct {
+		name       string
+		signCount  uint32
+		userHandle []byte
+	}{
+		{name: "sign counter did not increase", signCount: 1, userHandle: []byte("42")},
+		{name: "user handle of another user", signCount: 2, userHandle: []byte("7")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authenticator.signCount = tt.signCount
+			assertion, ceremonyID, err := svc.BeginLogin(ctx)
+			require.NoError(t, err)
+			_, err = svc.FinishLogin(ctx, ceremonyID, authenticator.assert(t, assertion.Response.Challenge.String(), tt.userHandle))
+			assert.Equal(t, errors.ErrWebAuthnFailed, err)
+		})
+	}
+
+	// Удаление ключа
+	id := base64.RawURLEncoding.EncodeToString(authenticator.id)
+	require.NoError(t, svc.DeleteCredential(ctx, user.ID, id))
+	assert.Equal(t, errors.ErrNotFound, svc.DeleteCredential(ctx, user.ID, id))
+}
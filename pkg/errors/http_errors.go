@@ -6,6 +6,7 @@ import (
 	"net/http"
 
 	"github.com/Alias1177/Auth/internal/dto"
+	apperrors "github.com/Alias1177/Auth/internal/errors"
 	"github.com/Alias1177/Auth/pkg/httputil"
 	"github.com/Alias1177/Auth/pkg/logger"
 )
@@ -62,3 +63,17 @@ func HandleUnauthorizedError(w http.ResponseWriter, message string, log *logger.
 	log.Warnw("Unauthorized access", "message", message)
 	httputil.JSONErrorWithID(w, http.StatusUnauthorized, dto.MsgUnauthorized)
 }
+
+// HandlePasswordPolicyError отвечает 400 со списком нарушенных правил, если err — ошибка политики паролей.
+// Возвращает false, если ошибка другого типа и ответ не отправлен.
+func HandlePasswordPolicyError(w http.ResponseWriter, err error, log *logger.Logger) bool {
+	var policyErr *apperrors.PasswordPolicyError
+	if !errors.As(err, &policyErr) {
+		return false
+	}
+	log.Warnw("Password rejected by policy", "error", err)
+	httputil.JSONErrorWithData(w, http.StatusBadRequest, dto.MsgPasswordTooWeak, map[string]interface{}{
+		"violations": policyErr.Violations,
+	})
+	return true
+}
@@ -0,0 +1,94 @@
+package httputil
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ClientIP возвращает адрес клиента по адресу соединения. Заголовки X-Forwarded-For и X-Real-IP
+// задает сам клиент, поэтому они учитываются только за доверенными прокси: RealIP подставляет
+// из них адрес в RemoteAddr до обработки запроса.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// TrustedProxies сети прокси, которым разрешено сообщать адрес клиента в X-Forwarded-For
+type TrustedProxies []*net.IPNet
+
+// ParseTrustedProxies разбирает список сетей в нотации CIDR или отдельных адресов
+func ParseTrustedProxies(values []string) (TrustedProxies, error) {
+	proxies := make(TrustedProxies, 0, len(values))
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		if !strings.Contains(value, "/") {
+			ip := net.ParseIP(value)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q", value)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			proxies = append(proxies, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", value, err)
+		}
+		proxies = append(proxies, network)
+	}
+	return proxies, nil
+}
+
+// RealIP определяет адрес клиента. Если соединение пришло не от доверенного прокси, это адрес соединения.
+// Иначе X-Forwarded-For просматривается справа налево и берется первый адрес, не принадлежащий
+// доверенным прокси: левые записи мог дописать сам клиент. Без X-Forwarded-For используется X-Real-IP.
+func (p TrustedProxies) RealIP(r *http.Request) string {
+	ip := ClientIP(r)
+	if !p.contains(ip) {
+		return ip
+	}
+
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		hops := strings.Split(forwarded, ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if net.ParseIP(hop) == nil {
+				// Прокси пишут в заголовок только адреса: дальше доверять цепочке нельзя
+				return ip
+			}
+			ip = hop
+			if !p.contains(hop) {
+				return hop
+			}
+		}
+		return ip
+	}
+	if realIP := strings.TrimSpace(r.Header.Get("X-Real-IP")); net.ParseIP(realIP) != nil {
+		return realIP
+	}
+	return ip
+}
+
+func (p TrustedProxies) contains(value string) bool {
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return false
+	}
+	for _, network := range p {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
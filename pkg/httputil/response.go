@@ -46,6 +46,96 @@ func MessageEnByID(id int) string {
 		return "Password reset confirmed"
 	case 1009:
 		return "User information retrieved"
+	case 1010:
+		return "You have been logged out on all devices"
+	case 1011:
+		return "Sessions retrieved"
+	case 1012:
+		return "Session revoked"
+	case 1013:
+		return "All other sessions revoked"
+	case 1014:
+		return "Two-factor authentication code required"
+	case 1015:
+		return "Two-factor enrollment started, confirm it with a code from your authenticator app"
+	case 1016:
+		return "Two-factor authentication enabled"
+	case 1017:
+		return "Two-factor authentication disabled"
+	case 1018:
+		return "Recovery codes generated, store them in a safe place"
+	case 1019:
+		return "Account recovered, password changed and all sessions signed out"
+	case 1020:
+		return "WebAuthn options created"
+	case 1021:
+		return "Passkey registered"
+	case 1022:
+		return "Passkeys retrieved"
+	case 1023:
+		return "Passkey deleted"
+	case 1024:
+		return "Email address verified"
+	case 1025:
+		return "If the account exists and is not verified, a verification email has been sent"
+	case 1026:
+		return "Registration successful, check your email to verify the address"
+	case 1027:
+		return "Account unlocked"
+	case 1028:
+		return "Confirmation email sent to the new address"
+	case 1029:
+		return "Email address changed"
+	case 1030:
+		return "Email change cancelled, all sessions have been signed out"
+	case 1031:
+		return "Roles retrieved"
+	case 1032:
+		return "Role created"
+	case 1033:
+		return "Role updated"
+	case 1034:
+		return "Role deleted"
+	case 1035:
+		return "Permissions retrieved"
+	case 1036:
+		return "User roles retrieved"
+	case 1037:
+		return "Role assigned"
+	case 1038:
+		return "Role removed"
+	case 1039:
+		return "Users retrieved"
+	case 1040:
+		return "User created"
+	case 1041:
+		return "User disabled"
+	case 1042:
+		return "User enabled"
+	case 1043:
+		return "Password reset forced, the user has been signed out and sent a reset email"
+	case 1044:
+		return "All user sessions revoked"
+	case 1045:
+		return "User deleted"
+	case 1046:
+		return "Audit log retrieved"
+	case 1047:
+		return "OAuth clients retrieved"
+	case 1048:
+		return "OAuth client created, store the client secret now: it is shown only once"
+	case 1049:
+		return "Client secret rotated, the previous secret remains valid for the grace period"
+	case 1050:
+		return "OAuth client deleted"
+	case 1051:
+		return "Linked accounts retrieved"
+	case 1052:
+		return "Continue at the authorization URL to link the account"
+	case 1053:
+		return "External account linked"
+	case 1054:
+		return "External account unlinked"
 	case 2000:
 		return "Invalid email"
 	case 2001:
@@ -66,6 +156,10 @@ func MessageEnByID(id int) string {
 		return "Email and password must be filled"
 	case 2009:
 		return "Invalid request data"
+	case 2010:
+		return "Some fields cannot be updated with this request"
+	case 2011:
+		return "Unknown permission"
 	case 3000:
 		return "Wrong password"
 	case 3001:
@@ -84,6 +178,58 @@ func MessageEnByID(id int) string {
 		return "Resource not found"
 	case 3008:
 		return "Too many reset attempts"
+	case 3009:
+		return "Refresh token reuse detected, session revoked"
+	case 3010:
+		return "Session not found"
+	case 3011:
+		return "Invalid two-factor authentication code"
+	case 3012:
+		return "Two-factor authentication is not enrolled"
+	case 3013:
+		return "Two-factor authentication is already enabled"
+	case 3014:
+		return "Invalid email or recovery code"
+	case 3015:
+		return "Recovery codes already generated, use regenerate to replace them"
+	case 3016:
+		return "Too many recovery attempts, try again later"
+	case 3017:
+		return "Passkey verification failed"
+	case 3018:
+		return "Passkey not found"
+	case 3019:
+		return "Email address is not verified"
+	case 3020:
+		return "Too many verification emails requested, try again later"
+	case 3021:
+		return "Too many requests, try again later"
+	case 3022:
+		return "Too many failed login attempts, sign-in is temporarily locked. Check your email for an unlock link"
+	case 3023:
+		return "Access denied"
+	case 3024:
+		return "The resource has been modified, reload it and try again"
+	case 3025:
+		return "Role not found"
+	case 3026:
+		return "Role already exists"
+	case 3027:
+		return "Built-in role cannot be changed"
+	case 3028:
+		return "Account disabled"
+	case 3029:
+		return "OAuth client not found"
+	case 3030:
+		return "Linked account not found"
+	case 3031:
+		return "External account is already linked to a user"
+	case 3032:
+		return "An account with this email already exists, sign in and link the external account in account settings"
+	case 3033:
+		return "Cannot remove the last sign-in method, set a password or add a passkey first"
+	case 3034:
+		return "Sign in again or enter a code from your authenticator app to confirm this action"
 	case 4000:
 		return "Internal server error"
 	case 4001:
@@ -113,6 +259,17 @@ func JSONErrorWithID(w http.ResponseWriter, statusCode int, idMessage int) {
 	})
 }
 
+// JSONErrorWithData отправляет JSON ошибку с id_message, message_en и подробностями в data
+func JSONErrorWithData(w http.ResponseWriter, statusCode int, idMessage int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id_message": idMessage,
+		"message_en": MessageEnByID(idMessage),
+		"data":       data,
+	})
+}
+
 // JSONSuccessWithID отправляет успешный JSON ответ с id_message и message_en
 func JSONSuccessWithID(w http.ResponseWriter, statusCode int, idMessage int, data interface{}) error {
 	w.Header().Set("Content-Type", "application/json")
@@ -147,6 +304,19 @@ func SetTokenCookie(w http.ResponseWriter, cookieName, token string) {
 	})
 }
 
+// ClearTokenCookie удаляет куки с JWT токеном
+func ClearTokenCookie(w http.ResponseWriter, cookieName string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     cookieName,
+		Value:    "",
+		HttpOnly: true,
+		Secure:   true,
+		Path:     "/",
+		MaxAge:   -1,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
 // SuccessResponse создает стандартный успешный ответ
 func SuccessResponse(message string) map[string]string {
 	return map[string]string{
@@ -1,82 +1,248 @@
 package jwt
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"math"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/Alias1177/Auth/internal/config"
 	"github.com/Alias1177/Auth/internal/domain"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
 type JWTTokenManager struct {
-	secret string
+	mu      sync.RWMutex
+	ring    *KeyRing
+	keysDir string
+	legacy  *SigningKey // ключ из JWT_SECRET/JWT_PRIVATE_KEY, при связке ключей только проверяет подписи
 }
 
+// Типы токенов (claim type)
+const (
+	TokenTypeAccess     = "access"
+	TokenTypeRefresh    = "refresh"
+	TokenTypeMFAPending = "mfa_pending"
+	TokenTypeID         = "id"
+	// TokenTypeClientAccess access токен сервиса (client_credentials): субъект - клиент, а не пользователь
+	TokenTypeClientAccess = "client_access"
+
+	TokenTypeEmailVerification = "email_verification"
+	TokenTypeAccountUnlock     = "account_unlock"
+)
+
+// Время жизни токенов
+const (
+	AccessTokenTTL     = 15 * time.Minute
+	RefreshTokenTTL    = 7 * 24 * time.Hour
+	MFAPendingTokenTTL = 5 * time.Minute
+)
+
 type RefreshTokenStruct struct {
 	Token string `json:"refresh_token"`
 }
 
-func NewJWTTokenManager(cfg config.JWTConfig) *JWTTokenManager {
-	return &JWTTokenManager{
-		secret: cfg.Secret,
+// NewJWTTokenManager создает менеджер токенов.
+// Если задан JWT_KEYS_DIR, ключи берутся из связки в этом каталоге (см. KeyRing),
+// иначе используется единственный ключ из JWT_SECRET или JWT_PRIVATE_KEY.
+func NewJWTTokenManager(cfg config.JWTConfig) (*JWTTokenManager, error) {
+	manager := &JWTTokenManager{keysDir: cfg.KeysDir}
+
+	hasConfigKey := cfg.Secret != "" || cfg.PrivateKey != "" || cfg.PrivateKeyPath != ""
+	if cfg.KeysDir == "" || hasConfigKey {
+		key, err := NewSigningKeyFromConfig(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load JWT signing key: %w", err)
+		}
+		manager.legacy = key
+	}
+
+	if cfg.KeysDir == "" {
+		manager.ring = NewStaticKeyRing(manager.legacy)
+		return manager, nil
 	}
+
+	if err := manager.Reload(); err != nil {
+		return nil, err
+	}
+	return manager, nil
+}
+
+// Reload перечитывает связку ключей из каталога, подхватывая ключи, добавленные командой ротации.
+// Связка без действующего ключа подписи отклоняется с ErrNoSigningKey.
+func (j *JWTTokenManager) Reload() error {
+	if j.keysDir == "" {
+		return nil
+	}
+
+	ring, err := LoadKeyRing(j.keysDir)
+	if err != nil {
+		return fmt.Errorf("failed to load JWT key ring: %w", err)
+	}
+	// Без ключа подписи не выдается ни один токен: при запуске сервис не стартует,
+	// а при перечитывании остается прежняя связка
+	if _, err := ring.SigningKey(time.Now()); err != nil {
+		return fmt.Errorf("invalid JWT key ring %s: %w", j.keysDir, err)
+	}
+	if j.legacy != nil {
+		ring.AddVerificationKey(j.legacy)
+	}
+
+	j.mu.Lock()
+	j.ring = ring
+	j.mu.Unlock()
+	return nil
+}
+
+// StartAutoReload периодически перечитывает связку ключей до отмены контекста
+func (j *JWTTokenManager) StartAutoReload(ctx context.Context, interval time.Duration, onError func(error)) {
+	if j.keysDir == "" || interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := j.Reload(); err != nil && onError != nil {
+					onError(err)
+				}
+			}
+		}
+	}()
+}
+
+func (j *JWTTokenManager) keyRing() *KeyRing {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	return j.ring
+}
+
+// JWKS возвращает публичные ключи для проверки токенов сторонними сервисами
+func (j *JWTTokenManager) JWKS() JWKS {
+	return j.keyRing().PublicKeys(time.Now())
+}
+
+// sign подписывает claims текущим ключом и проставляет заголовок kid
+func (j *JWTTokenManager) sign(claims jwt.MapClaims) (string, error) {
+	key, err := j.keyRing().SigningKey(time.Now())
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(key.Method, claims)
+	if key.ID != "" {
+		token.Header["kid"] = key.ID
+	}
+	return token.SignedString(key.Private)
+}
+
+// keyFunc выбирает ключ проверки по kid и не допускает подмену алгоритма
+func (j *JWTTokenManager) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	keys := j.keyRing().VerificationKeys(kid, token.Method.Alg(), time.Now())
+	switch len(keys) {
+	case 0:
+		return nil, errors.New("no verification key for token")
+	case 1:
+		return keys[0].Public, nil
+	}
+
+	set := jwt.VerificationKeySet{}
+	for _, key := range keys {
+		set.Keys = append(set.Keys, key.Public)
+	}
+	return set, nil
 }
 
 func (j *JWTTokenManager) GenerateAccessToken(userClaims domain.UserClaims) (string, error) {
-	exp := time.Now().Add(15 * time.Minute).Unix()
+	now := time.Now()
 	tokenClaims := jwt.MapClaims{
 		"sub":   userClaims.UserID,
 		"email": userClaims.Email,
-		"exp":   exp,
+		"type":  TokenTypeAccess,
+		"jti":   uuid.NewString(),
+		// Дробная часть iat (RFC 7519 допускает нецелый NumericDate) нужна для отзыва всех токенов пользователя
+		"iat": float64(now.UnixMilli()) / 1000,
+		"exp": now.Add(AccessTokenTTL).Unix(),
+	}
+	if userClaims.FamilyID != "" {
+		tokenClaims["fid"] = userClaims.FamilyID
+	}
+	// Роли и разрешения позволяют сторонним сервисам авторизовать запрос по самому токену
+	if len(userClaims.Roles) > 0 {
+		tokenClaims["roles"] = userClaims.Roles
+	}
+	if len(userClaims.Permissions) > 0 {
+		tokenClaims["perms"] = userClaims.Permissions
+	}
+	setClientClaims(tokenClaims, userClaims)
+	return j.sign(tokenClaims)
+}
+
+// setClientClaims записывает приложение (aud) и области доступа токена, выданного через /oauth2/token
+func setClientClaims(tokenClaims jwt.MapClaims, userClaims domain.UserClaims) {
+	if userClaims.ClientID == "" {
+		return
+	}
+	tokenClaims["aud"] = userClaims.ClientID
+	if userClaims.Scope != "" {
+		tokenClaims["scope"] = userClaims.Scope
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, tokenClaims)
-	return token.SignedString([]byte(j.secret))
 }
 
 func (j *JWTTokenManager) ValidateAccessToken(token string) (*domain.UserClaims, error) {
-	return j.validateToken(token, true)
+	return j.validateToken(token, TokenTypeAccess)
 }
 
-func (j *JWTTokenManager) validateToken(token string, isAccess bool) (*domain.UserClaims, error) {
-	parsedToken, err := jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected signing method")
-		}
-		return []byte(j.secret), nil
-	})
+// parseClaims проверяет подпись и срок действия токена и возвращает его claims вместе с exp
+func (j *JWTTokenManager) parseClaims(token string) (jwt.MapClaims, int64, error) {
+	parsedToken, err := jwt.Parse(token, j.keyFunc)
 	if err != nil {
-		return nil, fmt.Errorf("invalid token format: %w", err)
+		return nil, 0, fmt.Errorf("invalid token format: %w", err)
 	}
 	if !parsedToken.Valid {
-		return nil, errors.New("invalid token")
+		return nil, 0, errors.New("invalid token")
 	}
 	claims, ok := parsedToken.Claims.(jwt.MapClaims)
 	if !ok {
-		return nil, errors.New("invalid claims format")
+		return nil, 0, errors.New("invalid claims format")
 	}
 
 	// Проверка exp
+	var expiresAt int64
 	if expRaw, ok := claims["exp"]; ok {
 		switch exp := expRaw.(type) {
 		case float64:
-			if int64(exp) < time.Now().Unix() {
-				return nil, errors.New("token expired")
-			}
+			expiresAt = int64(exp)
 		case int64:
-			if exp < time.Now().Unix() {
-				return nil, errors.New("token expired")
-			}
+			expiresAt = exp
 		}
-	}
-	// Проверка типа для refresh
-	if !isAccess {
-		t, ok := claims["type"].(string)
-		if !ok || t != "refresh" {
-			return nil, errors.New("not a refresh token")
+		if expiresAt < time.Now().Unix() {
+			return nil, 0, errors.New("token expired")
 		}
 	}
+	return claims, expiresAt, nil
+}
+
+func (j *JWTTokenManager) validateToken(token string, tokenType string) (*domain.UserClaims, error) {
+	claims, expiresAt, err := j.parseClaims(token)
+	if err != nil {
+		return nil, err
+	}
+	// Проверка типа: access токены, выпущенные до появления claim type, его не содержат
+	t, _ := claims["type"].(string)
+	if t != tokenType && !(tokenType == TokenTypeAccess && t == "") {
+		return nil, fmt.Errorf("not a %s token", tokenType)
+	}
 	userID, ok := claims["sub"].(string)
 	if !ok {
 		return nil, errors.New("missing or invalid 'sub' claim")
@@ -85,44 +251,163 @@ func (j *JWTTokenManager) validateToken(token string, isAccess bool) (*domain.Us
 	if !ok {
 		return nil, errors.New("missing or invalid 'email' claim")
 	}
+	tokenID, _ := claims["jti"].(string)
+	familyID, _ := claims["fid"].(string)
+	issuedAt, _ := claims["iat"].(float64)
+	scope, _ := claims["scope"].(string)
+	var clientID string
+	if audience, err := claims.GetAudience(); err == nil && len(audience) > 0 {
+		clientID = audience[0]
+	}
 
 	return &domain.UserClaims{
-		UserID: userID,
-		Email:  email,
+		UserID:        userID,
+		Email:         email,
+		ExpiresAt:     expiresAt,
+		IssuedAt:      int64(issuedAt),
+		IssuedAtMilli: int64(math.Round(issuedAt * 1000)),
+		TokenID:       tokenID,
+		FamilyID:      familyID,
+		Roles:         stringSliceClaim(claims["roles"]),
+		Permissions:   stringSliceClaim(claims["perms"]),
+		ClientID:      clientID,
+		Scope:         scope,
 	}, nil
 }
 
+// stringSliceClaim читает claim-массив строк; после разбора JSON это []interface{}
+func stringSliceClaim(raw interface{}) []string {
+	items, ok := raw.([]interface{})
+	if !ok {
+		return nil
+	}
+	values := make([]string, 0, len(items))
+	for _, item := range items {
+		if value, ok := item.(string); ok {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// GenerateRefreshToken выпускает refresh токен. Идентификатор (jti) и семейство (fid)
+// задаются вызывающей стороной, которая сохраняет их в хранилище refresh токенов.
 func (j *JWTTokenManager) GenerateRefreshToken(userClaims domain.UserClaims) (string, error) {
-	exp := time.Now().Add(7 * 24 * time.Hour).Unix()
+	if userClaims.TokenID == "" {
+		return "", errors.New("refresh token requires a token id")
+	}
+	now := time.Now()
 	claims := jwt.MapClaims{
 		"sub":   userClaims.UserID,
 		"email": userClaims.Email,
-		"type":  "refresh",
-		"exp":   exp,
+		"type":  TokenTypeRefresh,
+		"jti":   userClaims.TokenID,
+		"fid":   userClaims.FamilyID,
+		"iat":   now.Unix(),
+		"exp":   now.Add(RefreshTokenTTL).Unix(),
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(j.secret))
+	setClientClaims(claims, userClaims)
+	return j.sign(claims)
 }
 
-func (j *JWTTokenManager) RefreshTokens(refreshToken string) (string, string, error) {
-	claims, err := j.validateToken(refreshToken, false)
-	if err != nil {
-		return "", "", err
+func (j *JWTTokenManager) ValidateRefreshToken(token string) (*domain.UserClaims, error) {
+	return j.validateToken(token, TokenTypeRefresh)
+}
+
+// GenerateIDToken выпускает ID токен OpenID Connect для приложения claims.Audience.
+// Claim type отличает его от access токена: без него ID токен с email прошел бы проверку как access токен.
+func (j *JWTTokenManager) GenerateIDToken(idClaims domain.IDTokenClaims, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":       idClaims.Issuer,
+		"sub":       idClaims.Subject,
+		"aud":       idClaims.Audience,
+		"type":      TokenTypeID,
+		"iat":       now.Unix(),
+		"exp":       now.Add(ttl).Unix(),
+		"auth_time": idClaims.AuthTime,
+	}
+	if idClaims.Nonce != "" {
+		claims["nonce"] = idClaims.Nonce
 	}
+	if idClaims.Email != "" {
+		claims["email"] = idClaims.Email
+		claims["email_verified"] = idClaims.EmailVerified
+	}
+	if idClaims.Username != "" {
+		claims["preferred_username"] = idClaims.Username
+	}
+	return j.sign(claims)
+}
 
-	newAccessToken, err := j.GenerateAccessToken(*claims)
-	if err != nil {
-		return "", "", err
+// GenerateClientToken выпускает access токен сервиса по client_credentials: sub и client_id - идентификатор
+// клиента, scope - разрешенные области через пробел (RFC 9068). Свой claim type не дает принять его
+// как access токен пользователя.
+func (j *JWTTokenManager) GenerateClientToken(clientClaims domain.ClientClaims, ttl time.Duration) (string, error) {
+	if clientClaims.ClientID == "" {
+		return "", errors.New("client token requires a client id")
+	}
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"sub":       clientClaims.ClientID,
+		"client_id": clientClaims.ClientID,
+		"type":      TokenTypeClientAccess,
+		"jti":       uuid.NewString(),
+		"iat":       now.Unix(),
+		"exp":       now.Add(ttl).Unix(),
 	}
+	if len(clientClaims.Scopes) > 0 {
+		claims["scope"] = strings.Join(clientClaims.Scopes, " ")
+	}
+	return j.sign(claims)
+}
 
-	newRefreshToken, err := j.GenerateRefreshToken(*claims)
+// ValidateClientToken проверяет access токен сервиса, выпущенный GenerateClientToken
+func (j *JWTTokenManager) ValidateClientToken(token string) (*domain.ClientClaims, error) {
+	claims, expiresAt, err := j.parseClaims(token)
 	if err != nil {
-		return "", "", err
+		return nil, err
 	}
+	if t, _ := claims["type"].(string); t != TokenTypeClientAccess {
+		return nil, fmt.Errorf("not a %s token", TokenTypeClientAccess)
+	}
+	clientID, ok := claims["client_id"].(string)
+	if !ok || clientID == "" {
+		return nil, errors.New("missing or invalid 'client_id' claim")
+	}
+	scope, _ := claims["scope"].(string)
+	tokenID, _ := claims["jti"].(string)
+	issuedAt, _ := claims["iat"].(float64)
 
-	return newAccessToken, newRefreshToken, nil
+	return &domain.ClientClaims{
+		ClientID:  clientID,
+		Scopes:    strings.Fields(scope),
+		TokenID:   tokenID,
+		IssuedAt:  int64(issuedAt),
+		ExpiresAt: expiresAt,
+	}, nil
 }
 
-func (j *JWTTokenManager) ValidateRefreshToken(token string) (*domain.UserClaims, error) {
-	return j.validateToken(token, false)
+// GenerateScopedToken выпускает короткоживущий токен для промежуточного шага (например, ввода кода MFA).
+// Такой токен не принимается как access или refresh токен.
+func (j *JWTTokenManager) GenerateScopedToken(userClaims domain.UserClaims, tokenType string, ttl time.Duration) (string, error) {
+	if tokenType == "" || tokenType == TokenTypeAccess || tokenType == TokenTypeRefresh ||
+		tokenType == TokenTypeID || tokenType == TokenTypeClientAccess {
+		return "", fmt.Errorf("invalid scoped token type %q", tokenType)
+	}
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"sub":   userClaims.UserID,
+		"email": userClaims.Email,
+		"type":  tokenType,
+		"jti":   uuid.NewString(),
+		"iat":   now.Unix(),
+		"exp":   now.Add(ttl).Unix(),
+	}
+	return j.sign(claims)
+}
+
+// ValidateScopedToken проверяет токен промежуточного шага с типом tokenType
+func (j *JWTTokenManager) ValidateScopedToken(token, tokenType string) (*domain.UserClaims, error) {
+	return j.validateToken(token, tokenType)
 }
@@ -0,0 +1,260 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/Alias1177/Auth/internal/config"
+	"github.com/Alias1177/Auth/internal/domain"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func encodePKCS8(t *testing.T, key interface{}) string {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+}
+
+func TestJWTTokenManager_SignAndValidate(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		cfg     config.JWTConfig
+		alg     string
+		kty     string
+		publish bool
+	}{
+		{name: "HS256", cfg: config.JWTConfig{Secret: "test-secret"}, alg: AlgHS256},
+		{name: "RS256", cfg: config.JWTConfig{PrivateKey: encodePKCS8(t, rsaKey)}, alg: AlgRS256, kty: "RSA", publish: true},
+		{name: "ES256", cfg: config.JWTConfig{PrivateKey: encodePKCS8(t, ecKey), KeyID: "ec-1"}, alg: AlgES256, kty: "EC", publish: true},
+		{name: "EdDSA", cfg: config.JWTConfig{PrivateKey: encodePKCS8(t, edKey)}, alg: AlgEdDSA, kty: "OKP", publish: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager, err := NewJWTTokenManager(tt.cfg)
+			require.NoError(t, err)
+
+			token, err := manager.GenerateAccessToken(domain.UserClaims{UserID: "42", Email: "user@example.com"})
+			require.NoError(t, err)
+
+			parsed, _, err := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+			require.NoError(t, err)
+			assert.Equal(t, tt.alg, parsed.Method.Alg())
+
+			claims, err := manager.ValidateAccessToken(token)
+			require.NoError(t, err)
+			assert.Equal(t, "42", claims.UserID)
+			assert.Equal(t, "user@example.com", claims.Email)
+			assert.WithinDuration(t, time.Now(), time.UnixMilli(claims.IssuedAtMilli), time.Second)
+			assert.Equal(t, claims.IssuedAt, claims.IssuedAtMilli/1000)
+
+			jwks := manager.JWKS()
+			if !tt.publish {
+				assert.Empty(t, jwks.Keys)
+				return
+			}
+			require.Len(t, jwks.Keys, 1)
+			assert.Equal(t, tt.kty, jwks.Keys[0].Kty)
+			assert.Equal(t, tt.alg, jwks.Keys[0].Alg)
+			assert.Equal(t, parsed.Header["kid"], jwks.Keys[0].Kid)
+		})
+	}
+}
+
+func TestJWTTokenManager_RoleClaims(t *testing.T) {
+	manager, err := NewJWTTokenManager(config.JWTConfig{Secret: "test-secret"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		roles       []string
+		permissions []string
+	}{
+		{name: "admin", roles: []string{"admin"}, permissions: []string{"users:read", "users:write"}},
+		{name: "no roles"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := manager.GenerateAccessToken(domain.UserClaims{
+				UserID:      "42",
+				Email:       "user@example.com",
+				Roles:       tt.roles,
+				Permissions: tt.permissions,
+			})
+			require.NoError(t, err)
+
+			claims, err := manager.ValidateAccessToken(token)
+			require.NoError(t, err)
+			assert.Equal(t, tt.roles, claims.Roles)
+			assert.Equal(t, tt.permissions, claims.Permissions)
+		})
+	}
+}
+
+func TestJWTTokenManager_ApplicationClaims(t *testing.T) {
+	manager, err := NewJWTTokenManager(config.JWTConfig{Secret: "test-secret"})
+	require.NoError(t, err)
+
+	userClaims := domain.UserClaims{UserID: "42", Email: "user@example.com", TokenID: "jti", FamilyID: "fid", ClientID: "app", Scope: "openid email"}
+	access, err := manager.GenerateAccessToken(userClaims)
+	require.NoError(t, err)
+	refresh, err := manager.GenerateRefreshToken(userClaims)
+	require.NoError(t, err)
+
+	claims, err := manager.ValidateAccessToken(access)
+	require.NoError(t, err)
+	assert.Equal(t, "app", claims.ClientID)
+	assert.Equal(t, "openid email", claims.Scope)
+	claims, err = manager.ValidateRefreshToken(refresh)
+	require.NoError(t, err)
+	assert.Equal(t, "app", claims.ClientID)
+
+	// Токен входа в сам сервис не привязан к приложению
+	token, err := manager.GenerateAccessToken(domain.UserClaims{UserID: "42", Email: "user@example.com"})
+	require.NoError(t, err)
+	claims, err = manager.ValidateAccessToken(token)
+	require.NoError(t, err)
+	assert.Empty(t, claims.ClientID)
+	assert.Empty(t, claims.Scope)
+}
+
+func TestJWTTokenManager_RejectsForeignKey(t *testing.T) {
+	first, err := NewJWTTokenManager(config.JWTConfig{Secret: "first"})
+	require.NoError(t, err)
+	second, err := NewJWTTokenManager(config.JWTConfig{Secret: "second"})
+	require.NoError(t, err)
+
+	token, err := first.GenerateAccessToken(domain.UserClaims{UserID: "1", Email: "a@b.c"})
+	require.NoError(t, err)
+
+	_, err = second.ValidateAccessToken(token)
+	assert.Error(t, err)
+}
+
+func TestJWTTokenManager_ScopedTokenIsNotAccessToken(t *testing.T) {
+	manager, err := NewJWTTokenManager(config.JWTConfig{Secret: "secret"})
+	require.NoError(t, err)
+
+	token, err := manager.GenerateScopedToken(domain.UserClaims{UserID: "1", Email: "a@b.c"}, TokenTypeMFAPending, time.Minute)
+	require.NoError(t, err)
+
+	claims, err := manager.ValidateScopedToken(token, TokenTypeMFAPending)
+	require.NoError(t, err)
+	assert.Equal(t, "1", claims.UserID)
+
+	_, err = manager.ValidateAccessToken(token)
+	assert.Error(t, err)
+	_, err = manager.ValidateRefreshToken(token)
+	assert.Error(t, err)
+}
+
+func TestJWTTokenManager_IDTokenIsNotAccessToken(t *testing.T) {
+	manager, err := NewJWTTokenManager(config.JWTConfig{Secret: "secret"})
+	require.NoError(t, err)
+
+	token, err := manager.GenerateIDToken(domain.IDTokenClaims{
+		Issuer:   "https://auth.example.com",
+		Subject:  "1",
+		Audience: "client",
+		Nonce:    "n-0S6_WzA2Mj",
+		Email:    "a@b.c",
+	}, time.Minute)
+	require.NoError(t, err)
+
+	parsed, err := jwt.Parse(token, manager.keyFunc)
+	require.NoError(t, err)
+	claims := parsed.Claims.(jwt.MapClaims)
+	assert.Equal(t, "client", claims["aud"])
+	assert.Equal(t, "n-0S6_WzA2Mj", claims["nonce"])
+	assert.Equal(t, false, claims["email_verified"])
+
+	_, err = manager.ValidateAccessToken(token)
+	assert.Error(t, err)
+}
+
+func TestJWTTokenManager_ClientToken(t *testing.T) {
+	manager, err := NewJWTTokenManager(config.JWTConfig{Secret: "secret"})
+	require.NoError(t, err)
+
+	token, err := manager.GenerateClientToken(domain.ClientClaims{
+		ClientID: "billing",
+		Scopes:   []string{"invoices:read", "invoices:write"},
+	}, time.Minute)
+	require.NoError(t, err)
+
+	claims, err := manager.ValidateClientToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, "billing", claims.ClientID)
+	assert.Equal(t, []string{"invoices:read", "invoices:write"}, claims.Scopes)
+	assert.NotEmpty(t, claims.TokenID)
+
+	// Токен сервиса не принимается как токен пользователя, и наоборот
+	_, err = manager.ValidateAccessToken(token)
+	assert.Error(t, err)
+	accessToken, err := manager.GenerateAccessToken(domain.UserClaims{UserID: "1", Email: "a@b.c"})
+	require.NoError(t, err)
+	_, err = manager.ValidateClientToken(accessToken)
+	assert.Error(t, err)
+}
+
+func TestNewJWTTokenManager_AlgorithmMismatch(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	_, err = NewJWTTokenManager(config.JWTConfig{PrivateKey: encodePKCS8(t, ecKey), Algorithm: AlgRS256})
+	assert.Error(t, err)
+}
+
+func TestJWTTokenManager_KeyRotation(t *testing.T) {
+	dir := t.TempDir()
+	first, err := GenerateKey(dir, AlgES256, time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+
+	manager, err := NewJWTTokenManager(config.JWTConfig{KeysDir: dir})
+	require.NoError(t, err)
+
+	oldToken, err := manager.GenerateAccessToken(domain.UserClaims{UserID: "1", Email: "a@b.c"})
+	require.NoError(t, err)
+
+	// Новый ключ становится ключом подписи, старый продолжает приниматься
+	second, err := RotateKeys(dir, AlgEdDSA, 0, time.Hour)
+	require.NoError(t, err)
+	require.NoError(t, manager.Reload())
+	assert.Len(t, manager.JWKS().Keys, 2)
+
+	newToken, err := manager.GenerateAccessToken(domain.UserClaims{UserID: "1", Email: "a@b.c"})
+	require.NoError(t, err)
+	parsed, _, err := jwt.NewParser().ParseUnverified(newToken, jwt.MapClaims{})
+	require.NoError(t, err)
+	assert.Equal(t, second.ID, parsed.Header["kid"])
+
+	_, err = manager.ValidateAccessToken(oldToken)
+	assert.NoError(t, err)
+
+	// После вывода старого ключа выпущенные им токены отклоняются
+	require.NoError(t, RetireKey(dir, first.ID, time.Now()))
+	require.NoError(t, manager.Reload())
+
+	_, err = manager.ValidateAccessToken(oldToken)
+	assert.Error(t, err)
+	_, err = manager.ValidateAccessToken(newToken)
+	assert.NoError(t, err)
+	assert.Len(t, manager.JWKS().Keys, 1)
+}
@@ -0,0 +1,369 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ManifestFile имя файла манифеста в каталоге ключей
+const ManifestFile = "keyring.json"
+
+var (
+	ErrNoSigningKey = errors.New("no active signing key in key ring")
+	ErrKeyNotFound  = errors.New("key not found in key ring")
+)
+
+// KeyEntry запись о ключе в манифесте.
+// Ключ подписывает токены начиная с ActivatesAt и принимается при проверке до RetiresAt.
+// До активации ключ уже публикуется в JWKS, чтобы потребители успели его закэшировать.
+type KeyEntry struct {
+	ID          string     `json:"kid"`
+	Algorithm   string     `json:"alg"`
+	File        string     `json:"file"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ActivatesAt time.Time  `json:"activates_at"`
+	RetiresAt   *time.Time `json:"retires_at,omitempty"`
+}
+
+// Manifest содержимое keyring.json
+type Manifest struct {
+	Keys []KeyEntry `json:"keys"`
+}
+
+// ringKey ключ связки с окном действия
+type ringKey struct {
+	*SigningKey
+	activatesAt time.Time
+	retiresAt   *time.Time
+	verifyOnly  bool
+}
+
+func (k *ringKey) retired(now time.Time) bool {
+	return k.retiresAt != nil && !now.Before(*k.retiresAt)
+}
+
+func (k *ringKey) canSign(now time.Time) bool {
+	return !k.verifyOnly && !now.Before(k.activatesAt) && !k.retired(now)
+}
+
+// KeyRing набор ключей: один текущий ключ подписи и несколько ключей проверки, выбираемых по kid
+type KeyRing struct {
+	keys []*ringKey
+}
+
+// NewStaticKeyRing создает связку из одного постоянно активного ключа
+func NewStaticKeyRing(key *SigningKey) *KeyRing {
+	return &KeyRing{keys: []*ringKey{{SigningKey: key}}}
+}
+
+// AddVerificationKey добавляет ключ, который только проверяет подписи (например, прежний JWT_SECRET)
+func (r *KeyRing) AddVerificationKey(key *SigningKey) {
+	r.keys = append(r.keys, &ringKey{SigningKey: key, verifyOnly: true})
+}
+
+// SigningKey возвращает текущий ключ подписи: самый поздно активированный из действующих
+func (r *KeyRing) SigningKey(now time.Time) (*SigningKey, error) {
+	var current *ringKey
+	for _, k := range r.keys {
+		if !k.canSign(now) {
+			continue
+		}
+		if current == nil || k.activatesAt.After(current.activatesAt) {
+			current = k
+		}
+	}
+	if current == nil {
+		return nil, ErrNoSigningKey
+	}
+	return current.SigningKey, nil
+}
+
+// VerificationKeys возвращает ключи, которыми может быть подписан токен с данными kid и alg.
+// Для токенов без kid (выпущенных до ротации) возвращаются все действующие ключи этого алгоритма.
+func (r *KeyRing) VerificationKeys(kid, alg string, now time.Time) []*SigningKey {
+	var keys []*SigningKey
+	for _, k := range r.keys {
+		if k.retired(now) || k.Method.Alg() != alg {
+			continue
+		}
+		if kid != "" && k.ID != kid {
+			continue
+		}
+		keys = append(keys, k.SigningKey)
+	}
+	return keys
+}
+
+// PublicKeys возвращает JWKS из всех действующих и ожидающих активации асимметричных ключей
+func (r *KeyRing) PublicKeys(now time.Time) JWKS {
+	jwks := JWKS{Keys: []JWK{}}
+	for _, k := range r.keys {
+		if k.retired(now) || k.IsSymmetric() {
+			continue
+		}
+		// Тип ключа уже проверен при загрузке, поэтому ошибка здесь невозможна
+		if jwk, err := newJWK(k.SigningKey); err == nil {
+			jwks.Keys = append(jwks.Keys, *jwk)
+		}
+	}
+	return jwks
+}
+
+// LoadKeyRing загружает связку ключей из каталога с манифестом keyring.json
+func LoadKeyRing(dir string) (*KeyRing, error) {
+	manifest, err := ReadManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	ring := &KeyRing{}
+	for _, entry := range manifest.Keys {
+		key, err := loadKeyFile(dir, entry)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load key %s: %w", entry.ID, err)
+		}
+		ring.keys = append(ring.keys, &ringKey{
+			SigningKey:  key,
+			activatesAt: entry.ActivatesAt,
+			retiresAt:   entry.RetiresAt,
+		})
+	}
+	return ring, nil
+}
+
+// ReadManifest читает keyring.json; отсутствующий манифест считается пустым
+func ReadManifest(dir string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return &Manifest{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key manifest: %w", err)
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse key manifest: %w", err)
+	}
+	return &manifest, nil
+}
+
+// WriteManifest атомарно записывает keyring.json
+func WriteManifest(dir string, manifest *Manifest) error {
+	sort.Slice(manifest.Keys, func(i, j int) bool {
+		return manifest.Keys[i].ActivatesAt.Before(manifest.Keys[j].ActivatesAt)
+	})
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := filepath.Join(dir, ManifestFile+".tmp")
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write key manifest: %w", err)
+	}
+	return os.Rename(tmp, filepath.Join(dir, ManifestFile))
+}
+
+// loadKeyFile читает файл ключа: PEM для асимметричных алгоритмов, base64 секрет для HS256
+func loadKeyFile(dir string, entry KeyEntry) (*SigningKey, error) {
+	data, err := os.ReadFile(filepath.Join(dir, entry.File))
+	if err != nil {
+		return nil, err
+	}
+
+	if entry.Algorithm == AlgHS256 {
+		secret, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil {
+			return nil, fmt.Errorf("invalid HMAC secret encoding: %w", err)
+		}
+		return NewHMACKey(entry.ID, secret), nil
+	}
+
+	signer, err := ParsePrivateKeyPEM(data)
+	if err != nil {
+		return nil, err
+	}
+	return NewAsymmetricKey(entry.ID, entry.Algorithm, signer)
+}
+
+// GenerateKey создает новый ключ алгоритма alg, сохраняет его в каталог и добавляет в манифест
+func GenerateKey(dir, alg string, activatesAt time.Time) (*KeyEntry, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create keys directory: %w", err)
+	}
+
+	manifest, err := ReadManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	kid, err := newKeyID()
+	if err != nil {
+		return nil, err
+	}
+
+	data, ext, err := generateKeyMaterial(alg)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := KeyEntry{
+		ID:          kid,
+		Algorithm:   alg,
+		File:        kid + ext,
+		CreatedAt:   time.Now().UTC(),
+		ActivatesAt: activatesAt.UTC(),
+	}
+	if err := os.WriteFile(filepath.Join(dir, entry.File), data, 0o600); err != nil {
+		return nil, fmt.Errorf("failed to write key file: %w", err)
+	}
+
+	manifest.Keys = append(manifest.Keys, entry)
+	if err := WriteManifest(dir, manifest); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// RotateKeys генерирует новый ключ, который станет ключом подписи через activateAfter,
+// и назначает текущему ключу подписи вывод через retireAfter после активации нового.
+// Заранее подготовленные ключи, ожидающие активации, не затрагиваются.
+// retireAfter должен покрывать время жизни refresh токенов, иначе пользователи будут разлогинены.
+func RotateKeys(dir, alg string, activateAfter, retireAfter time.Duration) (*KeyEntry, error) {
+	manifest, err := ReadManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	activatesAt := now.Add(activateAfter)
+	retiresAt := activatesAt.Add(retireAfter)
+	if current := manifest.signingEntry(now); current != nil && current.RetiresAt == nil {
+		current.RetiresAt = &retiresAt
+		if err := WriteManifest(dir, manifest); err != nil {
+			return nil, err
+		}
+	}
+
+	return GenerateKey(dir, alg, activatesAt)
+}
+
+// signingEntry возвращает запись ключа, которым подписываются токены в момент now, как KeyRing.SigningKey
+func (m *Manifest) signingEntry(now time.Time) *KeyEntry {
+	var current *KeyEntry
+	for i := range m.Keys {
+		entry := &m.Keys[i]
+		if now.Before(entry.ActivatesAt) || (entry.RetiresAt != nil && !now.Before(*entry.RetiresAt)) {
+			continue
+		}
+		if current == nil || entry.ActivatesAt.After(current.ActivatesAt) {
+			current = entry
+		}
+	}
+	return current
+}
+
+// RetireKey назначает выводу ключа kid момент at
+func RetireKey(dir, kid string, at time.Time) error {
+	manifest, err := ReadManifest(dir)
+	if err != nil {
+		return err
+	}
+
+	for i := range manifest.Keys {
+		if manifest.Keys[i].ID == kid {
+			at = at.UTC()
+			manifest.Keys[i].RetiresAt = &at
+			return WriteManifest(dir, manifest)
+		}
+	}
+	return ErrKeyNotFound
+}
+
+// PruneKeys удаляет из каталога ключи, выведенные из оборота до момента now
+func PruneKeys(dir string, now time.Time) ([]KeyEntry, error) {
+	manifest, err := ReadManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var kept, pruned []KeyEntry
+	for _, entry := range manifest.Keys {
+		if entry.RetiresAt != nil && !now.Before(*entry.RetiresAt) {
+			pruned = append(pruned, entry)
+			continue
+		}
+		kept = append(kept, entry)
+	}
+
+	manifest.Keys = kept
+	if err := WriteManifest(dir, manifest); err != nil {
+		return nil, err
+	}
+	for _, entry := range pruned {
+		if err := os.Remove(filepath.Join(dir, entry.File)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return pruned, fmt.Errorf("failed to remove key file %s: %w", entry.File, err)
+		}
+	}
+	return pruned, nil
+}
+
+// newKeyID формирует читаемый kid вида 20060102-a1b2c3d4
+func newKeyID() (string, error) {
+	buf := make([]byte, 4)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return time.Now().UTC().Format("20060102") + "-" + hex.EncodeToString(buf), nil
+}
+
+// generateKeyMaterial создает ключ и возвращает его сериализованное представление и расширение файла
+func generateKeyMaterial(alg string) ([]byte, string, error) {
+	var (
+		key crypto.Signer
+		err error
+	)
+	switch alg {
+	case AlgHS256:
+		secret := make([]byte, 64)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, "", err
+		}
+		return []byte(base64.StdEncoding.EncodeToString(secret) + "\n"), ".key", nil
+	case AlgRS256:
+		key, err = rsa.GenerateKey(rand.Reader, 2048)
+	case AlgES256:
+		key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case AlgEdDSA:
+		_, key, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, "", fmt.Errorf("unsupported algorithm %q", alg)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate key: %w", err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, "", err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), ".pem", nil
+}
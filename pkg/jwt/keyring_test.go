@@ -0,0 +1,87 @@
+package jwt
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Alias1177/Auth/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRotateKeys_KeepsPendingKey(t *testing.T) {
+	dir := t.TempDir()
+	current, err := GenerateKey(dir, AlgES256, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	pending, err := GenerateKey(dir, AlgES256, time.Now().Add(24*time.Hour))
+	require.NoError(t, err)
+
+	rotated, err := RotateKeys(dir, AlgEdDSA, time.Minute, time.Hour)
+	require.NoError(t, err)
+
+	manifest, err := ReadManifest(dir)
+	require.NoError(t, err)
+	retires := map[string]*time.Time{}
+	for _, entry := range manifest.Keys {
+		retires[entry.ID] = entry.RetiresAt
+	}
+	require.Len(t, retires, 3)
+	require.NotNil(t, retires[current.ID], "current signing key is retired")
+	assert.Equal(t, rotated.ActivatesAt.Add(time.Hour), *retires[current.ID])
+	assert.Nil(t, retires[pending.ID], "pending key stays in rotation")
+	assert.Nil(t, retires[rotated.ID])
+}
+
+func TestNewJWTTokenManager_NoSigningKey(t *testing.T) {
+	tests := []struct {
+		name  string
+		cfg   func(dir string) config.JWTConfig
+		setup func(t *testing.T, dir string)
+	}{
+		{
+			name: "empty key ring with legacy secret",
+			cfg:  func(dir string) config.JWTConfig { return config.JWTConfig{KeysDir: dir, Secret: "legacy"} },
+		},
+		{
+			name: "only a pending key",
+			cfg:  func(dir string) config.JWTConfig { return config.JWTConfig{KeysDir: dir} },
+			setup: func(t *testing.T, dir string) {
+				_, err := GenerateKey(dir, AlgES256, time.Now().Add(time.Hour))
+				require.NoError(t, err)
+			},
+		},
+		{
+			name: "only retired keys",
+			cfg:  func(dir string) config.JWTConfig { return config.JWTConfig{KeysDir: dir, Secret: "legacy"} },
+			setup: func(t *testing.T, dir string) {
+				entry, err := GenerateKey(dir, AlgES256, time.Now().Add(-time.Hour))
+				require.NoError(t, err)
+				require.NoError(t, RetireKey(dir, entry.ID, time.Now().Add(-time.Minute)))
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if tt.setup != nil {
+				tt.setup(t, dir)
+			}
+			_, err := NewJWTTokenManager(tt.cfg(dir))
+			assert.ErrorIs(t, err, ErrNoSigningKey)
+		})
+	}
+}
+
+func TestJWTTokenManager_ReloadKeepsRingWithoutSigningKey(t *testing.T) {
+	dir := t.TempDir()
+	entry, err := GenerateKey(dir, AlgES256, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	manager, err := NewJWTTokenManager(config.JWTConfig{KeysDir: dir})
+	require.NoError(t, err)
+
+	require.NoError(t, RetireKey(dir, entry.ID, time.Now().Add(-time.Minute)))
+	assert.ErrorIs(t, manager.Reload(), ErrNoSigningKey)
+
+	_, err = manager.keyRing().SigningKey(time.Now())
+	assert.NoError(t, err, "previous ring stays in use")
+}
@@ -0,0 +1,182 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Alias1177/Auth/internal/config"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Поддерживаемые алгоритмы подписи
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgES256 = "ES256"
+	AlgEdDSA = "EdDSA"
+)
+
+// SigningKey ключ, которым подписываются и проверяются токены
+type SigningKey struct {
+	ID      string
+	Method  jwt.SigningMethod
+	Private interface{} // []byte для HMAC, crypto.Signer для асимметричных алгоритмов
+	Public  interface{} // []byte для HMAC, публичный ключ для асимметричных алгоритмов
+}
+
+// IsSymmetric сообщает, является ли ключ общим секретом (не публикуется в JWKS)
+func (k *SigningKey) IsSymmetric() bool {
+	_, ok := k.Method.(*jwt.SigningMethodHMAC)
+	return ok
+}
+
+// NewSigningKeyFromConfig создает ключ подписи на основе конфигурации.
+// Если задан приватный ключ (JWT_PRIVATE_KEY или JWT_PRIVATE_KEY_PATH), используется
+// асимметричная подпись, иначе HS256 с JWT_SECRET.
+func NewSigningKeyFromConfig(cfg config.JWTConfig) (*SigningKey, error) {
+	pemData, err := readPrivateKeyPEM(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if pemData == nil {
+		if cfg.Algorithm != "" && cfg.Algorithm != AlgHS256 {
+			return nil, fmt.Errorf("algorithm %s requires JWT_PRIVATE_KEY or JWT_PRIVATE_KEY_PATH", cfg.Algorithm)
+		}
+		if cfg.Secret == "" {
+			return nil, errors.New("JWT_SECRET is required for HS256 signing")
+		}
+		return NewHMACKey(cfg.KeyID, []byte(cfg.Secret)), nil
+	}
+
+	signer, err := ParsePrivateKeyPEM(pemData)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewAsymmetricKey(cfg.KeyID, cfg.Algorithm, signer)
+}
+
+// NewHMACKey создает симметричный ключ HS256
+func NewHMACKey(kid string, secret []byte) *SigningKey {
+	return &SigningKey{
+		ID:      kid,
+		Method:  jwt.SigningMethodHS256,
+		Private: secret,
+		Public:  secret,
+	}
+}
+
+// NewAsymmetricKey создает ключ RS256/ES256/EdDSA. Если alg пуст, алгоритм
+// определяется по типу ключа; если kid пуст, используется JWK thumbprint (RFC 7638).
+func NewAsymmetricKey(kid, alg string, signer crypto.Signer) (*SigningKey, error) {
+	method, err := signingMethodForKey(signer, alg)
+	if err != nil {
+		return nil, err
+	}
+
+	key := &SigningKey{
+		ID:      kid,
+		Method:  method,
+		Private: signer,
+		Public:  signer.Public(),
+	}
+
+	if key.ID == "" {
+		jwk, err := newJWK(key)
+		if err != nil {
+			return nil, err
+		}
+		key.ID, err = jwk.Thumbprint()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return key, nil
+}
+
+// readPrivateKeyPEM читает PEM из переменной окружения или файла.
+// Возвращает nil, если приватный ключ не сконфигурирован.
+func readPrivateKeyPEM(cfg config.JWTConfig) ([]byte, error) {
+	if cfg.PrivateKey != "" {
+		// В .env файлах переводы строк обычно экранированы
+		return []byte(strings.ReplaceAll(cfg.PrivateKey, `\n`, "\n")), nil
+	}
+	if cfg.PrivateKeyPath != "" {
+		data, err := os.ReadFile(cfg.PrivateKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read private key file: %w", err)
+		}
+		return data, nil
+	}
+	return nil, nil
+}
+
+// ParsePrivateKeyPEM разбирает приватный ключ в форматах PKCS#8, PKCS#1 и SEC 1
+func ParsePrivateKeyPEM(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("failed to decode PEM private key")
+	}
+
+	var (
+		key interface{}
+		err error
+	)
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block type %q", block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("private key does not support signing")
+	}
+	return signer, nil
+}
+
+// signingMethodForKey подбирает метод подписи по типу ключа и проверяет совместимость с alg
+func signingMethodForKey(signer crypto.Signer, alg string) (jwt.SigningMethod, error) {
+	var expected string
+	switch k := signer.(type) {
+	case *rsa.PrivateKey:
+		if k.N.BitLen() < 2048 {
+			return nil, errors.New("RSA key must be at least 2048 bits")
+		}
+		expected = AlgRS256
+	case *ecdsa.PrivateKey:
+		if k.Curve != elliptic.P256() {
+			return nil, errors.New("ES256 requires a P-256 key")
+		}
+		expected = AlgES256
+	case ed25519.PrivateKey:
+		expected = AlgEdDSA
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T", signer)
+	}
+
+	if alg != "" && alg != expected {
+		return nil, fmt.Errorf("algorithm %s does not match private key type (expected %s)", alg, expected)
+	}
+
+	return jwt.GetSigningMethod(expected), nil
+}
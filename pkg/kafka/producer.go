@@ -22,6 +22,52 @@ type RegistrationRequest struct {
 	Username string `json:"username,omitempty"`
 }
 
+// EmailVerificationRequest структура для письма подтверждения почты
+type EmailVerificationRequest struct {
+	Email    string `json:"email"`
+	Username string `json:"username,omitempty"`
+	Token    string `json:"verification_token"`
+}
+
+// AccountUnlockRequest структура для письма со ссылкой разблокировки входа
+type AccountUnlockRequest struct {
+	Email       string    `json:"email"`
+	Username    string    `json:"username,omitempty"`
+	Token       string    `json:"unlock_token"`
+	LockedUntil time.Time `json:"locked_until"`
+}
+
+// PasswordChangedEvent структура для уведомления о смене пароля
+type PasswordChangedEvent struct {
+	Email     string    `json:"email"`
+	Username  string    `json:"username,omitempty"`
+	Event     string    `json:"event"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+// RecoveryCodesRegeneratedEvent структура для уведомления о выпуске нового набора кодов восстановления
+type RecoveryCodesRegeneratedEvent struct {
+	Email         string    `json:"email"`
+	Username      string    `json:"username,omitempty"`
+	Event         string    `json:"event"`
+	RegeneratedAt time.Time `json:"regenerated_at"`
+}
+
+// EmailChangeConfirmationRequest структура для письма с подтверждением нового адреса
+type EmailChangeConfirmationRequest struct {
+	Email    string `json:"email"`
+	Username string `json:"username,omitempty"`
+	Token    string `json:"email_change_token"`
+}
+
+// EmailChangeNoticeRequest структура для уведомления старого адреса о смене почты со ссылкой отмены
+type EmailChangeNoticeRequest struct {
+	Email       string `json:"email"`
+	Username    string `json:"username,omitempty"`
+	NewEmail    string `json:"new_email"`
+	CancelToken string `json:"cancel_token"`
+}
+
 // Producer представляет собой клиент для отправки сообщений в Kafka
 type Producer struct {
 	writer *kafka.Writer
@@ -103,6 +149,143 @@ func (p *Producer) SendRegistrationRequest(ctx context.Context, email, username
 	return nil
 }
 
+// SendEmailVerification отправляет запрос на письмо с токеном подтверждения почты
+func (p *Producer) SendEmailVerification(ctx context.Context, email, username, token string) error {
+	request := EmailVerificationRequest{
+		Email:    email,
+		Username: username,
+		Token:    token,
+	}
+
+	data, err := json.Marshal(request)
+	if err != nil {
+		p.logger.Errorw("Failed to marshal email verification request", "error", err)
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	err = p.writer.WriteMessages(ctx, kafka.Message{
+		Value: data,
+		Key:   []byte(email),
+	})
+
+	if err != nil {
+		p.logger.Errorw("Failed to send email verification request", "error", err)
+		return fmt.Errorf("failed to send message to Kafka: %w", err)
+	}
+
+	p.logger.Infow("Email verification request sent to Kafka", "email", email)
+	return nil
+}
+
+// SendAccountUnlock отправляет запрос на письмо о блокировке входа со ссылкой разблокировки
+func (p *Producer) SendAccountUnlock(ctx context.Context, email, username, token string, lockedUntil time.Time) error {
+	request := AccountUnlockRequest{
+		Email:       email,
+		Username:    username,
+		Token:       token,
+		LockedUntil: lockedUntil,
+	}
+
+	data, err := json.Marshal(request)
+	if err != nil {
+		p.logger.Errorw("Failed to marshal account unlock request", "error", err)
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	err = p.writer.WriteMessages(ctx, kafka.Message{
+		Value: data,
+		Key:   []byte(email),
+	})
+
+	if err != nil {
+		p.logger.Errorw("Failed to send account unlock request", "error", err)
+		return fmt.Errorf("failed to send message to Kafka: %w", err)
+	}
+
+	p.logger.Infow("Account unlock request sent to Kafka", "email", email)
+	return nil
+}
+
+// SendPasswordChanged отправляет уведомление о смене пароля, чтобы пользователь узнал о чужой смене
+func (p *Producer) SendPasswordChanged(ctx context.Context, email, username string, changedAt time.Time) error {
+	request := PasswordChangedEvent{
+		Email:     email,
+		Username:  username,
+		Event:     "password_changed",
+		ChangedAt: changedAt,
+	}
+
+	data, err := json.Marshal(request)
+	if err != nil {
+		p.logger.Errorw("Failed to marshal password changed event", "error", err)
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	err = p.writer.WriteMessages(ctx, kafka.Message{
+		Value: data,
+		Key:   []byte(email),
+	})
+
+	if err != nil {
+		p.logger.Errorw("Failed to send password changed event", "error", err)
+		return fmt.Errorf("failed to send message to Kafka: %w", err)
+	}
+
+	p.logger.Infow("Password changed event sent to Kafka", "email", email)
+	return nil
+}
+
+// SendEmailChangeConfirmation отправляет запрос на письмо с подтверждением на новый адрес
+func (p *Producer) SendEmailChangeConfirmation(ctx context.Context, newEmail, username, token string) error {
+	return p.send(ctx, newEmail, "email change confirmation", EmailChangeConfirmationRequest{
+		Email:    newEmail,
+		Username: username,
+		Token:    token,
+	})
+}
+
+// SendEmailChangeNotice отправляет на старый адрес уведомление о смене почты со ссылкой отмены
+func (p *Producer) SendEmailChangeNotice(ctx context.Context, oldEmail, username, newEmail, cancelToken string) error {
+	return p.send(ctx, oldEmail, "email change notice", EmailChangeNoticeRequest{
+		Email:       oldEmail,
+		Username:    username,
+		NewEmail:    newEmail,
+		CancelToken: cancelToken,
+	})
+}
+
+// SendRecoveryCodesRegenerated отправляет уведомление о новом наборе кодов восстановления,
+// чтобы пользователь узнал о чужом выпуске кодов
+func (p *Producer) SendRecoveryCodesRegenerated(ctx context.Context, email, username string, regeneratedAt time.Time) error {
+	return p.send(ctx, email, "recovery codes regenerated", RecoveryCodesRegeneratedEvent{
+		Email:         email,
+		Username:      username,
+		Event:         "recovery_codes_regenerated",
+		RegeneratedAt: regeneratedAt,
+	})
+}
+
+// send сериализует запрос и отправляет его в Kafka с ключом email
+func (p *Producer) send(ctx context.Context, email, kind string, request interface{}) error {
+	data, err := json.Marshal(request)
+	if err != nil {
+		p.logger.Errorw("Failed to marshal request", "kind", kind, "error", err)
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	err = p.writer.WriteMessages(ctx, kafka.Message{
+		Value: data,
+		Key:   []byte(email),
+	})
+	if err != nil {
+		p.logger.Errorw("Failed to send request", "kind", kind, "error", err)
+		return fmt.Errorf("failed to send message to Kafka: %w", err)
+	}
+
+	p.logger.Infow("Request sent to Kafka", "kind", kind, "email", email)
+	return nil
+}
+
 // SendEmailRegistration отправляет email адрес пользователя в Kafka (для обратной совместимости)
 // Теперь отправляем только email как строку
 func (p *Producer) SendEmailRegistration(ctx context.Context, email, username string) error {
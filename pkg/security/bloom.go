@@ -0,0 +1,135 @@
+package crypto
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+// Формат файла фильтра: "PWBF", версия, число хеш-функций k, размер m в битах (big endian), затем биты
+var bloomMagic = [4]byte{'P', 'W', 'B', 'F'}
+
+const bloomVersion = 1
+
+// ErrInvalidBloomFilter файл не является фильтром Блума утекших паролей
+var ErrInvalidBloomFilter = errors.New("invalid breach bloom filter")
+
+// BloomFilter компактная база утекших паролей: фильтр Блума по SHA-1.
+// Ложные срабатывания возможны с заданной при построении вероятностью, пропусков нет.
+type BloomFilter struct {
+	bits []byte
+	m    uint64
+	k    uint8
+}
+
+// NewBloomFilter создает пустой фильтр на n хешей с вероятностью ложного срабатывания fpRate
+func NewBloomFilter(n uint64, fpRate float64) *BloomFilter {
+	if n == 0 {
+		n = 1
+	}
+	if fpRate <= 0 || fpRate >= 1 {
+		fpRate = 0.001
+	}
+	m := uint64(math.Ceil(-float64(n) * math.Log(fpRate) / (math.Ln2 * math.Ln2)))
+	k := math.Round(float64(m) / float64(n) * math.Ln2)
+	k = math.Max(1, math.Min(k, 30))
+	return &BloomFilter{bits: make([]byte, (m+7)/8), m: m, k: uint8(k)}
+}
+
+// Add добавляет SHA-1 пароля
+func (f *BloomFilter) Add(digest [sha1.Size]byte) {
+	h1, h2 := bloomHashes(digest)
+	for i := uint64(0); i < uint64(f.k); i++ {
+		pos := (h1 + i*h2) % f.m
+		f.bits[pos/8] |= 1 << (pos % 8)
+	}
+}
+
+// AddHex добавляет SHA-1 в шестнадцатеричной записи, как в выгрузках Have I Been Pwned
+func (f *BloomFilter) AddHex(hash string) error {
+	var digest [sha1.Size]byte
+	if len(hash) != hex.EncodedLen(sha1.Size) {
+		return fmt.Errorf("invalid sha1 hash %q", hash)
+	}
+	if _, err := hex.Decode(digest[:], []byte(hash)); err != nil {
+		return fmt.Errorf("invalid sha1 hash %q: %w", hash, err)
+	}
+	f.Add(digest)
+	return nil
+}
+
+// Contains сообщает, что SHA-1, вероятно, был добавлен в фильтр
+func (f *BloomFilter) Contains(digest [sha1.Size]byte) bool {
+	h1, h2 := bloomHashes(digest)
+	for i := uint64(0); i < uint64(f.k); i++ {
+		pos := (h1 + i*h2) % f.m
+		if f.bits[pos/8]&(1<<(pos%8)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// IsBreached реализует BreachChecker
+func (f *BloomFilter) IsBreached(_ context.Context, password string) (bool, error) {
+	return f.Contains(sha1.Sum([]byte(password))), nil
+}
+
+// WriteTo записывает фильтр в формате, который читает ReadBloomFilter
+func (f *BloomFilter) WriteTo(w io.Writer) (int64, error) {
+	header := make([]byte, 0, 14)
+	header = append(header, bloomMagic[:]...)
+	header = append(header, bloomVersion, f.k)
+	header = binary.BigEndian.AppendUint64(header, f.m)
+
+	n, err := w.Write(header)
+	if err != nil {
+		return int64(n), err
+	}
+	written, err := w.Write(f.bits)
+	return int64(n + written), err
+}
+
+// ReadBloomFilter читает фильтр, записанный WriteTo
+func ReadBloomFilter(r io.Reader) (*BloomFilter, error) {
+	header := make([]byte, 14)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBloomFilter, err)
+	}
+	if [4]byte(header[:4]) != bloomMagic || header[4] != bloomVersion {
+		return nil, ErrInvalidBloomFilter
+	}
+
+	f := &BloomFilter{k: header[5], m: binary.BigEndian.Uint64(header[6:])}
+	if f.k == 0 || f.m == 0 {
+		return nil, ErrInvalidBloomFilter
+	}
+	f.bits = make([]byte, (f.m+7)/8)
+	if _, err := io.ReadFull(r, f.bits); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBloomFilter, err)
+	}
+	return f, nil
+}
+
+// LoadBloomFilter загружает фильтр из файла
+func LoadBloomFilter(path string) (*BloomFilter, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open breach bloom filter: %w", err)
+	}
+	defer file.Close()
+	return ReadBloomFilter(bufio.NewReader(file))
+}
+
+// bloomHashes делит SHA-1 на две независимые половины для двойного хеширования;
+// h2 нечетный, чтобы шаг не вырождался в ноль
+func bloomHashes(digest [sha1.Size]byte) (uint64, uint64) {
+	return binary.BigEndian.Uint64(digest[0:8]), binary.BigEndian.Uint64(digest[8:16]) | 1
+}
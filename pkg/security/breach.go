@@ -0,0 +1,141 @@
+package crypto
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Длина префикса SHA-1 в запросах диапазона (k-anonymity), как в Have I Been Pwned
+const breachPrefixLength = 5
+
+// BreachChecker проверка пароля по базе утекших паролей
+type BreachChecker interface {
+	// IsBreached сообщает, встречается ли пароль в базе утечек
+	IsBreached(ctx context.Context, password string) (bool, error)
+}
+
+// MultiBreachChecker опрашивает источники по очереди; пароль считается утекшим, если его нашел любой из них
+type MultiBreachChecker []BreachChecker
+
+// IsBreached реализует BreachChecker
+func (m MultiBreachChecker) IsBreached(ctx context.Context, password string) (bool, error) {
+	for _, checker := range m {
+		breached, err := checker.IsBreached(ctx, password)
+		if err != nil {
+			return false, err
+		}
+		if breached {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// sha1Hex возвращает SHA-1 пароля в верхнем регистре, как в файлах диапазонов
+func sha1Hex(password string) string {
+	sum := sha1.Sum([]byte(password))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+// containsSuffix ищет суффикс хеша в ответе диапазона: строки "SUFFIX:COUNT".
+// Строки с нулевым счетчиком — заполнение ответа (Add-Padding) и совпадением не считаются.
+func containsSuffix(r io.Reader, suffix string) (bool, error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		hash, count, _ := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if !strings.EqualFold(hash, suffix) {
+			continue
+		}
+		if strings.TrimSpace(count) == "0" {
+			return false, nil
+		}
+		return true, nil
+	}
+	return false, scanner.Err()
+}
+
+// RangeDirChecker проверка по локальной копии файлов диапазонов: каталог с файлами,
+// названными пятью первыми символами SHA-1 (ABCDE или ABCDE.txt), в формате ответа range API
+type RangeDirChecker struct {
+	dir string
+}
+
+// NewRangeDirChecker создает проверку по каталогу файлов диапазонов
+func NewRangeDirChecker(dir string) (*RangeDirChecker, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open breach corpus: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("breach corpus %s is not a directory", dir)
+	}
+	return &RangeDirChecker{dir: dir}, nil
+}
+
+// IsBreached реализует BreachChecker; отсутствие файла диапазона означает, что утечек с таким префиксом нет
+func (c *RangeDirChecker) IsBreached(_ context.Context, password string) (bool, error) {
+	hash := sha1Hex(password)
+	prefix, suffix := hash[:breachPrefixLength], hash[breachPrefixLength:]
+
+	for _, name := range []string{prefix, prefix + ".txt", strings.ToLower(prefix), strings.ToLower(prefix) + ".txt"} {
+		f, err := os.Open(filepath.Join(c.dir, name))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return false, fmt.Errorf("failed to open breach range file: %w", err)
+		}
+		defer f.Close()
+		return containsSuffix(f, suffix)
+	}
+	return false, nil
+}
+
+// RangeClient клиент range API с k-anonymity: на сервер уходят только пять первых символов SHA-1.
+// Совместим с api.pwnedpasswords.com и его внутренними зеркалами.
+type RangeClient struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewRangeClient создает клиент range API; запрос отправляется на {baseURL}/range/{prefix}
+func NewRangeClient(baseURL string, timeout time.Duration) *RangeClient {
+	return &RangeClient{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+// IsBreached реализует BreachChecker
+func (c *RangeClient) IsBreached(ctx context.Context, password string) (bool, error) {
+	hash := sha1Hex(password)
+	prefix, suffix := hash[:breachPrefixLength], hash[breachPrefixLength:]
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/range/"+prefix, nil)
+	if err != nil {
+		return false, err
+	}
+	// Заполнение выравнивает размер ответа, чтобы по нему нельзя было судить о префиксе
+	req.Header.Set("Add-Padding", "true")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("breach range request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("breach range request failed: status %d", resp.StatusCode)
+	}
+	return containsSuffix(resp.Body, suffix)
+}
@@ -0,0 +1,88 @@
+package crypto
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// "password" → 5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8
+const (
+	breachedPrefix = "5BAA6"
+	breachedRange  = "003D68EB55068C33ACE09247EE4C639306B:3\r\n1E4C9B93F3F0682250B6CF8331B7EE68FD8:10434004\r\n"
+)
+
+func TestRangeDirChecker(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, breachedPrefix+".txt"), []byte(breachedRange), 0o600))
+
+	checker, err := NewRangeDirChecker(dir)
+	require.NoError(t, err)
+
+	breached, err := checker.IsBreached(context.Background(), "password")
+	require.NoError(t, err)
+	assert.True(t, breached)
+
+	// Нет файла диапазона — нет утечки
+	breached, err = checker.IsBreached(context.Background(), "vK9#unique-passphrase")
+	require.NoError(t, err)
+	assert.False(t, breached)
+}
+
+func TestRangeClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "true", r.Header.Get("Add-Padding"))
+		if r.URL.Path != "/range/"+breachedPrefix {
+			// Заполнение с нулевым счетчиком не считается утечкой
+			w.Write([]byte("1E4C9B93F3F0682250B6CF8331B7EE68FD8:0\r\n"))
+			return
+		}
+		w.Write([]byte(breachedRange))
+	}))
+	defer server.Close()
+
+	client := NewRangeClient(server.URL+"/", time.Second)
+
+	breached, err := client.IsBreached(context.Background(), "password")
+	require.NoError(t, err)
+	assert.True(t, breached)
+
+	breached, err = client.IsBreached(context.Background(), "vK9#unique-passphrase")
+	require.NoError(t, err)
+	assert.False(t, breached)
+}
+
+func TestBloomFilter_RoundTrip(t *testing.T) {
+	filter := NewBloomFilter(100, 0.001)
+	require.NoError(t, filter.AddHex("5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8"))
+	filter.Add(sha1.Sum([]byte("qwerty123")))
+	assert.Error(t, filter.AddHex("not-a-hash"))
+
+	var buf bytes.Buffer
+	_, err := filter.WriteTo(&buf)
+	require.NoError(t, err)
+
+	loaded, err := ReadBloomFilter(&buf)
+	require.NoError(t, err)
+
+	for _, password := range []string{"password", "qwerty123"} {
+		breached, err := loaded.IsBreached(context.Background(), password)
+		require.NoError(t, err)
+		assert.True(t, breached, password)
+	}
+	breached, err := loaded.IsBreached(context.Background(), "vK9#unique-passphrase")
+	require.NoError(t, err)
+	assert.False(t, breached)
+
+	_, err = ReadBloomFilter(bytes.NewReader([]byte("not a filter at all")))
+	assert.ErrorIs(t, err, ErrInvalidBloomFilter)
+}
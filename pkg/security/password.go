@@ -1,19 +1,128 @@
 package crypto
 
 import (
-	"golang.org/x/crypto/bcrypt"
+	"errors"
+	"strings"
+	"sync"
 )
 
-// HashPassword хеширует пароль с использованием bcrypt
-func HashPassword(password string) (string, error) {
-	hashedBytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
-		return "", err
+// ErrMismatchedPassword пароль не соответствует хешу
+var ErrMismatchedPassword = errors.New("password does not match hash")
+
+// ErrUnknownHashFormat хеш записан в неизвестном формате
+var ErrUnknownHashFormat = errors.New("unknown password hash format")
+
+// Hasher алгоритм хеширования паролей
+type Hasher interface {
+	// Hash возвращает хеш пароля со всеми параметрами в строке
+	Hash(password string) (string, error)
+	// Verify проверяет пароль; ErrMismatchedPassword, если пароль не подходит
+	Verify(hash, password string) error
+	// Supports сообщает, записан ли хеш этим алгоритмом
+	Supports(hash string) bool
+	// NeedsRehash сообщает, что хеш записан этим алгоритмом, но с устаревшими параметрами
+	NeedsRehash(hash string) bool
+}
+
+// PasswordHasher хеширует новые пароли основным алгоритмом и проверяет хеши всех известных алгоритмов
+type PasswordHasher struct {
+	primary Hasher
+	legacy  []Hasher
+
+	dummyOnce sync.Once
+	dummyHash string
+}
+
+// NewPasswordHasher создает хешер с основным алгоритмом primary и алгоритмами legacy для старых хешей
+func NewPasswordHasher(primary Hasher, legacy ...Hasher) *PasswordHasher {
+	return &PasswordHasher{primary: primary, legacy: legacy}
+}
+
+// Hash хеширует пароль основным алгоритмом
+func (h *PasswordHasher) Hash(password string) (string, error) {
+	return h.primary.Hash(password)
+}
+
+// Verify проверяет пароль алгоритмом, которым записан хеш
+func (h *PasswordHasher) Verify(hash, password string) error {
+	if h.primary.Supports(hash) {
+		return h.primary.Verify(hash, password)
 	}
-	return string(hashedBytes), nil
+	for _, hasher := range h.legacy {
+		if hasher.Supports(hash) {
+			return hasher.Verify(hash, password)
+		}
+	}
+	return ErrUnknownHashFormat
+}
+
+// VerifyDummy проверяет пароль по заранее посчитанному хешу основного алгоритма и всегда возвращает
+// ErrMismatchedPassword. Вход с неизвестным адресом тратит столько же времени, сколько с известным.
+func (h *PasswordHasher) VerifyDummy(password string) error {
+	h.dummyOnce.Do(func() {
+		h.dummyHash, _ = h.primary.Hash("dummy password for constant-time login")
+	})
+	if h.dummyHash != "" {
+		_ = h.primary.Verify(h.dummyHash, password)
+	}
+	return ErrMismatchedPassword
+}
+
+// NeedsRehash сообщает, что хеш записан другим алгоритмом или с устаревшими параметрами
+func (h *PasswordHasher) NeedsRehash(hash string) bool {
+	return !h.primary.Supports(hash) || h.primary.NeedsRehash(hash)
 }
 
-// VerifyPassword проверяет соответствие пароля и хеша
+var (
+	defaultHasherMu sync.RWMutex
+	defaultHasher   = NewPasswordHasher(NewArgon2idHasher(DefaultArgon2idParams), NewBcryptHasher(DefaultBcryptCost))
+)
+
+// SetDefaultHasher заменяет хешер, используемый функциями пакета (настраивается при старте сервиса)
+func SetDefaultHasher(h *PasswordHasher) {
+	defaultHasherMu.Lock()
+	defer defaultHasherMu.Unlock()
+	defaultHasher = h
+}
+
+func getDefaultHasher() *PasswordHasher {
+	defaultHasherMu.RLock()
+	defer defaultHasherMu.RUnlock()
+	return defaultHasher
+}
+
+// HashPassword хеширует пароль основным алгоритмом (по умолчанию Argon2id)
+func HashPassword(password string) (string, error) {
+	return getDefaultHasher().Hash(password)
+}
+
+// VerifyPassword проверяет соответствие пароля и хеша (Argon2id или bcrypt)
 func VerifyPassword(hashedPassword, password string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+	return getDefaultHasher().Verify(hashedPassword, password)
+}
+
+// VerifyDummyPassword проверяет пароль по фиктивному хешу, когда настоящего нет (неизвестный адрес)
+func VerifyDummyPassword(password string) error {
+	return getDefaultHasher().VerifyDummy(password)
+}
+
+// NeedsRehash сообщает, что хеш пароля нужно пересчитать текущим алгоритмом и параметрами
+func NeedsRehash(hashedPassword string) bool {
+	return getDefaultHasher().NeedsRehash(hashedPassword)
+}
+
+// NewHasherFromConfig создает хешер по имени основного алгоритма ("argon2id" или "bcrypt").
+// Хеши второго алгоритма продолжают проверяться и пересчитываются при входе.
+func NewHasherFromConfig(algorithm string, argon Argon2idParams, bcryptCost int) (*PasswordHasher, error) {
+	argonHasher := NewArgon2idHasher(argon)
+	bcryptHasher := NewBcryptHasher(bcryptCost)
+
+	switch strings.ToLower(algorithm) {
+	case "", "argon2id":
+		return NewPasswordHasher(argonHasher, bcryptHasher), nil
+	case "bcrypt":
+		return NewPasswordHasher(bcryptHasher, argonHasher), nil
+	default:
+		return nil, errors.New("unsupported password hash algorithm: " + algorithm)
+	}
 }
@@ -0,0 +1,91 @@
+package crypto
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testArgon2idParams облегченные параметры, чтобы тесты выполнялись быстро
+var testArgon2idParams = Argon2idParams{Memory: 1024, Time: 1, Parallelism: 1}
+
+func TestArgon2idHasher(t *testing.T) {
+	hasher := NewArgon2idHasher(testArgon2idParams)
+
+	hash, err := hasher.Hash("correct horse")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$"), hash)
+
+	assert.NoError(t, hasher.Verify(hash, "correct horse"))
+	assert.ErrorIs(t, hasher.Verify(hash, "wrong horse"), ErrMismatchedPassword)
+	assert.False(t, hasher.NeedsRehash(hash))
+
+	// Соль случайная
+	other, err := hasher.Hash("correct horse")
+	require.NoError(t, err)
+	assert.NotEqual(t, hash, other)
+
+	stronger := NewArgon2idHasher(Argon2idParams{Memory: 2048, Time: 1, Parallelism: 1})
+	assert.True(t, stronger.NeedsRehash(hash))
+	assert.NoError(t, stronger.Verify(hash, "correct horse"), "old parameters are still verified")
+}
+
+func TestPasswordHasher(t *testing.T) {
+	hasher := NewPasswordHasher(NewArgon2idHasher(testArgon2idParams), NewBcryptHasher(bcrypt.MinCost))
+
+	legacy, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+	current, err := hasher.Hash("secret")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		hash        string
+		password    string
+		wantErr     error
+		needsRehash bool
+	}{
+		{name: "argon2id", hash: current, password: "secret"},
+		{name: "argon2id wrong password", hash: current, password: "other", wantErr: ErrMismatchedPassword},
+		{name: "legacy bcrypt", hash: string(legacy), password: "secret", needsRehash: true},
+		{name: "legacy bcrypt wrong password", hash: string(legacy), password: "other", wantErr: ErrMismatchedPassword, needsRehash: true},
+		{name: "unknown format", hash: "plain", password: "plain", wantErr: ErrUnknownHashFormat, needsRehash: true},
+		{name: "malformed argon2id", hash: "$argon2id$v=19$m=1,t=1$x$y", password: "secret", wantErr: ErrUnknownHashFormat, needsRehash: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := hasher.Verify(tt.hash, tt.password)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.needsRehash, hasher.NeedsRehash(tt.hash))
+		})
+	}
+}
+
+func TestPasswordHasher_VerifyDummy(t *testing.T) {
+	primary := NewArgon2idHasher(testArgon2idParams)
+	hasher := NewPasswordHasher(primary, NewBcryptHasher(bcrypt.MinCost))
+
+	assert.ErrorIs(t, hasher.VerifyDummy("secret"), ErrMismatchedPassword)
+	assert.ErrorIs(t, hasher.VerifyDummy(""), ErrMismatchedPassword)
+	// Фиктивный хеш считается основным алгоритмом, чтобы проверка длилась столько же, сколько настоящая
+	assert.True(t, primary.Supports(hasher.dummyHash))
+	assert.False(t, primary.NeedsRehash(hasher.dummyHash))
+}
+
+func TestNewHasherFromConfig(t *testing.T) {
+	hasher, err := NewHasherFromConfig("bcrypt", testArgon2idParams, bcrypt.MinCost)
+	require.NoError(t, err)
+	hash, err := hasher.Hash("secret")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hash, "$2a$"))
+
+	_, err = NewHasherFromConfig("md5", testArgon2idParams, bcrypt.MinCost)
+	assert.Error(t, err)
+}
@@ -0,0 +1,60 @@
+package totp
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCode_RFC6238Vectors(t *testing.T) {
+	// Секрет из приложения B RFC 6238 ("12345678901234567890"), последние 6 цифр ожидаемых значений
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{unix: 59, code: "287082"},
+		{unix: 1111111109, code: "081804"},
+		{unix: 1111111111, code: "050471"},
+		{unix: 1234567890, code: "005924"},
+		{unix: 2000000000, code: "279037"},
+	}
+
+	for _, tt := range tests {
+		code, err := Code(secret, Step(time.Unix(tt.unix, 0)))
+		require.NoError(t, err)
+		assert.Equal(t, tt.code, code, "time %d", tt.unix)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := GenerateSecret()
+	require.NoError(t, err)
+	now := time.Now()
+
+	current, err := Code(secret, Step(now))
+	require.NoError(t, err)
+	step, err := Validate(secret, current, now)
+	require.NoError(t, err)
+	assert.Equal(t, Step(now), step)
+
+	// Код предыдущего шага принимается из-за расхождения часов, более старый - нет
+	previous, err := Code(secret, Step(now)-1)
+	require.NoError(t, err)
+	_, err = Validate(secret, previous, now)
+	assert.NoError(t, err)
+
+	stale, err := Code(secret, Step(now)-3)
+	require.NoError(t, err)
+	if stale != current && stale != previous {
+		_, err = Validate(secret, stale, now)
+		assert.ErrorIs(t, err, ErrInvalidCode)
+	}
+
+	_, err = Validate(secret, "12345", now)
+	assert.ErrorIs(t, err, ErrInvalidCode)
+}